@@ -0,0 +1,60 @@
+package catalog
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// SeedProducts are the products referenced by the seeded cart_items, for local development.
+var SeedProducts = []model.Product{
+	{ID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440001"), Name: "Wireless Mouse", SKU: "SKU-0001", WeightGrams: 150, Price: 15.50, Available: true},
+	{ID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440002"), Name: "Mechanical Keyboard", SKU: "SKU-0002", WeightGrams: 1100, Price: 75.00, Available: true},
+	{ID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440003"), Name: "USB-C Cable", SKU: "SKU-0003", WeightGrams: 50, Price: 5.25, Available: true},
+	{ID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440005"), Name: "Laptop Stand", SKU: "SKU-0005", WeightGrams: 1800, Price: 32.00, Available: true},
+	{ID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440006"), Name: "Webcam", SKU: "SKU-0006", WeightGrams: 300, Price: 48.90, Available: false},
+}
+
+// fake is an in-memory product catalog for tests and local development.
+type fake struct {
+	mu       sync.RWMutex
+	products map[uuid.UUID]model.Product
+}
+
+// NewFake is a constructor function that returns a fake catalog holding the given products.
+func NewFake(products ...model.Product) *fake {
+	f := &fake{
+		products: make(map[uuid.UUID]model.Product, len(products)),
+	}
+	for _, p := range products {
+		f.products[p.ID] = p
+	}
+
+	return f
+}
+
+// SetProduct is a method that adds or replaces a product in the catalog.
+func (f *fake) SetProduct(product model.Product) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.products[product.ID] = product
+}
+
+// GetProducts is a method that returns the known products among productIDs, keyed by ID.
+// Unknown IDs are left out of the result.
+func (f *fake) GetProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.Product, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	result := make(map[uuid.UUID]model.Product, len(productIDs))
+	for _, id := range productIDs {
+		if p, ok := f.products[id]; ok {
+			result[id] = p
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package catalog
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// httpClient is a product catalog backed by the product service HTTP API.
+type httpClient struct {
+	baseURL string
+	client  *http.Client
+	logger  zerolog.Logger
+}
+
+// NewHTTPClient is a constructor function that returns a catalog client for the product service at baseURL.
+func NewHTTPClient(baseURL string, timeout time.Duration, logger zerolog.Logger) *httpClient {
+	return &httpClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+		logger:  logger,
+	}
+}
+
+// GetProducts is a method that fetches the given products with GET {baseURL}/products?ids=...
+// and returns them keyed by ID. Products unknown to the service are left out of the result.
+func (c *httpClient) GetProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.Product, error) {
+	logMsgStr := "Client:Catalog - GetProducts:"
+
+	result := make(map[uuid.UUID]model.Product, len(productIDs))
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	ids := make([]string, 0, len(productIDs))
+	for _, id := range productIDs {
+		ids = append(ids, id.String())
+	}
+
+	reqURL := fmt.Sprintf("%s/products?ids=%s", c.baseURL, url.QueryEscape(strings.Join(ids, ",")))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to call catalog", logMsgStr))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error().Int("Status", resp.StatusCode).Msg(fmt.Sprintf("%v Unexpected response status", logMsgStr))
+		return nil, fmt.Errorf("catalog: unexpected status %d", resp.StatusCode)
+	}
+
+	var products []model.Product
+	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+		c.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to decode response", logMsgStr))
+		return nil, err
+	}
+
+	for _, p := range products {
+		result[p.ID] = p
+	}
+
+	return result, nil
+}
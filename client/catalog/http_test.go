@@ -0,0 +1,95 @@
+package catalog
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func TestHTTPClientGetProducts(t *testing.T) {
+	known := model.Product{ID: uuid.New(), Name: "Mug", SKU: "MUG-1", Price: 25000, Available: true, WeightGrams: 350}
+	unknown := uuid.New()
+
+	var gotIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/products" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		gotIDs = strings.Split(r.URL.Query().Get("ids"), ",")
+		json.NewEncoder(w).Encode([]model.Product{known})
+	}))
+	defer srv.Close()
+
+	c := NewHTTPClient(srv.URL+"/", time.Second, zerolog.Nop())
+	products, err := c.GetProducts(context.Background(), []uuid.UUID{known.ID, unknown})
+	if err != nil {
+		t.Fatalf("GetProducts: %v", err)
+	}
+
+	if len(gotIDs) != 2 || gotIDs[0] != known.ID.String() || gotIDs[1] != unknown.String() {
+		t.Errorf("ids = %v, want both product IDs", gotIDs)
+	}
+
+	if got, ok := products[known.ID]; !ok || got != known {
+		t.Errorf("products[%s] = %+v, want %+v", known.ID, got, known)
+	}
+
+	if _, ok := products[unknown]; ok {
+		t.Errorf("unknown product %s is in the result", unknown)
+	}
+}
+
+func TestHTTPClientGetProductsNoIDs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("catalog called for an empty product list")
+	}))
+	defer srv.Close()
+
+	products, err := NewHTTPClient(srv.URL, time.Second, zerolog.Nop()).GetProducts(context.Background(), nil)
+	if err != nil || len(products) != 0 {
+		t.Fatalf("GetProducts(nil) = %v, %v, want empty result", products, err)
+	}
+}
+
+func TestHTTPClientGetProductsErrorStatus(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+
+		_, err := NewHTTPClient(srv.URL, time.Second, zerolog.Nop()).GetProducts(context.Background(), []uuid.UUID{uuid.New()})
+		if err == nil {
+			t.Errorf("status %d: GetProducts returned no error", status)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestHTTPClientGetProductsTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	_, err := NewHTTPClient(srv.URL, 50*time.Millisecond, zerolog.Nop()).GetProducts(context.Background(), []uuid.UUID{uuid.New()})
+	if err == nil {
+		t.Fatal("GetProducts returned no error for a slow catalog")
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("GetProducts took %v, want it to give up after the timeout", elapsed)
+	}
+}
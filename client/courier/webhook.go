@@ -0,0 +1,49 @@
+package courier
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/signature"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookHeaders names the headers that carry the signature of a courier webhook.
+var WebhookHeaders = signature.Headers{
+	Signature: "X-Courier-Signature",
+	Timestamp: "X-Courier-Timestamp",
+}
+
+// webhook verifies and decodes courier tracking webhooks. Couriers sign the JSON model.CourierEvent
+// body with a shared secret using WebhookHeaders.
+type webhook struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewWebhook is a constructor function that returns a courier webhook parser verifying signatures with secret.
+func NewWebhook(secret string) *webhook {
+	return &webhook{
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+}
+
+// ParseWebhook is a method that verifies a webhook signature and decodes its tracking event.
+func (c *webhook) ParseWebhook(header http.Header, body []byte) (*model.CourierEvent, error) {
+	if err := WebhookHeaders.Verify(c.secret, header, body, c.now()); err != nil {
+		return nil, err
+	}
+
+	var event model.CourierEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidWebhookPayload, err)
+	}
+
+	if event.Courier == "" || event.TrackingNumber == "" || !event.Status.IsValid() || event.OccurredAt.IsZero() {
+		return nil, fmt.Errorf("%w: courier, tracking_number, a known status and occurred_at are required", model.ErrInvalidWebhookPayload)
+	}
+
+	return &event, nil
+}
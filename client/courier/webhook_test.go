@@ -0,0 +1,62 @@
+package courier
+
+import (
+	model "cart-order-service/repository/models"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseWebhook(t *testing.T) {
+	secret := []byte("courier-secret")
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	valid := []byte(`{"courier":"jne","tracking_number":"TRK-1","status":"delivered","occurred_at":"2026-10-17T11:58:00Z"}`)
+
+	tests := []struct {
+		name    string
+		body    []byte
+		header  http.Header
+		wantErr error
+	}{
+		{name: "signed event", body: valid, header: WebhookHeaders.Sign(secret, valid, now)},
+		{name: "wrong secret", body: valid, header: WebhookHeaders.Sign([]byte("other"), valid, now), wantErr: model.ErrInvalidSignature},
+		{name: "replayed", body: valid, header: WebhookHeaders.Sign(secret, valid, now.Add(-time.Hour)), wantErr: model.ErrInvalidSignature},
+		{name: "unsigned", body: valid, header: http.Header{}, wantErr: model.ErrInvalidSignature},
+		{
+			name:    "unknown status",
+			body:    []byte(`{"courier":"jne","tracking_number":"TRK-1","status":"lost","occurred_at":"2026-10-17T11:58:00Z"}`),
+			wantErr: model.ErrInvalidWebhookPayload,
+		},
+		{
+			name:    "no tracking number",
+			body:    []byte(`{"courier":"jne","status":"delivered","occurred_at":"2026-10-17T11:58:00Z"}`),
+			wantErr: model.ErrInvalidWebhookPayload,
+		},
+		{name: "not JSON", body: []byte(`delivered`), wantErr: model.ErrInvalidWebhookPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWebhook(string(secret))
+			w.now = func() time.Time { return now }
+
+			header := tt.header
+			if header == nil {
+				header = WebhookHeaders.Sign(secret, tt.body, now)
+			}
+
+			event, err := w.ParseWebhook(header, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseWebhook error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if event.TrackingNumber != "TRK-1" || event.Status != model.ShipmentStatusDelivered {
+				t.Errorf("event = %+v, want TRK-1 delivered", event)
+			}
+		})
+	}
+}
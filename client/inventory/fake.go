@@ -0,0 +1,117 @@
+package inventory
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// fakeReservation is stock held by the fake inventory for a reservation ID.
+type fakeReservation struct {
+	lines     []model.StockLine
+	confirmed bool
+	released  bool
+}
+
+// fake is an in-memory inventory service for tests and local development.
+// Calls for a reservation ID behave like the real service: repeating them has no further effect.
+type fake struct {
+	mu           sync.Mutex
+	stock        map[uuid.UUID]int
+	reservations map[uuid.UUID]*fakeReservation
+}
+
+// NewFake is a constructor function that returns a fake inventory holding the given qty of each product.
+// Products without stock cannot be reserved.
+func NewFake(stock map[uuid.UUID]int) *fake {
+	f := &fake{
+		stock:        make(map[uuid.UUID]int, len(stock)),
+		reservations: make(map[uuid.UUID]*fakeReservation),
+	}
+	for id, qty := range stock {
+		f.stock[id] = qty
+	}
+
+	return f
+}
+
+// SetStock is a method that sets the qty of a product that is available to reserve.
+func (f *fake) SetStock(productID uuid.UUID, qty int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stock[productID] = qty
+}
+
+// Stock is a method that returns the qty of a product that is available to reserve.
+func (f *fake) Stock(productID uuid.UUID) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.stock[productID]
+}
+
+// Reserve is a method that holds the qty of every line, or returns model.ErrInsufficientStock and holds nothing.
+func (f *fake) Reserve(ctx context.Context, bReq model.ReserveStockRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.reservations[bReq.ReservationID]; ok {
+		return nil
+	}
+
+	wanted := make(map[uuid.UUID]int, len(bReq.Lines))
+	for _, line := range bReq.Lines {
+		wanted[line.ProductID] += line.Qty
+	}
+
+	for productID, qty := range wanted {
+		if f.stock[productID] < qty {
+			return fmt.Errorf("%w: product %s", model.ErrInsufficientStock, productID)
+		}
+	}
+
+	for productID, qty := range wanted {
+		f.stock[productID] -= qty
+	}
+	f.reservations[bReq.ReservationID] = &fakeReservation{lines: bReq.Lines}
+
+	return nil
+}
+
+// Release is a method that puts the stock of a reservation back, confirmed or not.
+// Unknown reservations are ignored, as nothing is held for them.
+func (f *fake) Release(ctx context.Context, reservationID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reservation, ok := f.reservations[reservationID]
+	if !ok || reservation.released {
+		return nil
+	}
+
+	for _, line := range reservation.lines {
+		f.stock[line.ProductID] += line.Qty
+	}
+	reservation.released = true
+
+	return nil
+}
+
+// Confirm is a method that turns a reservation into a sale, so its stock is not put back unless it is released.
+// It returns model.ErrReservationNotFound if the reservation is unknown or released.
+func (f *fake) Confirm(ctx context.Context, reservationID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reservation, ok := f.reservations[reservationID]
+	if !ok || reservation.released {
+		return fmt.Errorf("%w: %s", model.ErrReservationNotFound, reservationID)
+	}
+	reservation.confirmed = true
+
+	return nil
+}
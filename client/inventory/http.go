@@ -0,0 +1,124 @@
+package inventory
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// httpClient is an inventory client backed by the stock service HTTP API.
+// Every call carries the reservation ID, which the service uses to make repeated calls safe.
+type httpClient struct {
+	baseURL string
+	client  *http.Client
+	logger  zerolog.Logger
+}
+
+// NewHTTPClient is a constructor function that returns an inventory client for the stock service at baseURL.
+func NewHTTPClient(baseURL string, timeout time.Duration, logger zerolog.Logger) *httpClient {
+	return &httpClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+		logger:  logger,
+	}
+}
+
+// Reserve is a method that posts the request as JSON to POST {baseURL}/reservations.
+// A 409 Conflict response means some line is out of stock and is returned as model.ErrInsufficientStock.
+func (c *httpClient) Reserve(ctx context.Context, bReq model.ReserveStockRequest) error {
+	logMsgStr := "Client:Inventory - Reserve:"
+
+	body, err := json.Marshal(bReq)
+	if err != nil {
+		return err
+	}
+
+	status, respBody, err := c.post(ctx, logMsgStr, "/reservations", body)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", model.ErrInsufficientStock, strings.TrimSpace(respBody))
+	default:
+		c.logger.Error().Int("Status", status).Msg(fmt.Sprintf("%v Unexpected response status", logMsgStr))
+		return fmt.Errorf("inventory: unexpected status %d", status)
+	}
+}
+
+// Release is a method that calls POST {baseURL}/reservations/{id}/release.
+// A 404 Not Found response means nothing is held for the reservation and counts as released.
+func (c *httpClient) Release(ctx context.Context, reservationID uuid.UUID) error {
+	logMsgStr := "Client:Inventory - Release:"
+
+	status, _, err := c.post(ctx, logMsgStr, "/reservations/"+reservationID.String()+"/release", nil)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		c.logger.Error().Int("Status", status).Msg(fmt.Sprintf("%v Unexpected response status", logMsgStr))
+		return fmt.Errorf("inventory: unexpected status %d", status)
+	}
+}
+
+// Confirm is a method that calls POST {baseURL}/reservations/{id}/confirm.
+// A 404 Not Found response is returned as model.ErrReservationNotFound.
+func (c *httpClient) Confirm(ctx context.Context, reservationID uuid.UUID) error {
+	logMsgStr := "Client:Inventory - Confirm:"
+
+	status, _, err := c.post(ctx, logMsgStr, "/reservations/"+reservationID.String()+"/confirm", nil)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", model.ErrReservationNotFound, reservationID)
+	default:
+		c.logger.Error().Int("Status", status).Msg(fmt.Sprintf("%v Unexpected response status", logMsgStr))
+		return fmt.Errorf("inventory: unexpected status %d", status)
+	}
+}
+
+// post sends a JSON POST request to path and returns the response status and up to 4 KiB of the response body.
+func (c *httpClient) post(ctx context.Context, logMsgStr, path string, body []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to call stock service", logMsgStr))
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if err != nil {
+		c.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to read response", logMsgStr))
+		return 0, "", err
+	}
+
+	return resp.StatusCode, string(respBody), nil
+}
@@ -0,0 +1,231 @@
+package inventory
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// stubStockService is an httptest handler that behaves like the stock service: it holds stock per reservation
+// ID, so repeating a call for a reservation has no further effect.
+type stubStockService struct {
+	mu       sync.Mutex
+	stock    map[uuid.UUID]int
+	reserved map[uuid.UUID][]model.StockLine
+	released map[uuid.UUID]bool
+}
+
+func newStubStockService(stock map[uuid.UUID]int) *stubStockService {
+	return &stubStockService{
+		stock:    stock,
+		reserved: map[uuid.UUID][]model.StockLine{},
+		released: map[uuid.UUID]bool{},
+	}
+}
+
+func (s *stubStockService) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /reservations", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var bReq model.ReserveStockRequest
+		if err := json.NewDecoder(r.Body).Decode(&bReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := s.reserved[bReq.ReservationID]; ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		for _, line := range bReq.Lines {
+			if s.stock[line.ProductID] < line.Qty {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte("product " + line.ProductID.String() + " is out of stock\n"))
+				return
+			}
+		}
+
+		for _, line := range bReq.Lines {
+			s.stock[line.ProductID] -= line.Qty
+		}
+		s.reserved[bReq.ReservationID] = bReq.Lines
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	mux.HandleFunc("POST /reservations/{id}/release", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		id, _ := uuid.Parse(r.PathValue("id"))
+		lines, ok := s.reserved[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if !s.released[id] {
+			for _, line := range lines {
+				s.stock[line.ProductID] += line.Qty
+			}
+			s.released[id] = true
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /reservations/{id}/confirm", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		id, _ := uuid.Parse(r.PathValue("id"))
+		if _, ok := s.reserved[id]; !ok || s.released[id] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+func TestHTTPClientReserve(t *testing.T) {
+	productID := uuid.New()
+	stub := newStubStockService(map[uuid.UUID]int{productID: 5})
+	srv := httptest.NewServer(stub.routes())
+	defer srv.Close()
+
+	client := NewHTTPClient(srv.URL+"/", time.Second, zerolog.Nop())
+	bReq := model.ReserveStockRequest{
+		ReservationID: uuid.New(),
+		Lines:         []model.StockLine{{ProductID: productID, Qty: 3}},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.Reserve(context.Background(), bReq); err != nil {
+			t.Fatalf("Reserve call %d: %v", i+1, err)
+		}
+	}
+
+	if stub.stock[productID] != 2 {
+		t.Errorf("stock after repeated Reserve = %d, want 2", stub.stock[productID])
+	}
+
+	err := client.Reserve(context.Background(), model.ReserveStockRequest{
+		ReservationID: uuid.New(),
+		Lines:         []model.StockLine{{ProductID: productID, Qty: 3}},
+	})
+	if !errors.Is(err, model.ErrInsufficientStock) {
+		t.Errorf("Reserve past stock: err = %v, want %v", err, model.ErrInsufficientStock)
+	}
+
+	if stub.stock[productID] != 2 {
+		t.Errorf("stock after refused Reserve = %d, want 2", stub.stock[productID])
+	}
+}
+
+func TestHTTPClientRelease(t *testing.T) {
+	productID := uuid.New()
+	stub := newStubStockService(map[uuid.UUID]int{productID: 5})
+	srv := httptest.NewServer(stub.routes())
+	defer srv.Close()
+
+	client := NewHTTPClient(srv.URL, time.Second, zerolog.Nop())
+	reservationID := uuid.New()
+
+	if err := client.Reserve(context.Background(), model.ReserveStockRequest{
+		ReservationID: reservationID,
+		Lines:         []model.StockLine{{ProductID: productID, Qty: 4}},
+	}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.Release(context.Background(), reservationID); err != nil {
+			t.Fatalf("Release call %d: %v", i+1, err)
+		}
+	}
+
+	if stub.stock[productID] != 5 {
+		t.Errorf("stock after repeated Release = %d, want 5", stub.stock[productID])
+	}
+
+	if err := client.Release(context.Background(), uuid.New()); err != nil {
+		t.Errorf("Release of an unknown reservation: %v, want it counted as released", err)
+	}
+}
+
+func TestHTTPClientConfirm(t *testing.T) {
+	productID := uuid.New()
+	stub := newStubStockService(map[uuid.UUID]int{productID: 5})
+	srv := httptest.NewServer(stub.routes())
+	defer srv.Close()
+
+	client := NewHTTPClient(srv.URL, time.Second, zerolog.Nop())
+	reservationID := uuid.New()
+
+	if err := client.Reserve(context.Background(), model.ReserveStockRequest{
+		ReservationID: reservationID,
+		Lines:         []model.StockLine{{ProductID: productID, Qty: 1}},
+	}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.Confirm(context.Background(), reservationID); err != nil {
+			t.Fatalf("Confirm call %d: %v", i+1, err)
+		}
+	}
+
+	if err := client.Confirm(context.Background(), uuid.New()); !errors.Is(err, model.ErrReservationNotFound) {
+		t.Errorf("Confirm of an unknown reservation: err = %v, want %v", err, model.ErrReservationNotFound)
+	}
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "error status", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}},
+		{name: "timeout", handler: func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			client := NewHTTPClient(srv.URL, 50*time.Millisecond, zerolog.Nop())
+			reservationID := uuid.New()
+
+			if err := client.Reserve(context.Background(), model.ReserveStockRequest{ReservationID: reservationID}); err == nil || errors.Is(err, model.ErrInsufficientStock) {
+				t.Errorf("Reserve: err = %v, want a retryable error", err)
+			}
+			if err := client.Release(context.Background(), reservationID); err == nil {
+				t.Error("Release returned no error")
+			}
+			if err := client.Confirm(context.Background(), reservationID); err == nil || errors.Is(err, model.ErrReservationNotFound) {
+				t.Errorf("Confirm: err = %v, want a retryable error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package notifier
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// log is a notifier that only writes reminders to the log, for local development.
+type log struct {
+	logger zerolog.Logger
+}
+
+// NewLog is a constructor function that returns a notifier writing reminders to logger.
+func NewLog(logger zerolog.Logger) *log {
+	return &log{
+		logger: logger,
+	}
+}
+
+// NotifyAbandonedCart is a method that logs the reminder for an abandoned cart.
+func (l *log) NotifyAbandonedCart(ctx context.Context, cart model.AbandonedCart) error {
+	logMsgStr := "Client:Notifier - NotifyAbandonedCart:"
+
+	l.logger.Info().
+		Any("UserID", cart.UserID).
+		Any("Items", cart.Items).
+		Msg(fmt.Sprintf("%v Cart idle for %v since %v", logMsgStr, cart.Threshold, cart.LastActivityAt))
+
+	return nil
+}
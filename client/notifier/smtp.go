@@ -0,0 +1,156 @@
+package notifier
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// SMTPConfig is a struct that holds the settings of the SMTP notifier.
+// The notifier is meant for development: it has no way to find a user's address, so every reminder goes to To.
+type SMTPConfig struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	// Username and Password authenticate with the server when Username is set.
+	Username string
+	Password string
+	// From is the sender address, optionally with a display name.
+	From string
+	// To is the recipient of every reminder, e.g. a developer's inbox. A {user_id} placeholder is replaced with
+	// the ID of the cart's user.
+	To string
+	// Timeout bounds a whole SMTP conversation.
+	Timeout time.Duration
+}
+
+// smtpNotifier is a notifier that emails reminders to a fixed address through an SMTP server.
+type smtpNotifier struct {
+	cfg    SMTPConfig
+	host   string
+	from   *mail.Address
+	now    func() time.Time
+	logger zerolog.Logger
+}
+
+// NewSMTP is a constructor function that returns a notifier emailing reminders through the SMTP server in cfg.
+// It returns an error if the server address or the sender address is malformed.
+func NewSMTP(cfg SMTPConfig, logger zerolog.Logger) (*smtpNotifier, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", cfg.Addr, err)
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender %q: %w", cfg.From, err)
+	}
+
+	return &smtpNotifier{
+		cfg:    cfg,
+		host:   host,
+		from:   from,
+		now:    time.Now,
+		logger: logger,
+	}, nil
+}
+
+// NotifyAbandonedCart is a method that emails the reminder for an abandoned cart.
+// STARTTLS is used when the server offers it.
+func (s *smtpNotifier) NotifyAbandonedCart(ctx context.Context, cart model.AbandonedCart) error {
+	logMsgStr := "Client:Notifier - NotifyAbandonedCart:"
+
+	to := strings.ReplaceAll(s.cfg.To, "{user_id}", cart.UserID.String())
+	if _, err := mail.ParseAddress(to); err != nil {
+		return fmt.Errorf("invalid SMTP recipient %q: %w", to, err)
+	}
+
+	dialer := net.Dialer{Timeout: s.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to connect to %v", logMsgStr, s.cfg.Addr))
+		return err
+	}
+	if err := conn.SetDeadline(s.now().Add(s.cfg.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to start SMTP session", logMsgStr))
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("rcpt to: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+
+	if _, err := w.Write(s.message(to, cart)); err != nil {
+		w.Close()
+		return fmt.Errorf("data: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// message builds the plain-text email for an abandoned cart. The X-User-ID and X-Cart-Idle-Threshold headers
+// let mail tooling route or filter reminders without parsing the body.
+func (s *smtpNotifier) message(to string, cart model.AbandonedCart) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	buf.WriteString("Subject: You left something in your cart\r\n")
+	fmt.Fprintf(&buf, "Date: %s\r\n", s.now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", uuid.New(), s.from.Address[strings.LastIndex(s.from.Address, "@")+1:])
+	fmt.Fprintf(&buf, "X-User-ID: %s\r\n", cart.UserID)
+	fmt.Fprintf(&buf, "X-Cart-Idle-Threshold: %s\r\n", cart.Threshold)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(&buf, "Hi,\r\n\r\nYou still have %d item(s) waiting in your cart:\r\n\r\n", cart.ItemCount())
+	for _, item := range cart.Items {
+		fmt.Fprintf(&buf, "- %d x product %s\r\n", item.Qty, item.ProductID)
+	}
+	buf.WriteString("\r\nCome back any time to complete your order.\r\n")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,187 @@
+package notifier
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// smtpSession is what the fake SMTP server received in one session.
+type smtpSession struct {
+	from string
+	rcpt []string
+	data string
+}
+
+// fakeSMTPServer accepts a single SMTP session on a local listener, without STARTTLS or auth, and sends what it
+// received on the returned channel once the session ends.
+func fakeSMTPServer(t *testing.T) (string, <-chan smtpSession) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session smtpSession
+		defer func() { sessions <- session }()
+
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ESMTP fake")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				text.PrintfLine("250-localhost")
+				text.PrintfLine("250 8BITMIME")
+			case "MAIL":
+				session.from = arg
+				text.PrintfLine("250 OK")
+			case "RCPT":
+				session.rcpt = append(session.rcpt, arg)
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				data, err := io.ReadAll(text.DotReader())
+				if err != nil {
+					return
+				}
+				session.data = string(data)
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), sessions
+}
+
+func TestSMTPNotifyAbandonedCart(t *testing.T) {
+	addr, sessions := fakeSMTPServer(t)
+
+	n, err := NewSMTP(SMTPConfig{
+		Addr:    addr,
+		From:    "ShopeeFun <no-reply@shopeefun.example>",
+		To:      "dev+{user_id}@localhost",
+		Timeout: time.Second,
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+
+	productA, productB := uuid.New(), uuid.New()
+	cart := model.AbandonedCart{
+		UserID:    uuid.New(),
+		Items:     []model.CartLine{{ProductID: productA, Qty: 2}, {ProductID: productB, Qty: 1}},
+		Threshold: 24 * time.Hour,
+	}
+
+	if err := n.NotifyAbandonedCart(context.Background(), cart); err != nil {
+		t.Fatalf("NotifyAbandonedCart: %v", err)
+	}
+
+	var session smtpSession
+	select {
+	case session = <-sessions:
+	case <-time.After(time.Second):
+		t.Fatal("the fake SMTP server did not finish the session")
+	}
+
+	to := "dev+" + cart.UserID.String() + "@localhost"
+	// net/smtp adds BODY=8BITMIME to MAIL when the server offers it.
+	if !strings.HasPrefix(session.from, "FROM:<no-reply@shopeefun.example>") {
+		t.Errorf("MAIL %s, want FROM:<no-reply@shopeefun.example>", session.from)
+	}
+	if len(session.rcpt) != 1 || session.rcpt[0] != "TO:<"+to+">" {
+		t.Errorf("RCPT %v, want TO:<%s>", session.rcpt, to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(session.data))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+
+	headers := map[string]string{
+		"From":                  `"ShopeeFun" <no-reply@shopeefun.example>`,
+		"To":                    to,
+		"Subject":               "You left something in your cart",
+		"X-User-Id":             cart.UserID.String(),
+		"X-Cart-Idle-Threshold": "24h0m0s",
+		"Content-Type":          "text/plain; charset=utf-8",
+	}
+	for key, want := range headers {
+		if got := msg.Header.Get(key); got != want {
+			t.Errorf("header %s = %q, want %q", key, got, want)
+		}
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-Id"), "@shopeefun.example>") {
+		t.Errorf("header Message-Id = %q, want one on the sender's domain", msg.Header.Get("Message-Id"))
+	}
+
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	for _, want := range []string{
+		"You still have 3 item(s) waiting in your cart:",
+		"- 2 x product " + productA.String(),
+		"- 1 x product " + productB.String(),
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestSMTPNotifyAbandonedCartInvalidRecipient(t *testing.T) {
+	n, err := NewSMTP(SMTPConfig{
+		Addr:    "127.0.0.1:1",
+		From:    "no-reply@shopeefun.example",
+		To:      "not an address",
+		Timeout: time.Second,
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+
+	if err := n.NotifyAbandonedCart(context.Background(), model.AbandonedCart{UserID: uuid.New()}); err == nil {
+		t.Error("NotifyAbandonedCart accepted a malformed recipient")
+	}
+}
+
+func TestNewSMTPValidation(t *testing.T) {
+	if _, err := NewSMTP(SMTPConfig{Addr: "localhost", From: "no-reply@shopeefun.example"}, zerolog.Nop()); err == nil {
+		t.Error("NewSMTP accepted an address without a port")
+	}
+
+	if _, err := NewSMTP(SMTPConfig{Addr: "localhost:1025", From: "no-reply"}, zerolog.Nop()); err == nil {
+		t.Error("NewSMTP accepted a malformed sender")
+	}
+}
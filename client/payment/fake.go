@@ -0,0 +1,84 @@
+package payment
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/signature"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeProvider is the provider name recorded on payments made through the fake gateway.
+const FakeProvider = "fake"
+
+// WebhookHeaders names the headers that carry the signature of a payment webhook.
+var WebhookHeaders = signature.Headers{
+	Signature: "X-Payment-Signature",
+	Timestamp: "X-Payment-Timestamp",
+}
+
+// fake is a payment gateway for tests and local development. It never moves money;
+// payments are completed by posting a webhook signed with WebhookHeaders.Sign.
+type fake struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewFake is a constructor function that returns a fake gateway verifying webhooks with secret.
+func NewFake(secret string) *fake {
+	return &fake{
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+}
+
+// Provider is a method that returns the name recorded on payments made through this gateway.
+func (f *fake) Provider() string {
+	return FakeProvider
+}
+
+// CreateIntent is a method that returns a fake payment intent. Requests with the same IdempotencyKey get the same
+// intent; requests without one get a new intent.
+func (f *fake) CreateIntent(ctx context.Context, bReq model.PaymentIntentRequest) (*model.PaymentIntent, error) {
+	key := bReq.IdempotencyKey
+	if key == "" {
+		key = uuid.NewString()
+	}
+	intentID := "pi_fake_" + key
+
+	return &model.PaymentIntent{
+		IntentID:    intentID,
+		CheckoutURL: fmt.Sprintf("https://fake-gateway.local/pay/%s?ref=%s", intentID, bReq.RefCode),
+	}, nil
+}
+
+// ParseWebhook is a method that verifies a webhook signature and decodes its payment event.
+// The body is a JSON model.PaymentEvent.
+func (f *fake) ParseWebhook(header http.Header, body []byte) (*model.PaymentEvent, error) {
+	return parseWebhook(f.secret, header, body, f.now())
+}
+
+// parseWebhook verifies the WebhookHeaders signature of a webhook body with secret and decodes its payment event.
+func parseWebhook(secret []byte, header http.Header, body []byte, now time.Time) (*model.PaymentEvent, error) {
+	if err := WebhookHeaders.Verify(secret, header, body, now); err != nil {
+		return nil, err
+	}
+
+	var event model.PaymentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidWebhookPayload, err)
+	}
+
+	return &event, nil
+}
+
+// Refund is a method that accepts every refund straight away.
+func (f *fake) Refund(ctx context.Context, bReq model.RefundRequest) (*model.RefundResult, error) {
+	return &model.RefundResult{
+		ProviderRefundID: "re_fake_" + bReq.IdempotencyKey,
+	}, nil
+}
@@ -0,0 +1,173 @@
+package payment
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// HTTPConfig is a struct that holds the settings of the HTTP payment gateway client.
+type HTTPConfig struct {
+	// Provider is the name recorded on payments made through the gateway. Payments are looked up by it when
+	// webhooks arrive, so it must not change while payments are open.
+	Provider string
+	BaseURL  string
+	// APIKey is sent as a bearer token with every call.
+	APIKey string
+	// WebhookSecret verifies the signature of the gateway's webhooks.
+	WebhookSecret string
+	Timeout       time.Duration
+}
+
+// httpGateway is a payment gateway backed by the payment provider's HTTP API.
+type httpGateway struct {
+	cfg    HTTPConfig
+	client *http.Client
+	now    func() time.Time
+	logger zerolog.Logger
+}
+
+// intentRequest is the body of POST {baseURL}/intents.
+type intentRequest struct {
+	OrderID  string  `json:"order_id"`
+	RefCode  string  `json:"ref_code"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// intentResponse is the body the gateway answers POST {baseURL}/intents with.
+type intentResponse struct {
+	IntentID    string `json:"intent_id"`
+	CheckoutURL string `json:"checkout_url"`
+}
+
+// refundRequest is the body of POST {baseURL}/refunds.
+type refundRequest struct {
+	IntentID string  `json:"intent_id"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// refundResponse is the body the gateway answers POST {baseURL}/refunds with.
+type refundResponse struct {
+	RefundID string `json:"refund_id"`
+}
+
+// NewHTTPGateway is a constructor function that returns a payment gateway client for the provider API in cfg.
+func NewHTTPGateway(cfg HTTPConfig, logger zerolog.Logger) *httpGateway {
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+
+	return &httpGateway{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+		logger: logger,
+	}
+}
+
+// Provider is a method that returns the name recorded on payments made through this gateway.
+func (g *httpGateway) Provider() string {
+	return g.cfg.Provider
+}
+
+// CreateIntent is a method that asks the gateway for a payment intent with POST {baseURL}/intents.
+func (g *httpGateway) CreateIntent(ctx context.Context, bReq model.PaymentIntentRequest) (*model.PaymentIntent, error) {
+	logMsgStr := "Client:Payment - CreateIntent:"
+
+	var resp intentResponse
+	err := g.post(ctx, logMsgStr, "/intents", bReq.IdempotencyKey, intentRequest{
+		OrderID:  bReq.OrderID.String(),
+		RefCode:  bReq.RefCode,
+		Amount:   bReq.Amount,
+		Currency: bReq.Currency,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IntentID == "" {
+		return nil, fmt.Errorf("payment: intent response without intent_id")
+	}
+
+	return &model.PaymentIntent{
+		IntentID:    resp.IntentID,
+		CheckoutURL: resp.CheckoutURL,
+	}, nil
+}
+
+// ParseWebhook is a method that verifies a webhook signature and decodes its payment event.
+// The gateway signs webhooks like the fake gateway, with WebhookHeaders, and the body is a JSON model.PaymentEvent.
+func (g *httpGateway) ParseWebhook(header http.Header, body []byte) (*model.PaymentEvent, error) {
+	return parseWebhook([]byte(g.cfg.WebhookSecret), header, body, g.now())
+}
+
+// Refund is a method that sends a refund with POST {baseURL}/refunds. The request's IdempotencyKey is sent as
+// the Idempotency-Key header, so a retried refund is not paid out twice.
+func (g *httpGateway) Refund(ctx context.Context, bReq model.RefundRequest) (*model.RefundResult, error) {
+	logMsgStr := "Client:Payment - Refund:"
+
+	var resp refundResponse
+	err := g.post(ctx, logMsgStr, "/refunds", bReq.IdempotencyKey, refundRequest{
+		IntentID: bReq.IntentID,
+		Amount:   bReq.Amount,
+		Currency: bReq.Currency,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.RefundID == "" {
+		return nil, fmt.Errorf("payment: refund response without refund_id")
+	}
+
+	return &model.RefundResult{
+		ProviderRefundID: resp.RefundID,
+	}, nil
+}
+
+// post sends body as JSON to path and decodes a 200 or 201 response into out.
+func (g *httpGateway) post(ctx context.Context, logMsgStr, path, idempotencyKey string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.cfg.APIKey)
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		g.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to call payment gateway", logMsgStr))
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		g.logger.Error().Int("Status", resp.StatusCode).Str("Body", string(respBody)).Msg(fmt.Sprintf("%v Unexpected response status", logMsgStr))
+		return fmt.Errorf("payment: unexpected status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		g.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to decode response", logMsgStr))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package payment
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func testGateway(url string) *httpGateway {
+	return NewHTTPGateway(HTTPConfig{
+		Provider:      "acme",
+		BaseURL:       url + "/",
+		APIKey:        "test-key",
+		WebhookSecret: "test-secret",
+		Timeout:       50 * time.Millisecond,
+	}, zerolog.Nop())
+}
+
+func TestHTTPGatewayCreateIntent(t *testing.T) {
+	orderID := uuid.New()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/intents" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.Header.Get("Idempotency-Key"); got != "order-"+orderID.String()+"-1" {
+			t.Errorf("Idempotency-Key = %q", got)
+		}
+
+		var got intentRequest
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		want := intentRequest{OrderID: orderID.String(), RefCode: "REF-1", Amount: 150.5, Currency: "IDR"}
+		if got != want {
+			t.Errorf("request = %+v, want %+v", got, want)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(intentResponse{IntentID: "pi_1", CheckoutURL: "https://pay.example/pi_1"})
+	}))
+	defer srv.Close()
+
+	g := testGateway(srv.URL)
+	if g.Provider() != "acme" {
+		t.Errorf("Provider = %q, want acme", g.Provider())
+	}
+
+	intent, err := g.CreateIntent(context.Background(), model.PaymentIntentRequest{
+		IdempotencyKey: "order-" + orderID.String() + "-1",
+		OrderID:        orderID,
+		RefCode:        "REF-1",
+		Amount:         150.5,
+		Currency:       "IDR",
+	})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+
+	if intent.IntentID != "pi_1" || intent.CheckoutURL != "https://pay.example/pi_1" {
+		t.Errorf("intent = %+v", intent)
+	}
+}
+
+func TestHTTPGatewayRefund(t *testing.T) {
+	key := "return-" + uuid.NewString()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/refunds" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Idempotency-Key"); got != key {
+			t.Errorf("Idempotency-Key = %q, want %s", got, key)
+		}
+
+		var got refundRequest
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if want := (refundRequest{IntentID: "pi_1", Amount: 20, Currency: "IDR"}); got != want {
+			t.Errorf("request = %+v, want %+v", got, want)
+		}
+
+		json.NewEncoder(w).Encode(refundResponse{RefundID: "re_1"})
+	}))
+	defer srv.Close()
+
+	result, err := testGateway(srv.URL).Refund(context.Background(), model.RefundRequest{
+		IdempotencyKey: key,
+		IntentID:       "pi_1",
+		Amount:         20,
+		Currency:       "IDR",
+	})
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+
+	if result.ProviderRefundID != "re_1" {
+		t.Errorf("ProviderRefundID = %q, want re_1", result.ProviderRefundID)
+	}
+}
+
+func TestHTTPGatewayErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "error status", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusPaymentRequired)
+		}},
+		{name: "missing id", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("{}"))
+		}},
+		{name: "timeout", handler: func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			g := testGateway(srv.URL)
+			if _, err := g.CreateIntent(context.Background(), model.PaymentIntentRequest{OrderID: uuid.New()}); err == nil {
+				t.Error("CreateIntent returned no error")
+			}
+			if _, err := g.Refund(context.Background(), model.RefundRequest{IdempotencyKey: uuid.NewString()}); err == nil {
+				t.Error("Refund returned no error")
+			}
+		})
+	}
+}
+
+func TestHTTPGatewayParseWebhook(t *testing.T) {
+	g := testGateway("http://gateway.invalid")
+	body := []byte(`{"intent_id":"pi_1","status":"paid","amount":150.5}`)
+
+	event, err := g.ParseWebhook(WebhookHeaders.Sign([]byte("test-secret"), body, time.Now()), body)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	if event.IntentID != "pi_1" || event.Amount != 150.5 {
+		t.Errorf("event = %+v", event)
+	}
+
+	if _, err := g.ParseWebhook(WebhookHeaders.Sign([]byte("other-secret"), body, time.Now()), body); !errors.Is(err, model.ErrInvalidSignature) {
+		t.Errorf("ParseWebhook with a wrong signature: err = %v, want %v", err, model.ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,36 @@
+package publisher
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"sync"
+)
+
+// memory is a publisher that keeps published events in memory, for tests and local development.
+type memory struct {
+	mu     sync.Mutex
+	events []model.OutboxEvent
+}
+
+// NewMemory is a constructor function that returns an empty in-memory publisher.
+func NewMemory() *memory {
+	return &memory{}
+}
+
+// Publish is a method that appends an event to the published events.
+func (m *memory) Publish(ctx context.Context, event model.OutboxEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+
+	return nil
+}
+
+// Events is a method that returns the published events, oldest first.
+func (m *memory) Events() []model.OutboxEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]model.OutboxEvent(nil), m.events...)
+}
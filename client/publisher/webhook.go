@@ -0,0 +1,77 @@
+package publisher
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/signature"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// WebhookHeaders names the headers that carry the signature of an event webhook.
+var WebhookHeaders = signature.Headers{
+	Signature: "X-Event-Signature",
+	Timestamp: "X-Event-Timestamp",
+}
+
+// webhook is a publisher that posts every event to an HTTP endpoint.
+type webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+	logger zerolog.Logger
+}
+
+// NewWebhook is a constructor function that returns a publisher posting events to url, signed with secret.
+func NewWebhook(url, secret string, timeout time.Duration, logger zerolog.Logger) *webhook {
+	return &webhook{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+		logger: logger,
+	}
+}
+
+// Publish is a method that posts the event as JSON to the webhook URL, signed using WebhookHeaders.
+// The X-Event-ID and X-Event-Type headers repeat the event's ID and type. Any 2xx response counts as delivered.
+func (c *webhook) Publish(ctx context.Context, event model.OutboxEvent) error {
+	logMsgStr := "Client:Publisher - Publish:"
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = WebhookHeaders.Sign(c.secret, body, c.now())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID.String())
+	req.Header.Set("X-Event-Type", string(event.EventType))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to call webhook", logMsgStr))
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("publisher: unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package shipping
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// httpClient is a shipping rate provider backed by a rates service HTTP API.
+type httpClient struct {
+	baseURL string
+	client  *http.Client
+	logger  zerolog.Logger
+}
+
+// NewHTTPClient is a constructor function that returns a rate provider for the rates service at baseURL.
+func NewHTTPClient(baseURL string, timeout time.Duration, logger zerolog.Logger) *httpClient {
+	return &httpClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+		logger:  logger,
+	}
+}
+
+// Quote is a method that posts the parcel as JSON to POST {baseURL}/rates and returns the quotes in the response,
+// a JSON array of model.ShippingQuote.
+func (c *httpClient) Quote(ctx context.Context, bReq model.ShippingQuoteRequest) ([]model.ShippingQuote, error) {
+	logMsgStr := "Client:Shipping - Quote:"
+
+	body, err := json.Marshal(bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/rates", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to call rates service", logMsgStr))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error().Int("Status", resp.StatusCode).Msg(fmt.Sprintf("%v Unexpected response status", logMsgStr))
+		return nil, fmt.Errorf("shipping: unexpected status %d", resp.StatusCode)
+	}
+
+	var quotes []model.ShippingQuote
+	if err := json.NewDecoder(resp.Body).Decode(&quotes); err != nil {
+		c.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to decode response", logMsgStr))
+		return nil, err
+	}
+
+	return quotes, nil
+}
@@ -0,0 +1,79 @@
+package shipping
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestHTTPClientQuote(t *testing.T) {
+	want := []model.ShippingQuote{{Service: "REG", Name: "Regular", Fee: 15000, EstimatedDays: 3}}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rates" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var got model.ShippingQuoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if got.PostalCode != "10110" || got.WeightGrams != 1500 || got.Subtotal != 99.5 {
+			t.Errorf("request = %+v", got)
+		}
+
+		json.NewEncoder(w).Encode(want)
+	}))
+	defer srv.Close()
+
+	quotes, err := NewHTTPClient(srv.URL+"/", time.Second, zerolog.Nop()).Quote(context.Background(), model.ShippingQuoteRequest{
+		PostalCode:  "10110",
+		WeightGrams: 1500,
+		Subtotal:    99.5,
+	})
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+
+	if len(quotes) != 1 || quotes[0] != want[0] {
+		t.Errorf("quotes = %+v, want %+v", quotes, want)
+	}
+}
+
+func TestHTTPClientQuoteErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "error status", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}},
+		{name: "malformed body", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("not json"))
+		}},
+		{name: "timeout", handler: func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			_, err := NewHTTPClient(srv.URL, 50*time.Millisecond, zerolog.Nop()).Quote(context.Background(), model.ShippingQuoteRequest{PostalCode: "10110"})
+			if err == nil {
+				t.Error("Quote returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package shipping
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TableConfig is a rate table: destinations are grouped into zones by postal code prefix, and every service
+// level has a fee per zone. Zone names are lower case, as viper lowercases map keys.
+type TableConfig struct {
+	// DefaultZone is the zone of postal codes that match no zone prefix.
+	DefaultZone string          `mapstructure:"default_zone"`
+	Zones       []ZoneConfig    `mapstructure:"zones"`
+	Services    []ServiceConfig `mapstructure:"services"`
+}
+
+type ZoneConfig struct {
+	Name           string   `mapstructure:"name"`
+	PostalPrefixes []string `mapstructure:"postal_prefixes"`
+}
+
+// ServiceConfig is a service level. A service without a fee for a zone is not offered there.
+type ServiceConfig struct {
+	Code          string               `mapstructure:"code"`
+	Name          string               `mapstructure:"name"`
+	EstimatedDays int                  `mapstructure:"estimated_days"`
+	Fees          map[string]FeeConfig `mapstructure:"fees"`
+}
+
+// FeeConfig prices a parcel at BaseFee for the first kilogram plus PerKg for every started kilogram after it.
+type FeeConfig struct {
+	BaseFee float64 `mapstructure:"base_fee"`
+	PerKg   float64 `mapstructure:"per_kg"`
+}
+
+// table is a shipping rate provider that prices parcels from a TableConfig.
+type table struct {
+	cfg TableConfig
+}
+
+// NewTable is a constructor function that returns a rate provider for cfg.
+// It returns an error if cfg has no services or a service has no code.
+func NewTable(cfg TableConfig) (*table, error) {
+	if len(cfg.Services) == 0 {
+		return nil, fmt.Errorf("shipping rate table has no services")
+	}
+
+	for _, service := range cfg.Services {
+		if service.Code == "" {
+			return nil, fmt.Errorf("shipping rate table has a service without a code")
+		}
+	}
+
+	return &table{cfg: cfg}, nil
+}
+
+// Quote is a method that returns the fee of every service offered in the zone of the destination postal code.
+func (t *table) Quote(ctx context.Context, bReq model.ShippingQuoteRequest) ([]model.ShippingQuote, error) {
+	zone := t.zoneFor(bReq.PostalCode)
+
+	kg := int(math.Ceil(float64(bReq.WeightGrams) / 1000))
+	if kg < 1 {
+		kg = 1
+	}
+
+	quotes := []model.ShippingQuote{}
+	for _, service := range t.cfg.Services {
+		fee, ok := service.Fees[zone]
+		if !ok {
+			continue
+		}
+
+		quotes = append(quotes, model.ShippingQuote{
+			Service:       service.Code,
+			Name:          service.Name,
+			Fee:           math.Round((fee.BaseFee+fee.PerKg*float64(kg-1))*100) / 100,
+			EstimatedDays: service.EstimatedDays,
+		})
+	}
+
+	return quotes, nil
+}
+
+// zoneFor returns the zone with the longest postal code prefix matching postalCode, or the default zone.
+func (t *table) zoneFor(postalCode string) string {
+	zone, longest := t.cfg.DefaultZone, 0
+	for _, z := range t.cfg.Zones {
+		for _, prefix := range z.PostalPrefixes {
+			if len(prefix) > longest && strings.HasPrefix(postalCode, prefix) {
+				zone, longest = z.Name, len(prefix)
+			}
+		}
+	}
+
+	return strings.ToLower(zone)
+}
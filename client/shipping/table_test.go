@@ -0,0 +1,111 @@
+package shipping
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"testing"
+)
+
+func testTable(t *testing.T, defaultZone string) *table {
+	t.Helper()
+
+	tbl, err := NewTable(TableConfig{
+		DefaultZone: defaultZone,
+		Zones: []ZoneConfig{
+			{Name: "Jabodetabek", PostalPrefixes: []string{"10", "11"}},
+			{Name: "jakbar", PostalPrefixes: []string{"115"}},
+		},
+		Services: []ServiceConfig{
+			{Code: "REG", Name: "Regular", EstimatedDays: 3, Fees: map[string]FeeConfig{
+				"jabodetabek": {BaseFee: 10000, PerKg: 5000},
+				"jakbar":      {BaseFee: 8000, PerKg: 4000},
+				"other":       {BaseFee: 20000, PerKg: 10000},
+			}},
+			{Code: "SAME", Name: "Same day", EstimatedDays: 0, Fees: map[string]FeeConfig{
+				"jabodetabek": {BaseFee: 25000, PerKg: 7500.5},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTable: %v", err)
+	}
+
+	return tbl
+}
+
+func TestTableQuoteWeightBrackets(t *testing.T) {
+	tbl := testTable(t, "other")
+
+	tests := []struct {
+		weightGrams int
+		wantREG     float64
+		wantSAME    float64
+	}{
+		{weightGrams: 0, wantREG: 10000, wantSAME: 25000},
+		{weightGrams: 1, wantREG: 10000, wantSAME: 25000},
+		{weightGrams: 1000, wantREG: 10000, wantSAME: 25000},
+		{weightGrams: 1001, wantREG: 15000, wantSAME: 32500.5},
+		{weightGrams: 2000, wantREG: 15000, wantSAME: 32500.5},
+		{weightGrams: 2001, wantREG: 20000, wantSAME: 40001},
+	}
+
+	for _, tt := range tests {
+		quotes, err := tbl.Quote(context.Background(), model.ShippingQuoteRequest{PostalCode: "10110", WeightGrams: tt.weightGrams})
+		if err != nil {
+			t.Fatalf("%dg: Quote: %v", tt.weightGrams, err)
+		}
+
+		fees := map[string]float64{}
+		for _, q := range quotes {
+			fees[q.Service] = q.Fee
+		}
+
+		if fees["REG"] != tt.wantREG || fees["SAME"] != tt.wantSAME {
+			t.Errorf("%dg: fees = %v, want REG %v and SAME %v", tt.weightGrams, fees, tt.wantREG, tt.wantSAME)
+		}
+	}
+}
+
+func TestTableQuoteZones(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultZone string
+		postalCode  string
+		want        map[string]float64
+	}{
+		{name: "zone prefix", defaultZone: "other", postalCode: "11410", want: map[string]float64{"REG": 10000, "SAME": 25000}},
+		{name: "longest prefix wins", defaultZone: "other", postalCode: "11520", want: map[string]float64{"REG": 8000}},
+		{name: "unmatched code falls back to the default zone", defaultZone: "Other", postalCode: "80361", want: map[string]float64{"REG": 20000}},
+		{name: "unknown zone has no services", defaultZone: "", postalCode: "80361", want: map[string]float64{}},
+		{name: "default zone without fees has no services", defaultZone: "papua", postalCode: "99111", want: map[string]float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes, err := testTable(t, tt.defaultZone).Quote(context.Background(), model.ShippingQuoteRequest{PostalCode: tt.postalCode, WeightGrams: 500})
+			if err != nil {
+				t.Fatalf("Quote: %v", err)
+			}
+
+			if len(quotes) != len(tt.want) {
+				t.Fatalf("quotes = %+v, want %v", quotes, tt.want)
+			}
+
+			for _, q := range quotes {
+				if fee, ok := tt.want[q.Service]; !ok || fee != q.Fee {
+					t.Errorf("quote %s = %v, want %v", q.Service, q.Fee, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTableValidation(t *testing.T) {
+	if _, err := NewTable(TableConfig{}); err == nil {
+		t.Error("NewTable accepted a table without services")
+	}
+
+	if _, err := NewTable(TableConfig{Services: []ServiceConfig{{Name: "No code"}}}); err == nil {
+		t.Error("NewTable accepted a service without a code")
+	}
+}
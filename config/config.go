@@ -1,6 +1,7 @@
 package config
 
 import (
+	"cart-order-service/client/shipping"
 	"fmt"
 	"time"
 
@@ -19,6 +20,89 @@ type Config struct {
 	DBDebug      bool
 	BaseURLPath  string
 	DBSSLMode    string
+
+	ProductCatalog      string
+	CatalogBaseURL      string
+	CatalogTimeout      time.Duration
+	TotalMismatchPolicy string
+
+	OrderNumberFormat string
+	RefCodeFormat     string
+	IDSeqWidth        int
+
+	IdempotencyTTL time.Duration
+
+	PaymentCurrency        string
+	PaymentWebhookSecret   string
+	PaymentGateway         string
+	PaymentGatewayProvider string
+	PaymentGatewayURL      string
+	PaymentGatewayAPIKey   string
+	PaymentGatewayTimeout  time.Duration
+
+	RefundReconcileInterval  time.Duration
+	RefundReconcileAfter     time.Duration
+	RefundReconcileBatchSize int
+
+	OrderPaymentWindow   time.Duration
+	OrderExpiryInterval  time.Duration
+	OrderExpiryBatchSize int
+
+	CourierWebhookSecret string
+
+	ShippingRatesURL     string
+	ShippingRatesTimeout time.Duration
+	ShippingRates        shipping.TableConfig
+
+	InvoiceNumberFormat string
+	InvoiceSeqWidth     int
+	InvoiceSellerName   string
+	InvoiceSellerAddr   []string
+	InvoiceSellerTaxID  string
+	InvoiceSellerEmail  string
+
+	ExportBatchSize int
+
+	AnalyticsTimezone string
+
+	OutboxPublisher      string
+	OutboxWebhookURL     string
+	OutboxWebhookSecret  string
+	OutboxWebhookTimeout time.Duration
+	OutboxRelayInterval  time.Duration
+	OutboxBatchSize      int
+	OutboxLease          time.Duration
+	OutboxMaxAttempts    int
+	OutboxRetryBackoff   time.Duration
+	OutboxMaxBackoff     time.Duration
+
+	InventoryClient             string
+	InventoryBaseURL            string
+	InventoryTimeout            time.Duration
+	InventoryReservationTimeout time.Duration
+	InventoryReconcileInterval  time.Duration
+	InventoryReconcileBatchSize int
+	InventoryRetryInterval      time.Duration
+
+	GuestCartTokenSecret     string
+	GuestCartTTL             time.Duration
+	GuestCartMergeRule       string
+	GuestCartCleanupInterval time.Duration
+	GuestCartCleanupBatch    int
+
+	AbandonedCartThresholds  []time.Duration
+	AbandonedCartInterval    time.Duration
+	AbandonedCartBatchSize   int
+	AbandonedCartMaxAttempts int
+	AbandonedCartRetry       time.Duration
+	AbandonedCartMaxBackoff  time.Duration
+	AbandonedCartNotifier    string
+	AbandonedCartSMTPAddr    string
+	AbandonedCartSMTPUser    string
+	AbandonedCartSMTPPass    string
+	AbandonedCartSMTPFrom    string
+	AbandonedCartSMTPTo      string
+	AbandonedCartSMTPTimeout time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -43,6 +127,365 @@ func LoadConfig() (*Config, error) {
 		DBName:      viper.GetString("DB_NAME"),
 		DBDebug:     viper.GetBool("DB_DEBUG"),
 		DBPort:      viper.GetInt("DB_PORT"),
+
+		ProductCatalog:      viper.GetString("PRODUCT_CATALOG"),
+		CatalogBaseURL:      viper.GetString("CATALOG_BASE_URL"),
+		CatalogTimeout:      viper.GetDuration("CATALOG_TIMEOUT"),
+		TotalMismatchPolicy: viper.GetString("ORDER_TOTAL_MISMATCH_POLICY"),
+
+		OrderNumberFormat: viper.GetString("ORDER_NUMBER_FORMAT"),
+		RefCodeFormat:     viper.GetString("REF_CODE_FORMAT"),
+		IDSeqWidth:        viper.GetInt("ID_SEQ_WIDTH"),
+
+		IdempotencyTTL: viper.GetDuration("IDEMPOTENCY_TTL"),
+
+		PaymentCurrency:        viper.GetString("PAYMENT_CURRENCY"),
+		PaymentWebhookSecret:   viper.GetString("PAYMENT_WEBHOOK_SECRET"),
+		PaymentGateway:         viper.GetString("PAYMENT_GATEWAY"),
+		PaymentGatewayProvider: viper.GetString("PAYMENT_GATEWAY_PROVIDER"),
+		PaymentGatewayURL:      viper.GetString("PAYMENT_GATEWAY_URL"),
+		PaymentGatewayAPIKey:   viper.GetString("PAYMENT_GATEWAY_API_KEY"),
+		PaymentGatewayTimeout:  viper.GetDuration("PAYMENT_GATEWAY_TIMEOUT"),
+
+		RefundReconcileInterval:  viper.GetDuration("REFUND_RECONCILE_INTERVAL"),
+		RefundReconcileAfter:     viper.GetDuration("REFUND_RECONCILE_AFTER"),
+		RefundReconcileBatchSize: viper.GetInt("REFUND_RECONCILE_BATCH_SIZE"),
+
+		OrderPaymentWindow:   viper.GetDuration("ORDER_PAYMENT_WINDOW"),
+		OrderExpiryInterval:  viper.GetDuration("ORDER_EXPIRY_INTERVAL"),
+		OrderExpiryBatchSize: viper.GetInt("ORDER_EXPIRY_BATCH_SIZE"),
+
+		CourierWebhookSecret: viper.GetString("COURIER_WEBHOOK_SECRET"),
+
+		ShippingRatesURL:     viper.GetString("SHIPPING_RATES_URL"),
+		ShippingRatesTimeout: viper.GetDuration("SHIPPING_RATES_TIMEOUT"),
+
+		InvoiceNumberFormat: viper.GetString("INVOICE_NUMBER_FORMAT"),
+		InvoiceSeqWidth:     viper.GetInt("INVOICE_SEQ_WIDTH"),
+		InvoiceSellerName:   viper.GetString("INVOICE_SELLER_NAME"),
+		InvoiceSellerAddr:   viper.GetStringSlice("INVOICE_SELLER_ADDRESS"),
+		InvoiceSellerTaxID:  viper.GetString("INVOICE_SELLER_TAX_ID"),
+		InvoiceSellerEmail:  viper.GetString("INVOICE_SELLER_EMAIL"),
+
+		ExportBatchSize: viper.GetInt("EXPORT_BATCH_SIZE"),
+
+		AnalyticsTimezone: viper.GetString("ANALYTICS_TIMEZONE"),
+
+		OutboxPublisher:      viper.GetString("OUTBOX_PUBLISHER"),
+		OutboxWebhookURL:     viper.GetString("OUTBOX_WEBHOOK_URL"),
+		OutboxWebhookSecret:  viper.GetString("OUTBOX_WEBHOOK_SECRET"),
+		OutboxWebhookTimeout: viper.GetDuration("OUTBOX_WEBHOOK_TIMEOUT"),
+		OutboxRelayInterval:  viper.GetDuration("OUTBOX_RELAY_INTERVAL"),
+		OutboxBatchSize:      viper.GetInt("OUTBOX_BATCH_SIZE"),
+		OutboxLease:          viper.GetDuration("OUTBOX_LEASE"),
+		OutboxMaxAttempts:    viper.GetInt("OUTBOX_MAX_ATTEMPTS"),
+		OutboxRetryBackoff:   viper.GetDuration("OUTBOX_RETRY_BACKOFF"),
+		OutboxMaxBackoff:     viper.GetDuration("OUTBOX_MAX_BACKOFF"),
+
+		InventoryClient:             viper.GetString("INVENTORY_CLIENT"),
+		InventoryBaseURL:            viper.GetString("INVENTORY_BASE_URL"),
+		InventoryTimeout:            viper.GetDuration("INVENTORY_TIMEOUT"),
+		InventoryReservationTimeout: viper.GetDuration("INVENTORY_RESERVATION_TIMEOUT"),
+		InventoryReconcileInterval:  viper.GetDuration("INVENTORY_RECONCILE_INTERVAL"),
+		InventoryReconcileBatchSize: viper.GetInt("INVENTORY_RECONCILE_BATCH_SIZE"),
+		InventoryRetryInterval:      viper.GetDuration("INVENTORY_RETRY_INTERVAL"),
+
+		GuestCartTokenSecret:     viper.GetString("GUEST_CART_TOKEN_SECRET"),
+		GuestCartTTL:             viper.GetDuration("GUEST_CART_TTL"),
+		GuestCartMergeRule:       viper.GetString("GUEST_CART_MERGE_RULE"),
+		GuestCartCleanupInterval: viper.GetDuration("GUEST_CART_CLEANUP_INTERVAL"),
+		GuestCartCleanupBatch:    viper.GetInt("GUEST_CART_CLEANUP_BATCH_SIZE"),
+
+		AbandonedCartInterval:    viper.GetDuration("ABANDONED_CART_INTERVAL"),
+		AbandonedCartBatchSize:   viper.GetInt("ABANDONED_CART_BATCH_SIZE"),
+		AbandonedCartMaxAttempts: viper.GetInt("ABANDONED_CART_MAX_ATTEMPTS"),
+		AbandonedCartRetry:       viper.GetDuration("ABANDONED_CART_RETRY_BACKOFF"),
+		AbandonedCartMaxBackoff:  viper.GetDuration("ABANDONED_CART_MAX_BACKOFF"),
+		AbandonedCartNotifier:    viper.GetString("ABANDONED_CART_NOTIFIER"),
+		AbandonedCartSMTPAddr:    viper.GetString("ABANDONED_CART_SMTP_ADDR"),
+		AbandonedCartSMTPUser:    viper.GetString("ABANDONED_CART_SMTP_USERNAME"),
+		AbandonedCartSMTPPass:    viper.GetString("ABANDONED_CART_SMTP_PASSWORD"),
+		AbandonedCartSMTPFrom:    viper.GetString("ABANDONED_CART_SMTP_FROM"),
+		AbandonedCartSMTPTo:      viper.GetString("ABANDONED_CART_SMTP_TO"),
+		AbandonedCartSMTPTimeout: viper.GetDuration("ABANDONED_CART_SMTP_TIMEOUT"),
+	}
+
+	if err := viper.UnmarshalKey("SHIPPING_RATES", &config.ShippingRates); err != nil {
+		return nil, fmt.Errorf("cannot read SHIPPING_RATES: %w", err)
+	}
+
+	if config.ProductCatalog == "" {
+		config.ProductCatalog = "http"
+	}
+
+	switch config.ProductCatalog {
+	case "fake":
+	case "http":
+		if config.CatalogBaseURL == "" {
+			return nil, fmt.Errorf("CATALOG_BASE_URL is required unless PRODUCT_CATALOG is fake")
+		}
+	default:
+		return nil, fmt.Errorf("PRODUCT_CATALOG must be http or fake, got %q", config.ProductCatalog)
+	}
+
+	if config.CatalogTimeout == 0 {
+		config.CatalogTimeout = 5 * time.Second
+	}
+
+	if config.TotalMismatchPolicy == "" {
+		config.TotalMismatchPolicy = "reject"
+	}
+
+	if config.OrderNumberFormat == "" {
+		config.OrderNumberFormat = "ORD-{date}-{seq}"
+	}
+
+	if config.RefCodeFormat == "" {
+		config.RefCodeFormat = "REF-{seq}-{rand}"
+	}
+
+	if config.IDSeqWidth == 0 {
+		config.IDSeqWidth = 8
+	}
+
+	if config.IdempotencyTTL == 0 {
+		config.IdempotencyTTL = 24 * time.Hour
+	}
+
+	if config.PaymentCurrency == "" {
+		config.PaymentCurrency = "IDR"
+	}
+
+	if config.PaymentWebhookSecret == "" {
+		return nil, fmt.Errorf("PAYMENT_WEBHOOK_SECRET is required")
+	}
+
+	if config.PaymentGateway == "" {
+		config.PaymentGateway = "http"
+	}
+
+	switch config.PaymentGateway {
+	case "fake":
+	case "http":
+		if config.PaymentGatewayURL == "" || config.PaymentGatewayAPIKey == "" {
+			return nil, fmt.Errorf("PAYMENT_GATEWAY_URL and PAYMENT_GATEWAY_API_KEY are required unless PAYMENT_GATEWAY is fake")
+		}
+	default:
+		return nil, fmt.Errorf("PAYMENT_GATEWAY must be http or fake, got %q", config.PaymentGateway)
+	}
+
+	if config.PaymentGatewayProvider == "" {
+		config.PaymentGatewayProvider = "gateway"
+	}
+
+	if config.PaymentGatewayTimeout == 0 {
+		config.PaymentGatewayTimeout = 10 * time.Second
+	}
+
+	if config.RefundReconcileInterval == 0 {
+		config.RefundReconcileInterval = time.Minute
+	}
+
+	if config.RefundReconcileAfter == 0 {
+		config.RefundReconcileAfter = 5 * time.Minute
+	}
+
+	if config.RefundReconcileBatchSize == 0 {
+		config.RefundReconcileBatchSize = 100
+	}
+
+	if config.CourierWebhookSecret == "" {
+		return nil, fmt.Errorf("COURIER_WEBHOOK_SECRET is required")
+	}
+
+	if config.ShippingRatesTimeout == 0 {
+		config.ShippingRatesTimeout = 5 * time.Second
+	}
+
+	if config.OrderPaymentWindow == 0 {
+		config.OrderPaymentWindow = 24 * time.Hour
+	}
+
+	if config.OrderExpiryInterval == 0 {
+		config.OrderExpiryInterval = time.Minute
+	}
+
+	if config.OrderExpiryBatchSize == 0 {
+		config.OrderExpiryBatchSize = 100
+	}
+
+	if config.InvoiceNumberFormat == "" {
+		config.InvoiceNumberFormat = "INV-{year}-{seq}"
+	}
+
+	if config.InvoiceSeqWidth == 0 {
+		config.InvoiceSeqWidth = 6
+	}
+
+	if config.InvoiceSellerName == "" {
+		config.InvoiceSellerName = "ShopeeFun"
+	}
+
+	if config.ExportBatchSize == 0 {
+		config.ExportBatchSize = 1000
+	}
+
+	if config.AnalyticsTimezone == "" {
+		config.AnalyticsTimezone = "UTC"
+	}
+
+	if config.OutboxPublisher == "" {
+		config.OutboxPublisher = "webhook"
+	}
+
+	switch config.OutboxPublisher {
+	case "memory":
+	case "webhook":
+		if config.OutboxWebhookURL == "" || config.OutboxWebhookSecret == "" {
+			return nil, fmt.Errorf("OUTBOX_WEBHOOK_URL and OUTBOX_WEBHOOK_SECRET are required unless OUTBOX_PUBLISHER is memory")
+		}
+	default:
+		return nil, fmt.Errorf("OUTBOX_PUBLISHER must be webhook or memory, got %q", config.OutboxPublisher)
+	}
+
+	if config.OutboxWebhookTimeout == 0 {
+		config.OutboxWebhookTimeout = 5 * time.Second
+	}
+
+	if config.OutboxRelayInterval == 0 {
+		config.OutboxRelayInterval = 5 * time.Second
+	}
+
+	if config.OutboxBatchSize == 0 {
+		config.OutboxBatchSize = 100
+	}
+
+	if config.OutboxLease == 0 {
+		config.OutboxLease = 10 * time.Minute
+	}
+
+	if config.OutboxLease < time.Duration(config.OutboxBatchSize)*config.OutboxWebhookTimeout {
+		return nil, fmt.Errorf("OUTBOX_LEASE must be at least OUTBOX_BATCH_SIZE times OUTBOX_WEBHOOK_TIMEOUT")
+	}
+
+	if config.OutboxMaxAttempts == 0 {
+		config.OutboxMaxAttempts = 10
+	}
+
+	if config.OutboxRetryBackoff == 0 {
+		config.OutboxRetryBackoff = 10 * time.Second
+	}
+
+	if config.OutboxMaxBackoff == 0 {
+		config.OutboxMaxBackoff = time.Hour
+	}
+
+	if config.InventoryClient == "" {
+		config.InventoryClient = "http"
+	}
+
+	switch config.InventoryClient {
+	case "fake":
+	case "http":
+		if config.InventoryBaseURL == "" {
+			return nil, fmt.Errorf("INVENTORY_BASE_URL is required unless INVENTORY_CLIENT is fake")
+		}
+	default:
+		return nil, fmt.Errorf("INVENTORY_CLIENT must be http or fake, got %q", config.InventoryClient)
+	}
+
+	if config.InventoryTimeout == 0 {
+		config.InventoryTimeout = 5 * time.Second
+	}
+
+	if config.InventoryReservationTimeout == 0 {
+		config.InventoryReservationTimeout = 5 * time.Minute
+	}
+
+	if config.InventoryReconcileInterval == 0 {
+		config.InventoryReconcileInterval = 30 * time.Second
+	}
+
+	if config.InventoryReconcileBatchSize == 0 {
+		config.InventoryReconcileBatchSize = 100
+	}
+
+	if config.InventoryRetryInterval == 0 {
+		config.InventoryRetryInterval = time.Minute
+	}
+
+	if config.GuestCartTokenSecret == "" {
+		return nil, fmt.Errorf("GUEST_CART_TOKEN_SECRET is required")
+	}
+
+	if config.GuestCartTTL == 0 {
+		config.GuestCartTTL = 30 * 24 * time.Hour
+	}
+
+	switch config.GuestCartMergeRule {
+	case "":
+		config.GuestCartMergeRule = "sum"
+	case "sum", "max":
+	default:
+		return nil, fmt.Errorf("GUEST_CART_MERGE_RULE must be sum or max, got %q", config.GuestCartMergeRule)
+	}
+
+	if config.GuestCartCleanupInterval == 0 {
+		config.GuestCartCleanupInterval = time.Hour
+	}
+
+	if config.GuestCartCleanupBatch == 0 {
+		config.GuestCartCleanupBatch = 500
+	}
+
+	thresholds := viper.GetStringSlice("ABANDONED_CART_THRESHOLDS")
+	if len(thresholds) == 0 {
+		thresholds = []string{"1h", "24h", "72h"}
+	}
+	for _, t := range thresholds {
+		threshold, err := time.ParseDuration(t)
+		if err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("ABANDONED_CART_THRESHOLDS must be positive durations, got %q", t)
+		}
+		config.AbandonedCartThresholds = append(config.AbandonedCartThresholds, threshold)
+	}
+
+	if config.AbandonedCartInterval == 0 {
+		config.AbandonedCartInterval = 5 * time.Minute
+	}
+
+	if config.AbandonedCartBatchSize == 0 {
+		config.AbandonedCartBatchSize = 100
+	}
+
+	if config.AbandonedCartMaxAttempts == 0 {
+		config.AbandonedCartMaxAttempts = 5
+	}
+
+	if config.AbandonedCartRetry == 0 {
+		config.AbandonedCartRetry = 5 * time.Minute
+	}
+
+	if config.AbandonedCartMaxBackoff == 0 {
+		config.AbandonedCartMaxBackoff = 6 * time.Hour
+	}
+
+	if config.AbandonedCartNotifier == "" {
+		config.AbandonedCartNotifier = "log"
+	}
+
+	switch config.AbandonedCartNotifier {
+	case "log":
+	case "smtp":
+		if config.AbandonedCartSMTPAddr == "" || config.AbandonedCartSMTPFrom == "" || config.AbandonedCartSMTPTo == "" {
+			return nil, fmt.Errorf("ABANDONED_CART_SMTP_ADDR, ABANDONED_CART_SMTP_FROM and ABANDONED_CART_SMTP_TO are required when ABANDONED_CART_NOTIFIER is smtp")
+		}
+	default:
+		return nil, fmt.Errorf("ABANDONED_CART_NOTIFIER must be log or smtp, got %q", config.AbandonedCartNotifier)
+	}
+
+	if config.AbandonedCartSMTPTimeout == 0 {
+		config.AbandonedCartSMTPTimeout = 10 * time.Second
 	}
 
 	return config, nil
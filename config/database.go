@@ -15,8 +15,10 @@ type Connection struct {
 }
 
 func ConnectToDatabase(conn Connection) (*sql.DB, error) {
+	// Timestamps are stored as TIMESTAMP in UTC, so every session runs in UTC: NOW() then writes UTC wall
+	// clock times and time parameters are converted to UTC, whatever the server's default TimeZone is.
 	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s "+
-		"password=%s dbname=%s sslmode=disable",
+		"password=%s dbname=%s sslmode=disable timezone=UTC",
 		conn.Host, conn.Port, conn.User, conn.Password, conn.DBName)
 	db, err := sql.Open("postgres", psqlInfo)
 	if err != nil {
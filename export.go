@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"cart-order-service/config"
+	"cart-order-service/repository/order"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	exportHandler "cart-order-service/handlers/export"
+	exportUseCase "cart-order-service/usecase/export"
+
+	"github.com/rs/zerolog"
+)
+
+// runExport implements the export subcommand, which writes the orders matching its flags to a file:
+//
+//	cart-order-service export -format csv -from 2026-01-01 -to 2026-01-31 -status paid,completed -out orders.csv
+func runExport(ctx context.Context, db *sql.DB, cfg *config.Config, args []string, logger zerolog.Logger) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "export format: csv or ndjson")
+	from := flags.String("from", "", "first creation date or RFC 3339 time to include")
+	to := flags.String("to", "", "last creation date to include, or RFC 3339 time to stop before")
+	status := flags.String("status", "", "comma-separated order statuses to include")
+	out := flags.String("out", "", "file to write the export to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("export: -out is required")
+	}
+
+	bReq, err := exportHandler.ParseExportRequest(*format, *from, *to, *status)
+	if err != nil {
+		return err
+	}
+
+	exportUseCase := exportUseCase.NewExport(order.NewStore(db, logger), cfg.ExportBatchSize, logger)
+	if err := exportUseCase.ValidateExport(bReq); err != nil {
+		return err
+	}
+
+	// The export goes to a temporary file that replaces out once complete, so a failed export leaves no partial file.
+	f, err := os.CreateTemp(filepath.Dir(*out), "."+filepath.Base(*out)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	count, err := exportUseCase.ExportOrders(ctx, bReq, w)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), *out); err != nil {
+		return err
+	}
+
+	logger.Info().Msg(fmt.Sprintf("Exported %v orders to %v", count, *out))
+	return nil
+}
@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// analyticsDto is an interface that defines the methods that our Handler struct depends on.
+type analyticsDto interface {
+	ResolveLocation(tz string) (*time.Location, error)
+	SalesByPeriod(bReq model.SalesReportRequest) (*model.SalesReport, error)
+	SalesByPaymentType(bReq model.SalesReportRequest) (*model.SalesReport, error)
+}
+
+// Handler is a struct that holds an analyticsDto.
+type Handler struct {
+	analytics analyticsDto
+	logger    zerolog.Logger
+}
+
+// NewHandler is a constructor function that returns a new Handler.
+func NewHandler(analytics analyticsDto, logger zerolog.Logger) *Handler {
+	return &Handler{
+		analytics: analytics,
+		logger:    logger,
+	}
+}
+
+// errorStatus maps usecase errors to HTTP status codes. Unknown errors are internal errors.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrInvalidTimezone),
+		errors.Is(err, model.ErrInvalidInterval),
+		errors.Is(err, model.ErrInvalidReportRange):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) SalesByPeriod(w http.ResponseWriter, r *http.Request) {
+	h.salesReport(w, r, "Handler:Analytics - SalesByPeriod:", h.analytics.SalesByPeriod)
+}
+
+func (h *Handler) SalesByPaymentType(w http.ResponseWriter, r *http.Request) {
+	h.salesReport(w, r, "Handler:Analytics - SalesByPaymentType:", h.analytics.SalesByPaymentType)
+}
+
+func (h *Handler) salesReport(w http.ResponseWriter, r *http.Request, logMsgStr string, report func(bReq model.SalesReportRequest) (*model.SalesReport, error)) {
+	bReq, err := h.parseSalesReportRequest(r)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to parse query", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	bRes, err := report(bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to build report", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+// parseSalesReportRequest reads the tz, interval, from and to query parameters of the sales reports.
+// from and to accept a date (2006-01-02), taken in the report timezone, or an RFC 3339 timestamp;
+// a date in to includes the whole day.
+func (h *Handler) parseSalesReportRequest(r *http.Request) (model.SalesReportRequest, error) {
+	query := r.URL.Query()
+
+	loc, err := h.analytics.ResolveLocation(query.Get("tz"))
+	if err != nil {
+		return model.SalesReportRequest{}, err
+	}
+
+	bReq := model.SalesReportRequest{
+		Interval: query.Get("interval"),
+		Location: loc,
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, _, err := helper.ParseDateParamIn(from, loc)
+		if err != nil {
+			return bReq, fmt.Errorf("%w: invalid from: %v", model.ErrInvalidReportRange, err)
+		}
+		bReq.From = t
+	}
+
+	if to := query.Get("to"); to != "" {
+		t, dateOnly, err := helper.ParseDateParamIn(to, loc)
+		if err != nil {
+			return bReq, fmt.Errorf("%w: invalid to: %v", model.ErrInvalidReportRange, err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		bReq.To = t
+	}
+
+	return bReq, nil
+}
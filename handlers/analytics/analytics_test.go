@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	model "cart-order-service/repository/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeAnalytics resolves every timezone but "Nowhere" and remembers the report request it was given.
+type fakeAnalytics struct {
+	bReq *model.SalesReportRequest
+}
+
+func (f *fakeAnalytics) ResolveLocation(tz string) (*time.Location, error) {
+	if tz == "Nowhere" {
+		return nil, fmt.Errorf("%w: %s", model.ErrInvalidTimezone, tz)
+	}
+	return time.FixedZone("UTC+7", 7*3600), nil
+}
+
+func (f *fakeAnalytics) SalesByPeriod(bReq model.SalesReportRequest) (*model.SalesReport, error) {
+	f.bReq = &bReq
+	if bReq.Interval == "hour" {
+		return nil, fmt.Errorf("%w: %s", model.ErrInvalidInterval, bReq.Interval)
+	}
+	return &model.SalesReport{}, nil
+}
+
+func (f *fakeAnalytics) SalesByPaymentType(bReq model.SalesReportRequest) (*model.SalesReport, error) {
+	return f.SalesByPeriod(bReq)
+}
+
+func TestSalesByPeriodQuery(t *testing.T) {
+	utc7 := time.FixedZone("UTC+7", 7*3600)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFrom   time.Time
+		wantTo     time.Time
+	}{
+		{
+			name:       "dates in the report timezone, to inclusive",
+			query:      "tz=Asia/Jakarta&from=2026-10-01&to=2026-10-31",
+			wantStatus: http.StatusOK,
+			wantFrom:   time.Date(2026, 10, 1, 0, 0, 0, 0, utc7),
+			wantTo:     time.Date(2026, 11, 1, 0, 0, 0, 0, utc7),
+		},
+		{
+			name:       "timestamps as given",
+			query:      "from=2026-10-01T00:00:00Z&to=2026-10-02T12:00:00Z",
+			wantStatus: http.StatusOK,
+			wantFrom:   time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:     time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{name: "unknown timezone", query: "tz=Nowhere", wantStatus: http.StatusBadRequest},
+		{name: "bad date", query: "from=01-10-2026", wantStatus: http.StatusBadRequest},
+		{name: "unknown interval", query: "interval=hour", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analytics := &fakeAnalytics{}
+			h := NewHandler(analytics, zerolog.Nop())
+
+			rec := httptest.NewRecorder()
+			h.SalesByPeriod(rec, httptest.NewRequest(http.MethodGet, "/admin/analytics/sales?"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if !analytics.bReq.From.Equal(tt.wantFrom) || !analytics.bReq.To.Equal(tt.wantTo) {
+				t.Errorf("range = %v to %v, want %v to %v", analytics.bReq.From, analytics.bReq.To, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package cart
 import (
 	"cart-order-service/helper"
 	model "cart-order-service/repository/models"
+	"context"
 	"fmt"
 	"net/http"
 
@@ -13,9 +14,16 @@ import (
 // cartDto is an interface that defines the methods that our Handler struct depends on.
 type cartDto interface {
 	GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error)
-	AddCart(bReq model.Cart) (*uuid.UUID, error)
-	UpdateQty(bReq model.Cart) (string, error)
-	DeleteCart(bReq model.DeleteCartRequest) (string, error)
+	GetPricedCart(ctx context.Context, userID uuid.UUID) (*model.PricedCart, error)
+	AddCart(ctx context.Context, bReq model.Cart) (*model.AddCartResponse, error)
+	UpdateQty(ctx context.Context, bReq model.Cart) (string, error)
+	DeleteCart(ctx context.Context, bReq model.DeleteCartRequest) (string, error)
+	CreateGuestCart() (*model.GuestCartToken, error)
+	GetGuestCart(token string) (*model.GuestCart, error)
+	AddGuestCart(ctx context.Context, token string, line model.CartLine) (*model.AddCartResponse, error)
+	UpdateGuestQty(token string, line model.CartLine) (string, error)
+	DeleteGuestProduct(token string, productID uuid.UUID) (string, error)
+	MergeGuestCart(ctx context.Context, bReq model.MergeGuestCartRequest) (*model.MergeGuestCartResponse, error)
 }
 
 // Handler is a struct that holds a cartDto.
@@ -74,6 +82,26 @@ func (h *Handler) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
 	helper.HandleResponse(w, http.StatusOK, bResp)
 }
 
+func (h *Handler) GetPricedCart(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Cart - GetPricedCart:"
+
+	uid, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, r.PathValue("user_id")))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bResp, err := h.cart.GetPricedCart(r.Context(), uid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to GetPricedCart", logMsgStr))
+		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
 func (h *Handler) AddCart(w http.ResponseWriter, r *http.Request) {
 	logMsgStr := "Handler:Cart - AddCart:"
 
@@ -84,13 +112,13 @@ func (h *Handler) AddCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if bReq.Qty == 0 {
+	if bReq.Qty <= 0 {
 		h.logger.Error().Msg(fmt.Sprintf("%v Qty must be greater than 0", logMsgStr))
 		helper.HandleResponse(w, http.StatusBadRequest, "Qty must be greater than 0")
 		return
 	}
 
-	bResp, err := h.cart.AddCart(bReq)
+	bResp, err := h.cart.AddCart(r.Context(), bReq)
 	if err != nil {
 		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to AddCart", logMsgStr))
 		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
@@ -125,7 +153,7 @@ func (h *Handler) UpdateCart(w http.ResponseWriter, r *http.Request) {
 	}
 	bReq.UserID = uid
 
-	bResp, err := h.cart.UpdateQty(bReq)
+	bResp, err := h.cart.UpdateQty(r.Context(), bReq)
 	if err != nil {
 		h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to UpdateQty", logMsgStr))
 		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
@@ -160,7 +188,7 @@ func (h *Handler) DeleteCart(w http.ResponseWriter, r *http.Request) {
 	}
 	bReq.UserID = uid
 
-	bResp, err := h.cart.DeleteCart(bReq)
+	bResp, err := h.cart.DeleteCart(r.Context(), bReq)
 	if err != nil {
 		h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to DeleteCart", logMsgStr))
 		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,140 @@
+package cart
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/carttoken"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// guestErrorStatus maps guest cart errors to HTTP status codes.
+func guestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrInvalidQty),
+		errors.Is(err, model.ErrInvalidMergeRule):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrInvalidCartToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, model.ErrGuestCartNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) CreateGuestCart(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Cart - CreateGuestCart:"
+
+	bResp, err := h.cart.CreateGuestCart()
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to CreateGuestCart", logMsgStr))
+		helper.HandleResponse(w, guestErrorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusCreated, bResp)
+}
+
+func (h *Handler) GetGuestCart(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Cart - GetGuestCart:"
+
+	bResp, err := h.cart.GetGuestCart(r.Header.Get(carttoken.Header))
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to GetGuestCart", logMsgStr))
+		helper.HandleResponse(w, guestErrorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
+func (h *Handler) AddGuestCart(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Cart - AddGuestCart:"
+
+	var bReq model.CartLine
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bResp, err := h.cart.AddGuestCart(r.Context(), r.Header.Get(carttoken.Header), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to AddGuestCart", logMsgStr))
+		helper.HandleResponse(w, guestErrorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
+func (h *Handler) UpdateGuestCart(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Cart - UpdateGuestCart:"
+
+	var bReq model.CartLine
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bResp, err := h.cart.UpdateGuestQty(r.Header.Get(carttoken.Header), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to UpdateGuestQty", logMsgStr))
+		helper.HandleResponse(w, guestErrorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
+func (h *Handler) DeleteGuestCart(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Cart - DeleteGuestCart:"
+
+	var bReq model.DeleteCartRequest
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bResp, err := h.cart.DeleteGuestProduct(r.Header.Get(carttoken.Header), bReq.ProductID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to DeleteGuestProduct", logMsgStr))
+		helper.HandleResponse(w, guestErrorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
+
+func (h *Handler) MergeGuestCart(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Cart - MergeGuestCart:"
+
+	var bReq model.MergeGuestCartRequest
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if bReq.UserID == uuid.Nil {
+		h.logger.Error().Msg(fmt.Sprintf("%v User ID is required", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+	bReq.CartToken = r.Header.Get(carttoken.Header)
+
+	bResp, err := h.cart.MergeGuestCart(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v Failed to MergeGuestCart", logMsgStr))
+		helper.HandleResponse(w, guestErrorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bResp)
+}
@@ -0,0 +1,179 @@
+package cart
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/carttoken"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// fakeGuestCart serves the guest cart methods of cartDto for the token "good" and remembers the token it was
+// given. Any other cartDto method panics.
+type fakeGuestCart struct {
+	cartDto
+	token string
+	merge model.MergeGuestCartRequest
+}
+
+func (f *fakeGuestCart) check(token string) error {
+	f.token = token
+	if token != "good" {
+		return model.ErrInvalidCartToken
+	}
+	return nil
+}
+
+func (f *fakeGuestCart) CreateGuestCart() (*model.GuestCartToken, error) {
+	return &model.GuestCartToken{CartToken: "good"}, nil
+}
+
+func (f *fakeGuestCart) GetGuestCart(token string) (*model.GuestCart, error) {
+	if err := f.check(token); err != nil {
+		return nil, err
+	}
+	return &model.GuestCart{Items: []model.GuestCartItem{}}, nil
+}
+
+func (f *fakeGuestCart) AddGuestCart(ctx context.Context, token string, line model.CartLine) (*model.AddCartResponse, error) {
+	if err := f.check(token); err != nil {
+		return nil, err
+	}
+	if line.Qty <= 0 {
+		return nil, fmt.Errorf("%w: product %s", model.ErrInvalidQty, line.ProductID)
+	}
+	return &model.AddCartResponse{ProductID: line.ProductID, Qty: line.Qty}, nil
+}
+
+func (f *fakeGuestCart) DeleteGuestProduct(token string, productID uuid.UUID) (string, error) {
+	if err := f.check(token); err != nil {
+		return "", err
+	}
+	return "", model.ErrGuestCartNotFound
+}
+
+func (f *fakeGuestCart) MergeGuestCart(ctx context.Context, bReq model.MergeGuestCartRequest) (*model.MergeGuestCartResponse, error) {
+	f.merge = bReq
+	if err := f.check(bReq.CartToken); err != nil {
+		return nil, err
+	}
+	if bReq.Rule != "" && bReq.Rule != model.CartMergeSum && bReq.Rule != model.CartMergeMax {
+		return nil, fmt.Errorf("%w: %q", model.ErrInvalidMergeRule, bReq.Rule)
+	}
+	return &model.MergeGuestCartResponse{UserID: bReq.UserID, Rule: model.CartMergeSum}, nil
+}
+
+func TestGuestCartHandlers(t *testing.T) {
+	productID, userID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name       string
+		handler    func(h *Handler) http.HandlerFunc
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "create", handler: func(h *Handler) http.HandlerFunc { return h.CreateGuestCart }, wantStatus: http.StatusCreated},
+		{name: "get", handler: func(h *Handler) http.HandlerFunc { return h.GetGuestCart }, token: "good", wantStatus: http.StatusOK},
+		{name: "get without token", handler: func(h *Handler) http.HandlerFunc { return h.GetGuestCart }, wantStatus: http.StatusUnauthorized},
+		{
+			name:       "add",
+			handler:    func(h *Handler) http.HandlerFunc { return h.AddGuestCart },
+			token:      "good",
+			body:       fmt.Sprintf(`{"product_id":%q,"qty":2}`, productID),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "add zero qty",
+			handler:    func(h *Handler) http.HandlerFunc { return h.AddGuestCart },
+			token:      "good",
+			body:       fmt.Sprintf(`{"product_id":%q,"qty":0}`, productID),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "add with bad body",
+			handler:    func(h *Handler) http.HandlerFunc { return h.AddGuestCart },
+			token:      "good",
+			body:       `{"qty":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "delete from expired cart",
+			handler:    func(h *Handler) http.HandlerFunc { return h.DeleteGuestCart },
+			token:      "good",
+			body:       fmt.Sprintf(`{"product_id":%q}`, productID),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "merge",
+			handler:    func(h *Handler) http.HandlerFunc { return h.MergeGuestCart },
+			token:      "good",
+			body:       fmt.Sprintf(`{"user_id":%q,"rule":"max"}`, userID),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "merge without user",
+			handler:    func(h *Handler) http.HandlerFunc { return h.MergeGuestCart },
+			token:      "good",
+			body:       `{"rule":"max"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "merge with unknown rule",
+			handler:    func(h *Handler) http.HandlerFunc { return h.MergeGuestCart },
+			token:      "good",
+			body:       fmt.Sprintf(`{"user_id":%q,"rule":"replace"}`, userID),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "merge with forged token",
+			handler:    func(h *Handler) http.HandlerFunc { return h.MergeGuestCart },
+			token:      "forged",
+			body:       fmt.Sprintf(`{"user_id":%q}`, userID),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := &fakeGuestCart{}
+			h := NewHandler(cart, zerolog.Nop())
+
+			req := httptest.NewRequest(http.MethodPost, "/cart/guest", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set(carttoken.Header, tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			tt.handler(h)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestMergeGuestCartPassesTokenAndRule(t *testing.T) {
+	cart := &fakeGuestCart{}
+	h := NewHandler(cart, zerolog.Nop())
+	userID := uuid.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/cart/guest/merge", strings.NewReader(fmt.Sprintf(`{"user_id":%q,"rule":"max","cart_token":"body"}`, userID)))
+	req.Header.Set(carttoken.Header, "good")
+	rec := httptest.NewRecorder()
+	h.MergeGuestCart(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if cart.merge.CartToken != "good" || cart.merge.UserID != userID || cart.merge.Rule != model.CartMergeMax {
+		t.Errorf("merge request = %+v, want the header token, user %s and rule max", cart.merge, userID)
+	}
+}
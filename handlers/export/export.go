@@ -0,0 +1,129 @@
+package export
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// exportDto is an interface that defines the methods that our Handler struct depends on.
+type exportDto interface {
+	ValidateExport(bReq model.ExportOrdersRequest) error
+	ExportOrders(ctx context.Context, bReq model.ExportOrdersRequest, w io.Writer) (int, error)
+}
+
+// Handler is a struct that holds an exportDto.
+type Handler struct {
+	export exportDto
+	logger zerolog.Logger
+}
+
+// NewHandler is a constructor function that returns a new Handler.
+func NewHandler(export exportDto, logger zerolog.Logger) *Handler {
+	return &Handler{
+		export: export,
+		logger: logger,
+	}
+}
+
+// errorStatus maps usecase errors to HTTP status codes. Unknown errors are internal errors.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrInvalidExportFormat):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// contentTypes are the media types of the export formats.
+var contentTypes = map[string]string{
+	model.ExportFormatCSV:    "text/csv; charset=utf-8",
+	model.ExportFormatNDJSON: "application/x-ndjson",
+}
+
+// ExportOrders streams the orders matching the query as a file download.
+// Once the first row is sent the status can no longer change, so later failures cut the download short.
+func (h *Handler) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Export - ExportOrders:"
+
+	query := r.URL.Query()
+	bReq, err := ParseExportRequest(query.Get("format"), query.Get("from"), query.Get("to"), query.Get("status"))
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to parse query", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.export.ValidateExport(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v invalid export request", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	// Large exports outlast the server write timeout.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to clear write deadline", logMsgStr))
+	}
+
+	filename := "orders-" + time.Now().UTC().Format("20060102-150405") + "." + bReq.Format
+	w.Header().Set("Content-Type", contentTypes[bReq.Format])
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := h.export.ExportOrders(r.Context(), bReq, w); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to export orders", logMsgStr))
+	}
+}
+
+// ParseExportRequest builds an export request from its text parameters. An empty format means CSV.
+// from and to accept a date (2006-01-02) or an RFC 3339 timestamp; a date in to includes the whole day.
+// status is a comma-separated list of order statuses.
+func ParseExportRequest(format, from, to, status string) (model.ExportOrdersRequest, error) {
+	bReq := model.ExportOrdersRequest{
+		Format: strings.ToLower(format),
+	}
+	if bReq.Format == "" {
+		bReq.Format = model.ExportFormatCSV
+	}
+
+	if status != "" {
+		for _, s := range strings.Split(status, ",") {
+			orderStatus := model.OrderStatus(strings.TrimSpace(s))
+			if !orderStatus.IsValid() {
+				return bReq, fmt.Errorf("%w: %s", model.ErrInvalidOrderStatus, s)
+			}
+			bReq.Status = append(bReq.Status, orderStatus)
+		}
+	}
+
+	if from != "" {
+		t, _, err := helper.ParseDateParam(from)
+		if err != nil {
+			return bReq, fmt.Errorf("invalid from: %w", err)
+		}
+		bReq.CreatedFrom = &t
+	}
+
+	if to != "" {
+		t, dateOnly, err := helper.ParseDateParam(to)
+		if err != nil {
+			return bReq, fmt.Errorf("invalid to: %w", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		bReq.CreatedTo = &t
+	}
+
+	return bReq, nil
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeExport writes one line per export and remembers the request it was given.
+type fakeExport struct {
+	bReq model.ExportOrdersRequest
+}
+
+func (f *fakeExport) ValidateExport(bReq model.ExportOrdersRequest) error {
+	if bReq.Format != model.ExportFormatCSV && bReq.Format != model.ExportFormatNDJSON {
+		return fmt.Errorf("%w: %q", model.ErrInvalidExportFormat, bReq.Format)
+	}
+	return nil
+}
+
+func (f *fakeExport) ExportOrders(ctx context.Context, bReq model.ExportOrdersRequest, w io.Writer) (int, error) {
+	f.bReq = bReq
+	_, err := io.WriteString(w, "row\n")
+	return 1, err
+}
+
+func TestParseExportRequest(t *testing.T) {
+	at := func(layout, v string) *time.Time {
+		t, _ := time.Parse(layout, v)
+		return &t
+	}
+
+	tests := []struct {
+		name                     string
+		format, from, to, status string
+		wantFormat               string
+		wantFrom, wantTo         *time.Time
+		wantStatus               []model.OrderStatus
+		wantErr                  error
+	}{
+		{name: "defaults to CSV", wantFormat: model.ExportFormatCSV},
+		{name: "format is case-insensitive", format: "NDJSON", wantFormat: model.ExportFormatNDJSON},
+		{
+			name:       "date to includes the whole day",
+			from:       "2026-10-01",
+			to:         "2026-10-31",
+			wantFormat: model.ExportFormatCSV,
+			wantFrom:   at(time.DateOnly, "2026-10-01"),
+			wantTo:     at(time.DateOnly, "2026-11-01"),
+		},
+		{
+			name:       "timestamp to is exclusive as given",
+			to:         "2026-10-31T12:00:00Z",
+			wantFormat: model.ExportFormatCSV,
+			wantTo:     at(time.RFC3339, "2026-10-31T12:00:00Z"),
+		},
+		{
+			name:       "statuses",
+			status:     "paid, completed",
+			wantFormat: model.ExportFormatCSV,
+			wantStatus: []model.OrderStatus{model.OrderStatusPaid, model.OrderStatusCompleted},
+		},
+		{name: "unknown status", status: "paid,lost", wantErr: model.ErrInvalidOrderStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bReq, err := ParseExportRequest(tt.format, tt.from, tt.to, tt.status)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseExportRequest error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if bReq.Format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", bReq.Format, tt.wantFormat)
+			}
+			if !sameTime(bReq.CreatedFrom, tt.wantFrom) || !sameTime(bReq.CreatedTo, tt.wantTo) {
+				t.Errorf("range = %v to %v, want %v to %v", bReq.CreatedFrom, bReq.CreatedTo, tt.wantFrom, tt.wantTo)
+			}
+			if fmt.Sprint(bReq.Status) != fmt.Sprint(tt.wantStatus) {
+				t.Errorf("status = %v, want %v", bReq.Status, tt.wantStatus)
+			}
+		})
+	}
+
+	if _, err := ParseExportRequest("", "yesterday", "", ""); err == nil {
+		t.Error("ParseExportRequest accepted an unparseable from")
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestExportOrdersHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		wantStatus      int
+		wantContentType string
+	}{
+		{name: "csv", query: "format=csv&status=paid", wantStatus: http.StatusOK, wantContentType: "text/csv; charset=utf-8"},
+		{name: "ndjson", query: "format=ndjson", wantStatus: http.StatusOK, wantContentType: "application/x-ndjson"},
+		{name: "unknown format", query: "format=xlsx", wantStatus: http.StatusBadRequest},
+		{name: "bad date", query: "from=2026-13-01", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := &fakeExport{}
+			h := NewHandler(export, zerolog.Nop())
+
+			rec := httptest.NewRecorder()
+			h.ExportOrders(rec, httptest.NewRequest(http.MethodGet, "/admin/orders/export?"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if export.bReq.Format != "" {
+					t.Error("a rejected request was exported")
+				}
+				return
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			disposition := rec.Header().Get("Content-Disposition")
+			if !strings.HasPrefix(disposition, "attachment;") || !strings.Contains(disposition, "."+export.bReq.Format) {
+				t.Errorf("Content-Disposition = %q, want an attachment named after the format", disposition)
+			}
+			if rec.Body.String() != "row\n" {
+				t.Errorf("body = %q, want the streamed export", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package invoice
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// invoiceDto is an interface that defines the methods that our Handler struct depends on.
+type invoiceDto interface {
+	GetInvoicePDF(ctx context.Context, orderID uuid.UUID) (*model.InvoiceFile, error)
+}
+
+// Handler is a struct that holds an invoiceDto.
+type Handler struct {
+	invoice invoiceDto
+	logger  zerolog.Logger
+}
+
+// NewHandler is a constructor function that returns a new Handler.
+func NewHandler(invoice invoiceDto, logger zerolog.Logger) *Handler {
+	return &Handler{
+		invoice: invoice,
+		logger:  logger,
+	}
+}
+
+// errorStatus maps usecase errors to HTTP status codes. Unknown errors are internal errors.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrOrderNotInvoiceable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetInvoicePDF serves the invoice of an order as a PDF download. Errors are still reported as JSON.
+func (h *Handler) GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Invoice - GetInvoicePDF:"
+
+	orderID := r.PathValue("id")
+	oid, err := uuid.Parse(orderID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, orderID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	file, err := h.invoice.GetInvoicePDF(r.Context(), oid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to get invoice", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.Content)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(file.Content); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to write invoice", logMsgStr))
+	}
+}
@@ -3,9 +3,13 @@ package order
 import (
 	"cart-order-service/helper"
 	model "cart-order-service/repository/models"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator"
 	"github.com/google/uuid"
@@ -13,7 +17,14 @@ import (
 )
 
 type orderDto interface {
-	CreateOrder(bReq model.Order) (*uuid.UUID, error)
+	CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, error)
+	Checkout(ctx context.Context, bReq model.CheckoutRequest) (*model.CheckoutResponse, error)
+	UpdateOrderStatus(ctx context.Context, bReq model.UpdateOrderStatusRequest) (*model.OrderItemsLogs, error)
+	GetOrderByID(orderID uuid.UUID) (*model.OrderDetail, error)
+	GetOrderByRefCode(refCode string) (*model.OrderDetail, error)
+	ListOrders(bReq model.ListOrdersRequest) (*model.ListOrdersResponse, error)
+	QuoteShipping(ctx context.Context, bReq model.QuoteShippingRequest) (*model.QuoteShippingResponse, error)
+	Reorder(ctx context.Context, orderID uuid.UUID) (*model.ReorderResponse, error)
 }
 
 type Handler struct {
@@ -22,6 +33,33 @@ type Handler struct {
 	logger    zerolog.Logger
 }
 
+// errorStatus maps usecase errors to HTTP status codes. Unknown errors are internal errors.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrEmptyCart),
+		errors.Is(err, model.ErrEmptyOrder),
+		errors.Is(err, model.ErrInvalidProductOrder),
+		errors.Is(err, model.ErrInvalidQty),
+		errors.Is(err, model.ErrInvalidOrderStatus),
+		errors.Is(err, model.ErrInvalidCursor),
+		errors.Is(err, model.ErrShippingPostalCode):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrInvalidStatusTransition),
+		errors.Is(err, model.ErrInsufficientStock),
+		errors.Is(err, model.ErrCartChanged):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrProductNotFound),
+		errors.Is(err, model.ErrProductUnavailable),
+		errors.Is(err, model.ErrTotalPriceMismatch),
+		errors.Is(err, model.ErrShippingServiceNotFound):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func NewHandler(order orderDto, validator *validator.Validate, logger zerolog.Logger) *Handler {
 	return &Handler{
 		order:     order,
@@ -40,8 +78,6 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bReq.RefCode = helper.GenerateRefCode()
-
 	if bReq.ProductOrder == nil {
 		bReq.ProductOrder = json.RawMessage("[]")
 	}
@@ -52,12 +88,296 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bRes, err := h.order.CreateOrder(bReq)
+	bRes, err := h.order.CreateOrder(r.Context(), bReq)
 	if err != nil {
 		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to create order", logMsgStr))
-		helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusCreated, bRes)
+}
+
+func (h *Handler) Checkout(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - Checkout:"
+
+	var bReq model.CheckoutRequest
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to validate request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.order.Checkout(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to checkout", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
 		return
 	}
 
 	helper.HandleResponse(w, http.StatusCreated, bRes)
 }
+
+func (h *Handler) QuoteShipping(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - QuoteShipping:"
+
+	var bReq model.QuoteShippingRequest
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to validate request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.order.QuoteShipping(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to quote shipping", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) Reorder(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - Reorder:"
+
+	orderID := r.PathValue("id")
+	oid, err := uuid.Parse(orderID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, orderID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.order.Reorder(r.Context(), oid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to reorder", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - UpdateOrderStatus:"
+
+	orderID := r.PathValue("id")
+	oid, err := uuid.Parse(orderID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, orderID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var bReq model.UpdateOrderStatusRequest
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bReq.OrderID = oid
+
+	if err := h.validator.Struct(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to validate request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.order.UpdateOrderStatus(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to update order status", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - GetOrderByID:"
+
+	orderID := r.PathValue("id")
+	oid, err := uuid.Parse(orderID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, orderID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.order.GetOrderByID(oid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to get order", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) GetOrderByRefCode(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - GetOrderByRefCode:"
+
+	refCode := r.PathValue("ref_code")
+	if refCode == "" {
+		h.logger.Error().Msg(fmt.Sprintf("%v Ref code is required", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, "Ref code is required")
+		return
+	}
+
+	bRes, err := h.order.GetOrderByRefCode(refCode)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to get order", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) ListOrdersByUserID(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - ListOrdersByUserID:"
+
+	userID := r.PathValue("user_id")
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, userID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bReq, err := parseListOrdersRequest(r)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to parse query", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bReq.UserID = uid
+
+	h.listOrders(w, bReq, logMsgStr)
+}
+
+func (h *Handler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Order - ListOrders:"
+
+	bReq, err := parseListOrdersRequest(r)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to parse query", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, userID))
+			helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		bReq.UserID = uid
+	}
+
+	h.listOrders(w, bReq, logMsgStr)
+}
+
+func (h *Handler) listOrders(w http.ResponseWriter, bReq model.ListOrdersRequest, logMsgStr string) {
+	bRes, err := h.order.ListOrders(bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to list orders", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+// parseListOrdersRequest reads the filter, sort and pagination query parameters shared by the order listings.
+// product_id keeps the orders containing that product.
+// created_from and created_to accept a date (2006-01-02) or an RFC 3339 timestamp; a date in created_to includes the whole day.
+func parseListOrdersRequest(r *http.Request) (model.ListOrdersRequest, error) {
+	query := r.URL.Query()
+	bReq := model.ListOrdersRequest{
+		SortBy:    query.Get("sort_by"),
+		SortOrder: query.Get("sort_order"),
+		Cursor:    query.Get("cursor"),
+	}
+
+	if status := query.Get("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			orderStatus := model.OrderStatus(strings.TrimSpace(s))
+			if !orderStatus.IsValid() {
+				return bReq, fmt.Errorf("%w: %s", model.ErrInvalidOrderStatus, s)
+			}
+			bReq.Status = append(bReq.Status, orderStatus)
+		}
+	}
+
+	if productID := query.Get("product_id"); productID != "" {
+		pid, err := uuid.Parse(productID)
+		if err != nil {
+			return bReq, fmt.Errorf("invalid product_id: %w", err)
+		}
+		bReq.ProductID = pid
+	}
+
+	if isPaid := query.Get("is_paid"); isPaid != "" {
+		v, err := strconv.ParseBool(isPaid)
+		if err != nil {
+			return bReq, fmt.Errorf("invalid is_paid: %w", err)
+		}
+		bReq.IsPaid = &v
+	}
+
+	if from := query.Get("created_from"); from != "" {
+		t, _, err := helper.ParseDateParam(from)
+		if err != nil {
+			return bReq, fmt.Errorf("invalid created_from: %w", err)
+		}
+		bReq.CreatedFrom = &t
+	}
+
+	if to := query.Get("created_to"); to != "" {
+		t, dateOnly, err := helper.ParseDateParam(to)
+		if err != nil {
+			return bReq, fmt.Errorf("invalid created_to: %w", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		bReq.CreatedTo = &t
+	}
+
+	switch bReq.SortBy {
+	case "", model.OrderSortByCreatedAt, model.OrderSortByTotalPrice:
+	default:
+		return bReq, fmt.Errorf("invalid sort_by: %s", bReq.SortBy)
+	}
+
+	switch bReq.SortOrder {
+	case "", model.SortOrderAsc, model.SortOrderDesc:
+	default:
+		return bReq, fmt.Errorf("invalid sort_order: %s", bReq.SortOrder)
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil || v <= 0 {
+			return bReq, fmt.Errorf("invalid limit: %s", limit)
+		}
+		bReq.Limit = v
+	}
+
+	return bReq, nil
+}
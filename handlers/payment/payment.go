@@ -0,0 +1,99 @@
+package payment
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// maxWebhookBodySize bounds the size of a webhook body read into memory.
+const maxWebhookBodySize = 1 << 20
+
+// paymentDto is an interface that defines the methods that our Handler struct depends on.
+type paymentDto interface {
+	CreatePaymentIntent(ctx context.Context, orderID uuid.UUID) (*model.Payment, error)
+	HandleWebhook(ctx context.Context, header http.Header, body []byte) error
+}
+
+// Handler is a struct that holds a paymentDto.
+type Handler struct {
+	payment paymentDto
+	logger  zerolog.Logger
+}
+
+// NewHandler is a constructor function that returns a new Handler.
+func NewHandler(payment paymentDto, logger zerolog.Logger) *Handler {
+	return &Handler{
+		payment: payment,
+		logger:  logger,
+	}
+}
+
+// errorStatus maps usecase errors to HTTP status codes. Unknown errors are internal errors.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrInvalidWebhookPayload):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrInvalidSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, model.ErrOrderNotFound),
+		errors.Is(err, model.ErrPaymentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrOrderNotPayable),
+		errors.Is(err, model.ErrPaymentPending):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrPaymentAmountMismatch):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) CreatePaymentIntent(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Payment - CreatePaymentIntent:"
+
+	orderID := r.PathValue("id")
+	oid, err := uuid.Parse(orderID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, orderID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.payment.CreatePaymentIntent(r.Context(), oid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to create payment intent", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusCreated, bRes)
+}
+
+// Webhook receives payment status callbacks from the gateway. The raw body is
+// passed on untouched, since the signature covers its exact bytes.
+func (h *Handler) Webhook(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Payment - Webhook:"
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to read request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.payment.HandleWebhook(r.Context(), r.Header, body); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to handle webhook", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, helper.SUCCESS_MESSSAGE)
+}
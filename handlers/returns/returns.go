@@ -0,0 +1,227 @@
+package returns
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// returnsDto is an interface that defines the methods that our Handler struct depends on.
+type returnsDto interface {
+	CreateReturn(ctx context.Context, bReq model.CreateReturnRequest) (*model.ReturnRequest, error)
+	ListReturns(orderID uuid.UUID) (*[]model.ReturnRequest, error)
+	GetReturn(orderID, returnID uuid.UUID) (*model.ReturnRequest, error)
+	ApproveReturn(ctx context.Context, bReq model.ReviewReturnRequest) (*model.ReturnRequest, error)
+	RejectReturn(ctx context.Context, bReq model.ReviewReturnRequest) (*model.ReturnRequest, error)
+	RefundReturn(ctx context.Context, bReq model.RefundReturnRequest) (*model.ReturnRequest, error)
+}
+
+// Handler is a struct that holds a returnsDto.
+type Handler struct {
+	returns   returnsDto
+	validator *validator.Validate
+	logger    zerolog.Logger
+}
+
+// NewHandler is a constructor function that returns a new Handler.
+func NewHandler(returns returnsDto, validator *validator.Validate, logger zerolog.Logger) *Handler {
+	return &Handler{
+		returns:   returns,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// errorStatus maps usecase errors to HTTP status codes. Unknown errors are internal errors.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrUnknownOrderItem):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrOrderNotFound),
+		errors.Is(err, model.ErrReturnNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrOrderNotReturnable),
+		errors.Is(err, model.ErrInvalidReturnTransition),
+		errors.Is(err, model.ErrInvalidStatusTransition),
+		errors.Is(err, model.ErrNoRefundablePayment),
+		errors.Is(err, model.ErrRefundInProgress),
+		errors.Is(err, model.ErrRefundAmountChanged):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrReturnQtyExceeded),
+		errors.Is(err, model.ErrRefundAmountExceeded):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parsePathIDs parses the order ID and, when present, the return ID from the request path.
+func (h *Handler) parsePathIDs(w http.ResponseWriter, r *http.Request, logMsgStr string) (uuid.UUID, uuid.UUID, bool) {
+	orderID := r.PathValue("id")
+	oid, err := uuid.Parse(orderID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, orderID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	returnID := r.PathValue("return_id")
+	if returnID == "" {
+		return oid, uuid.Nil, true
+	}
+
+	rid, err := uuid.Parse(returnID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, returnID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return oid, rid, true
+}
+
+func (h *Handler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Returns - CreateReturn:"
+
+	oid, _, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	var bReq model.CreateReturnRequest
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bReq.OrderID = oid
+
+	if err := h.validator.Struct(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to validate request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.returns.CreateReturn(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to create return", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusCreated, bRes)
+}
+
+func (h *Handler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Returns - ListReturns:"
+
+	oid, _, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	bRes, err := h.returns.ListReturns(oid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to list returns", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Returns - GetReturn:"
+
+	oid, rid, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	bRes, err := h.returns.GetReturn(oid, rid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to get return", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	h.reviewReturn(w, r, "Handler:Returns - ApproveReturn:", h.returns.ApproveReturn)
+}
+
+func (h *Handler) RejectReturn(w http.ResponseWriter, r *http.Request) {
+	h.reviewReturn(w, r, "Handler:Returns - RejectReturn:", h.returns.RejectReturn)
+}
+
+// reviewReturn parses a review request and passes it to review.
+func (h *Handler) reviewReturn(w http.ResponseWriter, r *http.Request, logMsgStr string, review func(context.Context, model.ReviewReturnRequest) (*model.ReturnRequest, error)) {
+	oid, rid, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	var bReq model.ReviewReturnRequest
+	if r.ContentLength != 0 {
+		if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+			h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+			helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	bReq.OrderID = oid
+	bReq.ReturnID = rid
+
+	bRes, err := review(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to review return", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) RefundReturn(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Returns - RefundReturn:"
+
+	oid, rid, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	var bReq model.RefundReturnRequest
+	if r.ContentLength != 0 {
+		if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+			h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+			helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	bReq.OrderID = oid
+	bReq.ReturnID = rid
+
+	if err := h.validator.Struct(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to validate request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.returns.RefundReturn(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to refund return", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
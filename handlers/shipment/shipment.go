@@ -0,0 +1,216 @@
+package shipment
+
+import (
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// maxWebhookBodySize bounds the size of a webhook body read into memory.
+const maxWebhookBodySize = 1 << 20
+
+// shipmentDto is an interface that defines the methods that our Handler struct depends on.
+type shipmentDto interface {
+	CreateShipment(ctx context.Context, bReq model.CreateShipmentRequest) (*model.Shipment, error)
+	AssignTracking(ctx context.Context, bReq model.AssignTrackingRequest) (*model.Shipment, error)
+	ListShipments(orderID uuid.UUID) (*[]model.Shipment, error)
+	GetShipment(orderID, shipmentID uuid.UUID) (*model.Shipment, error)
+	HandleWebhook(ctx context.Context, header http.Header, body []byte) error
+}
+
+// Handler is a struct that holds a shipmentDto.
+type Handler struct {
+	shipment  shipmentDto
+	validator *validator.Validate
+	logger    zerolog.Logger
+}
+
+// NewHandler is a constructor function that returns a new Handler.
+func NewHandler(shipment shipmentDto, validator *validator.Validate, logger zerolog.Logger) *Handler {
+	return &Handler{
+		shipment:  shipment,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// errorStatus maps usecase errors to HTTP status codes. Unknown errors are internal errors.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrUnknownOrderItem),
+		errors.Is(err, model.ErrInvalidWebhookPayload):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrInvalidSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, model.ErrOrderNotFound),
+		errors.Is(err, model.ErrShipmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrOrderNotShippable),
+		errors.Is(err, model.ErrNothingToShip),
+		errors.Is(err, model.ErrShipmentDispatched),
+		errors.Is(err, model.ErrTrackingNumberTaken),
+		errors.Is(err, model.ErrInvalidStatusTransition):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrShipmentQtyExceeded):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parsePathIDs parses the order ID and, when present, the shipment ID from the request path.
+func (h *Handler) parsePathIDs(w http.ResponseWriter, r *http.Request, logMsgStr string) (uuid.UUID, uuid.UUID, bool) {
+	orderID := r.PathValue("id")
+	oid, err := uuid.Parse(orderID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, orderID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	shipmentID := r.PathValue("shipment_id")
+	if shipmentID == "" {
+		return oid, uuid.Nil, true
+	}
+
+	sid, err := uuid.Parse(shipmentID)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v error parse uuid: %v", logMsgStr, shipmentID))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return oid, sid, true
+}
+
+func (h *Handler) CreateShipment(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Shipment - CreateShipment:"
+
+	oid, _, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	var bReq model.CreateShipmentRequest
+	if r.ContentLength != 0 {
+		if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+			h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+			helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	bReq.OrderID = oid
+
+	if err := h.validator.Struct(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to validate request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.shipment.CreateShipment(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to create shipment", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusCreated, bRes)
+}
+
+func (h *Handler) AssignTracking(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Shipment - AssignTracking:"
+
+	oid, sid, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	var bReq model.AssignTrackingRequest
+	if err := helper.ParseRequestBody(r, &bReq, h.logger); err != nil {
+		h.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v failed to decode request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bReq.OrderID = oid
+	bReq.ShipmentID = sid
+
+	if err := h.validator.Struct(bReq); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to validate request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bRes, err := h.shipment.AssignTracking(r.Context(), bReq)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to assign tracking", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) ListShipments(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Shipment - ListShipments:"
+
+	oid, _, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	bRes, err := h.shipment.ListShipments(oid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to list shipments", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+func (h *Handler) GetShipment(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Shipment - GetShipment:"
+
+	oid, sid, ok := h.parsePathIDs(w, r, logMsgStr)
+	if !ok {
+		return
+	}
+
+	bRes, err := h.shipment.GetShipment(oid, sid)
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to get shipment", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, bRes)
+}
+
+// Webhook receives tracking events from couriers. The raw body is passed on untouched,
+// since the signature covers its exact bytes.
+func (h *Handler) Webhook(w http.ResponseWriter, r *http.Request) {
+	logMsgStr := "Handler:Shipment - Webhook:"
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to read request body", logMsgStr))
+		helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.shipment.HandleWebhook(r.Context(), r.Header, body); err != nil {
+		h.logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to handle webhook", logMsgStr))
+		helper.HandleResponse(w, errorStatus(err), err.Error())
+		return
+	}
+
+	helper.HandleResponse(w, http.StatusOK, helper.SUCCESS_MESSSAGE)
+}
@@ -2,7 +2,6 @@ package helper
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"regexp"
@@ -11,14 +10,6 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func GenerateRefCode() string {
-	currentUnixTime := time.Now().Unix()
-
-	refCode := fmt.Sprintf("REF%d", currentUnixTime)
-
-	return refCode
-}
-
 func PrintAllRequest(w http.ResponseWriter, r *http.Request, logger zerolog.Logger) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -34,6 +25,7 @@ func PrintAllRequest(w http.ResponseWriter, r *http.Request, logger zerolog.Logg
 
 }
 
+// ParseRequestBody logs the request body with whitespace removed and decodes the original body into v.
 func ParseRequestBody(r *http.Request, v interface{}, logger zerolog.Logger) error {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -46,5 +38,21 @@ func ParseRequestBody(r *http.Request, v interface{}, logger zerolog.Logger) err
 
 	logger.Info().Any("Request Body", bodyStr).Msg("Print All Request")
 
-	return json.Unmarshal([]byte(bodyStr), v)
+	return json.Unmarshal(body, v)
+}
+
+// ParseDateParam parses a date (2006-01-02) or an RFC 3339 timestamp and reports whether it was a plain date.
+// Dates are midnight UTC.
+func ParseDateParam(v string) (time.Time, bool, error) {
+	return ParseDateParamIn(v, time.UTC)
+}
+
+// ParseDateParamIn is like ParseDateParam, but dates are midnight in loc.
+func ParseDateParamIn(v string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, v, loc); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
 }
@@ -1,24 +1,67 @@
 package main
 
 import (
+	"cart-order-service/client/catalog"
+	"cart-order-service/client/courier"
+	"cart-order-service/client/inventory"
+	"cart-order-service/client/notifier"
+	"cart-order-service/client/publisher"
+	"cart-order-service/client/shipping"
 	"cart-order-service/config"
 	cartHandler "cart-order-service/handlers/cart"
+	"cart-order-service/repository/analytics"
 	"cart-order-service/repository/cart"
+	"cart-order-service/repository/guestcart"
+	"cart-order-service/repository/idempotency"
+	"cart-order-service/repository/idgen"
+	"cart-order-service/repository/invoice"
+	model "cart-order-service/repository/models"
 	"cart-order-service/repository/order"
+	"cart-order-service/repository/outbox"
+	"cart-order-service/repository/payment"
+	"cart-order-service/repository/reminder"
+	"cart-order-service/repository/reservation"
+	"cart-order-service/repository/returns"
+	"cart-order-service/repository/shipment"
+	"cart-order-service/repository/transaction"
 	"cart-order-service/routes"
 	cartUsecase "cart-order-service/usecase/cart"
+	"cart-order-service/util/middleware"
+	"cart-order-service/worker"
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"os/signal"
 	"time"
 
+	paymentClient "cart-order-service/client/payment"
+	analyticsHandler "cart-order-service/handlers/analytics"
+	exportHandler "cart-order-service/handlers/export"
+	invoiceHandler "cart-order-service/handlers/invoice"
 	orderHandler "cart-order-service/handlers/order"
+	paymentHandler "cart-order-service/handlers/payment"
+	returnsHandler "cart-order-service/handlers/returns"
+	shipmentHandler "cart-order-service/handlers/shipment"
+	analyticsUseCase "cart-order-service/usecase/analytics"
+	exportUseCase "cart-order-service/usecase/export"
+	inventoryUseCase "cart-order-service/usecase/inventory"
+	invoiceUseCase "cart-order-service/usecase/invoice"
 	orderUseCase "cart-order-service/usecase/order"
+	outboxUseCase "cart-order-service/usecase/outbox"
+	paymentUseCase "cart-order-service/usecase/payment"
+	reminderUseCase "cart-order-service/usecase/reminder"
+	returnsUseCase "cart-order-service/usecase/returns"
+	shipmentUseCase "cart-order-service/usecase/shipment"
 
 	"github.com/go-playground/validator"
+	"github.com/google/uuid"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+
+	// Embedded so report timezones resolve on hosts without a zoneinfo database.
+	_ "time/tzdata"
 )
 
 func main() {
@@ -65,24 +108,333 @@ func main() {
 	}
 	defer sqlDb.Close()
 
+	// The export subcommand writes an order export to a file instead of starting the server.
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if err := runExport(ctx, sqlDb, cfg, os.Args[2:], logger); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to export orders")
+		}
+		return
+	}
+
 	validator := validator.New()
 
-	routes := setupRoutes(sqlDb, validator, logger)
+	routes, workers, err := setupRoutes(sqlDb, cfg, validator, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to setup routes")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, w := range workers {
+		go w.Run(ctx)
+	}
+
 	routes.Run(cfg.AppPort)
 }
 
-func setupRoutes(db *sql.DB, validator *validator.Validate, logger zerolog.Logger) *routes.Routes {
+// setupRoutes wires the stores, usecases and handlers, and returns the routes together with the background workers.
+func setupRoutes(db *sql.DB, cfg *config.Config, validator *validator.Validate, logger zerolog.Logger) (*routes.Routes, []*worker.Worker, error) {
+	productCatalog := newProductCatalog(cfg, logger)
+
+	idGenerator, err := idgen.NewGenerator(db, idgen.Config{
+		OrderNumberFormat: cfg.OrderNumberFormat,
+		RefCodeFormat:     cfg.RefCodeFormat,
+		SeqWidth:          cfg.IDSeqWidth,
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shippingRates, err := newShippingRateProvider(cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outboxRepository := outbox.NewStore(db, logger)
 
 	cartRepository := cart.NewStore(db, logger)
-	cartUseCase := cartUsecase.NewCart(cartRepository, logger)
+	guestCartRepository := guestcart.NewStore(db, logger)
+	cartTxManager := transaction.NewManager(db, func(tx *sql.Tx) cartUsecase.Repositories {
+		return cartUsecase.Repositories{
+			Cart:      cartRepository.WithTx(tx),
+			GuestCart: guestCartRepository.WithTx(tx),
+			Outbox:    outboxRepository.WithTx(tx),
+		}
+	}, logger)
+	cartUseCase := cartUsecase.NewCart(cartRepository, guestCartRepository, cartTxManager, productCatalog, cartUsecase.GuestConfig{
+		TokenSecret: cfg.GuestCartTokenSecret,
+		TTL:         cfg.GuestCartTTL,
+		MergeRule:   cfg.GuestCartMergeRule,
+	}, logger)
 	cartHandler := cartHandler.NewHandler(cartUseCase, logger)
 
 	orderRepository := order.NewStore(db, logger)
-	orderUseCase := orderUseCase.NewOrder(orderRepository, logger)
+	paymentRepository := payment.NewStore(db, logger)
+	reservationRepository := reservation.NewStore(db, logger)
+	inventoryClient := newInventoryClient(cfg, logger)
+
+	orderTxManager := transaction.NewManager(db, func(tx *sql.Tx) orderUseCase.Repositories {
+		return orderUseCase.Repositories{
+			Cart:        cartRepository.WithTx(tx),
+			Order:       orderRepository.WithTx(tx),
+			Payment:     paymentRepository.WithTx(tx),
+			Outbox:      outboxRepository.WithTx(tx),
+			Reservation: reservationRepository.WithTx(tx),
+		}
+	}, logger)
+	orderUseCase := orderUseCase.NewOrder(orderRepository, orderTxManager, productCatalog, cartUseCase, idGenerator, shippingRates, inventoryClient, reservationRepository, cfg.InventoryReservationTimeout, cfg.TotalMismatchPolicy, logger)
 	orderHandler := orderHandler.NewHandler(orderUseCase, validator, logger)
 
-	return &routes.Routes{
-		Cart:  cartHandler,
-		Order: orderHandler,
+	paymentTxManager := transaction.NewManager(db, func(tx *sql.Tx) paymentUseCase.Repositories {
+		return paymentUseCase.Repositories{
+			Order:       orderRepository.WithTx(tx),
+			Payment:     paymentRepository.WithTx(tx),
+			Outbox:      outboxRepository.WithTx(tx),
+			Reservation: reservationRepository.WithTx(tx),
+		}
+	}, logger)
+	paymentGateway := newPaymentGateway(cfg, logger)
+	paymentUseCase := paymentUseCase.NewPayment(orderRepository, paymentRepository, paymentTxManager, paymentGateway, cfg.PaymentCurrency, logger)
+	paymentHandler := paymentHandler.NewHandler(paymentUseCase, logger)
+
+	returnsRepository := returns.NewStore(db, logger)
+	returnsTxManager := transaction.NewManager(db, func(tx *sql.Tx) returnsUseCase.Repositories {
+		return returnsUseCase.Repositories{
+			Order:   orderRepository.WithTx(tx),
+			Payment: paymentRepository.WithTx(tx),
+			Return:  returnsRepository.WithTx(tx),
+			Outbox:  outboxRepository.WithTx(tx),
+		}
+	}, logger)
+	returnsUseCase := returnsUseCase.NewReturns(orderRepository, returnsRepository, returnsTxManager, paymentGateway, logger)
+	returnsHandler := returnsHandler.NewHandler(returnsUseCase, validator, logger)
+
+	shipmentRepository := shipment.NewStore(db, logger)
+	shipmentTxManager := transaction.NewManager(db, func(tx *sql.Tx) shipmentUseCase.Repositories {
+		return shipmentUseCase.Repositories{
+			Order:    orderRepository.WithTx(tx),
+			Shipment: shipmentRepository.WithTx(tx),
+			Outbox:   outboxRepository.WithTx(tx),
+		}
+	}, logger)
+	courierWebhook := courier.NewWebhook(cfg.CourierWebhookSecret)
+	shipmentUseCase := shipmentUseCase.NewShipment(orderRepository, shipmentRepository, shipmentTxManager, courierWebhook, logger)
+	shipmentHandler := shipmentHandler.NewHandler(shipmentUseCase, validator, logger)
+
+	invoiceRepository := invoice.NewStore(db, logger)
+	invoiceTxManager := transaction.NewManager(db, func(tx *sql.Tx) invoiceUseCase.Repositories {
+		return invoiceUseCase.Repositories{
+			Order:   orderRepository.WithTx(tx),
+			Payment: paymentRepository.WithTx(tx),
+			Invoice: invoiceRepository.WithTx(tx),
+		}
+	}, logger)
+	invoiceUseCase, err := invoiceUseCase.NewInvoice(invoiceRepository, invoiceTxManager, invoiceUseCase.Config{
+		NumberFormat: cfg.InvoiceNumberFormat,
+		SeqWidth:     cfg.InvoiceSeqWidth,
+		Currency:     cfg.PaymentCurrency,
+		Seller: model.InvoiceSeller{
+			Name:    cfg.InvoiceSellerName,
+			Address: cfg.InvoiceSellerAddr,
+			TaxID:   cfg.InvoiceSellerTaxID,
+			Email:   cfg.InvoiceSellerEmail,
+		},
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	invoiceHandler := invoiceHandler.NewHandler(invoiceUseCase, logger)
+
+	exportUseCase := exportUseCase.NewExport(orderRepository, cfg.ExportBatchSize, logger)
+	exportHandler := exportHandler.NewHandler(exportUseCase, logger)
+
+	analyticsLocation, err := time.LoadLocation(cfg.AnalyticsTimezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ANALYTICS_TIMEZONE: %w", err)
+	}
+	analyticsRepository := analytics.NewStore(db, logger)
+	analyticsUseCase := analyticsUseCase.NewAnalytics(analyticsRepository, analyticsLocation, logger)
+	analyticsHandler := analyticsHandler.NewHandler(analyticsUseCase, logger)
+
+	outboxRelay := outboxUseCase.NewRelay(outboxRepository, newPublisher(cfg, logger), outboxUseCase.Config{
+		BatchSize:    cfg.OutboxBatchSize,
+		Lease:        cfg.OutboxLease,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		RetryBackoff: cfg.OutboxRetryBackoff,
+		MaxBackoff:   cfg.OutboxMaxBackoff,
+	}, logger)
+
+	inventoryTxManager := transaction.NewManager(db, func(tx *sql.Tx) inventoryUseCase.Repositories {
+		return inventoryUseCase.Repositories{
+			Reservation: reservationRepository.WithTx(tx),
+		}
+	}, logger)
+	inventoryReconciler := inventoryUseCase.NewReconciler(inventoryTxManager, inventoryClient, inventoryUseCase.Config{
+		BatchSize:     cfg.InventoryReconcileBatchSize,
+		RetryInterval: cfg.InventoryRetryInterval,
+	}, logger)
+
+	abandonedCartNotifier, err := newNotifier(cfg, logger)
+	if err != nil {
+		return nil, nil, err
 	}
+	reminderRepository := reminder.NewStore(db, logger)
+	reminderTxManager := transaction.NewManager(db, func(tx *sql.Tx) reminderUseCase.Repositories {
+		return reminderUseCase.Repositories{
+			Reminder: reminderRepository.WithTx(tx),
+		}
+	}, logger)
+	cartReminder := reminderUseCase.NewReminder(reminderRepository, reminderTxManager, abandonedCartNotifier, reminderUseCase.Config{
+		Thresholds:   cfg.AbandonedCartThresholds,
+		BatchSize:    cfg.AbandonedCartBatchSize,
+		MaxAttempts:  cfg.AbandonedCartMaxAttempts,
+		RetryBackoff: cfg.AbandonedCartRetry,
+		MaxBackoff:   cfg.AbandonedCartMaxBackoff,
+	}, logger)
+
+	idempotencyRepository := idempotency.NewStore(db, cfg.IdempotencyTTL, logger)
+
+	routes := &routes.Routes{
+		Cart:        cartHandler,
+		Order:       orderHandler,
+		Payment:     paymentHandler,
+		Returns:     returnsHandler,
+		Shipment:    shipmentHandler,
+		Invoice:     invoiceHandler,
+		Export:      exportHandler,
+		Analytics:   analyticsHandler,
+		Idempotency: middleware.Idempotency(idempotencyRepository, logger),
+	}
+
+	workers := []*worker.Worker{
+		worker.NewWorker("OrderExpiry", cfg.OrderExpiryInterval, func(ctx context.Context) error {
+			_, err := orderUseCase.ExpireUnpaidOrders(ctx, cfg.OrderPaymentWindow, cfg.OrderExpiryBatchSize)
+			return err
+		}, logger),
+		worker.NewWorker("RefundReconciler", cfg.RefundReconcileInterval, func(ctx context.Context) error {
+			_, err := returnsUseCase.ReconcileRefunds(ctx, cfg.RefundReconcileAfter, cfg.RefundReconcileBatchSize)
+			return err
+		}, logger),
+		worker.NewWorker("OutboxRelay", cfg.OutboxRelayInterval, func(ctx context.Context) error {
+			_, err := outboxRelay.RelayEvents(ctx)
+			return err
+		}, logger),
+		worker.NewWorker("InventoryReconciler", cfg.InventoryReconcileInterval, func(ctx context.Context) error {
+			_, err := inventoryReconciler.Reconcile(ctx)
+			return err
+		}, logger),
+		worker.NewWorker("GuestCartCleanup", cfg.GuestCartCleanupInterval, func(ctx context.Context) error {
+			_, err := cartUseCase.CleanupGuestCarts(ctx, cfg.GuestCartCleanupBatch)
+			return err
+		}, logger),
+		worker.NewWorker("AbandonedCartReminder", cfg.AbandonedCartInterval, func(ctx context.Context) error {
+			_, err := cartReminder.SendReminders(ctx)
+			return err
+		}, logger),
+	}
+
+	return routes, workers, nil
+}
+
+// productCatalog is an interface satisfied by both catalog client implementations.
+type productCatalog interface {
+	GetProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.Product, error)
+}
+
+// newProductCatalog returns the HTTP catalog client, or a fake catalog holding the seeded products when
+// PRODUCT_CATALOG is fake. The fake prices orders from hard-coded seed prices and has to be chosen explicitly.
+func newProductCatalog(cfg *config.Config, logger zerolog.Logger) productCatalog {
+	if cfg.ProductCatalog == "fake" {
+		logger.Warn().Msg("PRODUCT_CATALOG is fake, products are priced from the seeded catalog")
+		return catalog.NewFake(catalog.SeedProducts...)
+	}
+
+	return catalog.NewHTTPClient(cfg.CatalogBaseURL, cfg.CatalogTimeout, logger)
+}
+
+// paymentGateway is the gateway used to collect payments and send refunds.
+type paymentGateway interface {
+	paymentUseCase.PaymentGateway
+	Refund(ctx context.Context, bReq model.RefundRequest) (*model.RefundResult, error)
+}
+
+// newPaymentGateway returns the HTTP payment gateway client, or the fake gateway when PAYMENT_GATEWAY is fake.
+// The fake never moves money and has to be chosen explicitly.
+func newPaymentGateway(cfg *config.Config, logger zerolog.Logger) paymentGateway {
+	if cfg.PaymentGateway == "fake" {
+		logger.Warn().Msg("PAYMENT_GATEWAY is fake, payments are never collected")
+		return paymentClient.NewFake(cfg.PaymentWebhookSecret)
+	}
+
+	return paymentClient.NewHTTPGateway(paymentClient.HTTPConfig{
+		Provider:      cfg.PaymentGatewayProvider,
+		BaseURL:       cfg.PaymentGatewayURL,
+		APIKey:        cfg.PaymentGatewayAPIKey,
+		WebhookSecret: cfg.PaymentWebhookSecret,
+		Timeout:       cfg.PaymentGatewayTimeout,
+	}, logger)
+}
+
+// fakeSeedStock is the qty of every seeded product held by the fake inventory.
+const fakeSeedStock = 1000
+
+// newInventoryClient returns the HTTP inventory client, or a fake inventory stocking the seeded products when
+// INVENTORY_CLIENT is fake. The fake lives in the process, so its stock is not shared between replicas, and it
+// has to be chosen explicitly.
+func newInventoryClient(cfg *config.Config, logger zerolog.Logger) orderUseCase.InventoryClient {
+	if cfg.InventoryClient == "fake" {
+		logger.Warn().Msg("INVENTORY_CLIENT is fake, stock is held in this process only")
+
+		stock := make(map[uuid.UUID]int, len(catalog.SeedProducts))
+		for _, p := range catalog.SeedProducts {
+			stock[p.ID] = fakeSeedStock
+		}
+		return inventory.NewFake(stock)
+	}
+
+	return inventory.NewHTTPClient(cfg.InventoryBaseURL, cfg.InventoryTimeout, logger)
+}
+
+// newShippingRateProvider returns the HTTP rates client when a rates service URL is configured,
+// and the rate table from the config otherwise.
+func newShippingRateProvider(cfg *config.Config, logger zerolog.Logger) (orderUseCase.ShippingRateProvider, error) {
+	if cfg.ShippingRatesURL == "" {
+		return shipping.NewTable(cfg.ShippingRates)
+	}
+
+	return shipping.NewHTTPClient(cfg.ShippingRatesURL, cfg.ShippingRatesTimeout, logger), nil
+}
+
+// newPublisher returns the publisher selected by OUTBOX_PUBLISHER: the webhook publisher, or an in-memory
+// publisher that drops events when the process exits.
+func newPublisher(cfg *config.Config, logger zerolog.Logger) outboxUseCase.Publisher {
+	if cfg.OutboxPublisher == "memory" {
+		logger.Warn().Msg("OUTBOX_PUBLISHER is memory, outbox events are only kept in memory")
+		return publisher.NewMemory()
+	}
+
+	return publisher.NewWebhook(cfg.OutboxWebhookURL, cfg.OutboxWebhookSecret, cfg.OutboxWebhookTimeout, logger)
+}
+
+// newNotifier returns the notifier selected by ABANDONED_CART_NOTIFIER: one that only logs abandoned-cart
+// reminders, or the development SMTP notifier, which mails every reminder to a single configured address.
+func newNotifier(cfg *config.Config, logger zerolog.Logger) (reminderUseCase.Notifier, error) {
+	if cfg.AbandonedCartNotifier != "smtp" {
+		return notifier.NewLog(logger), nil
+	}
+
+	logger.Warn().Msg(fmt.Sprintf("ABANDONED_CART_NOTIFIER is smtp, abandoned-cart reminders are mailed to %s, not to the users", cfg.AbandonedCartSMTPTo))
+
+	return notifier.NewSMTP(notifier.SMTPConfig{
+		Addr:     cfg.AbandonedCartSMTPAddr,
+		Username: cfg.AbandonedCartSMTPUser,
+		Password: cfg.AbandonedCartSMTPPass,
+		From:     cfg.AbandonedCartSMTPFrom,
+		To:       cfg.AbandonedCartSMTPTo,
+		Timeout:  cfg.AbandonedCartSMTPTimeout,
+	}, logger)
 }
@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE order_items (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    order_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    qty INT NOT NULL CHECK (qty > 0),
+    unit_price DOUBLE PRECISION NOT NULL,
+    line_total DOUBLE PRECISION NOT NULL,
+    product_name VARCHAR(255),
+    product_sku VARCHAR(100),
+    created_at TIMESTAMP DEFAULT now(),
+
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_order_items_order_id ON order_items (order_id);
+
+-- Backfill the lines of existing orders from product_order. Legacy product IDs
+-- that are not UUIDs (e.g. "prod1" in the seed data) get a deterministic UUID
+-- derived from the original value, which is kept in product_sku.
+INSERT INTO order_items (order_id, product_id, qty, unit_price, line_total, product_sku, created_at)
+SELECT
+    o.id,
+    CASE
+        WHEN item->>'product_id' ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+            THEN (item->>'product_id')::uuid
+        ELSE md5('legacy-product:' || (item->>'product_id'))::uuid
+    END,
+    (item->>'qty')::int,
+    COALESCE((item->>'price')::double precision, 0),
+    COALESCE((item->>'price')::double precision, 0) * (item->>'qty')::int,
+    CASE
+        WHEN item->>'product_id' ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+            THEN NULL
+        ELSE item->>'product_id'
+    END,
+    o.created_at
+FROM orders o
+CROSS JOIN LATERAL jsonb_array_elements(
+    CASE WHEN jsonb_typeof(o.product_order) = 'array' THEN o.product_order ELSE '[]'::jsonb END
+) AS item
+WHERE (item->>'qty')::int > 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Orders created after this migration only have their lines in order_items;
+-- write them back to product_order before dropping the table.
+UPDATE orders o
+SET product_order = items.product_order
+FROM (
+    SELECT
+        order_id,
+        jsonb_agg(jsonb_build_object('product_id', product_id, 'qty', qty, 'price', unit_price) ORDER BY created_at, id) AS product_order
+    FROM order_items
+    GROUP BY order_id
+) AS items
+WHERE o.id = items.order_id AND o.product_order IS NULL;
+
+DROP TABLE IF EXISTS order_items CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE SEQUENCE IF NOT EXISTS order_number_seq;
+CREATE SEQUENCE IF NOT EXISTS ref_code_seq;
+
+-- Existing rows may share a ref code or order number (the old ref codes were
+-- based on the current second). Keep the oldest row as is and suffix the others.
+WITH duplicates AS (
+    SELECT id, ref_code || '-' || ROW_NUMBER() OVER (PARTITION BY ref_code ORDER BY created_at, id) AS new_ref_code,
+        ROW_NUMBER() OVER (PARTITION BY ref_code ORDER BY created_at, id) AS rn
+    FROM orders
+    WHERE ref_code IS NOT NULL
+)
+UPDATE orders o
+SET ref_code = d.new_ref_code
+FROM duplicates d
+WHERE o.id = d.id AND d.rn > 1;
+
+UPDATE order_status_logs l
+SET ref_code = o.ref_code
+FROM orders o
+WHERE l.order_id = o.id AND l.ref_code <> o.ref_code;
+
+WITH duplicates AS (
+    SELECT id, order_number || '-' || ROW_NUMBER() OVER (PARTITION BY order_number ORDER BY created_at, id) AS new_order_number,
+        ROW_NUMBER() OVER (PARTITION BY order_number ORDER BY created_at, id) AS rn
+    FROM orders
+)
+UPDATE orders o
+SET order_number = d.new_order_number
+FROM duplicates d
+WHERE o.id = d.id AND d.rn > 1;
+
+ALTER TABLE orders ADD CONSTRAINT orders_order_number_key UNIQUE (order_number);
+ALTER TABLE orders ADD CONSTRAINT orders_ref_code_key UNIQUE (ref_code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_ref_code_key;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_order_number_key;
+
+DROP SEQUENCE IF EXISTS ref_code_seq;
+DROP SEQUENCE IF EXISTS order_number_seq;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE payments (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    order_id UUID NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    intent_id VARCHAR(255) NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    checkout_url TEXT,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP,
+
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    UNIQUE (provider, intent_id)
+);
+
+CREATE INDEX idx_payments_order_id ON payments (order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS payments CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX idx_orders_pending_unpaid_created_at ON orders (created_at)
+    WHERE deleted_at IS NULL AND status = 'pending' AND is_paid = FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_orders_pending_unpaid_created_at;
+-- +goose StatementEnd
@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE return_requests (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    order_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL,
+    resolution_notes TEXT,
+    refund_amount DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP,
+
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_return_requests_order_id ON return_requests (order_id, created_at);
+
+CREATE TABLE return_items (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    return_id UUID NOT NULL,
+    order_item_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    qty INT NOT NULL CHECK (qty > 0),
+    unit_price DOUBLE PRECISION NOT NULL,
+    line_total DOUBLE PRECISION NOT NULL,
+    reason TEXT,
+    photos JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP DEFAULT now(),
+
+    FOREIGN KEY (return_id) REFERENCES return_requests(id),
+    FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
+
+CREATE INDEX idx_return_items_return_id ON return_items (return_id);
+CREATE INDEX idx_return_items_order_item_id ON return_items (order_item_id);
+
+CREATE TABLE refunds (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    return_id UUID NOT NULL,
+    payment_id UUID NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    provider_refund_id VARCHAR(255),
+    amount DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP,
+
+    FOREIGN KEY (return_id) REFERENCES return_requests(id),
+    FOREIGN KEY (payment_id) REFERENCES payments(id)
+);
+
+CREATE INDEX idx_refunds_return_id ON refunds (return_id);
+CREATE INDEX idx_refunds_payment_id ON refunds (payment_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS refunds CASCADE;
+DROP TABLE IF EXISTS return_items CASCADE;
+DROP TABLE IF EXISTS return_requests CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE shipments (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    order_id UUID NOT NULL,
+    courier VARCHAR(50),
+    tracking_number VARCHAR(100),
+    status VARCHAR(50) NOT NULL,
+    -- Tracking times come from courier clocks, so they keep their time zone.
+    shipped_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    last_event_at TIMESTAMPTZ,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP,
+
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_shipments_order_id ON shipments (order_id, created_at);
+CREATE UNIQUE INDEX ux_shipments_courier_tracking_number ON shipments (courier, tracking_number)
+    WHERE tracking_number IS NOT NULL;
+
+CREATE TABLE shipment_items (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    shipment_id UUID NOT NULL,
+    order_item_id UUID NOT NULL,
+    qty INT NOT NULL CHECK (qty > 0),
+    created_at TIMESTAMP DEFAULT now(),
+
+    FOREIGN KEY (shipment_id) REFERENCES shipments(id),
+    FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
+
+CREATE INDEX idx_shipment_items_shipment_id ON shipment_items (shipment_id);
+CREATE INDEX idx_shipment_items_order_item_id ON shipment_items (order_item_id);
+
+CREATE TABLE shipment_events (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    shipment_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    description TEXT,
+    location VARCHAR(255),
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+
+    FOREIGN KEY (shipment_id) REFERENCES shipments(id),
+    -- Couriers retry webhooks; a repeated event is stored once.
+    UNIQUE (shipment_id, status, occurred_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shipment_events CASCADE;
+DROP TABLE IF EXISTS shipment_items CASCADE;
+DROP TABLE IF EXISTS shipments CASCADE;
+-- +goose StatementEnd
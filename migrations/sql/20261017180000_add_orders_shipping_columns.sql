@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE orders
+    ADD COLUMN subtotal DOUBLE PRECISION,
+    ADD COLUMN shipping_fee DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN shipping_service VARCHAR(50),
+    ADD COLUMN shipping_postal_code VARCHAR(20);
+
+-- Existing orders have no shipping fee, so their total is their subtotal.
+UPDATE orders SET subtotal = total_price;
+
+ALTER TABLE orders
+    ALTER COLUMN subtotal SET DEFAULT 0,
+    ALTER COLUMN subtotal SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shipping_postal_code,
+    DROP COLUMN IF EXISTS shipping_service,
+    DROP COLUMN IF EXISTS shipping_fee,
+    DROP COLUMN IF EXISTS subtotal;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Invoice numbers must not skip values, which a Postgres sequence cannot promise: a rolled back
+-- nextval is lost. The counter row is incremented inside the transaction that issues the invoice,
+-- so a rollback gives the number back and concurrent issuers queue up on the row lock.
+CREATE TABLE invoice_counters (
+    name VARCHAR(50) PRIMARY KEY,
+    last_value BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO invoice_counters (name, last_value) VALUES ('invoice', 0);
+
+CREATE TABLE invoices (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    order_id UUID NOT NULL UNIQUE,
+    seq BIGINT NOT NULL UNIQUE,
+    invoice_number VARCHAR(100) NOT NULL UNIQUE,
+    issued_at TIMESTAMPTZ NOT NULL,
+    -- Everything printed on the invoice, frozen when it is issued so reprints are identical.
+    document JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS invoices CASCADE;
+DROP TABLE IF EXISTS invoice_counters CASCADE;
+-- +goose StatementEnd
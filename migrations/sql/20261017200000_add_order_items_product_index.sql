@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Serves order searches by product. order_items holds the lines of every order, legacy ones included,
+-- so product_order needs no index of its own.
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items (product_id, order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_order_items_product_id;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Domain events are written to the outbox in the transaction that changes the data they describe,
+-- and published afterwards by the relay worker. seq orders the events of an aggregate: the relay
+-- only publishes the oldest unpublished event of each aggregate.
+CREATE TABLE outbox (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    seq BIGSERIAL NOT NULL UNIQUE,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMP NOT NULL DEFAULT now(),
+    created_at TIMESTAMP DEFAULT now(),
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (available_at, seq) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_pending_aggregate ON outbox (aggregate_type, aggregate_id, seq) WHERE published_at IS NULL;
+
+-- Events that failed OUTBOX_MAX_ATTEMPTS times are moved here so they stop blocking their aggregate.
+-- They can be replayed by inserting them back into the outbox.
+CREATE TABLE outbox_dead_letters (
+    id UUID PRIMARY KEY,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT,
+    created_at TIMESTAMP,
+    dead_at TIMESTAMP DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox_dead_letters CASCADE;
+DROP TABLE IF EXISTS outbox CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Stock is reserved at the inventory service before an order is committed. The reservation row is written
+-- before the call and records the action still owed to the inventory service: a pending reservation whose
+-- order never committed must be released, and releasing and confirming reservations wait for the reconciler.
+-- next_attempt_at is when the reconciler may act on the reservation.
+CREATE TABLE stock_reservations (
+    id UUID PRIMARY KEY,
+    order_id UUID UNIQUE,
+    lines JSONB NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP,
+
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_stock_reservations_reconcile ON stock_reservations (next_attempt_at)
+    WHERE status IN ('pending', 'releasing', 'confirming');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS stock_reservations CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Fold duplicate active lines into the oldest line of each product before the index rules them out.
+WITH ranked AS (
+    SELECT
+        id,
+        ROW_NUMBER() OVER (PARTITION BY user_id, product_id ORDER BY created_at, id) AS rn,
+        SUM(qty) OVER (PARTITION BY user_id, product_id) AS total_qty
+    FROM cart_items
+    WHERE deleted_at IS NULL
+), kept AS (
+    UPDATE cart_items c
+    SET qty = r.total_qty, updated_at = now()
+    FROM ranked r
+    WHERE c.id = r.id AND r.rn = 1 AND c.qty <> r.total_qty
+)
+UPDATE cart_items c
+SET deleted_at = now(), updated_at = now()
+FROM ranked r
+WHERE c.id = r.id AND r.rn > 1;
+
+-- A user has at most one active line per product; adding the product again raises its qty.
+CREATE UNIQUE INDEX uq_cart_items_user_product_active ON cart_items (user_id, product_id) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Lines folded together by the up migration are not split again.
+DROP INDEX IF EXISTS uq_cart_items_user_product_active;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The catalog price of the product when it was last added to the cart, so the priced cart can warn about
+-- price changes since. Lines added before this column existed have no price to compare against.
+ALTER TABLE cart_items ADD COLUMN added_unit_price DOUBLE PRECISION;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE cart_items DROP COLUMN IF EXISTS added_unit_price;
+-- +goose StatementEnd
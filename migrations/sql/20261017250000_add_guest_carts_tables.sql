@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A guest cart belongs to a shopper who has not logged in and is reached through a signed cart token.
+-- Every change pushes expires_at back; expired guest carts are deleted by a cleanup job.
+CREATE TABLE guest_carts (
+    id UUID PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP
+);
+
+CREATE INDEX idx_guest_carts_expires_at ON guest_carts (expires_at);
+
+-- Guest cart lines are removed outright; they are moved into the user's cart_items on merge.
+CREATE TABLE guest_cart_items (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    guest_cart_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    qty INT NOT NULL,
+    added_unit_price DOUBLE PRECISION,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP,
+
+    UNIQUE (guest_cart_id, product_id),
+    FOREIGN KEY (guest_cart_id) REFERENCES guest_carts(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS guest_cart_items CASCADE;
+DROP TABLE IF EXISTS guest_carts CASCADE;
+-- +goose StatementEnd
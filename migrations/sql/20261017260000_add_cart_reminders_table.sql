@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A reminder is sent once per abandoned-cart threshold for each idle period of a cart. cart_activity_at is the
+-- cart's most recent activity when the reminder was sent; any later change to the cart starts a new idle period.
+-- A reminder that failed to send is kept as failed with its attempts and is not retried before next_attempt_at.
+CREATE TABLE cart_reminders (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    threshold_seconds BIGINT NOT NULL,
+    cart_activity_at TIMESTAMP NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP,
+    sent_at TIMESTAMP,
+
+    UNIQUE (user_id, cart_activity_at, threshold_seconds)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cart_reminders CASCADE;
+-- +goose StatementEnd
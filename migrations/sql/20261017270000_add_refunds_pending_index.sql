@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A return has at most one pending refund: retries of an unsettled refund reuse its row, and the refund
+-- reconciler resends refunds left pending for too long.
+CREATE UNIQUE INDEX idx_refunds_pending_return ON refunds (return_id) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_refunds_pending_return;
+-- +goose StatementEnd
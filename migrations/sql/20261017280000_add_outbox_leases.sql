@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The relay claims a batch of events by leasing them, commits, and publishes them outside the transaction.
+-- lease_id identifies the claim; leased_until is when another relay may claim the event again if the
+-- claiming relay never reported back.
+ALTER TABLE outbox
+    ADD COLUMN lease_id UUID,
+    ADD COLUMN leased_until TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox
+    DROP COLUMN IF EXISTS leased_until,
+    DROP COLUMN IF EXISTS lease_id;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Idempotency keys are scoped to the caller that sent them, so two clients picking the same key do not see
+-- each other's responses. scope is a SHA-256 of the caller's identity; rows recorded before it existed keep
+-- the empty scope and expire with IDEMPOTENCY_TTL.
+ALTER TABLE idempotency_keys ADD COLUMN scope CHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey,
+    ADD PRIMARY KEY (scope, key, method, path);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM idempotency_keys WHERE scope <> '';
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey,
+    ADD PRIMARY KEY (key, method, path);
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS scope;
+-- +goose StatementEnd
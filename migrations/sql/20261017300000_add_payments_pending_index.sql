@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An order has at most one pending payment; a second request for a payment intent gets the pending one back.
+-- Succeeded payments are left out: a failed payment may still succeed at the gateway after the customer started
+-- another one, and that money must be recorded so it can be refunded. Older duplicate pending payments are
+-- expired first; a webhook for one of them is still applied.
+UPDATE payments p
+SET status = 'expired', updated_at = now()
+WHERE p.status = 'pending'
+    AND EXISTS (
+        SELECT 1
+        FROM payments n
+        WHERE n.order_id = p.order_id
+            AND n.status = 'pending'
+            AND (n.created_at, n.id) > (p.created_at, p.id)
+    );
+
+CREATE UNIQUE INDEX idx_payments_pending_order ON payments (order_id) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_payments_pending_order;
+-- +goose StatementEnd
@@ -0,0 +1,95 @@
+package analytics
+
+import (
+	model "cart-order-service/repository/models"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type store struct {
+	db     *sql.DB
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+// Reports only read, so the store is never bound to a transaction.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetSalesAggregates is a method that sums the orders created in the requested range per period and payment type.
+// Periods are truncated in the request location, and PeriodStart holds the wall clock time the period starts at
+// in that location. Order times are stored in UTC, which the UTC session time zone set by
+// config.ConnectToDatabase guarantees.
+func (s *store) GetSalesAggregates(bReq model.SalesReportRequest) (*[]model.SalesAggregate, error) {
+	logMsgStr := "Repository:Analytics - GetSalesAggregates:"
+
+	querySelect := `
+		SELECT
+			date_trunc($1, (created_at AT TIME ZONE 'UTC') AT TIME ZONE $2) AS period_start,
+			payment_type_id,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE is_paid),
+			COUNT(*) FILTER (WHERE status = $3),
+			COUNT(*) FILTER (WHERE is_paid AND status <> $3),
+			COALESCE(SUM(total_price) FILTER (WHERE is_paid AND status <> $3), 0)
+		FROM orders
+		WHERE deleted_at IS NULL
+			AND created_at >= $4
+			AND created_at < $5
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`
+
+	rows, err := s.db.Query(
+		querySelect,
+		bReq.Interval,
+		bReq.Location.String(),
+		model.OrderStatusCancelled,
+		bReq.From.UTC(),
+		bReq.To.UTC(),
+	)
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	aggregates := []model.SalesAggregate{}
+	for rows.Next() {
+		var (
+			agg         model.SalesAggregate
+			periodStart time.Time
+		)
+		if err := rows.Scan(
+			&periodStart,
+			&agg.PaymentTypeID,
+			&agg.OrderCount,
+			&agg.PaidCount,
+			&agg.CancelledCount,
+			&agg.GMVCount,
+			&agg.GMV,
+		); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+
+		// The database returns the wall clock time without a zone; place it back in the report location.
+		agg.PeriodStart = time.Date(periodStart.Year(), periodStart.Month(), periodStart.Day(),
+			periodStart.Hour(), periodStart.Minute(), periodStart.Second(), 0, bReq.Location)
+		aggregates = append(aggregates, agg)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &aggregates, nil
+}
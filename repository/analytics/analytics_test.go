@@ -0,0 +1,143 @@
+package analytics
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/testdb"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// insertOrder inserts a paid order of paymentTypeID created at createdAt, or at NOW() if createdAt is nil.
+func insertOrder(t *testing.T, db *sql.DB, paymentTypeID uuid.UUID, createdAt *time.Time) {
+	t.Helper()
+
+	id := uuid.NewString()
+	if _, err := db.Exec(`
+		INSERT INTO orders (user_id, payment_type_id, order_number, total_price, status, is_paid, ref_code, created_at)
+		VALUES ($1, $2, $3, 100, $4, true, $3, COALESCE($5::timestamp, NOW()))
+	`, uuid.New(), paymentTypeID, "TEST-"+id, model.OrderStatusPaid, createdAt); err != nil {
+		t.Fatalf("insert order: %v", err)
+	}
+}
+
+// utc returns a UTC wall clock time written like the database stores order times.
+func utc(value string) *time.Time {
+	ts, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return &ts
+}
+
+// ordersPerPeriod returns the order count of each period of a report, keyed by the period start in its location.
+func ordersPerPeriod(t *testing.T, s *store, paymentTypeID uuid.UUID, bReq model.SalesReportRequest) map[string]int64 {
+	t.Helper()
+
+	aggregates, err := s.GetSalesAggregates(bReq)
+	if err != nil {
+		t.Fatalf("GetSalesAggregates: %v", err)
+	}
+
+	counts := map[string]int64{}
+	for _, agg := range *aggregates {
+		if agg.PaymentTypeID != paymentTypeID {
+			continue
+		}
+		if agg.PeriodStart.Location() != bReq.Location {
+			t.Errorf("period %v is not in %v", agg.PeriodStart, bReq.Location)
+		}
+		counts[agg.PeriodStart.Format("2006-01-02 15:04")] += agg.OrderCount
+	}
+
+	return counts
+}
+
+func TestGetSalesAggregatesBucketsInTheRequestLocation(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("no Asia/Jakarta timezone data: %v", err)
+	}
+
+	paymentTypeID := uuid.New()
+	for _, createdAt := range []string{
+		"2025-02-28 16:30", // 23:30 on the 28th in Jakarta
+		"2025-02-28 17:30", // 00:30 on the 1st in Jakarta
+		"2025-03-01 16:59", // 23:59 on the 1st in Jakarta
+		"2025-03-31 18:00", // 01:00 on April 1st in Jakarta
+	} {
+		insertOrder(t, db, paymentTypeID, utc(createdAt))
+	}
+
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, jakarta)
+	to := time.Date(2025, 5, 1, 0, 0, 0, 0, jakarta)
+
+	tests := []struct {
+		name     string
+		interval string
+		location *time.Location
+		want     map[string]int64
+	}{
+		{
+			name:     "days in Jakarta",
+			interval: "day",
+			location: jakarta,
+			want:     map[string]int64{"2025-02-28 00:00": 1, "2025-03-01 00:00": 2, "2025-04-01 00:00": 1},
+		},
+		{
+			name:     "days in UTC",
+			interval: "day",
+			location: time.UTC,
+			want:     map[string]int64{"2025-02-28 00:00": 2, "2025-03-01 00:00": 1, "2025-03-31 00:00": 1},
+		},
+		{
+			name:     "months in Jakarta",
+			interval: "month",
+			location: jakarta,
+			want:     map[string]int64{"2025-02-01 00:00": 1, "2025-03-01 00:00": 2, "2025-04-01 00:00": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ordersPerPeriod(t, s, paymentTypeID, model.SalesReportRequest{From: from, To: to, Interval: tt.interval, Location: tt.location})
+
+			if len(got) != len(tt.want) {
+				t.Errorf("periods = %v, want %v", got, tt.want)
+			}
+			for period, count := range tt.want {
+				if got[period] != count {
+					t.Errorf("period %s has %d orders, want %d", period, got[period], count)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSalesAggregatesWithOrdersCreatedNow(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+
+	// NOW() writes the session's wall clock time, so this only lands in today's UTC bucket if the session is in UTC.
+	paymentTypeID := uuid.New()
+	insertOrder(t, db, paymentTypeID, nil)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	got := ordersPerPeriod(t, s, paymentTypeID, model.SalesReportRequest{
+		From:     today.Add(-24 * time.Hour),
+		To:       today.Add(48 * time.Hour),
+		Interval: "day",
+		Location: time.UTC,
+	})
+
+	if len(got) != 1 || got[today.Format("2006-01-02 15:04")] != 1 {
+		t.Errorf("periods = %v, want the order in today's UTC period", got)
+	}
+}
@@ -2,17 +2,20 @@ package cart
 
 import (
 	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
 	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
 type store struct {
 	db     *sql.DB
+	tx     *sql.Tx
 	logger zerolog.Logger
 }
 
@@ -24,6 +27,28 @@ func NewStore(db *sql.DB, logger zerolog.Logger) *store {
 	}
 }
 
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (s *store) querier() transaction.Querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
 // GetCartByUserID is a method that retrieves the cart for a given user.
 // It returns a slice of cart and an error if any occurs during the retrieval process.
 func (s *store) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error) {
@@ -31,7 +56,14 @@ func (s *store) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error
 
 	querySelect := `
 		SELECT
-			*
+			id,
+			user_id,
+			product_id,
+			qty,
+			added_unit_price,
+			created_at,
+			updated_at,
+			deleted_at
 		FROM cart_items
 		WHERE deleted_at IS NULL
 	`
@@ -54,7 +86,7 @@ func (s *store) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error
 		querySelect += " AND " + strings.Join(queryConditions, " AND ")
 	}
 
-	rows, err := s.db.Query(querySelect)
+	rows, err := s.querier().Query(querySelect)
 	if err != nil {
 		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
 		return nil, err
@@ -69,6 +101,7 @@ func (s *store) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error
 			&cart.UserID,
 			&cart.ProductID,
 			&cart.Qty,
+			&cart.AddedUnitPrice,
 			&cart.CreatedAt,
 			&cart.UpdatedAt,
 			&cart.DeletedAt,
@@ -87,37 +120,69 @@ func (s *store) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error
 	return &carts, nil
 }
 
-func (s *store) AddCart(bReq model.Cart) (*uuid.UUID, error) {
+// upsertQuery returns a statement that puts a qty of a product in the user's cart, creating the line if there
+// is none and setting the qty of an existing line to qtyExpr, and records the product's current price on it.
+// The partial unique index on (user_id, product_id) makes concurrent adds of the same product merge
+// instead of racing to insert. xmax is 0 only for a freshly inserted row.
+func upsertQuery(qtyExpr string) string {
+	return fmt.Sprintf(`
+		INSERT INTO cart_items (
+			user_id,
+			product_id,
+			qty,
+			added_unit_price,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, NOW()
+		)
+		ON CONFLICT (user_id, product_id) WHERE deleted_at IS NULL
+		DO UPDATE SET
+			qty = %s,
+			added_unit_price = COALESCE(EXCLUDED.added_unit_price, cart_items.added_unit_price),
+			updated_at = NOW()
+		RETURNING id, user_id, product_id, qty, added_unit_price, created_at, updated_at, deleted_at, (xmax = 0) AS created
+	`, qtyExpr)
+}
+
+var (
+	// queryUpsert adds the qty to the product's line.
+	queryUpsert = upsertQuery("cart_items.qty + EXCLUDED.qty")
+	// queryUpsertKeepMax keeps the larger of the line's qty and the given qty.
+	queryUpsertKeepMax = upsertQuery("GREATEST(cart_items.qty, EXCLUDED.qty)")
+)
+
+// AddCart is a method that adds a product to a user's cart. If the product already has an active line,
+// its qty is increased instead of a second line being created.
+func (s *store) AddCart(bReq model.Cart) (*model.AddCartResponse, error) {
 	logMsgStr := "Repository:Cart - AddCart:"
 
-	tx, err := s.db.Begin()
+	tx, err := s.begin()
 	if err != nil {
 		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
 		return nil, err
 	}
 
-	var id uuid.UUID
-	queryCreate := `
-		INSERT INTO cart_items (
-			user_id,
-			product_id,
-			qty,
-			created_at
-		) VALUES (
-			$1,
-			$2,
-			$3,
-			NOW()
-		) RETURNING id
-	`
+	var cart model.Cart
+	var created bool
 	if err := tx.QueryRow(
-		queryCreate,
+		queryUpsert,
 		bReq.UserID,
 		bReq.ProductID,
 		bReq.Qty,
-	).Scan(&id); err != nil {
+		bReq.AddedUnitPrice,
+	).Scan(
+		&cart.ID,
+		&cart.UserID,
+		&cart.ProductID,
+		&cart.Qty,
+		&cart.AddedUnitPrice,
+		&cart.CreatedAt,
+		&cart.UpdatedAt,
+		&cart.DeletedAt,
+		&created,
+	); err != nil {
 		tx.Rollback()
-		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to scan id", logMsgStr))
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to scan cart item", logMsgStr))
 		return nil, err
 	}
 
@@ -127,13 +192,23 @@ func (s *store) AddCart(bReq model.Cart) (*uuid.UUID, error) {
 		return nil, err
 	}
 
-	return &id, nil
+	result := model.AddCartResultMerged
+	if created {
+		result = model.AddCartResultCreated
+	}
+
+	return &model.AddCartResponse{
+		ID:        cart.ID,
+		ProductID: cart.ProductID,
+		Qty:       cart.Qty,
+		Result:    result,
+	}, nil
 }
 
 func (s *store) UpdateQty(userID, productID uuid.UUID, qty int) error {
 	logMsgStr := "Repository:Cart - UpdateQty:"
 
-	tx, err := s.db.Begin()
+	tx, err := s.begin()
 	if err != nil {
 		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
 		return err
@@ -153,7 +228,7 @@ func (s *store) UpdateQty(userID, productID uuid.UUID, qty int) error {
 
 	queryUpdate := `
 		UPDATE cart_items
-		SET qty = $1
+		SET qty = $1, updated_at = NOW()
 		WHERE deleted_at IS NULL AND user_id = $2 AND product_id = $3
 	`
 	result, err := tx.Exec(queryUpdate, qty, userID, productID)
@@ -188,7 +263,7 @@ func (s *store) UpdateQty(userID, productID uuid.UUID, qty int) error {
 func (s *store) DeleteProduct(bReq model.DeleteCartRequest) error {
 	logMsgStr := "Repository:Cart - DeleteProduct:"
 
-	tx, err := s.db.Begin()
+	tx, err := s.begin()
 	if err != nil {
 		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
 		return err
@@ -208,7 +283,7 @@ func (s *store) DeleteProduct(bReq model.DeleteCartRequest) error {
 
 	queryUpdate := `
 		UPDATE cart_items
-		SET deleted_at = NOW()
+		SET deleted_at = NOW(), updated_at = NOW()
 		WHERE deleted_at IS NULL AND user_id = $1 AND product_id = $2
 	`
 	result, err := tx.Exec(queryUpdate, bReq.UserID, bReq.ProductID)
@@ -239,3 +314,178 @@ func (s *store) DeleteProduct(bReq model.DeleteCartRequest) error {
 
 	return nil
 }
+
+// GetCheckoutCart is a method that retrieves and locks the active cart rows of a user for checkout.
+// If productIDs is not empty, only those products are returned.
+func (s *store) GetCheckoutCart(userID uuid.UUID, productIDs []uuid.UUID) (*[]model.Cart, error) {
+	logMsgStr := "Repository:Cart - GetCheckoutCart:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT
+			id,
+			user_id,
+			product_id,
+			qty,
+			added_unit_price,
+			created_at,
+			updated_at,
+			deleted_at
+		FROM cart_items
+		WHERE deleted_at IS NULL AND user_id = $1
+			AND (cardinality($2::uuid[]) = 0 OR product_id = ANY($2::uuid[]))
+		ORDER BY created_at
+		FOR UPDATE
+	`
+
+	pids := make([]string, 0, len(productIDs))
+	for _, pid := range productIDs {
+		pids = append(pids, pid.String())
+	}
+
+	rows, err := tx.Query(querySelect, userID, pq.Array(pids))
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var carts []model.Cart
+	for rows.Next() {
+		var cart model.Cart
+		if err := rows.Scan(
+			&cart.ID,
+			&cart.UserID,
+			&cart.ProductID,
+			&cart.Qty,
+			&cart.AddedUnitPrice,
+			&cart.CreatedAt,
+			&cart.UpdatedAt,
+			&cart.DeletedAt,
+		); err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		carts = append(carts, cart)
+	}
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to commit", logMsgStr))
+		return nil, err
+	}
+
+	return &carts, nil
+}
+
+// DeleteCartItems is a method that soft-deletes the given cart rows.
+// It returns an error if any of the rows is already deleted.
+func (s *store) DeleteCartItems(ids []uuid.UUID) error {
+	logMsgStr := "Repository:Cart - DeleteCartItems:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	cids := make([]string, 0, len(ids))
+	for _, id := range ids {
+		cids = append(cids, id.String())
+	}
+
+	queryUpdate := `
+		UPDATE cart_items
+		SET deleted_at = NOW(), updated_at = NOW()
+		WHERE deleted_at IS NULL AND id = ANY($1::uuid[])
+	`
+	result, err := tx.Exec(queryUpdate, pq.Array(cids))
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to delete data", logMsgStr))
+		return errors.New("failed to delete data")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return errors.New("failed to get rows affected")
+	}
+
+	if rowsAffected != int64(len(ids)) {
+		tx.Rollback()
+		s.logger.Warn().Msg(fmt.Sprintf("%v Expected %d rows affected, got %d", logMsgStr, len(ids), rowsAffected))
+		return model.ErrCartChanged
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to commit transaction", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// MergeCartItems is a method that puts the given quantities in a user's cart in one transaction.
+// A product already in the cart has the qty added to its line under model.CartMergeSum, or keeps the larger
+// qty under model.CartMergeMax; other products get a new row.
+// It returns the resulting cart rows of the given products.
+func (s *store) MergeCartItems(userID uuid.UUID, lines []model.CartLine, rule string) (*[]model.Cart, error) {
+	logMsgStr := "Repository:Cart - MergeCartItems:"
+
+	query := queryUpsert
+	if rule == model.CartMergeMax {
+		query = queryUpsertKeepMax
+	}
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	carts := make([]model.Cart, 0, len(lines))
+	for _, line := range lines {
+		var cart model.Cart
+		var created bool
+		if err := tx.QueryRow(query, userID, line.ProductID, line.Qty, line.AddedUnitPrice).Scan(
+			&cart.ID,
+			&cart.UserID,
+			&cart.ProductID,
+			&cart.Qty,
+			&cart.AddedUnitPrice,
+			&cart.CreatedAt,
+			&cart.UpdatedAt,
+			&cart.DeletedAt,
+			&created,
+		); err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan cart item", logMsgStr))
+			return nil, err
+		}
+		carts = append(carts, cart)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to commit transaction", logMsgStr))
+		return nil, err
+	}
+
+	return &carts, nil
+}
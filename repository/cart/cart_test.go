@@ -0,0 +1,122 @@
+package cart
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/testdb"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// addBackdated adds a product to a new user's cart and moves the line's timestamps an hour into the past.
+func addBackdated(t *testing.T, db *sql.DB, s *store) model.Cart {
+	t.Helper()
+
+	line := model.Cart{UserID: uuid.New(), ProductID: uuid.New(), Qty: 1}
+	if _, err := s.AddCart(line); err != nil {
+		t.Fatalf("AddCart: %v", err)
+	}
+
+	if _, err := db.Exec(
+		`UPDATE cart_items SET created_at = NOW() - INTERVAL '1 hour', updated_at = NOW() - INTERVAL '1 hour' WHERE user_id = $1`,
+		line.UserID,
+	); err != nil {
+		t.Fatalf("backdate cart line: %v", err)
+	}
+
+	return line
+}
+
+// checkJustUpdated fails the test unless the user's line of a product, deleted or not, was updated within a minute.
+func checkJustUpdated(t *testing.T, db *sql.DB, line model.Cart) {
+	t.Helper()
+
+	var age float64
+	if err := db.QueryRow(
+		`SELECT EXTRACT(EPOCH FROM NOW() - updated_at) FROM cart_items WHERE user_id = $1 AND product_id = $2`,
+		line.UserID, line.ProductID,
+	).Scan(&age); err != nil {
+		t.Fatalf("read updated_at: %v", err)
+	}
+	if age > 60 {
+		t.Errorf("updated_at is %.0fs old, want it set by the change", age)
+	}
+}
+
+func TestUpdateQtyTouchesUpdatedAt(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+	line := addBackdated(t, db, s)
+
+	if err := s.UpdateQty(line.UserID, line.ProductID, 3); err != nil {
+		t.Fatalf("UpdateQty: %v", err)
+	}
+
+	checkJustUpdated(t, db, line)
+}
+
+func TestDeleteProductTouchesUpdatedAt(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+	line := addBackdated(t, db, s)
+
+	if err := s.DeleteProduct(model.DeleteCartRequest{UserID: line.UserID, ProductID: line.ProductID}); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+
+	checkJustUpdated(t, db, line)
+}
+
+func TestMergeCartItemsRules(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+
+	tests := []struct {
+		name string
+		rule string
+		want int
+	}{
+		{name: "sum", rule: model.CartMergeSum, want: 5},
+		{name: "max keeps the larger cart qty", rule: model.CartMergeMax, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, shared, guestOnly := uuid.New(), uuid.New(), uuid.New()
+			if _, err := s.AddCart(model.Cart{UserID: userID, ProductID: shared, Qty: 3}); err != nil {
+				t.Fatalf("AddCart: %v", err)
+			}
+
+			carts, err := s.MergeCartItems(userID, []model.CartLine{
+				{ProductID: shared, Qty: 2},
+				{ProductID: guestOnly, Qty: 4},
+			}, tt.rule)
+			if err != nil {
+				t.Fatalf("MergeCartItems: %v", err)
+			}
+
+			got := map[uuid.UUID]int{}
+			for _, cart := range *carts {
+				got[cart.ProductID] = cart.Qty
+			}
+			if got[shared] != tt.want {
+				t.Errorf("qty of product in both carts = %d, want %d", got[shared], tt.want)
+			}
+			if got[guestOnly] != 4 {
+				t.Errorf("qty of new product = %d, want 4", got[guestOnly])
+			}
+
+			var lines int
+			if err := db.QueryRow(
+				`SELECT COUNT(*) FROM cart_items WHERE user_id = $1 AND deleted_at IS NULL`, userID,
+			).Scan(&lines); err != nil {
+				t.Fatalf("count cart lines: %v", err)
+			}
+			if lines != 2 {
+				t.Errorf("user has %d cart lines, want 2", lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package guestcart
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (s *store) querier() transaction.Querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
+// CreateGuestCart is a method that creates an empty guest cart expiring ttl from now.
+func (s *store) CreateGuestCart(id uuid.UUID, ttl time.Duration) (*model.GuestCart, error) {
+	logMsgStr := "Repository:GuestCart - CreateGuestCart:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	queryCreate := `
+		INSERT INTO guest_carts (
+			id,
+			expires_at,
+			created_at
+		) VALUES (
+			$1, NOW() + make_interval(secs => $2), NOW()
+		)
+		RETURNING expires_at, created_at
+	`
+	cart := model.GuestCart{
+		ID:    id,
+		Items: []model.GuestCartItem{},
+	}
+	if err := tx.QueryRow(queryCreate, id, ttl.Seconds()).Scan(&cart.ExpiresAt, &cart.CreatedAt); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to insert data", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+// GetGuestCart is a method that retrieves a guest cart with its lines.
+// It returns model.ErrGuestCartNotFound if the cart does not exist or has expired.
+func (s *store) GetGuestCart(id uuid.UUID) (*model.GuestCart, error) {
+	logMsgStr := "Repository:GuestCart - GetGuestCart:"
+
+	querySelect := `
+		SELECT expires_at, created_at
+		FROM guest_carts
+		WHERE id = $1 AND expires_at > NOW()
+	`
+
+	return s.getGuestCart(logMsgStr, s.querier(), querySelect, id)
+}
+
+// GetGuestCartForUpdate is a method that retrieves a guest cart with its lines and locks it until the
+// transaction ends. It returns model.ErrGuestCartNotFound if the cart does not exist or has expired.
+func (s *store) GetGuestCartForUpdate(id uuid.UUID) (*model.GuestCart, error) {
+	logMsgStr := "Repository:GuestCart - GetGuestCartForUpdate:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT expires_at, created_at
+		FROM guest_carts
+		WHERE id = $1 AND expires_at > NOW()
+		FOR UPDATE
+	`
+
+	cart, err := s.getGuestCart(logMsgStr, tx, querySelect, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// getGuestCart reads the guest cart selected by querySelect and its lines through q.
+func (s *store) getGuestCart(logMsgStr string, q transaction.Querier, querySelect string, id uuid.UUID) (*model.GuestCart, error) {
+	cart := model.GuestCart{
+		ID:    id,
+		Items: []model.GuestCartItem{},
+	}
+	if err := q.QueryRow(querySelect, id).Scan(&cart.ExpiresAt, &cart.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrGuestCartNotFound
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to scan guest cart", logMsgStr))
+		return nil, err
+	}
+
+	queryItems := `
+		SELECT
+			id,
+			product_id,
+			qty,
+			added_unit_price,
+			created_at,
+			updated_at
+		FROM guest_cart_items
+		WHERE guest_cart_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := q.Query(queryItems, id)
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Query queryItems", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item model.GuestCartItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.ProductID,
+			&item.Qty,
+			&item.AddedUnitPrice,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		); err != nil {
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		cart.Items = append(cart.Items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+// AddItem is a method that adds a product to a guest cart, increasing the qty of its line if the product is
+// in the cart already, and pushes the cart's expiry back to ttl from now.
+// It returns model.ErrGuestCartNotFound if the cart does not exist or has expired.
+func (s *store) AddItem(id uuid.UUID, line model.CartLine, ttl time.Duration) (*model.AddCartResponse, error) {
+	logMsgStr := "Repository:GuestCart - AddItem:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	if err := s.extend(logMsgStr, tx, id, ttl); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	queryUpsert := `
+		INSERT INTO guest_cart_items (
+			guest_cart_id,
+			product_id,
+			qty,
+			added_unit_price,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, NOW()
+		)
+		ON CONFLICT (guest_cart_id, product_id)
+		DO UPDATE SET
+			qty = guest_cart_items.qty + EXCLUDED.qty,
+			added_unit_price = COALESCE(EXCLUDED.added_unit_price, guest_cart_items.added_unit_price),
+			updated_at = NOW()
+		RETURNING id, product_id, qty, (xmax = 0) AS created
+	`
+	var bResp model.AddCartResponse
+	var created bool
+	if err := tx.QueryRow(queryUpsert, id, line.ProductID, line.Qty, line.AddedUnitPrice).Scan(
+		&bResp.ID,
+		&bResp.ProductID,
+		&bResp.Qty,
+		&created,
+	); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to scan cart item", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to commit", logMsgStr))
+		return nil, err
+	}
+
+	bResp.Result = model.AddCartResultMerged
+	if created {
+		bResp.Result = model.AddCartResultCreated
+	}
+
+	return &bResp, nil
+}
+
+// UpdateQty is a method that sets the qty of a product in a guest cart and pushes the cart's expiry back to
+// ttl from now. It returns model.ErrGuestCartNotFound if the cart does not exist or has expired.
+func (s *store) UpdateQty(id, productID uuid.UUID, qty int, ttl time.Duration) error {
+	logMsgStr := "Repository:GuestCart - UpdateQty:"
+
+	queryUpdate := `
+		UPDATE guest_cart_items
+		SET qty = $3, updated_at = NOW()
+		WHERE guest_cart_id = $1 AND product_id = $2
+	`
+
+	return s.updateItem(logMsgStr, id, ttl, queryUpdate, id, productID, qty)
+}
+
+// DeleteProduct is a method that removes a product from a guest cart and pushes the cart's expiry back to
+// ttl from now. It returns model.ErrGuestCartNotFound if the cart does not exist or has expired.
+func (s *store) DeleteProduct(id, productID uuid.UUID, ttl time.Duration) error {
+	logMsgStr := "Repository:GuestCart - DeleteProduct:"
+
+	queryDelete := `
+		DELETE FROM guest_cart_items
+		WHERE guest_cart_id = $1 AND product_id = $2
+	`
+
+	return s.updateItem(logMsgStr, id, ttl, queryDelete, id, productID)
+}
+
+// updateItem extends the guest cart and runs a statement that must change exactly one of its lines.
+func (s *store) updateItem(logMsgStr string, id uuid.UUID, ttl time.Duration, query string, args ...any) error {
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	if err := s.extend(logMsgStr, tx, id, ttl); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return errors.New("failed to update data")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return errors.New("failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		s.logger.Warn().Msg(fmt.Sprintf("%v No rows affected", logMsgStr))
+		return errors.New("no rows affected")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to commit transaction", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// extend pushes the expiry of an unexpired guest cart back to ttl from now, locking the cart row.
+func (s *store) extend(logMsgStr string, tx transaction.Tx, id uuid.UUID, ttl time.Duration) error {
+	queryUpdate := `
+		UPDATE guest_carts
+		SET expires_at = NOW() + make_interval(secs => $2), updated_at = NOW()
+		WHERE id = $1 AND expires_at > NOW()
+	`
+	result, err := tx.Exec(queryUpdate, id, ttl.Seconds())
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to extend guest cart", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return model.ErrGuestCartNotFound
+	}
+
+	return nil
+}
+
+// DeleteGuestCart is a method that deletes a guest cart and its lines.
+func (s *store) DeleteGuestCart(id uuid.UUID) error {
+	logMsgStr := "Repository:GuestCart - DeleteGuestCart:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryDelete := `
+		DELETE FROM guest_carts
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(queryDelete, id); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to delete data", logMsgStr))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to commit transaction", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpiredGuestCarts is a method that deletes up to limit expired guest carts, oldest first, together
+// with their lines. Carts locked by a concurrent merge are skipped. It returns the number of carts deleted.
+func (s *store) DeleteExpiredGuestCarts(limit int) (int, error) {
+	logMsgStr := "Repository:GuestCart - DeleteExpiredGuestCarts:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return 0, err
+	}
+
+	queryDelete := `
+		DELETE FROM guest_carts
+		WHERE id IN (
+			SELECT id
+			FROM guest_carts
+			WHERE expires_at <= NOW()
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	result, err := tx.Exec(queryDelete, limit)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to delete data", logMsgStr))
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to commit transaction", logMsgStr))
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
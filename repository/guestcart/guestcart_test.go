@@ -0,0 +1,94 @@
+package guestcart
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/testdb"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// expire moves the expiry of a guest cart into the past.
+func expire(t *testing.T, db *sql.DB, id uuid.UUID) {
+	t.Helper()
+
+	if _, err := db.Exec(`UPDATE guest_carts SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, id); err != nil {
+		t.Fatalf("expire guest cart: %v", err)
+	}
+}
+
+func TestAddItemMergesAndExtends(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+	id, productID := uuid.New(), uuid.New()
+
+	created, err := s.CreateGuestCart(id, time.Minute)
+	if err != nil {
+		t.Fatalf("CreateGuestCart: %v", err)
+	}
+
+	first, err := s.AddItem(id, model.CartLine{ProductID: productID, Qty: 2}, time.Hour)
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	second, err := s.AddItem(id, model.CartLine{ProductID: productID, Qty: 3}, time.Hour)
+	if err != nil {
+		t.Fatalf("AddItem again: %v", err)
+	}
+
+	if first.Result != model.AddCartResultCreated || second.Result != model.AddCartResultMerged || second.Qty != 5 {
+		t.Errorf("adds = %+v then %+v, want created then merged into 5", first, second)
+	}
+
+	guest, err := s.GetGuestCart(id)
+	if err != nil {
+		t.Fatalf("GetGuestCart: %v", err)
+	}
+	if len(guest.Items) != 1 || guest.Items[0].Qty != 5 {
+		t.Errorf("items = %+v, want one line of 5", guest.Items)
+	}
+	if !guest.ExpiresAt.After(created.ExpiresAt.Add(30 * time.Minute)) {
+		t.Errorf("expiry %v was not pushed back from %v", guest.ExpiresAt, created.ExpiresAt)
+	}
+}
+
+func TestExpiredGuestCart(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+	id, productID := uuid.New(), uuid.New()
+
+	if _, err := s.CreateGuestCart(id, time.Hour); err != nil {
+		t.Fatalf("CreateGuestCart: %v", err)
+	}
+	if _, err := s.AddItem(id, model.CartLine{ProductID: productID, Qty: 1}, time.Hour); err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	expire(t, db, id)
+
+	if _, err := s.GetGuestCart(id); !errors.Is(err, model.ErrGuestCartNotFound) {
+		t.Errorf("GetGuestCart of an expired cart error = %v, want %v", err, model.ErrGuestCartNotFound)
+	}
+	if _, err := s.AddItem(id, model.CartLine{ProductID: productID, Qty: 1}, time.Hour); !errors.Is(err, model.ErrGuestCartNotFound) {
+		t.Errorf("AddItem to an expired cart error = %v, want %v", err, model.ErrGuestCartNotFound)
+	}
+
+	// Only expired carts are deleted, so sweeping the shared database is safe.
+	if _, err := s.DeleteExpiredGuestCarts(1000); err != nil {
+		t.Fatalf("DeleteExpiredGuestCarts: %v", err)
+	}
+
+	var carts, items int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM guest_carts WHERE id = $1`, id).Scan(&carts); err != nil {
+		t.Fatalf("count guest carts: %v", err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM guest_cart_items WHERE guest_cart_id = $1`, id).Scan(&items); err != nil {
+		t.Fatalf("count guest cart items: %v", err)
+	}
+	if carts != 0 || items != 0 {
+		t.Errorf("%d carts and %d lines left after cleanup, want none", carts, items)
+	}
+}
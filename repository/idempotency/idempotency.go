@@ -0,0 +1,169 @@
+package idempotency
+
+import (
+	model "cart-order-service/repository/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// lockTimeout is how long an unfinished request holds its key. It outlives the
+// server write timeout, so a key is only reclaimed from a request that died.
+const lockTimeout = time.Minute
+
+type store struct {
+	db     *sql.DB
+	ttl    time.Duration
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+// Completed keys are kept for ttl before the same key may be used for a new request.
+func NewStore(db *sql.DB, ttl time.Duration, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Reserve is a method that claims a key for a new request.
+// It returns false if the key is held by a live in-flight request or a completed one that has not expired.
+func (s *store) Reserve(bReq model.IdempotencyKey) (bool, error) {
+	logMsgStr := "Repository:Idempotency - Reserve:"
+
+	queryReserve := `
+		INSERT INTO idempotency_keys (
+			scope,
+			key,
+			method,
+			path,
+			request_hash,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, NOW()
+		)
+		ON CONFLICT (scope, key, method, path) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_headers = NULL,
+			response_body = NULL,
+			created_at = NOW(),
+			completed_at = NULL
+		WHERE idempotency_keys.created_at < NOW() - make_interval(secs => $6)
+			OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $7))
+		RETURNING key
+	`
+
+	var key string
+	err := s.db.QueryRow(
+		queryReserve,
+		bReq.Scope,
+		bReq.Key,
+		bReq.Method,
+		bReq.Path,
+		bReq.RequestHash,
+		s.ttl.Seconds(),
+		lockTimeout.Seconds(),
+	).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to reserve key", logMsgStr))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Get is a method that retrieves a recorded key.
+// It returns sql.ErrNoRows if the key is not recorded, for instance because its request released it.
+func (s *store) Get(scope, key, method, path string) (*model.IdempotencyKey, error) {
+	logMsgStr := "Repository:Idempotency - Get:"
+
+	querySelect := `
+		SELECT
+			scope,
+			key,
+			method,
+			path,
+			request_hash,
+			COALESCE(status_code, 0),
+			COALESCE(response_headers, '{}'::jsonb),
+			response_body,
+			created_at,
+			completed_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND method = $3 AND path = $4
+	`
+
+	var rec model.IdempotencyKey
+	err := s.db.QueryRow(querySelect, scope, key, method, path).Scan(
+		&rec.Scope,
+		&rec.Key,
+		&rec.Method,
+		&rec.Path,
+		&rec.RequestHash,
+		&rec.StatusCode,
+		&rec.ResponseHeaders,
+		&rec.ResponseBody,
+		&rec.CreatedAt,
+		&rec.CompletedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan key", logMsgStr))
+		return nil, err
+	}
+
+	return &rec, nil
+}
+
+// Complete is a method that records the response of a reserved key.
+func (s *store) Complete(bReq model.IdempotencyKey) error {
+	logMsgStr := "Repository:Idempotency - Complete:"
+
+	queryUpdate := `
+		UPDATE idempotency_keys
+		SET status_code = $1, response_headers = $2, response_body = $3, completed_at = NOW()
+		WHERE scope = $4 AND key = $5 AND method = $6 AND path = $7 AND request_hash = $8
+	`
+	if _, err := s.db.Exec(
+		queryUpdate,
+		bReq.StatusCode,
+		bReq.ResponseHeaders,
+		bReq.ResponseBody,
+		bReq.Scope,
+		bReq.Key,
+		bReq.Method,
+		bReq.Path,
+		bReq.RequestHash,
+	); err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// Release is a method that deletes an unfinished key so the request can be retried.
+func (s *store) Release(scope, key, method, path string) error {
+	logMsgStr := "Repository:Idempotency - Release:"
+
+	queryDelete := `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND method = $3 AND path = $4 AND completed_at IS NULL
+	`
+	if _, err := s.db.Exec(queryDelete, scope, key, method, path); err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to delete data", logMsgStr))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package idempotency
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/testdb"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func newKey() model.IdempotencyKey {
+	return model.IdempotencyKey{
+		Scope:       "scope-" + uuid.NewString()[:8],
+		Key:         uuid.NewString(),
+		Method:      "POST",
+		Path:        "/order/create",
+		RequestHash: "hash",
+	}
+}
+
+// backdate moves the creation of a key age into the past.
+func backdate(t *testing.T, db *sql.DB, rec model.IdempotencyKey, age time.Duration) {
+	t.Helper()
+
+	if _, err := db.Exec(
+		`UPDATE idempotency_keys SET created_at = NOW() - make_interval(secs => $1) WHERE scope = $2 AND key = $3`,
+		age.Seconds(), rec.Scope, rec.Key,
+	); err != nil {
+		t.Fatalf("backdate key: %v", err)
+	}
+}
+
+func mustReserve(t *testing.T, s *store, rec model.IdempotencyKey, want bool) {
+	t.Helper()
+
+	reserved, err := s.Reserve(rec)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if reserved != want {
+		t.Fatalf("Reserve = %v, want %v", reserved, want)
+	}
+}
+
+func TestStoreRecordsAndReplaysKeys(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, time.Hour, zerolog.Nop())
+	rec := newKey()
+
+	mustReserve(t, s, rec, true)
+	mustReserve(t, s, rec, false)
+
+	got, err := s.Get(rec.Scope, rec.Key, rec.Method, rec.Path)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.CompletedAt != nil || got.RequestHash != rec.RequestHash {
+		t.Errorf("in-flight key = %+v, want uncompleted with hash %q", got, rec.RequestHash)
+	}
+
+	rec.StatusCode = 201
+	rec.ResponseHeaders = []byte(`{"Content-Type":["application/json"]}`)
+	rec.ResponseBody = []byte(`{"id":1}`)
+	if err := s.Complete(rec); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	// A completed key is not released.
+	if err := s.Release(rec.Scope, rec.Key, rec.Method, rec.Path); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	got, err = s.Get(rec.Scope, rec.Key, rec.Method, rec.Path)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.CompletedAt == nil || got.StatusCode != 201 || string(got.ResponseBody) != `{"id":1}` {
+		t.Errorf("completed key = %+v, want the recorded response", got)
+	}
+	mustReserve(t, s, rec, false)
+}
+
+func TestStoreScopesKeys(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, time.Hour, zerolog.Nop())
+	rec := newKey()
+	other := rec
+	other.Scope = rec.Scope + "-other"
+
+	mustReserve(t, s, rec, true)
+	mustReserve(t, s, other, true)
+}
+
+func TestStoreReleasesUnfinishedKeys(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, time.Hour, zerolog.Nop())
+	rec := newKey()
+
+	mustReserve(t, s, rec, true)
+	if err := s.Release(rec.Scope, rec.Key, rec.Method, rec.Path); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	if _, err := s.Get(rec.Scope, rec.Key, rec.Method, rec.Path); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get of a released key: err = %v, want %v", err, sql.ErrNoRows)
+	}
+	mustReserve(t, s, rec, true)
+}
+
+func TestStoreReclaimsKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		complete bool
+		age      time.Duration
+		want     bool
+	}{
+		{name: "completed within the TTL", complete: true, age: 30 * time.Minute, want: false},
+		{name: "completed past the TTL", complete: true, age: 2 * time.Hour, want: true},
+		{name: "in flight within the lock timeout", age: lockTimeout / 2, want: false},
+		{name: "in flight past the lock timeout", age: 2 * lockTimeout, want: true},
+	}
+
+	db := testdb.Open(t)
+	s := NewStore(db, time.Hour, zerolog.Nop())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := newKey()
+			mustReserve(t, s, rec, true)
+			if tt.complete {
+				rec.StatusCode = 200
+				if err := s.Complete(rec); err != nil {
+					t.Fatalf("Complete: %v", err)
+				}
+			}
+			backdate(t, db, rec, tt.age)
+
+			rec.RequestHash = "other-hash"
+			mustReserve(t, s, rec, tt.want)
+
+			got, err := s.Get(rec.Scope, rec.Key, rec.Method, rec.Path)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if reclaimed := got.RequestHash == "other-hash" && got.CompletedAt == nil; reclaimed != tt.want {
+				t.Errorf("key = %+v, reclaimed %v, want %v", got, reclaimed, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Placeholders understood in order number and ref code formats.
+const (
+	// PlaceholderDate is replaced with the current UTC date as YYYYMMDD.
+	PlaceholderDate = "{date}"
+	// PlaceholderSeq is replaced with the next sequence value, zero-padded to the configured width.
+	PlaceholderSeq = "{seq}"
+	// PlaceholderRand is replaced with random characters that make ref codes hard to guess.
+	PlaceholderRand = "{rand}"
+)
+
+// randAlphabet is Crockford's base32 alphabet, which leaves out easily confused letters.
+const randAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const randLength = 4
+
+// Config holds the formats of generated identifiers.
+type Config struct {
+	OrderNumberFormat string
+	RefCodeFormat     string
+	SeqWidth          int
+}
+
+type generator struct {
+	db     *sql.DB
+	cfg    Config
+	logger zerolog.Logger
+}
+
+// NewGenerator is a constructor function that returns a generator of order numbers and ref codes.
+// Uniqueness comes from the order_number_seq and ref_code_seq Postgres sequences, so every format
+// must contain PlaceholderSeq.
+func NewGenerator(db *sql.DB, cfg Config, logger zerolog.Logger) (*generator, error) {
+	for _, format := range []string{cfg.OrderNumberFormat, cfg.RefCodeFormat} {
+		if !strings.Contains(format, PlaceholderSeq) {
+			return nil, fmt.Errorf("idgen: format %q must contain %s", format, PlaceholderSeq)
+		}
+	}
+
+	return &generator{
+		db:     db,
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+// NextOrderNumber is a method that returns a new unique order number.
+func (g *generator) NextOrderNumber() (string, error) {
+	return g.next("order_number_seq", g.cfg.OrderNumberFormat)
+}
+
+// NextRefCode is a method that returns a new unique reference code.
+func (g *generator) NextRefCode() (string, error) {
+	return g.next("ref_code_seq", g.cfg.RefCodeFormat)
+}
+
+func (g *generator) next(sequence, format string) (string, error) {
+	logMsgStr := "Repository:IDGen - next:"
+
+	var seq int64
+	if err := g.db.QueryRow("SELECT nextval($1::regclass)", sequence).Scan(&seq); err != nil {
+		g.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get nextval of %v", logMsgStr, sequence))
+		return "", err
+	}
+
+	id := strings.ReplaceAll(format, PlaceholderDate, time.Now().UTC().Format("20060102"))
+	id = strings.ReplaceAll(id, PlaceholderSeq, fmt.Sprintf("%0*d", g.cfg.SeqWidth, seq))
+
+	if strings.Contains(id, PlaceholderRand) {
+		suffix, err := randomString(randLength)
+		if err != nil {
+			return "", err
+		}
+		id = strings.ReplaceAll(id, PlaceholderRand, suffix)
+	}
+
+	return id, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	for i := range b {
+		b[i] = randAlphabet[int(b[i])%len(randAlphabet)]
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,185 @@
+package invoice
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// invoiceCounter is the invoice_counters row that numbers invoices.
+const invoiceCounter = "invoice"
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (s *store) querier() transaction.Querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
+// GetInvoiceByOrderID is a method that retrieves the invoice issued for an order.
+// It returns model.ErrInvoiceNotFound if none has been issued yet.
+func (s *store) GetInvoiceByOrderID(orderID uuid.UUID) (*model.Invoice, error) {
+	logMsgStr := "Repository:Invoice - GetInvoiceByOrderID:"
+
+	querySelect := `
+		SELECT
+			id,
+			order_id,
+			seq,
+			invoice_number,
+			issued_at,
+			document,
+			created_at
+		FROM invoices
+		WHERE order_id = $1
+	`
+
+	var (
+		invoice  model.Invoice
+		document []byte
+	)
+	if err := s.querier().QueryRow(querySelect, orderID).Scan(
+		&invoice.ID,
+		&invoice.OrderID,
+		&invoice.Seq,
+		&invoice.InvoiceNumber,
+		&invoice.IssuedAt,
+		&document,
+		&invoice.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrInvoiceNotFound
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan invoice", logMsgStr))
+		return nil, err
+	}
+
+	if err := json.Unmarshal(document, &invoice.Document); err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Unmarshal document", logMsgStr))
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+// NextInvoiceSeq is a method that takes the next invoice sequence number.
+// The counter row stays locked until the surrounding transaction ends, and a rollback gives the number back,
+// so numbers are gap-free as long as the invoice is stored in the same transaction.
+func (s *store) NextInvoiceSeq() (int64, error) {
+	logMsgStr := "Repository:Invoice - NextInvoiceSeq:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return 0, err
+	}
+
+	queryUpdate := `
+		UPDATE invoice_counters
+		SET last_value = last_value + 1
+		WHERE name = $1
+		RETURNING last_value
+	`
+
+	var seq int64
+	if err := tx.QueryRow(queryUpdate, invoiceCounter).Scan(&seq); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan last_value", logMsgStr))
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// CreateInvoice is a method that stores an issued invoice together with its document and returns its ID.
+func (s *store) CreateInvoice(bReq model.Invoice) (*uuid.UUID, error) {
+	logMsgStr := "Repository:Invoice - CreateInvoice:"
+
+	document, err := json.Marshal(bReq.Document)
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Marshal document", logMsgStr))
+		return nil, err
+	}
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	queryCreate := `
+		INSERT INTO invoices (
+			order_id,
+			seq,
+			invoice_number,
+			issued_at,
+			document,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, NOW()
+		) RETURNING id
+	`
+
+	var id uuid.UUID
+	if err := tx.QueryRow(
+		queryCreate,
+		bReq.OrderID,
+		bReq.Seq,
+		bReq.InvoiceNumber,
+		bReq.IssuedAt,
+		document,
+	).Scan(&id); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan id", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &id, nil
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Intervals sales reports can be bucketed by. Weeks start on Monday.
+const (
+	AnalyticsIntervalDay   = "day"
+	AnalyticsIntervalWeek  = "week"
+	AnalyticsIntervalMonth = "month"
+)
+
+// SalesReportRequest selects the orders of a sales report by creation time. To is exclusive.
+// Buckets start at midnight in Location.
+type SalesReportRequest struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	Location *time.Location
+}
+
+// SalesAggregate holds the order counts and paid amount of one period and payment type, as summed by the database.
+// GMVCount is the number of orders GMV is made of.
+type SalesAggregate struct {
+	PeriodStart    time.Time
+	PaymentTypeID  uuid.UUID
+	OrderCount     int64
+	PaidCount      int64
+	CancelledCount int64
+	GMVCount       int64
+	GMV            float64
+}
+
+// SalesMetrics are the sales figures of a set of orders.
+// GMV sums the total price of paid orders that were not cancelled, and AOV spreads it over those orders.
+// PaidRatio and CancellationRate are shares of all orders placed.
+type SalesMetrics struct {
+	OrderCount       int64   `json:"order_count"`
+	PaidCount        int64   `json:"paid_count"`
+	UnpaidCount      int64   `json:"unpaid_count"`
+	CancelledCount   int64   `json:"cancelled_count"`
+	GMV              float64 `json:"gmv"`
+	AOV              float64 `json:"aov"`
+	PaidRatio        float64 `json:"paid_ratio"`
+	CancellationRate float64 `json:"cancellation_rate"`
+}
+
+// SalesBucket is the sales of one period or one payment type.
+type SalesBucket struct {
+	PeriodStart   *time.Time `json:"period_start,omitempty"`
+	PaymentTypeID *uuid.UUID `json:"payment_type_id,omitempty"`
+	SalesMetrics
+}
+
+type SalesReport struct {
+	Timezone string        `json:"timezone"`
+	Interval string        `json:"interval,omitempty"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Totals   SalesMetrics  `json:"totals"`
+	Buckets  []SalesBucket `json:"buckets"`
+}
@@ -7,13 +7,15 @@ import (
 )
 
 type Cart struct {
-	ID        uuid.UUID  `json:"id"`
-	UserID    uuid.UUID  `json:"user_id"`
-	ProductID uuid.UUID  `json:"product_id"`
-	Qty       int        `json:"qty"`
-	CreatedAt *time.Time `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at"`
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+	// AddedUnitPrice is the catalog price of the product when it was last added to the cart.
+	AddedUnitPrice *float64   `json:"added_unit_price"`
+	CreatedAt      *time.Time `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at"`
 }
 
 type GetCartRequest struct {
@@ -25,3 +27,68 @@ type DeleteCartRequest struct {
 	UserID    uuid.UUID `json:"user_id"`
 	ProductID uuid.UUID `json:"product_id"`
 }
+
+// CartLine is a quantity of a product to put in a cart.
+type CartLine struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+	// AddedUnitPrice is the catalog price recorded on the cart line, filled in by the cart usecase.
+	AddedUnitPrice *float64 `json:"-"`
+}
+
+// Results of adding a product to a cart.
+const (
+	// AddCartResultCreated means the product was not in the cart and got a new line.
+	AddCartResultCreated = "created"
+	// AddCartResultMerged means the qty was added to the product's existing line.
+	AddCartResultMerged = "merged"
+)
+
+// AddCartResponse is the cart line a product was added to, with its qty after the add.
+type AddCartResponse struct {
+	ID        uuid.UUID `json:"id"`
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+	Result    string    `json:"result"`
+}
+
+// Codes of the warnings on a priced cart.
+const (
+	// CartWarningNotFound is a product the catalog no longer knows.
+	CartWarningNotFound = "not_found"
+	// CartWarningUnavailable is a product that cannot be ordered at the moment.
+	CartWarningUnavailable = "unavailable"
+	// CartWarningPriceChanged is a product whose price changed since it was added to the cart.
+	CartWarningPriceChanged = "price_changed"
+)
+
+// PricedCartLine is a cart line with the current catalog details of its product.
+// Lines of products that are not found or unavailable have no subtotal.
+type PricedCartLine struct {
+	ID             uuid.UUID `json:"id"`
+	ProductID      uuid.UUID `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	ProductSKU     string    `json:"product_sku"`
+	Qty            int       `json:"qty"`
+	UnitPrice      float64   `json:"unit_price"`
+	AddedUnitPrice *float64  `json:"added_unit_price"`
+	LineSubtotal   float64   `json:"line_subtotal"`
+	Available      bool      `json:"available"`
+}
+
+// CartWarning points out a cart line that will not check out as the customer may expect.
+type CartWarning struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Code      string    `json:"code"`
+	Message   string    `json:"message"`
+}
+
+// PricedCart is a user's cart priced from the catalog.
+// ItemCount and Subtotal only cover the lines that can be ordered.
+type PricedCart struct {
+	UserID    uuid.UUID        `json:"user_id"`
+	Items     []PricedCartLine `json:"items"`
+	ItemCount int              `json:"item_count"`
+	Subtotal  float64          `json:"subtotal"`
+	Warnings  []CartWarning    `json:"warnings"`
+}
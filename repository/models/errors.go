@@ -0,0 +1,54 @@
+package model
+
+import "errors"
+
+var (
+	ErrEmptyCart               = errors.New("cart is empty")
+	ErrCartChanged             = errors.New("cart changed during checkout")
+	ErrOrderNotFound           = errors.New("order not found")
+	ErrInvalidOrderStatus      = errors.New("invalid order status")
+	ErrInvalidStatusTransition = errors.New("order status transition is not allowed")
+	ErrInvalidCursor           = errors.New("invalid cursor")
+	ErrEmptyOrder              = errors.New("order has no products")
+	ErrInvalidProductOrder     = errors.New("invalid product_order")
+	ErrInvalidQty              = errors.New("qty must be greater than 0")
+	ErrProductNotFound         = errors.New("product not found")
+	ErrProductUnavailable      = errors.New("product is not available")
+	ErrTotalPriceMismatch      = errors.New("submitted total price does not match the computed total")
+	ErrOrderNotPayable         = errors.New("order cannot be paid")
+	ErrPaymentNotFound         = errors.New("payment not found")
+	ErrPaymentPending          = errors.New("order already has a pending payment")
+	ErrInvalidSignature        = errors.New("invalid webhook signature")
+	ErrInvalidWebhookPayload   = errors.New("invalid webhook payload")
+	ErrPaymentAmountMismatch   = errors.New("paid amount does not match the payment")
+	ErrOrderNotReturnable      = errors.New("order cannot be returned")
+	ErrReturnNotFound          = errors.New("return not found")
+	ErrUnknownOrderItem        = errors.New("item is not part of the order")
+	ErrReturnQtyExceeded       = errors.New("return qty exceeds the qty left to return")
+	ErrInvalidReturnTransition = errors.New("return status transition is not allowed")
+	ErrNoRefundablePayment     = errors.New("order has no payment to refund")
+	ErrRefundInProgress        = errors.New("a refund for this return is already in progress")
+	ErrRefundAmountExceeded    = errors.New("refund amount exceeds the refundable amount")
+	ErrRefundNotPending        = errors.New("refund has already been settled")
+	ErrRefundAmountChanged     = errors.New("refund amount differs from an earlier attempt for this return")
+	ErrOrderNotShippable       = errors.New("order cannot be shipped")
+	ErrShipmentNotFound        = errors.New("shipment not found")
+	ErrShipmentQtyExceeded     = errors.New("shipment qty exceeds the qty left to ship")
+	ErrNothingToShip           = errors.New("all items of the order have been shipped")
+	ErrShipmentDispatched      = errors.New("shipment has already been dispatched")
+	ErrTrackingNumberTaken     = errors.New("tracking number is already assigned to another shipment")
+	ErrShippingPostalCode      = errors.New("shipping_postal_code is required to quote shipping")
+	ErrShippingServiceNotFound = errors.New("shipping service is not offered for this destination")
+	ErrOrderNotInvoiceable     = errors.New("order cannot be invoiced before it is paid")
+	ErrInvoiceNotFound         = errors.New("invoice not found")
+	ErrInvalidExportFormat     = errors.New("export format must be csv or ndjson")
+	ErrInvalidTimezone         = errors.New("unknown timezone")
+	ErrInvalidInterval         = errors.New("interval must be day, week or month")
+	ErrInvalidReportRange      = errors.New("report range must end after it starts")
+	ErrInsufficientStock       = errors.New("insufficient stock")
+	ErrReservationNotFound     = errors.New("stock reservation not found")
+	ErrInvalidCartToken        = errors.New("invalid cart token")
+	ErrGuestCartNotFound       = errors.New("guest cart not found or expired")
+	ErrInvalidMergeRule        = errors.New("merge rule must be sum or max")
+	ErrOutboxLeaseLost         = errors.New("outbox event is no longer leased by this relay")
+)
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Formats an order export can be written in.
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// ExportOrdersRequest selects the orders of an export. CreatedTo is exclusive.
+type ExportOrdersRequest struct {
+	Format      string
+	Status      []OrderStatus
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rules for a product that is in both the guest cart and the user's cart when they are merged.
+const (
+	// CartMergeSum adds the guest qty to the user's qty.
+	CartMergeSum = "sum"
+	// CartMergeMax keeps the larger of the two quantities.
+	CartMergeMax = "max"
+)
+
+// GuestCartItem is a line of a guest cart.
+type GuestCartItem struct {
+	ID        uuid.UUID `json:"id"`
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+	// AddedUnitPrice is the catalog price of the product when it was last added to the cart.
+	AddedUnitPrice *float64   `json:"added_unit_price"`
+	CreatedAt      *time.Time `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+// GuestCart is the cart of a shopper who has not logged in. Its ID is only handed out inside a cart token.
+type GuestCart struct {
+	ID        uuid.UUID       `json:"-"`
+	Items     []GuestCartItem `json:"items"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	CreatedAt *time.Time      `json:"created_at"`
+}
+
+// GuestCartToken is a newly issued guest cart. CartToken is sent back in the X-Cart-Token header.
+type GuestCartToken struct {
+	CartToken string    `json:"cart_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MergeGuestCartRequest moves a guest cart into a user's cart after login.
+// An empty Rule uses the configured default.
+type MergeGuestCartRequest struct {
+	CartToken string    `json:"-"`
+	UserID    uuid.UUID `json:"user_id" validate:"required"`
+	Rule      string    `json:"rule"`
+}
+
+// MergeGuestCartResponse is the user's cart lines of the merged products, with their qty after the merge.
+type MergeGuestCartResponse struct {
+	UserID uuid.UUID `json:"user_id"`
+	Rule   string    `json:"rule"`
+	Items  []Cart    `json:"items"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotencyKey is a request recorded under an Idempotency-Key header, with its response once it completes.
+type IdempotencyKey struct {
+	// Scope identifies the caller the key belongs to.
+	Scope           string          `json:"scope"`
+	Key             string          `json:"key"`
+	Method          string          `json:"method"`
+	Path            string          `json:"path"`
+	RequestHash     string          `json:"request_hash"`
+	StatusCode      int             `json:"status_code"`
+	ResponseHeaders json.RawMessage `json:"response_headers"`
+	ResponseBody    []byte          `json:"response_body"`
+	CreatedAt       *time.Time      `json:"created_at"`
+	CompletedAt     *time.Time      `json:"completed_at"`
+}
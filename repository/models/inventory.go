@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReservationStatus is the state of a stock reservation held at the inventory service.
+type ReservationStatus string
+
+const (
+	// ReservationStatusPending is a reservation requested for an order that has not been committed yet.
+	ReservationStatusPending  ReservationStatus = "pending"
+	ReservationStatusReserved ReservationStatus = "reserved"
+	// ReservationStatusReleasing and ReservationStatusConfirming are waiting for the reconciler to
+	// release or confirm the reservation at the inventory service.
+	ReservationStatusReleasing  ReservationStatus = "releasing"
+	ReservationStatusReleased   ReservationStatus = "released"
+	ReservationStatusConfirming ReservationStatus = "confirming"
+	ReservationStatusConfirmed  ReservationStatus = "confirmed"
+	// ReservationStatusFailed is a reservation the inventory service refused or lost; nothing is held for it.
+	ReservationStatusFailed ReservationStatus = "failed"
+)
+
+// StockLine is a quantity of a product to hold at the inventory service.
+type StockLine struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+}
+
+// StockReservation is a hold on stock for the lines of an order.
+// Its ID is the reservation ID at the inventory service, so calls for it can be repeated safely.
+type StockReservation struct {
+	ID            uuid.UUID         `json:"id"`
+	OrderID       *uuid.UUID        `json:"order_id"`
+	Lines         []StockLine       `json:"lines"`
+	Status        ReservationStatus `json:"status"`
+	Attempts      int               `json:"attempts"`
+	LastError     *string           `json:"last_error"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at"`
+	CreatedAt     *time.Time        `json:"created_at"`
+	UpdatedAt     *time.Time        `json:"updated_at"`
+}
+
+// ReserveStockRequest asks the inventory service to hold stock for every line, or for none of them.
+type ReserveStockRequest struct {
+	ReservationID uuid.UUID   `json:"reservation_id"`
+	Lines         []StockLine `json:"lines"`
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice is the invoice issued for a paid order. Its number is taken from a gap-free counter the first time
+// the invoice is requested, and its document is frozen at that moment.
+type Invoice struct {
+	ID            uuid.UUID       `json:"id"`
+	OrderID       uuid.UUID       `json:"order_id"`
+	Seq           int64           `json:"seq"`
+	InvoiceNumber string          `json:"invoice_number"`
+	IssuedAt      time.Time       `json:"issued_at"`
+	Document      InvoiceDocument `json:"document"`
+	CreatedAt     *time.Time      `json:"created_at"`
+}
+
+// InvoiceDocument is everything printed on an invoice.
+type InvoiceDocument struct {
+	InvoiceNumber   string          `json:"invoice_number"`
+	IssuedAt        time.Time       `json:"issued_at"`
+	Seller          InvoiceSeller   `json:"seller"`
+	OrderID         uuid.UUID       `json:"order_id"`
+	OrderNumber     string          `json:"order_number"`
+	RefCode         string          `json:"ref_code"`
+	OrderedAt       *time.Time      `json:"ordered_at"`
+	CustomerID      uuid.UUID       `json:"customer_id"`
+	PaymentTypeID   uuid.UUID       `json:"payment_type_id"`
+	Currency        string          `json:"currency"`
+	Items           []InvoiceLine   `json:"items"`
+	Subtotal        float64         `json:"subtotal"`
+	ShippingFee     float64         `json:"shipping_fee"`
+	ShippingService string          `json:"shipping_service,omitempty"`
+	Total           float64         `json:"total"`
+	Payment         *InvoicePayment `json:"payment,omitempty"`
+}
+
+// InvoiceSeller is the company an invoice is issued by.
+type InvoiceSeller struct {
+	Name    string   `json:"name"`
+	Address []string `json:"address,omitempty"`
+	TaxID   string   `json:"tax_id,omitempty"`
+	Email   string   `json:"email,omitempty"`
+}
+
+// InvoiceLine is an order line as printed on an invoice.
+type InvoiceLine struct {
+	ProductName string  `json:"product_name"`
+	ProductSKU  string  `json:"product_sku"`
+	Qty         int     `json:"qty"`
+	UnitPrice   float64 `json:"unit_price"`
+	LineTotal   float64 `json:"line_total"`
+}
+
+// InvoicePayment is the payment that settled an order.
+type InvoicePayment struct {
+	Provider string     `json:"provider"`
+	IntentID string     `json:"intent_id"`
+	Amount   float64    `json:"amount"`
+	PaidAt   *time.Time `json:"paid_at"`
+}
+
+// InvoiceFile is a rendered invoice.
+type InvoiceFile struct {
+	Filename string
+	Content  []byte
+}
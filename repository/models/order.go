@@ -7,35 +7,230 @@ import (
 	"github.com/google/uuid"
 )
 
-var (
-	OrderStatusPending    = "pending"
-	OrderStatusProcessing = "processing"
-	OrderStatusCompleted  = "completed"
-	OrderStatusCancelled  = "cancelled"
-	OrderStatusPakcing    = "packing"
-	OrderStatusPaid       = "paid"
-	OrderStatusPacking    = "pickup"
+// OrderStatus is the lifecycle state of an order.
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusPacking   OrderStatus = "packing"
+	OrderStatusPickup    OrderStatus = "pickup"
+	OrderStatusShipped   OrderStatus = "shipped"
+	OrderStatusDelivered OrderStatus = "delivered"
+	OrderStatusCompleted OrderStatus = "completed"
+	OrderStatusCancelled OrderStatus = "cancelled"
+
+	// Statuses set by the returns workflow only.
+	OrderStatusReturnRequested   OrderStatus = "return_requested"
+	OrderStatusPartiallyRefunded OrderStatus = "partially_refunded"
+	OrderStatusRefunded          OrderStatus = "refunded"
 )
 
+// orderStatusTransitions lists the statuses an order may move to from each status.
+// Statuses without an entry are final.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusPacking, OrderStatusCancelled},
+	OrderStatusPacking:   {OrderStatusPickup, OrderStatusShipped},
+	OrderStatusPickup:    {OrderStatusCompleted, OrderStatusShipped},
+	OrderStatusShipped:   {OrderStatusDelivered},
+	OrderStatusDelivered: {OrderStatusCompleted},
+
+	OrderStatusCompleted:         {OrderStatusReturnRequested},
+	OrderStatusReturnRequested:   {OrderStatusCompleted, OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusPartiallyRefunded: {OrderStatusReturnRequested},
+}
+
+// IsValid reports whether s is a known order status.
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusPaid, OrderStatusPacking,
+		OrderStatusPickup, OrderStatusShipped, OrderStatusDelivered, OrderStatusCompleted, OrderStatusCancelled,
+		OrderStatusReturnRequested, OrderStatusPartiallyRefunded, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// IsReturnStatus reports whether s is set by the returns workflow rather than by a status update.
+func (s OrderStatus) IsReturnStatus() bool {
+	switch s {
+	case OrderStatusReturnRequested, OrderStatusPartiallyRefunded, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// IsPaymentStatus reports whether s is set by the payment webhook rather than by a status update.
+func (s OrderStatus) IsPaymentStatus() bool {
+	return s == OrderStatusPaid
+}
+
+// IsShipmentStatus reports whether s is set by the shipment workflow rather than by a status update.
+func (s OrderStatus) IsShipmentStatus() bool {
+	switch s {
+	case OrderStatusShipped, OrderStatusDelivered:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an order in status s may move to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Order struct {
-	UserID        uuid.UUID       `json:"user_id" validate:"required"`
-	PaymentTypeID uuid.UUID       `json:"payment_type_id" validate:"required"`
-	OrderNumber   string          `json:"order_number" validate:"required"`
-	TotalPrice    float64         `json:"total_price" validate:"required"`
-	ProductOrder  json.RawMessage `json:"product_order"`
-	Status        string          `json:"status" validate:"required"`
-	IsPaid        bool            `json:"is_paid"`
-	RefCode       string          `json:"ref_code"`
-	CreatedAt     *time.Time      `json:"created_at"`
-	UpdatedAt     *time.Time      `json:"updated_at"`
-	DeletedAt     *time.Time      `json:"deleted_at"`
+	ID            uuid.UUID `json:"id"`
+	UserID        uuid.UUID `json:"user_id" validate:"required"`
+	PaymentTypeID uuid.UUID `json:"payment_type_id" validate:"required"`
+	OrderNumber   string    `json:"order_number"`
+	// TotalPrice submitted with a new order is checked against the computed total; zero leaves it to the server.
+	TotalPrice float64 `json:"total_price"`
+	// Subtotal is the sum of the line totals; TotalPrice adds the shipping fee to it.
+	Subtotal           float64         `json:"subtotal"`
+	ShippingFee        float64         `json:"shipping_fee"`
+	ShippingService    string          `json:"shipping_service,omitempty"`
+	ShippingPostalCode string          `json:"shipping_postal_code,omitempty"`
+	ProductOrder       json.RawMessage `json:"product_order,omitempty"`
+	Items              []OrderItem     `json:"items,omitempty"`
+	Status             OrderStatus     `json:"status"`
+	IsPaid             bool            `json:"is_paid"`
+	RefCode            string          `json:"ref_code"`
+	CreatedAt          *time.Time      `json:"created_at"`
+	UpdatedAt          *time.Time      `json:"updated_at"`
+	DeletedAt          *time.Time      `json:"deleted_at"`
 }
 
 type OrderItemsLogs struct {
-	OrderID    uuid.UUID  `json:"order_id"`
-	RefCode    string     `json:"ref_code"`
-	FromStatus string     `json:"from_status"`
-	ToStatus   string     `json:"to_status"`
-	Notes      string     `json:"notes"`
-	CreatedAt  *time.Time `json:"created_at"`
+	OrderID    uuid.UUID   `json:"order_id"`
+	RefCode    string      `json:"ref_code"`
+	FromStatus OrderStatus `json:"from_status"`
+	ToStatus   OrderStatus `json:"to_status"`
+	Notes      string      `json:"notes"`
+	CreatedAt  *time.Time  `json:"created_at"`
+}
+
+// OrderProduct is a line of an order as submitted by a client.
+type OrderProduct struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+}
+
+// OrderItem is a priced line of an order, with a snapshot of the product at the time of ordering.
+type OrderItem struct {
+	ID          uuid.UUID  `json:"id"`
+	OrderID     uuid.UUID  `json:"order_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	Qty         int        `json:"qty"`
+	UnitPrice   float64    `json:"unit_price"`
+	LineTotal   float64    `json:"line_total"`
+	ProductName string     `json:"product_name"`
+	ProductSKU  string     `json:"product_sku"`
+	CreatedAt   *time.Time `json:"created_at"`
+}
+
+// Policies for an order whose submitted total price differs from the computed one.
+const (
+	TotalMismatchReject = "reject"
+	TotalMismatchFlag   = "flag"
+)
+
+// CheckoutRequest turns a cart into an order. ShippingService picks one of the service levels quoted for
+// ShippingPostalCode; without it the order has no shipping fee.
+type CheckoutRequest struct {
+	UserID             uuid.UUID   `json:"user_id" validate:"required"`
+	PaymentTypeID      uuid.UUID   `json:"payment_type_id" validate:"required"`
+	TotalPrice         float64     `json:"total_price"`
+	ProductID          []uuid.UUID `json:"product_id"`
+	ShippingService    string      `json:"shipping_service"`
+	ShippingPostalCode string      `json:"shipping_postal_code"`
+}
+
+type CheckoutResponse struct {
+	OrderID         uuid.UUID   `json:"order_id"`
+	OrderNumber     string      `json:"order_number"`
+	RefCode         string      `json:"ref_code"`
+	Status          OrderStatus `json:"status"`
+	Subtotal        float64     `json:"subtotal"`
+	ShippingFee     float64     `json:"shipping_fee"`
+	ShippingService string      `json:"shipping_service,omitempty"`
+	TotalPrice      float64     `json:"total_price"`
+	Items           []OrderItem `json:"items"`
+	PriceFlagged    bool        `json:"price_flagged"`
+}
+
+type UpdateOrderStatusRequest struct {
+	OrderID uuid.UUID   `json:"-"`
+	Status  OrderStatus `json:"status" validate:"required"`
+	Notes   string      `json:"notes"`
+}
+
+type OrderDetail struct {
+	Order
+	Timeline []OrderItemsLogs `json:"timeline"`
+}
+
+const (
+	OrderSortByCreatedAt  = "created_at"
+	OrderSortByTotalPrice = "total_price"
+
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// OrderCursor marks the last order of a page: the value of the sort column and the order ID as tie-breaker.
+type OrderCursor struct {
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// ListOrdersRequest filters, sorts and paginates an order listing. ProductID keeps the orders with a line
+// of that product.
+type ListOrdersRequest struct {
+	UserID      uuid.UUID
+	ProductID   uuid.UUID
+	Status      []OrderStatus
+	IsPaid      *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortBy      string
+	SortOrder   string
+	Limit       int
+	Cursor      string
+	After       *OrderCursor
+}
+
+type ListOrdersResponse struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// Reasons a product of a past order cannot be bought again.
+const (
+	ReorderReasonNotFound    = "not_found"
+	ReorderReasonUnavailable = "unavailable"
+)
+
+// ReorderResponse reports which products of a past order were added back to the cart and which could not be.
+type ReorderResponse struct {
+	OrderID     uuid.UUID     `json:"order_id"`
+	UserID      uuid.UUID     `json:"user_id"`
+	Added       []ReorderItem `json:"added"`
+	Unavailable []ReorderItem `json:"unavailable"`
+}
+
+// ReorderItem is a product of a reordered order. CartQty is the product's qty in the cart after the merge,
+// and Reason says why an unavailable product was left out.
+type ReorderItem struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Qty         int       `json:"qty"`
+	CartQty     int       `json:"cart_qty,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
 }
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Aggregate types of outbox events. Events of one aggregate are published in the order they were written.
+const (
+	OutboxAggregateOrder = "order"
+	// OutboxAggregateCart events use the owner's user ID as aggregate ID.
+	OutboxAggregateCart = "cart"
+)
+
+// OutboxEventType names what happened to an aggregate.
+type OutboxEventType string
+
+const (
+	OutboxEventOrderCreated       OutboxEventType = "order.created"
+	OutboxEventOrderStatusChanged OutboxEventType = "order.status_changed"
+	OutboxEventCartItemAdded      OutboxEventType = "cart.item_added"
+	OutboxEventCartItemUpdated    OutboxEventType = "cart.item_updated"
+	OutboxEventCartItemRemoved    OutboxEventType = "cart.item_removed"
+)
+
+// OutboxEvent is a domain event waiting in, or published from, the outbox.
+// Publishers send it at least once; consumers should ignore IDs they have seen.
+type OutboxEvent struct {
+	ID            uuid.UUID       `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	EventType     OutboxEventType `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     *time.Time      `json:"occurred_at"`
+	Attempts      int             `json:"-"`
+	LastError     *string         `json:"-"`
+	AvailableAt   *time.Time      `json:"-"`
+	PublishedAt   *time.Time      `json:"-"`
+}
+
+// OrderCreatedPayload is the payload of an order.created event.
+type OrderCreatedPayload struct {
+	OrderID         uuid.UUID   `json:"order_id"`
+	OrderNumber     string      `json:"order_number"`
+	RefCode         string      `json:"ref_code"`
+	UserID          uuid.UUID   `json:"user_id"`
+	Status          OrderStatus `json:"status"`
+	Subtotal        float64     `json:"subtotal"`
+	ShippingFee     float64     `json:"shipping_fee"`
+	ShippingService string      `json:"shipping_service"`
+	TotalPrice      float64     `json:"total_price"`
+	Items           []OrderItem `json:"items"`
+}
+
+// OrderStatusChangedPayload is the payload of an order.status_changed event.
+type OrderStatusChangedPayload struct {
+	OrderID    uuid.UUID   `json:"order_id"`
+	RefCode    string      `json:"ref_code"`
+	FromStatus OrderStatus `json:"from_status"`
+	ToStatus   OrderStatus `json:"to_status"`
+	Notes      string      `json:"notes"`
+}
+
+// CartItemPayload is the payload of the cart.item_* events.
+// Qty is the product's qty in the cart after the change, 0 once it is removed.
+type CartItemPayload struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ProductID uuid.UUID `json:"product_id"`
+	Qty       int       `json:"qty"`
+}
+
+// NewOutboxEvent returns an event of eventType for an aggregate, with payload encoded as JSON.
+func NewOutboxEvent(aggregateType string, aggregateID uuid.UUID, eventType OutboxEventType, payload any) (OutboxEvent, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+
+	return OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       body,
+	}, nil
+}
+
+// NewOrderCreatedEvent returns the order.created event of an order and its items.
+func NewOrderCreatedEvent(order Order, items []OrderItem) (OutboxEvent, error) {
+	return NewOutboxEvent(OutboxAggregateOrder, order.ID, OutboxEventOrderCreated, OrderCreatedPayload{
+		OrderID:         order.ID,
+		OrderNumber:     order.OrderNumber,
+		RefCode:         order.RefCode,
+		UserID:          order.UserID,
+		Status:          order.Status,
+		Subtotal:        order.Subtotal,
+		ShippingFee:     order.ShippingFee,
+		ShippingService: order.ShippingService,
+		TotalPrice:      order.TotalPrice,
+		Items:           items,
+	})
+}
+
+// NewOrderStatusChangedEvent returns the order.status_changed event of a status log entry.
+func NewOrderStatusChangedEvent(statusLog OrderItemsLogs) (OutboxEvent, error) {
+	return NewOutboxEvent(OutboxAggregateOrder, statusLog.OrderID, OutboxEventOrderStatusChanged, OrderStatusChangedPayload{
+		OrderID:    statusLog.OrderID,
+		RefCode:    statusLog.RefCode,
+		FromStatus: statusLog.FromStatus,
+		ToStatus:   statusLog.ToStatus,
+		Notes:      statusLog.Notes,
+	})
+}
+
+// NewCartItemEvent returns a cart.item_* event for a product in a user's cart.
+func NewCartItemEvent(eventType OutboxEventType, userID, productID uuid.UUID, qty int) (OutboxEvent, error) {
+	return NewOutboxEvent(OutboxAggregateCart, userID, eventType, CartItemPayload{
+		UserID:    userID,
+		ProductID: productID,
+		Qty:       qty,
+	})
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentStatus is the state of a payment at the gateway.
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusSucceeded PaymentStatus = "succeeded"
+	PaymentStatusFailed    PaymentStatus = "failed"
+	// PaymentStatusExpired marks a pending payment whose order was cancelled for non-payment.
+	PaymentStatusExpired PaymentStatus = "expired"
+)
+
+type Payment struct {
+	ID          uuid.UUID     `json:"id"`
+	OrderID     uuid.UUID     `json:"order_id"`
+	Provider    string        `json:"provider"`
+	IntentID    string        `json:"intent_id"`
+	Amount      float64       `json:"amount"`
+	Currency    string        `json:"currency"`
+	Status      PaymentStatus `json:"status"`
+	CheckoutURL string        `json:"checkout_url"`
+	CreatedAt   *time.Time    `json:"created_at"`
+	UpdatedAt   *time.Time    `json:"updated_at"`
+}
+
+// PaymentIntentRequest asks a gateway to collect payment for an order.
+type PaymentIntentRequest struct {
+	// IdempotencyKey is the same for concurrent requests to pay an order, so the gateway opens one intent for them.
+	IdempotencyKey string
+	OrderID        uuid.UUID
+	RefCode        string
+	Amount         float64
+	Currency       string
+}
+
+// PaymentIntent is a gateway's handle for collecting a payment.
+type PaymentIntent struct {
+	IntentID    string
+	CheckoutURL string
+}
+
+// PaymentEvent is a verified payment status change reported by a gateway webhook.
+type PaymentEvent struct {
+	IntentID string        `json:"intent_id"`
+	Status   PaymentStatus `json:"status"`
+	Amount   float64       `json:"amount"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "github.com/google/uuid"
+
+// Product is a product as described by the product catalog.
+type Product struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	SKU       string    `json:"sku"`
+	Price     float64   `json:"price"`
+	Available bool      `json:"available"`
+	// WeightGrams is the shipping weight of one unit.
+	WeightGrams int `json:"weight_grams"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AbandonedCart is a user's cart that has been idle for at least Threshold.
+type AbandonedCart struct {
+	UserID uuid.UUID `json:"user_id"`
+	// Items are the active lines of the cart.
+	Items []CartLine `json:"items"`
+	// LastActivityAt is when a line of the cart was last added, changed or removed.
+	LastActivityAt time.Time     `json:"last_activity_at"`
+	Threshold      time.Duration `json:"threshold"`
+	// Attempts is how many times the reminder for Threshold failed to send in the current idle period.
+	Attempts int `json:"attempts"`
+}
+
+// ItemCount returns the total qty of the cart's lines.
+func (c AbandonedCart) ItemCount() int {
+	var count int
+	for _, item := range c.Items {
+		count += item.Qty
+	}
+	return count
+}
+
+// CartReminderStatus is the state of an abandoned-cart reminder.
+type CartReminderStatus string
+
+const (
+	CartReminderStatusSent CartReminderStatus = "sent"
+	// CartReminderStatusFailed is a reminder that failed to send and is retried after NextAttemptAt.
+	CartReminderStatusFailed CartReminderStatus = "failed"
+)
+
+// CartReminder records an abandoned-cart reminder sent to a user.
+type CartReminder struct {
+	ID             uuid.UUID          `json:"id"`
+	UserID         uuid.UUID          `json:"user_id"`
+	Threshold      time.Duration      `json:"threshold"`
+	CartActivityAt time.Time          `json:"cart_activity_at"`
+	Status         CartReminderStatus `json:"status"`
+	Attempts       int                `json:"attempts"`
+	LastError      *string            `json:"last_error"`
+	NextAttemptAt  *time.Time         `json:"next_attempt_at"`
+	SentAt         *time.Time         `json:"sent_at"`
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReturnStatus is the state of a return request in the approval workflow.
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "requested"
+	ReturnStatusApproved  ReturnStatus = "approved"
+	ReturnStatusRejected  ReturnStatus = "rejected"
+	ReturnStatusRefunded  ReturnStatus = "refunded"
+)
+
+// returnStatusTransitions lists the statuses a return may move to from each status.
+// Statuses without an entry are final.
+var returnStatusTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnStatusRequested: {ReturnStatusApproved, ReturnStatusRejected},
+	ReturnStatusApproved:  {ReturnStatusRefunded, ReturnStatusRejected},
+}
+
+// CanTransitionTo reports whether a return in status s may move to next.
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, allowed := range returnStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ReturnRequest is a customer's request to send back some of the items of an order.
+// RefundAmount is the value of the returned items at the price they were bought for.
+type ReturnRequest struct {
+	ID              uuid.UUID    `json:"id"`
+	OrderID         uuid.UUID    `json:"order_id"`
+	Status          ReturnStatus `json:"status"`
+	Reason          string       `json:"reason"`
+	ResolutionNotes string       `json:"resolution_notes,omitempty"`
+	RefundAmount    float64      `json:"refund_amount"`
+	Items           []ReturnItem `json:"items"`
+	Refunds         []Refund     `json:"refunds"`
+	CreatedAt       *time.Time   `json:"created_at"`
+	UpdatedAt       *time.Time   `json:"updated_at"`
+}
+
+// ReturnItem is a quantity of one order line being returned.
+type ReturnItem struct {
+	ID          uuid.UUID     `json:"id"`
+	ReturnID    uuid.UUID     `json:"return_id"`
+	OrderItemID uuid.UUID     `json:"order_item_id"`
+	ProductID   uuid.UUID     `json:"product_id"`
+	Qty         int           `json:"qty"`
+	UnitPrice   float64       `json:"unit_price"`
+	LineTotal   float64       `json:"line_total"`
+	Reason      string        `json:"reason,omitempty"`
+	Photos      []ReturnPhoto `json:"photos"`
+	CreatedAt   *time.Time    `json:"created_at"`
+}
+
+// ReturnPhoto is the metadata of a photo uploaded as evidence for a return.
+// The photo itself is stored elsewhere and referenced by URL.
+type ReturnPhoto struct {
+	URL         string `json:"url" validate:"required,url"`
+	ContentType string `json:"content_type,omitempty"`
+	SizeBytes   int64  `json:"size_bytes,omitempty"`
+}
+
+type CreateReturnRequest struct {
+	OrderID uuid.UUID          `json:"-"`
+	Reason  string             `json:"reason" validate:"required"`
+	Items   []CreateReturnItem `json:"items" validate:"required,min=1,dive"`
+}
+
+type CreateReturnItem struct {
+	OrderItemID uuid.UUID     `json:"order_item_id" validate:"required"`
+	Qty         int           `json:"qty" validate:"required,gt=0"`
+	Reason      string        `json:"reason"`
+	Photos      []ReturnPhoto `json:"photos" validate:"dive"`
+}
+
+// ReviewReturnRequest approves or rejects a return.
+type ReviewReturnRequest struct {
+	OrderID  uuid.UUID `json:"-"`
+	ReturnID uuid.UUID `json:"-"`
+	Notes    string    `json:"notes"`
+}
+
+// RefundReturnRequest refunds an approved return. A zero Amount refunds the full value of the returned items;
+// a smaller amount makes a partial refund.
+type RefundReturnRequest struct {
+	OrderID  uuid.UUID `json:"-"`
+	ReturnID uuid.UUID `json:"-"`
+	Amount   float64   `json:"amount" validate:"gte=0"`
+	Notes    string    `json:"notes"`
+}
+
+// RefundStatus is the state of a refund at the gateway.
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// Refund is money sent back against the payment an order was paid with.
+type Refund struct {
+	ID               uuid.UUID    `json:"id"`
+	ReturnID         uuid.UUID    `json:"return_id"`
+	PaymentID        uuid.UUID    `json:"payment_id"`
+	Provider         string       `json:"provider"`
+	ProviderRefundID string       `json:"provider_refund_id,omitempty"`
+	Amount           float64      `json:"amount"`
+	Currency         string       `json:"currency"`
+	Status           RefundStatus `json:"status"`
+	CreatedAt        *time.Time   `json:"created_at"`
+	UpdatedAt        *time.Time   `json:"updated_at"`
+}
+
+// PendingRefund is a refund waiting for the outcome at the gateway, with what it takes to send it again.
+type PendingRefund struct {
+	Refund
+	OrderID  uuid.UUID
+	IntentID string
+}
+
+// RefundRequest asks a gateway to refund part or all of a payment.
+// IdempotencyKey is the same for every attempt to refund a return, so the gateway pays a return out at most
+// once, even when an attempt that seemed to fail went through.
+type RefundRequest struct {
+	IdempotencyKey string
+	IntentID       string
+	Amount         float64
+	Currency       string
+}
+
+// RefundResult is a gateway's handle for a refund.
+type RefundResult struct {
+	ProviderRefundID string
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShipmentStatus is the tracking state of a shipment as reported by its courier.
+type ShipmentStatus string
+
+const (
+	ShipmentStatusPending        ShipmentStatus = "pending"
+	ShipmentStatusPickedUp       ShipmentStatus = "picked_up"
+	ShipmentStatusInTransit      ShipmentStatus = "in_transit"
+	ShipmentStatusOutForDelivery ShipmentStatus = "out_for_delivery"
+	ShipmentStatusDelivered      ShipmentStatus = "delivered"
+	ShipmentStatusFailed         ShipmentStatus = "failed"
+)
+
+// IsValid reports whether s is a known shipment status.
+func (s ShipmentStatus) IsValid() bool {
+	switch s {
+	case ShipmentStatusPending, ShipmentStatusPickedUp, ShipmentStatusInTransit,
+		ShipmentStatusOutForDelivery, ShipmentStatusDelivered, ShipmentStatusFailed:
+		return true
+	}
+	return false
+}
+
+// IsDispatched reports whether a shipment in status s has left the warehouse.
+func (s ShipmentStatus) IsDispatched() bool {
+	switch s {
+	case ShipmentStatusPickedUp, ShipmentStatusInTransit, ShipmentStatusOutForDelivery, ShipmentStatusDelivered:
+		return true
+	}
+	return false
+}
+
+// Shipment is a parcel carrying some or all of the items of an order.
+// An order may be split over several shipments.
+type Shipment struct {
+	ID             uuid.UUID       `json:"id"`
+	OrderID        uuid.UUID       `json:"order_id"`
+	Courier        string          `json:"courier"`
+	TrackingNumber string          `json:"tracking_number"`
+	Status         ShipmentStatus  `json:"status"`
+	Items          []ShipmentItem  `json:"items"`
+	Events         []ShipmentEvent `json:"events"`
+	ShippedAt      *time.Time      `json:"shipped_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	LastEventAt    *time.Time      `json:"last_event_at"`
+	CreatedAt      *time.Time      `json:"created_at"`
+	UpdatedAt      *time.Time      `json:"updated_at"`
+}
+
+// ShipmentItem is a quantity of one order line packed in a shipment.
+type ShipmentItem struct {
+	ID          uuid.UUID  `json:"id"`
+	ShipmentID  uuid.UUID  `json:"shipment_id"`
+	OrderItemID uuid.UUID  `json:"order_item_id"`
+	Qty         int        `json:"qty"`
+	CreatedAt   *time.Time `json:"created_at"`
+}
+
+// ShipmentEvent is a tracking update reported by a courier.
+type ShipmentEvent struct {
+	ID          uuid.UUID      `json:"id"`
+	ShipmentID  uuid.UUID      `json:"shipment_id"`
+	Status      ShipmentStatus `json:"status"`
+	Description string         `json:"description,omitempty"`
+	Location    string         `json:"location,omitempty"`
+	OccurredAt  time.Time      `json:"occurred_at"`
+	CreatedAt   *time.Time     `json:"created_at"`
+}
+
+// CreateShipmentRequest packs items of an order into a new shipment.
+// Without items, everything not yet shipped goes into the shipment.
+type CreateShipmentRequest struct {
+	OrderID        uuid.UUID            `json:"-"`
+	Courier        string               `json:"courier"`
+	TrackingNumber string               `json:"tracking_number"`
+	Items          []CreateShipmentItem `json:"items" validate:"dive"`
+}
+
+type CreateShipmentItem struct {
+	OrderItemID uuid.UUID `json:"order_item_id" validate:"required"`
+	Qty         int       `json:"qty" validate:"required,gt=0"`
+}
+
+// AssignTrackingRequest sets the courier and tracking number of a shipment.
+type AssignTrackingRequest struct {
+	OrderID        uuid.UUID `json:"-"`
+	ShipmentID     uuid.UUID `json:"-"`
+	Courier        string    `json:"courier" validate:"required"`
+	TrackingNumber string    `json:"tracking_number" validate:"required"`
+}
+
+// CourierEvent is a verified tracking update received from a courier webhook.
+type CourierEvent struct {
+	Courier        string         `json:"courier"`
+	TrackingNumber string         `json:"tracking_number"`
+	Status         ShipmentStatus `json:"status"`
+	Description    string         `json:"description"`
+	Location       string         `json:"location"`
+	OccurredAt     time.Time      `json:"occurred_at"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "github.com/google/uuid"
+
+// ShippingQuoteRequest asks a shipping rate provider for the fees of sending a parcel.
+type ShippingQuoteRequest struct {
+	PostalCode  string  `json:"postal_code"`
+	WeightGrams int     `json:"weight_grams"`
+	Subtotal    float64 `json:"subtotal"`
+}
+
+// ShippingQuote is the fee of sending a parcel with one service level.
+type ShippingQuote struct {
+	Service       string  `json:"service"`
+	Name          string  `json:"name"`
+	Fee           float64 `json:"fee"`
+	EstimatedDays int     `json:"estimated_days"`
+}
+
+// QuoteShippingRequest asks for the shipping options of an order before it is placed, for either explicit
+// Products or the user's cart, optionally limited to ProductID as at checkout.
+type QuoteShippingRequest struct {
+	UserID     uuid.UUID      `json:"user_id"`
+	ProductID  []uuid.UUID    `json:"product_id"`
+	Products   []OrderProduct `json:"products"`
+	PostalCode string         `json:"postal_code" validate:"required"`
+}
+
+type QuoteShippingResponse struct {
+	Subtotal    float64         `json:"subtotal"`
+	WeightGrams int             `json:"weight_grams"`
+	Quotes      []ShippingQuote `json:"quotes"`
+}
@@ -2,15 +2,71 @@ package order
 
 import (
 	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
+// orderColumns is the column list scanned by scanOrder.
+const orderColumns = `
+	id,
+	user_id,
+	payment_type_id,
+	order_number,
+	total_price,
+	subtotal,
+	shipping_fee,
+	COALESCE(shipping_service, ''),
+	COALESCE(shipping_postal_code, ''),
+	status,
+	is_paid,
+	COALESCE(ref_code, ''),
+	created_at,
+	updated_at,
+	deleted_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanOrder scans a row selected with orderColumns into an order.
+func scanOrder(row rowScanner) (*model.Order, error) {
+	var order model.Order
+	if err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.PaymentTypeID,
+		&order.OrderNumber,
+		&order.TotalPrice,
+		&order.Subtotal,
+		&order.ShippingFee,
+		&order.ShippingService,
+		&order.ShippingPostalCode,
+		&order.Status,
+		&order.IsPaid,
+		&order.RefCode,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.DeletedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
 type store struct {
 	db     *sql.DB
+	tx     *sql.Tx
 	logger zerolog.Logger
 }
 
@@ -21,12 +77,35 @@ func NewStore(db *sql.DB, logger zerolog.Logger) *store {
 	}
 }
 
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (o *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     o.db,
+		tx:     tx,
+		logger: o.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (o *store) querier() transaction.Querier {
+	if o.tx != nil {
+		return o.tx
+	}
+	return o.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (o *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(o.db, o.tx)
+}
+
 // CreateOrder is a method that creates a new order and returns the order ID.
+// The order lines are stored separately with CreateOrderItems.
 // It returns an error if any occurs during the creation process.
 func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
 	logMsgStr := "Repository:Order - CreateOrder:"
 
-	tx, err := o.db.Begin()
+	tx, err := o.begin()
 	if err != nil {
 		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
 		return nil, nil, err
@@ -38,13 +117,16 @@ func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
 			payment_type_id,
 			order_number,
 			total_price,
-			product_order,
+			subtotal,
+			shipping_fee,
+			shipping_service,
+			shipping_postal_code,
 			status,
 			is_paid,
 			ref_code,
 			created_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, NOW()
+			$1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, NOW()
 		) RETURNING id, ref_code
 	`
 
@@ -56,7 +138,10 @@ func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
 		bReq.PaymentTypeID,
 		bReq.OrderNumber,
 		bReq.TotalPrice,
-		bReq.ProductOrder,
+		bReq.Subtotal,
+		bReq.ShippingFee,
+		bReq.ShippingService,
+		bReq.ShippingPostalCode,
 		bReq.Status,
 		bReq.IsPaid,
 		bReq.RefCode,
@@ -80,7 +165,7 @@ func (o *store) CreateOrder(bReq model.Order) (*uuid.UUID, *string, error) {
 func (o *store) CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error) {
 	logMsgStr := "Repository:Order - CreateOrderItemsLogs:"
 
-	tx, err := o.db.Begin()
+	tx, err := o.begin()
 	if err != nil {
 		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
 		return nil, err
@@ -121,3 +206,563 @@ func (o *store) CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error)
 
 	return &refCode, nil
 }
+
+// GetOrderForUpdate is a method that retrieves an order and locks it until the transaction ends.
+// It returns model.ErrOrderNotFound if the order does not exist.
+func (o *store) GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error) {
+	logMsgStr := "Repository:Order - GetOrderForUpdate:"
+
+	tx, err := o.begin()
+	if err != nil {
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE deleted_at IS NULL AND id = $1
+		FOR UPDATE
+	`
+
+	order, err := scanOrder(tx.QueryRow(querySelect, orderID))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrOrderNotFound
+		}
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan order", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// UpdateOrderStatus is a method that sets the status of an order.
+// It returns model.ErrOrderNotFound if the order does not exist.
+func (o *store) UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error {
+	logMsgStr := "Repository:Order - UpdateOrderStatus:"
+
+	tx, err := o.begin()
+	if err != nil {
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE orders
+		SET status = $1, updated_at = NOW()
+		WHERE deleted_at IS NULL AND id = $2
+	`
+	result, err := tx.Exec(queryUpdate, status, orderID)
+	if err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		return model.ErrOrderNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// GetOrderByID is a method that retrieves an order by its ID.
+// It returns model.ErrOrderNotFound if the order does not exist.
+func (o *store) GetOrderByID(orderID uuid.UUID) (*model.Order, error) {
+	logMsgStr := "Repository:Order - GetOrderByID:"
+
+	querySelect := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE deleted_at IS NULL AND id = $1
+	`
+
+	order, err := scanOrder(o.querier().QueryRow(querySelect, orderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrOrderNotFound
+		}
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan order", logMsgStr))
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetOrderByRefCode is a method that retrieves an order by its reference code.
+// It returns model.ErrOrderNotFound if the order does not exist.
+func (o *store) GetOrderByRefCode(refCode string) (*model.Order, error) {
+	logMsgStr := "Repository:Order - GetOrderByRefCode:"
+
+	querySelect := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE deleted_at IS NULL AND ref_code = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	order, err := scanOrder(o.querier().QueryRow(querySelect, refCode))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrOrderNotFound
+		}
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan order", logMsgStr))
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetOrderStatusLogs is a method that retrieves the status history of an order, oldest first.
+func (o *store) GetOrderStatusLogs(orderID uuid.UUID) (*[]model.OrderItemsLogs, error) {
+	logMsgStr := "Repository:Order - GetOrderStatusLogs:"
+
+	querySelect := `
+		SELECT
+			order_id,
+			ref_code,
+			from_status,
+			to_status,
+			COALESCE(notes, ''),
+			created_at
+		FROM order_status_logs
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := o.querier().Query(querySelect, orderID)
+	if err != nil {
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []model.OrderItemsLogs{}
+	for rows.Next() {
+		var log model.OrderItemsLogs
+		if err := rows.Scan(
+			&log.OrderID,
+			&log.RefCode,
+			&log.FromStatus,
+			&log.ToStatus,
+			&log.Notes,
+			&log.CreatedAt,
+		); err != nil {
+			o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &logs, nil
+}
+
+// ListOrders is a method that retrieves a page of orders matching the request filters.
+// Orders are keyset-paginated on (sort column, id), starting after bReq.After when it is set.
+func (o *store) ListOrders(bReq model.ListOrdersRequest) (*[]model.Order, error) {
+	logMsgStr := "Repository:Order - ListOrders:"
+
+	sortColumn := "created_at"
+	castType := "timestamp"
+	if bReq.SortBy == model.OrderSortByTotalPrice {
+		sortColumn = "total_price"
+		castType = "double precision"
+	}
+
+	direction, comparator := "DESC", "<"
+	if bReq.SortOrder == model.SortOrderAsc {
+		direction, comparator = "ASC", ">"
+	}
+
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	queryConditions := filterConditions(bReq.Status, bReq.CreatedFrom, bReq.CreatedTo, arg)
+
+	if bReq.UserID != uuid.Nil {
+		queryConditions = append(queryConditions, "user_id = "+arg(bReq.UserID))
+	}
+
+	if bReq.ProductID != uuid.Nil {
+		queryConditions = append(queryConditions,
+			"EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = orders.id AND oi.product_id = "+arg(bReq.ProductID)+")")
+	}
+
+	if bReq.IsPaid != nil {
+		queryConditions = append(queryConditions, "is_paid = "+arg(*bReq.IsPaid))
+	}
+
+	if bReq.After != nil {
+		queryConditions = append(queryConditions, fmt.Sprintf("(%s, id) %s (%s::%s, %s)",
+			sortColumn, comparator, arg(bReq.After.Value), castType, arg(bReq.After.ID)))
+	}
+
+	querySelect := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE ` + strings.Join(queryConditions, " AND ") + `
+		ORDER BY ` + sortColumn + ` ` + direction + `, id ` + direction + `
+		LIMIT ` + arg(bReq.Limit)
+
+	rows, err := o.querier().Query(querySelect, args...)
+	if err != nil {
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []model.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &orders, nil
+}
+
+// filterConditions returns the conditions on status and creation time shared by order listings and exports.
+// arg adds a query argument and returns its placeholder.
+func filterConditions(status []model.OrderStatus, createdFrom, createdTo *time.Time, arg func(v any) string) []string {
+	queryConditions := []string{"deleted_at IS NULL"}
+
+	if len(status) > 0 {
+		statuses := make([]string, 0, len(status))
+		for _, s := range status {
+			statuses = append(statuses, string(s))
+		}
+		queryConditions = append(queryConditions, "status = ANY("+arg(pq.Array(statuses))+")")
+	}
+
+	if createdFrom != nil {
+		queryConditions = append(queryConditions, "created_at >= "+arg(*createdFrom))
+	}
+
+	if createdTo != nil {
+		queryConditions = append(queryConditions, "created_at < "+arg(*createdTo))
+	}
+
+	return queryConditions
+}
+
+// StreamOrders is a method that calls fn for every order matching the export filters, oldest first.
+// The orders are read through a server-side cursor in batches of batchSize, so memory use stays flat however many
+// orders match. The cursor lives in a read-only transaction of its own, which ends when ctx is done or fn fails.
+func (o *store) StreamOrders(ctx context.Context, bReq model.ExportOrdersRequest, batchSize int, fn func(order model.Order) error) error {
+	logMsgStr := "Repository:Order - StreamOrders:"
+
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	queryConditions := filterConditions(bReq.Status, bReq.CreatedFrom, bReq.CreatedTo, arg)
+
+	tx, err := o.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+	// Closing the transaction also closes the cursor.
+	defer tx.Rollback()
+
+	queryDeclare := `
+		DECLARE orders_export NO SCROLL CURSOR FOR
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE ` + strings.Join(queryConditions, " AND ") + `
+		ORDER BY created_at ASC, id ASC
+	`
+
+	if _, err := tx.ExecContext(ctx, queryDeclare, args...); err != nil {
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Exec queryDeclare", logMsgStr))
+		return err
+	}
+
+	queryFetch := fmt.Sprintf("FETCH FORWARD %d FROM orders_export", batchSize)
+
+	for {
+		rows, err := tx.QueryContext(ctx, queryFetch)
+		if err != nil {
+			o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query queryFetch", logMsgStr))
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			order, err := scanOrder(rows)
+			if err != nil {
+				rows.Close()
+				o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+				return err
+			}
+			fetched++
+
+			if err := fn(*order); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+			return err
+		}
+		rows.Close()
+
+		if fetched < batchSize {
+			return nil
+		}
+	}
+}
+
+// CreateOrderItems is a method that stores the lines of an order.
+// It fills in the ID, OrderID and CreatedAt of each item.
+func (o *store) CreateOrderItems(orderID uuid.UUID, items []model.OrderItem) error {
+	logMsgStr := "Repository:Order - CreateOrderItems:"
+
+	tx, err := o.begin()
+	if err != nil {
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryCreate := `
+		INSERT INTO order_items (
+			order_id,
+			product_id,
+			qty,
+			unit_price,
+			line_total,
+			product_name,
+			product_sku,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, NOW()
+		) RETURNING id, created_at
+	`
+
+	for i, item := range items {
+		if err := tx.QueryRow(
+			queryCreate,
+			orderID,
+			item.ProductID,
+			item.Qty,
+			item.UnitPrice,
+			item.LineTotal,
+			item.ProductName,
+			item.ProductSKU,
+		).Scan(&items[i].ID, &items[i].CreatedAt); err != nil {
+			tx.Rollback()
+			o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan order item id", logMsgStr))
+			return err
+		}
+		items[i].OrderID = orderID
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// GetOrderItems is a method that retrieves the lines of an order in the order they were added.
+func (o *store) GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error) {
+	logMsgStr := "Repository:Order - GetOrderItems:"
+
+	querySelect := `
+		SELECT
+			id,
+			order_id,
+			product_id,
+			qty,
+			unit_price,
+			line_total,
+			COALESCE(product_name, ''),
+			COALESCE(product_sku, ''),
+			created_at
+		FROM order_items
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := o.querier().Query(querySelect, orderID)
+	if err != nil {
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.OrderItem{}
+	for rows.Next() {
+		var item model.OrderItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.OrderID,
+			&item.ProductID,
+			&item.Qty,
+			&item.UnitPrice,
+			&item.LineTotal,
+			&item.ProductName,
+			&item.ProductSKU,
+			&item.CreatedAt,
+		); err != nil {
+			o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &items, nil
+}
+
+// MarkOrderPaid is a method that flags an order as paid.
+// It returns model.ErrOrderNotFound if the order does not exist.
+func (o *store) MarkOrderPaid(orderID uuid.UUID) error {
+	logMsgStr := "Repository:Order - MarkOrderPaid:"
+
+	tx, err := o.begin()
+	if err != nil {
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE orders
+		SET is_paid = TRUE, updated_at = NOW()
+		WHERE deleted_at IS NULL AND id = $1
+	`
+	result, err := tx.Exec(queryUpdate, orderID)
+	if err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		return model.ErrOrderNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// GetExpiredOrdersForUpdate is a method that retrieves up to limit pending, unpaid orders created more than
+// paymentWindow ago, oldest first, and locks them until the transaction ends.
+// Orders locked by another transaction are skipped, so concurrent callers never pick up the same order.
+func (o *store) GetExpiredOrdersForUpdate(paymentWindow time.Duration, limit int) (*[]model.Order, error) {
+	logMsgStr := "Repository:Order - GetExpiredOrdersForUpdate:"
+
+	tx, err := o.begin()
+	if err != nil {
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE deleted_at IS NULL
+			AND status = $1
+			AND is_paid = FALSE
+			AND created_at < NOW() - make_interval(secs => $2)
+		ORDER BY created_at ASC
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.Query(querySelect, model.OrderStatusPending, paymentWindow.Seconds(), limit)
+	if err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []model.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			tx.Rollback()
+			o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		o.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &orders, nil
+}
@@ -0,0 +1,94 @@
+package order
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/testdb"
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// insertOrders inserts n orders of status created a second apart from start, and returns their IDs oldest first.
+func insertOrders(t *testing.T, db *sql.DB, start time.Time, status model.OrderStatus, n int) []uuid.UUID {
+	t.Helper()
+
+	ids := make([]uuid.UUID, 0, n)
+	for i := 0; i < n; i++ {
+		var id uuid.UUID
+		number := "TEST-" + uuid.NewString()
+		if err := db.QueryRow(`
+			INSERT INTO orders (user_id, payment_type_id, order_number, total_price, status, is_paid, ref_code, created_at)
+			VALUES ($1, $2, $3, 100, $4, false, $3, $5)
+			RETURNING id
+		`, uuid.New(), uuid.New(), number, status, start.Add(time.Duration(i)*time.Second)).Scan(&id); err != nil {
+			t.Fatalf("insert order: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// exportWindow returns an hour in the 1990s of its own, so the export only sees the orders the test inserts in it.
+func exportWindow() (time.Time, time.Time) {
+	from := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(rand.Int63n(10*365*24)) * time.Hour)
+	return from, from.Add(time.Hour)
+}
+
+func TestStreamOrdersReadsEveryBatch(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+	from, to := exportWindow()
+
+	paid := insertOrders(t, db, from, model.OrderStatusPaid, 5)
+	insertOrders(t, db, from.Add(time.Minute), model.OrderStatusCancelled, 2)
+	insertOrders(t, db, to, model.OrderStatusPaid, 1)
+
+	var got []uuid.UUID
+	bReq := model.ExportOrdersRequest{Status: []model.OrderStatus{model.OrderStatusPaid}, CreatedFrom: &from, CreatedTo: &to}
+	if err := s.StreamOrders(context.Background(), bReq, 2, func(order model.Order) error {
+		got = append(got, order.ID)
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamOrders: %v", err)
+	}
+
+	if len(got) != len(paid) {
+		t.Fatalf("streamed %d orders, want the %d paid orders inside the window", len(got), len(paid))
+	}
+	for i := range paid {
+		if got[i] != paid[i] {
+			t.Errorf("order %d = %s, want %s, oldest first", i, got[i], paid[i])
+		}
+	}
+}
+
+func TestStreamOrdersStopsWhenFnFails(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewStore(db, zerolog.Nop())
+	from, to := exportWindow()
+	insertOrders(t, db, from, model.OrderStatusPaid, 5)
+
+	stop := errors.New("client went away")
+	calls := 0
+	err := s.StreamOrders(context.Background(), model.ExportOrdersRequest{CreatedFrom: &from, CreatedTo: &to}, 2, func(order model.Order) error {
+		calls++
+		if calls == 3 {
+			return stop
+		}
+		return nil
+	})
+
+	if !errors.Is(err, stop) {
+		t.Fatalf("StreamOrders error = %v, want %v", err, stop)
+	}
+	if calls != 3 {
+		t.Errorf("fn was called %d times, want the stream to stop at the failing call", calls)
+	}
+}
@@ -0,0 +1,325 @@
+package outbox
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
+// CreateEvent is a method that writes an event to the outbox.
+// Bound to a usecase transaction, the event is only published if that transaction commits.
+func (s *store) CreateEvent(event model.OutboxEvent) error {
+	logMsgStr := "Repository:Outbox - CreateEvent:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryCreate := `
+		INSERT INTO outbox (
+			aggregate_type,
+			aggregate_id,
+			event_type,
+			payload,
+			available_at,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, NOW(), NOW()
+		)
+	`
+	if _, err := tx.Exec(
+		queryCreate,
+		event.AggregateType,
+		event.AggregateID,
+		event.EventType,
+		[]byte(event.Payload),
+	); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to insert data", logMsgStr))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// ClaimEvents is a method that leases up to limit events that are due for publishing to leaseID for lease,
+// and returns them oldest first.
+// Only the oldest unpublished event of each aggregate is claimed, so the events of an aggregate are
+// published in order. Events leased by another relay are skipped until their lease runs out.
+// The claim commits before the events are published, so no row lock is held while publishing.
+func (s *store) ClaimEvents(leaseID uuid.UUID, limit int, lease time.Duration) (*[]model.OutboxEvent, error) {
+	logMsgStr := "Repository:Outbox - ClaimEvents:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	queryClaim := `
+		WITH due AS (
+			SELECT o.id
+			FROM outbox o
+			WHERE o.published_at IS NULL
+				AND o.available_at <= NOW()
+				AND (o.leased_until IS NULL OR o.leased_until <= NOW())
+				AND NOT EXISTS (
+					SELECT 1
+					FROM outbox p
+					WHERE p.published_at IS NULL
+						AND p.aggregate_type = o.aggregate_type
+						AND p.aggregate_id = o.aggregate_id
+						AND p.seq < o.seq
+				)
+			ORDER BY o.seq ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE outbox o
+			SET lease_id = $1, leased_until = NOW() + make_interval(secs => $3)
+			FROM due
+			WHERE o.id = due.id
+			RETURNING
+				o.id,
+				o.seq,
+				o.aggregate_type,
+				o.aggregate_id,
+				o.event_type,
+				o.payload,
+				o.attempts,
+				o.last_error,
+				o.available_at,
+				o.created_at,
+				o.published_at
+		)
+		SELECT
+			id,
+			aggregate_type,
+			aggregate_id,
+			event_type,
+			payload,
+			attempts,
+			last_error,
+			available_at,
+			created_at,
+			published_at
+		FROM claimed
+		ORDER BY seq ASC
+	`
+
+	rows, err := tx.Query(queryClaim, leaseID, limit, lease.Seconds())
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Query queryClaim", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.OutboxEvent{}
+	for rows.Next() {
+		var event model.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(
+			&event.ID,
+			&event.AggregateType,
+			&event.AggregateID,
+			&event.EventType,
+			&payload,
+			&event.Attempts,
+			&event.LastError,
+			&event.AvailableAt,
+			&event.CreatedAt,
+			&event.PublishedAt,
+		); err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &events, nil
+}
+
+// MarkPublished is a method that records that an event leased to leaseID has been published.
+// It returns model.ErrOutboxLeaseLost if the lease ran out and another relay claimed the event.
+func (s *store) MarkPublished(eventID, leaseID uuid.UUID) error {
+	logMsgStr := "Repository:Outbox - MarkPublished:"
+
+	queryUpdate := `
+		UPDATE outbox
+		SET published_at = NOW(), attempts = attempts + 1, last_error = NULL, lease_id = NULL, leased_until = NULL
+		WHERE id = $1 AND lease_id = $2 AND published_at IS NULL
+	`
+	return s.updateLeasedEvent(logMsgStr, queryUpdate, eventID, leaseID)
+}
+
+// MarkFailed is a method that records a failed publish of an event leased to leaseID, releases the lease
+// and holds the event back for retryIn.
+// It returns model.ErrOutboxLeaseLost if the lease ran out and another relay claimed the event.
+func (s *store) MarkFailed(eventID, leaseID uuid.UUID, lastError string, retryIn time.Duration) error {
+	logMsgStr := "Repository:Outbox - MarkFailed:"
+
+	queryUpdate := `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+			last_error = $3,
+			available_at = NOW() + make_interval(secs => $4),
+			lease_id = NULL,
+			leased_until = NULL
+		WHERE id = $1 AND lease_id = $2 AND published_at IS NULL
+	`
+	return s.updateLeasedEvent(logMsgStr, queryUpdate, eventID, leaseID, lastError, retryIn.Seconds())
+}
+
+// DeadLetter is a method that moves an event leased to leaseID whose last publish failed from the outbox
+// to the dead-letter table.
+// It returns model.ErrOutboxLeaseLost if the lease ran out and another relay claimed the event.
+func (s *store) DeadLetter(eventID, leaseID uuid.UUID, lastError string) error {
+	logMsgStr := "Repository:Outbox - DeadLetter:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryMove := `
+		WITH dead AS (
+			DELETE FROM outbox
+			WHERE id = $1 AND lease_id = $2 AND published_at IS NULL
+			RETURNING id, aggregate_type, aggregate_id, event_type, payload, attempts, created_at
+		)
+		INSERT INTO outbox_dead_letters (
+			id,
+			aggregate_type,
+			aggregate_id,
+			event_type,
+			payload,
+			attempts,
+			last_error,
+			created_at,
+			dead_at
+		)
+		SELECT id, aggregate_type, aggregate_id, event_type, payload, attempts + 1, $3, created_at, NOW()
+		FROM dead
+	`
+	result, err := tx.Exec(queryMove, eventID, leaseID, lastError)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to move data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		s.logger.Warn().Msg(fmt.Sprintf("%v No rows affected, the lease was lost", logMsgStr))
+		return model.ErrOutboxLeaseLost
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// updateLeasedEvent runs an update of a single unpublished event that is still leased to the caller.
+func (s *store) updateLeasedEvent(logMsgStr, query string, args ...any) error {
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		s.logger.Warn().Msg(fmt.Sprintf("%v No rows affected, the lease was lost", logMsgStr))
+		return model.ErrOutboxLeaseLost
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,306 @@
+package payment
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// uniqueViolation is the Postgres error code raised when a unique constraint is violated.
+const uniqueViolation = "23505"
+
+// pendingOrderIndex is the unique index that allows one pending payment per order.
+const pendingOrderIndex = "idx_payments_pending_order"
+
+// paymentColumns is the column list scanned by scanPayment.
+const paymentColumns = `
+	id,
+	order_id,
+	provider,
+	intent_id,
+	amount,
+	currency,
+	status,
+	COALESCE(checkout_url, ''),
+	created_at,
+	updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPayment scans a row selected with paymentColumns into a payment.
+func scanPayment(row rowScanner) (*model.Payment, error) {
+	var payment model.Payment
+	if err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.Provider,
+		&payment.IntentID,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.Status,
+		&payment.CheckoutURL,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (p *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     p.db,
+		tx:     tx,
+		logger: p.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (p *store) querier() transaction.Querier {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (p *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(p.db, p.tx)
+}
+
+// isUniqueViolation reports whether err was raised by the unique constraint or index named constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == constraint
+}
+
+// CreatePayment is a method that records a payment intent and returns the payment ID.
+// It returns model.ErrPaymentPending if the order already has a pending payment.
+func (p *store) CreatePayment(bReq model.Payment) (*uuid.UUID, error) {
+	logMsgStr := "Repository:Payment - CreatePayment:"
+
+	tx, err := p.begin()
+	if err != nil {
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	queryCreate := `
+		INSERT INTO payments (
+			order_id,
+			provider,
+			intent_id,
+			amount,
+			currency,
+			status,
+			checkout_url,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, NOW()
+		) RETURNING id
+	`
+
+	var id uuid.UUID
+	if err := tx.QueryRow(
+		queryCreate,
+		bReq.OrderID,
+		bReq.Provider,
+		bReq.IntentID,
+		bReq.Amount,
+		bReq.Currency,
+		bReq.Status,
+		bReq.CheckoutURL,
+	).Scan(&id); err != nil {
+		tx.Rollback()
+		if isUniqueViolation(err, pendingOrderIndex) {
+			return nil, model.ErrPaymentPending
+		}
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan id", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// GetPaymentByIntentIDForUpdate is a method that retrieves a payment by gateway intent ID and locks it
+// until the transaction ends. It returns model.ErrPaymentNotFound if there is no such payment.
+func (p *store) GetPaymentByIntentIDForUpdate(provider, intentID string) (*model.Payment, error) {
+	logMsgStr := "Repository:Payment - GetPaymentByIntentIDForUpdate:"
+
+	tx, err := p.begin()
+	if err != nil {
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE provider = $1 AND intent_id = $2
+		FOR UPDATE
+	`
+
+	payment, err := scanPayment(tx.QueryRow(querySelect, provider, intentID))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrPaymentNotFound
+		}
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan payment", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// GetPaymentsByOrderID is a method that retrieves the payments of an order, oldest first.
+func (p *store) GetPaymentsByOrderID(orderID uuid.UUID) (*[]model.Payment, error) {
+	logMsgStr := "Repository:Payment - GetPaymentsByOrderID:"
+
+	querySelect := `
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := p.querier().Query(querySelect, orderID)
+	if err != nil {
+		p.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []model.Payment{}
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			p.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		payments = append(payments, *payment)
+	}
+
+	if err := rows.Err(); err != nil {
+		p.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &payments, nil
+}
+
+// UpdatePaymentStatus is a method that sets the status of a payment.
+func (p *store) UpdatePaymentStatus(paymentID uuid.UUID, status model.PaymentStatus) error {
+	logMsgStr := "Repository:Payment - UpdatePaymentStatus:"
+
+	tx, err := p.begin()
+	if err != nil {
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE payments
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+	result, err := tx.Exec(queryUpdate, status, paymentID)
+	if err != nil {
+		tx.Rollback()
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		return model.ErrPaymentNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// ExpirePendingPayments is a method that expires the pending payments of an order.
+// Payments locked by another transaction, such as a webhook being applied, are left alone.
+func (p *store) ExpirePendingPayments(orderID uuid.UUID) error {
+	logMsgStr := "Repository:Payment - ExpirePendingPayments:"
+
+	tx, err := p.begin()
+	if err != nil {
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE payments
+		SET status = $1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id
+			FROM payments
+			WHERE order_id = $2 AND status = $3
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	if _, err := tx.Exec(queryUpdate, model.PaymentStatusExpired, orderID, model.PaymentStatusPending); err != nil {
+		tx.Rollback()
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		p.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package reminder
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (s *store) querier() transaction.Querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
+// GetAbandonedCarts is a method that retrieves up to limit carts with active lines whose most recent activity is
+// at least threshold ago, longest idle first. A cart is left out if a reminder for threshold was already sent
+// for its current idle period, failed maxAttempts times or is held back after a failure, and if a reminder for
+// a longer threshold was sent or tried.
+func (s *store) GetAbandonedCarts(threshold time.Duration, limit, maxAttempts int) (*[]model.AbandonedCart, error) {
+	logMsgStr := "Repository:Reminder - GetAbandonedCarts:"
+
+	querySelect := `
+		WITH activity AS (
+			SELECT
+				user_id,
+				MAX(GREATEST(created_at, updated_at, deleted_at)) AS last_activity_at
+			FROM cart_items
+			GROUP BY user_id
+		)
+		SELECT
+			a.user_id,
+			a.last_activity_at,
+			jsonb_agg(jsonb_build_object('product_id', c.product_id, 'qty', c.qty) ORDER BY c.created_at) AS items,
+			COALESCE(f.attempts, 0) AS attempts
+		FROM activity a
+		JOIN cart_items c ON c.user_id = a.user_id AND c.deleted_at IS NULL
+		LEFT JOIN cart_reminders f ON f.user_id = a.user_id
+			AND f.cart_activity_at = a.last_activity_at
+			AND f.threshold_seconds = $1::bigint
+		WHERE a.last_activity_at <= NOW() - make_interval(secs => $1::bigint)
+			AND (f.id IS NULL OR (f.status = $4 AND f.attempts < $3 AND f.next_attempt_at <= NOW()))
+			AND NOT EXISTS (
+				SELECT 1
+				FROM cart_reminders r
+				WHERE r.user_id = a.user_id
+					AND r.cart_activity_at = a.last_activity_at
+					AND r.threshold_seconds > $1::bigint
+			)
+		GROUP BY a.user_id, a.last_activity_at, f.attempts
+		ORDER BY a.last_activity_at
+		LIMIT $2
+	`
+
+	rows, err := s.querier().Query(querySelect, int64(threshold.Seconds()), limit, maxAttempts, model.CartReminderStatusFailed)
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	carts := []model.AbandonedCart{}
+	for rows.Next() {
+		cart := model.AbandonedCart{Threshold: threshold}
+		var items []byte
+		if err := rows.Scan(&cart.UserID, &cart.LastActivityAt, &items, &cart.Attempts); err != nil {
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+
+		if err := json.Unmarshal(items, &cart.Items); err != nil {
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to unmarshal items", logMsgStr))
+			return nil, err
+		}
+		carts = append(carts, cart)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &carts, nil
+}
+
+// CreateReminder is a method that records a reminder for a cart's idle period and threshold as sent, replacing
+// a failed attempt. It returns false if the reminder is recorded as sent already, e.g. by another replica.
+// Inside a transaction the record also keeps other replicas from sending the same reminder until the
+// transaction ends.
+func (s *store) CreateReminder(bReq model.CartReminder) (bool, error) {
+	logMsgStr := "Repository:Reminder - CreateReminder:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return false, err
+	}
+
+	queryInsert := `
+		INSERT INTO cart_reminders (
+			user_id,
+			threshold_seconds,
+			cart_activity_at,
+			status,
+			sent_at
+		) VALUES (
+			$1, $2, $3, $4, NOW()
+		)
+		ON CONFLICT (user_id, cart_activity_at, threshold_seconds) DO UPDATE
+		SET status = EXCLUDED.status, sent_at = EXCLUDED.sent_at, next_attempt_at = NULL
+		WHERE cart_reminders.status <> EXCLUDED.status
+		RETURNING id
+	`
+	var id uuid.UUID
+	err = tx.QueryRow(queryInsert, bReq.UserID, int64(bReq.Threshold.Seconds()), bReq.CartActivityAt, model.CartReminderStatusSent).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return false, nil
+	}
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to insert data", logMsgStr))
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RecordFailure is a method that records a failed attempt to send a reminder for a cart's idle period and
+// threshold and holds the reminder back for retryIn. A reminder recorded as sent is left alone.
+func (s *store) RecordFailure(bReq model.CartReminder, lastError string, retryIn time.Duration) error {
+	logMsgStr := "Repository:Reminder - RecordFailure:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpsert := `
+		INSERT INTO cart_reminders (
+			user_id,
+			threshold_seconds,
+			cart_activity_at,
+			status,
+			attempts,
+			last_error,
+			next_attempt_at
+		) VALUES (
+			$1, $2, $3, $4, 1, $5, NOW() + make_interval(secs => $6)
+		)
+		ON CONFLICT (user_id, cart_activity_at, threshold_seconds) DO UPDATE
+		SET attempts = cart_reminders.attempts + 1,
+			last_error = EXCLUDED.last_error,
+			next_attempt_at = EXCLUDED.next_attempt_at
+		WHERE cart_reminders.status = EXCLUDED.status
+	`
+	_, err = tx.Exec(
+		queryUpsert,
+		bReq.UserID,
+		int64(bReq.Threshold.Seconds()),
+		bReq.CartActivityAt,
+		model.CartReminderStatusFailed,
+		lastError,
+		retryIn.Seconds(),
+	)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to upsert data", logMsgStr))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package reservation
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
+// CreateReservation is a method that records a pending reservation before it is requested from the inventory service.
+// The reconciler releases it once pendingTimeout has passed without an order being attached.
+func (s *store) CreateReservation(bReq model.StockReservation, pendingTimeout time.Duration) error {
+	logMsgStr := "Repository:Reservation - CreateReservation:"
+
+	lines, err := json.Marshal(bReq.Lines)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryCreate := `
+		INSERT INTO stock_reservations (
+			id,
+			lines,
+			status,
+			next_attempt_at,
+			created_at
+		) VALUES (
+			$1, $2, $3, NOW() + make_interval(secs => $4), NOW()
+		)
+	`
+	if _, err := tx.Exec(queryCreate, bReq.ID, lines, model.ReservationStatusPending, pendingTimeout.Seconds()); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to insert data", logMsgStr))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// AttachOrder is a method that marks a pending reservation as held for an order.
+// It returns model.ErrReservationNotFound if the reservation is no longer pending, e.g. because the reconciler
+// released it in the meantime.
+func (s *store) AttachOrder(reservationID, orderID uuid.UUID) error {
+	logMsgStr := "Repository:Reservation - AttachOrder:"
+
+	queryUpdate := `
+		UPDATE stock_reservations
+		SET order_id = $2, status = $3, updated_at = NOW()
+		WHERE id = $1 AND status = $4
+	`
+	return s.update(logMsgStr, true, queryUpdate, reservationID, orderID, model.ReservationStatusReserved, model.ReservationStatusPending)
+}
+
+// UpdateReservationStatus is a method that moves a reservation from status from to status to.
+// It returns model.ErrReservationNotFound if the reservation is not in status from.
+func (s *store) UpdateReservationStatus(reservationID uuid.UUID, from, to model.ReservationStatus, lastError string) error {
+	logMsgStr := "Repository:Reservation - UpdateReservationStatus:"
+
+	queryUpdate := `
+		UPDATE stock_reservations
+		SET status = $2, last_error = NULLIF($3, ''), updated_at = NOW()
+		WHERE id = $1 AND status = $4
+	`
+	return s.update(logMsgStr, true, queryUpdate, reservationID, to, lastError, from)
+}
+
+// RequestRelease is a method that queues the release of the stock held for an order, whether or not it was confirmed.
+// Orders without a held reservation are left alone.
+func (s *store) RequestRelease(orderID uuid.UUID) error {
+	logMsgStr := "Repository:Reservation - RequestRelease:"
+
+	queryUpdate := `
+		UPDATE stock_reservations
+		SET status = $2, attempts = 0, last_error = NULL, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE order_id = $1 AND status IN ($3, $4, $5)
+	`
+	return s.update(logMsgStr, false, queryUpdate, orderID, model.ReservationStatusReleasing,
+		model.ReservationStatusReserved, model.ReservationStatusConfirming, model.ReservationStatusConfirmed)
+}
+
+// RequestConfirm is a method that queues the confirmation of the stock held for a paid order.
+// Orders without a held reservation are left alone.
+func (s *store) RequestConfirm(orderID uuid.UUID) error {
+	logMsgStr := "Repository:Reservation - RequestConfirm:"
+
+	queryUpdate := `
+		UPDATE stock_reservations
+		SET status = $2, attempts = 0, last_error = NULL, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE order_id = $1 AND status = $3
+	`
+	return s.update(logMsgStr, false, queryUpdate, orderID, model.ReservationStatusConfirming, model.ReservationStatusReserved)
+}
+
+// RetryReservation is a method that records a failed call to the inventory service and holds the reservation back
+// for retryIn.
+func (s *store) RetryReservation(reservationID uuid.UUID, lastError string, retryIn time.Duration) error {
+	logMsgStr := "Repository:Reservation - RetryReservation:"
+
+	queryUpdate := `
+		UPDATE stock_reservations
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3), updated_at = NOW()
+		WHERE id = $1
+	`
+	return s.update(logMsgStr, true, queryUpdate, reservationID, lastError, retryIn.Seconds())
+}
+
+// GetDueReservationsForUpdate is a method that retrieves up to limit reservations the reconciler has to act on,
+// oldest first, and locks them until the transaction ends: pending reservations past their timeout, and
+// reservations waiting to be released or confirmed. Reservations locked by another transaction are skipped.
+func (s *store) GetDueReservationsForUpdate(limit int) (*[]model.StockReservation, error) {
+	logMsgStr := "Repository:Reservation - GetDueReservationsForUpdate:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT
+			id,
+			order_id,
+			lines,
+			status,
+			attempts,
+			last_error,
+			next_attempt_at,
+			created_at,
+			updated_at
+		FROM stock_reservations
+		WHERE status IN ($1, $2, $3) AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at ASC
+		LIMIT $4
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.Query(
+		querySelect,
+		model.ReservationStatusPending,
+		model.ReservationStatusReleasing,
+		model.ReservationStatusConfirming,
+		limit,
+	)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []model.StockReservation{}
+	for rows.Next() {
+		var reservation model.StockReservation
+		var lines []byte
+		if err := rows.Scan(
+			&reservation.ID,
+			&reservation.OrderID,
+			&lines,
+			&reservation.Status,
+			&reservation.Attempts,
+			&reservation.LastError,
+			&reservation.NextAttemptAt,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+		); err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+
+		if err := json.Unmarshal(lines, &reservation.Lines); err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to decode lines", logMsgStr))
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &reservations, nil
+}
+
+// update runs an update of reservations. If mustMatch is set, it returns model.ErrReservationNotFound when no
+// reservation was updated.
+func (s *store) update(logMsgStr string, mustMatch bool, query string, args ...any) error {
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if mustMatch && rowsAffected == 0 {
+		tx.Rollback()
+		s.logger.Warn().Msg(fmt.Sprintf("%v No rows affected", logMsgStr))
+		return model.ErrReservationNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
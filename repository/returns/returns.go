@@ -0,0 +1,710 @@
+package returns
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// returnColumns is the column list scanned by scanReturn.
+const returnColumns = `
+	id,
+	order_id,
+	status,
+	reason,
+	COALESCE(resolution_notes, ''),
+	refund_amount,
+	created_at,
+	updated_at
+`
+
+// refundColumns is the column list scanned by scanRefund.
+const refundColumns = `
+	id,
+	return_id,
+	payment_id,
+	provider,
+	COALESCE(provider_refund_id, ''),
+	amount,
+	currency,
+	status,
+	created_at,
+	updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanReturn scans a row selected with returnColumns into a return request.
+func scanReturn(row rowScanner) (*model.ReturnRequest, error) {
+	var ret model.ReturnRequest
+	if err := row.Scan(
+		&ret.ID,
+		&ret.OrderID,
+		&ret.Status,
+		&ret.Reason,
+		&ret.ResolutionNotes,
+		&ret.RefundAmount,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// scanRefund scans a row selected with refundColumns into a refund.
+func scanRefund(row rowScanner) (*model.Refund, error) {
+	var refund model.Refund
+	if err := row.Scan(
+		&refund.ID,
+		&refund.ReturnID,
+		&refund.PaymentID,
+		&refund.Provider,
+		&refund.ProviderRefundID,
+		&refund.Amount,
+		&refund.Currency,
+		&refund.Status,
+		&refund.CreatedAt,
+		&refund.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &refund, nil
+}
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (s *store) querier() transaction.Querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
+// CreateReturn is a method that stores a return request without its items and returns its ID.
+func (s *store) CreateReturn(bReq model.ReturnRequest) (*uuid.UUID, error) {
+	logMsgStr := "Repository:Returns - CreateReturn:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	queryCreate := `
+		INSERT INTO return_requests (
+			order_id,
+			status,
+			reason,
+			refund_amount,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, NOW()
+		) RETURNING id
+	`
+
+	var id uuid.UUID
+	if err := tx.QueryRow(
+		queryCreate,
+		bReq.OrderID,
+		bReq.Status,
+		bReq.Reason,
+		bReq.RefundAmount,
+	).Scan(&id); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan id", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// CreateReturnItems is a method that stores the items of a return request.
+// It fills in the ID, ReturnID and CreatedAt of each item.
+func (s *store) CreateReturnItems(returnID uuid.UUID, items []model.ReturnItem) error {
+	logMsgStr := "Repository:Returns - CreateReturnItems:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryCreate := `
+		INSERT INTO return_items (
+			return_id,
+			order_item_id,
+			product_id,
+			qty,
+			unit_price,
+			line_total,
+			reason,
+			photos,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, NOW()
+		) RETURNING id, created_at
+	`
+
+	for i, item := range items {
+		photos := item.Photos
+		if photos == nil {
+			photos = []model.ReturnPhoto{}
+		}
+
+		photosJSON, err := json.Marshal(photos)
+		if err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Marshal photos", logMsgStr))
+			return err
+		}
+
+		if err := tx.QueryRow(
+			queryCreate,
+			returnID,
+			item.OrderItemID,
+			item.ProductID,
+			item.Qty,
+			item.UnitPrice,
+			item.LineTotal,
+			item.Reason,
+			photosJSON,
+		).Scan(&items[i].ID, &items[i].CreatedAt); err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan return item id", logMsgStr))
+			return err
+		}
+		items[i].ReturnID = returnID
+		items[i].Photos = photos
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// GetReturnByID is a method that retrieves a return request of an order without its items.
+// It returns model.ErrReturnNotFound if the order has no such return.
+func (s *store) GetReturnByID(orderID, returnID uuid.UUID) (*model.ReturnRequest, error) {
+	logMsgStr := "Repository:Returns - GetReturnByID:"
+
+	querySelect := `
+		SELECT ` + returnColumns + `
+		FROM return_requests
+		WHERE order_id = $1 AND id = $2
+	`
+
+	ret, err := scanReturn(s.querier().QueryRow(querySelect, orderID, returnID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrReturnNotFound
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan return", logMsgStr))
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetReturnForUpdate is a method that retrieves a return request of an order and locks it until the transaction ends.
+// It returns model.ErrReturnNotFound if the order has no such return.
+func (s *store) GetReturnForUpdate(orderID, returnID uuid.UUID) (*model.ReturnRequest, error) {
+	logMsgStr := "Repository:Returns - GetReturnForUpdate:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT ` + returnColumns + `
+		FROM return_requests
+		WHERE order_id = $1 AND id = $2
+		FOR UPDATE
+	`
+
+	ret, err := scanReturn(tx.QueryRow(querySelect, orderID, returnID))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrReturnNotFound
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan return", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetReturnsByOrderID is a method that retrieves the return requests of an order without their items, oldest first.
+func (s *store) GetReturnsByOrderID(orderID uuid.UUID) (*[]model.ReturnRequest, error) {
+	logMsgStr := "Repository:Returns - GetReturnsByOrderID:"
+
+	querySelect := `
+		SELECT ` + returnColumns + `
+		FROM return_requests
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := s.querier().Query(querySelect, orderID)
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	returns := []model.ReturnRequest{}
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		returns = append(returns, *ret)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &returns, nil
+}
+
+// GetReturnItems is a method that retrieves the items of the given return requests.
+func (s *store) GetReturnItems(returnIDs []uuid.UUID) (*[]model.ReturnItem, error) {
+	logMsgStr := "Repository:Returns - GetReturnItems:"
+
+	querySelect := `
+		SELECT
+			id,
+			return_id,
+			order_item_id,
+			product_id,
+			qty,
+			unit_price,
+			line_total,
+			COALESCE(reason, ''),
+			photos,
+			created_at
+		FROM return_items
+		WHERE return_id = ANY($1::uuid[])
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := s.querier().Query(querySelect, pq.Array(returnIDs))
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.ReturnItem{}
+	for rows.Next() {
+		var (
+			item   model.ReturnItem
+			photos []byte
+		)
+		if err := rows.Scan(
+			&item.ID,
+			&item.ReturnID,
+			&item.OrderItemID,
+			&item.ProductID,
+			&item.Qty,
+			&item.UnitPrice,
+			&item.LineTotal,
+			&item.Reason,
+			&photos,
+			&item.CreatedAt,
+		); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+
+		if err := json.Unmarshal(photos, &item.Photos); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Unmarshal photos", logMsgStr))
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &items, nil
+}
+
+// GetReturnedQty is a method that sums, per order item, the qty in return requests of an order that were not rejected.
+func (s *store) GetReturnedQty(orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	logMsgStr := "Repository:Returns - GetReturnedQty:"
+
+	querySelect := `
+		SELECT ri.order_item_id, SUM(ri.qty)
+		FROM return_items ri
+		JOIN return_requests rr ON rr.id = ri.return_id
+		WHERE rr.order_id = $1 AND rr.status <> $2
+		GROUP BY ri.order_item_id
+	`
+
+	rows, err := s.querier().Query(querySelect, orderID, model.ReturnStatusRejected)
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	returned := map[uuid.UUID]int{}
+	for rows.Next() {
+		var (
+			orderItemID uuid.UUID
+			qty         int
+		)
+		if err := rows.Scan(&orderItemID, &qty); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		returned[orderItemID] = qty
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return returned, nil
+}
+
+// UpdateReturnStatus is a method that sets the status and resolution notes of a return request.
+// It returns model.ErrReturnNotFound if there is no such return.
+func (s *store) UpdateReturnStatus(returnID uuid.UUID, status model.ReturnStatus, notes string) error {
+	logMsgStr := "Repository:Returns - UpdateReturnStatus:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE return_requests
+		SET status = $1, resolution_notes = COALESCE(NULLIF($2, ''), resolution_notes), updated_at = NOW()
+		WHERE id = $3
+	`
+	result, err := tx.Exec(queryUpdate, status, notes, returnID)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		return model.ErrReturnNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// CreateRefund is a method that records a refund and returns its ID.
+func (s *store) CreateRefund(bReq model.Refund) (*uuid.UUID, error) {
+	logMsgStr := "Repository:Returns - CreateRefund:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	queryCreate := `
+		INSERT INTO refunds (
+			return_id,
+			payment_id,
+			provider,
+			amount,
+			currency,
+			status,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, NOW()
+		) RETURNING id
+	`
+
+	var id uuid.UUID
+	if err := tx.QueryRow(
+		queryCreate,
+		bReq.ReturnID,
+		bReq.PaymentID,
+		bReq.Provider,
+		bReq.Amount,
+		bReq.Currency,
+		bReq.Status,
+	).Scan(&id); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan id", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// UpdateRefund is a method that records the outcome of a pending refund at the gateway.
+// It returns model.ErrRefundNotPending if the refund has been settled already, e.g. by the refund reconciler.
+func (s *store) UpdateRefund(refundID uuid.UUID, status model.RefundStatus, providerRefundID string) error {
+	logMsgStr := "Repository:Returns - UpdateRefund:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE refunds
+		SET status = $1, provider_refund_id = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $3 AND status = $4
+	`
+	result, err := tx.Exec(queryUpdate, status, providerRefundID, refundID, model.RefundStatusPending)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return model.ErrRefundNotPending
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// GetStalePendingRefunds is a method that retrieves up to limit refunds that have been pending for at least
+// olderThan, oldest first, with the order and payment intent they belong to.
+func (s *store) GetStalePendingRefunds(olderThan time.Duration, limit int) (*[]model.PendingRefund, error) {
+	logMsgStr := "Repository:Returns - GetStalePendingRefunds:"
+
+	querySelect := `
+		SELECT
+			rf.id,
+			rf.return_id,
+			rf.payment_id,
+			rf.provider,
+			COALESCE(rf.provider_refund_id, ''),
+			rf.amount,
+			rf.currency,
+			rf.status,
+			rf.created_at,
+			rf.updated_at,
+			rr.order_id,
+			p.intent_id
+		FROM refunds rf
+		JOIN return_requests rr ON rr.id = rf.return_id
+		JOIN payments p ON p.id = rf.payment_id
+		WHERE rf.status = $1 AND rf.created_at <= NOW() - make_interval(secs => $2)
+		ORDER BY rf.created_at ASC
+		LIMIT $3
+	`
+
+	rows, err := s.querier().Query(querySelect, model.RefundStatusPending, olderThan.Seconds(), limit)
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []model.PendingRefund{}
+	for rows.Next() {
+		var refund model.PendingRefund
+		if err := rows.Scan(
+			&refund.ID,
+			&refund.ReturnID,
+			&refund.PaymentID,
+			&refund.Provider,
+			&refund.ProviderRefundID,
+			&refund.Amount,
+			&refund.Currency,
+			&refund.Status,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+			&refund.OrderID,
+			&refund.IntentID,
+		); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &refunds, nil
+}
+
+// GetRefunds is a method that retrieves the refunds of the given return requests, oldest first.
+func (s *store) GetRefunds(returnIDs []uuid.UUID) (*[]model.Refund, error) {
+	logMsgStr := "Repository:Returns - GetRefunds:"
+
+	querySelect := `
+		SELECT ` + refundColumns + `
+		FROM refunds
+		WHERE return_id = ANY($1::uuid[])
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := s.querier().Query(querySelect, pq.Array(returnIDs))
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []model.Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		refunds = append(refunds, *refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &refunds, nil
+}
+
+// GetPaymentRefundedAmount is a method that sums the pending and succeeded refunds made against a payment.
+func (s *store) GetPaymentRefundedAmount(paymentID uuid.UUID) (float64, error) {
+	logMsgStr := "Repository:Returns - GetPaymentRefundedAmount:"
+
+	querySelect := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM refunds
+		WHERE payment_id = $1 AND status IN ($2, $3)
+	`
+
+	var amount float64
+	if err := s.querier().QueryRow(querySelect, paymentID, model.RefundStatusPending, model.RefundStatusSucceeded).Scan(&amount); err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan amount", logMsgStr))
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+// GetOrderRefundedAmount is a method that sums the succeeded refunds of all returns of an order.
+func (s *store) GetOrderRefundedAmount(orderID uuid.UUID) (float64, error) {
+	logMsgStr := "Repository:Returns - GetOrderRefundedAmount:"
+
+	querySelect := `
+		SELECT COALESCE(SUM(rf.amount), 0)
+		FROM refunds rf
+		JOIN return_requests rr ON rr.id = rf.return_id
+		WHERE rr.order_id = $1 AND rf.status = $2
+	`
+
+	var amount float64
+	if err := s.querier().QueryRow(querySelect, orderID, model.RefundStatusSucceeded).Scan(&amount); err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan amount", logMsgStr))
+		return 0, err
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,577 @@
+package shipment
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/repository/transaction"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// uniqueViolation is the Postgres error code raised when a unique constraint is violated.
+const uniqueViolation = "23505"
+
+// shipmentColumns is the column list scanned by scanShipment.
+const shipmentColumns = `
+	id,
+	order_id,
+	COALESCE(courier, ''),
+	COALESCE(tracking_number, ''),
+	status,
+	shipped_at,
+	delivered_at,
+	last_event_at,
+	created_at,
+	updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanShipment scans a row selected with shipmentColumns into a shipment.
+func scanShipment(row rowScanner) (*model.Shipment, error) {
+	var shipment model.Shipment
+	if err := row.Scan(
+		&shipment.ID,
+		&shipment.OrderID,
+		&shipment.Courier,
+		&shipment.TrackingNumber,
+		&shipment.Status,
+		&shipment.ShippedAt,
+		&shipment.DeliveredAt,
+		&shipment.LastEventAt,
+		&shipment.CreatedAt,
+		&shipment.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &shipment, nil
+}
+
+type store struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	logger zerolog.Logger
+}
+
+// NewStore is a constructor function that returns a new store instance.
+func NewStore(db *sql.DB, logger zerolog.Logger) *store {
+	return &store{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that returns a copy of the store whose queries run inside tx.
+func (s *store) WithTx(tx *sql.Tx) *store {
+	return &store{
+		db:     s.db,
+		tx:     tx,
+		logger: s.logger,
+	}
+}
+
+// querier returns the transaction the store is bound to, or the connection pool otherwise.
+func (s *store) querier() transaction.Querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// begin starts a transaction for a single store call, joining the bound transaction if there is one.
+func (s *store) begin() (transaction.Tx, error) {
+	return transaction.Begin(s.db, s.tx)
+}
+
+// isUniqueViolation reports whether err was raised by a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// CreateShipment is a method that stores a shipment without its items and returns its ID.
+// It returns model.ErrTrackingNumberTaken if the courier's tracking number is used by another shipment.
+func (s *store) CreateShipment(bReq model.Shipment) (*uuid.UUID, error) {
+	logMsgStr := "Repository:Shipment - CreateShipment:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	queryCreate := `
+		INSERT INTO shipments (
+			order_id,
+			courier,
+			tracking_number,
+			status,
+			created_at
+		) VALUES (
+			$1, NULLIF($2, ''), NULLIF($3, ''), $4, NOW()
+		) RETURNING id
+	`
+
+	var id uuid.UUID
+	if err := tx.QueryRow(
+		queryCreate,
+		bReq.OrderID,
+		bReq.Courier,
+		bReq.TrackingNumber,
+		bReq.Status,
+	).Scan(&id); err != nil {
+		tx.Rollback()
+		if isUniqueViolation(err) {
+			return nil, model.ErrTrackingNumberTaken
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan id", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// CreateShipmentItems is a method that stores the items of a shipment.
+// It fills in the ID, ShipmentID and CreatedAt of each item.
+func (s *store) CreateShipmentItems(shipmentID uuid.UUID, items []model.ShipmentItem) error {
+	logMsgStr := "Repository:Shipment - CreateShipmentItems:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryCreate := `
+		INSERT INTO shipment_items (
+			shipment_id,
+			order_item_id,
+			qty,
+			created_at
+		) VALUES (
+			$1, $2, $3, NOW()
+		) RETURNING id, created_at
+	`
+
+	for i, item := range items {
+		if err := tx.QueryRow(
+			queryCreate,
+			shipmentID,
+			item.OrderItemID,
+			item.Qty,
+		).Scan(&items[i].ID, &items[i].CreatedAt); err != nil {
+			tx.Rollback()
+			s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan shipment item id", logMsgStr))
+			return err
+		}
+		items[i].ShipmentID = shipmentID
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// GetShippedQty is a method that sums, per order item, the qty packed in the shipments of an order.
+func (s *store) GetShippedQty(orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	logMsgStr := "Repository:Shipment - GetShippedQty:"
+
+	querySelect := `
+		SELECT si.order_item_id, SUM(si.qty)
+		FROM shipment_items si
+		JOIN shipments sh ON sh.id = si.shipment_id
+		WHERE sh.order_id = $1
+		GROUP BY si.order_item_id
+	`
+
+	rows, err := s.querier().Query(querySelect, orderID)
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipped := map[uuid.UUID]int{}
+	for rows.Next() {
+		var (
+			orderItemID uuid.UUID
+			qty         int
+		)
+		if err := rows.Scan(&orderItemID, &qty); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		shipped[orderItemID] = qty
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return shipped, nil
+}
+
+// GetShipmentByID is a method that retrieves a shipment of an order without its items and events.
+// It returns model.ErrShipmentNotFound if the order has no such shipment.
+func (s *store) GetShipmentByID(orderID, shipmentID uuid.UUID) (*model.Shipment, error) {
+	logMsgStr := "Repository:Shipment - GetShipmentByID:"
+
+	querySelect := `
+		SELECT ` + shipmentColumns + `
+		FROM shipments
+		WHERE order_id = $1 AND id = $2
+	`
+
+	shipment, err := scanShipment(s.querier().QueryRow(querySelect, orderID, shipmentID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrShipmentNotFound
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan shipment", logMsgStr))
+		return nil, err
+	}
+
+	return shipment, nil
+}
+
+// GetShipmentForUpdate is a method that retrieves a shipment of an order and locks it until the transaction ends.
+// It returns model.ErrShipmentNotFound if the order has no such shipment.
+func (s *store) GetShipmentForUpdate(orderID, shipmentID uuid.UUID) (*model.Shipment, error) {
+	return s.getShipmentForUpdate("Repository:Shipment - GetShipmentForUpdate:", `order_id = $1 AND id = $2`, orderID, shipmentID)
+}
+
+// GetShipmentByTrackingForUpdate is a method that retrieves a shipment by courier and tracking number and locks it
+// until the transaction ends. It returns model.ErrShipmentNotFound if there is no such shipment.
+func (s *store) GetShipmentByTrackingForUpdate(courier, trackingNumber string) (*model.Shipment, error) {
+	return s.getShipmentForUpdate("Repository:Shipment - GetShipmentByTrackingForUpdate:", `courier = $1 AND tracking_number = $2`, courier, trackingNumber)
+}
+
+func (s *store) getShipmentForUpdate(logMsgStr, condition string, args ...any) (*model.Shipment, error) {
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return nil, err
+	}
+
+	querySelect := `
+		SELECT ` + shipmentColumns + `
+		FROM shipments
+		WHERE ` + condition + `
+		FOR UPDATE
+	`
+
+	shipment, err := scanShipment(tx.QueryRow(querySelect, args...))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrShipmentNotFound
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Scan shipment", logMsgStr))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return nil, err
+	}
+
+	return shipment, nil
+}
+
+// GetShipmentsByOrderID is a method that retrieves the shipments of an order without their items and events, oldest first.
+func (s *store) GetShipmentsByOrderID(orderID uuid.UUID) (*[]model.Shipment, error) {
+	logMsgStr := "Repository:Shipment - GetShipmentsByOrderID:"
+
+	querySelect := `
+		SELECT ` + shipmentColumns + `
+		FROM shipments
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := s.querier().Query(querySelect, orderID)
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := []model.Shipment{}
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		shipments = append(shipments, *shipment)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &shipments, nil
+}
+
+// GetShipmentItems is a method that retrieves the items of the given shipments.
+func (s *store) GetShipmentItems(shipmentIDs []uuid.UUID) (*[]model.ShipmentItem, error) {
+	logMsgStr := "Repository:Shipment - GetShipmentItems:"
+
+	querySelect := `
+		SELECT id, shipment_id, order_item_id, qty, created_at
+		FROM shipment_items
+		WHERE shipment_id = ANY($1::uuid[])
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := s.querier().Query(querySelect, pq.Array(shipmentIDs))
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.ShipmentItem{}
+	for rows.Next() {
+		var item model.ShipmentItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.ShipmentID,
+			&item.OrderItemID,
+			&item.Qty,
+			&item.CreatedAt,
+		); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &items, nil
+}
+
+// GetShipmentEvents is a method that retrieves the tracking events of the given shipments in the order they occurred.
+func (s *store) GetShipmentEvents(shipmentIDs []uuid.UUID) (*[]model.ShipmentEvent, error) {
+	logMsgStr := "Repository:Shipment - GetShipmentEvents:"
+
+	querySelect := `
+		SELECT
+			id,
+			shipment_id,
+			status,
+			COALESCE(description, ''),
+			COALESCE(location, ''),
+			occurred_at,
+			created_at
+		FROM shipment_events
+		WHERE shipment_id = ANY($1::uuid[])
+		ORDER BY occurred_at ASC, id ASC
+	`
+
+	rows, err := s.querier().Query(querySelect, pq.Array(shipmentIDs))
+	if err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to Query querySelect", logMsgStr))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.ShipmentEvent{}
+	for rows.Next() {
+		var event model.ShipmentEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.ShipmentID,
+			&event.Status,
+			&event.Description,
+			&event.Location,
+			&event.OccurredAt,
+			&event.CreatedAt,
+		); err != nil {
+			s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Scan", logMsgStr))
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error().Any("Err", err.Error()).Msg(fmt.Sprintf("%v Failed to rows.Err", logMsgStr))
+		return nil, err
+	}
+
+	return &events, nil
+}
+
+// UpdateTracking is a method that sets the courier and tracking number of a shipment.
+// It returns model.ErrTrackingNumberTaken if the courier's tracking number is used by another shipment.
+func (s *store) UpdateTracking(shipmentID uuid.UUID, courier, trackingNumber string) error {
+	logMsgStr := "Repository:Shipment - UpdateTracking:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE shipments
+		SET courier = $1, tracking_number = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	result, err := tx.Exec(queryUpdate, courier, trackingNumber, shipmentID)
+	if err != nil {
+		tx.Rollback()
+		if isUniqueViolation(err) {
+			return model.ErrTrackingNumberTaken
+		}
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		return model.ErrShipmentNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
+
+// CreateShipmentEvent is a method that appends a tracking event to a shipment.
+// It reports false, without error, if the same event has been stored before.
+func (s *store) CreateShipmentEvent(bReq model.ShipmentEvent) (bool, error) {
+	logMsgStr := "Repository:Shipment - CreateShipmentEvent:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return false, err
+	}
+
+	queryCreate := `
+		INSERT INTO shipment_events (
+			shipment_id,
+			status,
+			description,
+			location,
+			occurred_at,
+			created_at
+		) VALUES (
+			$1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NOW()
+		)
+		ON CONFLICT (shipment_id, status, occurred_at) DO NOTHING
+	`
+	result, err := tx.Exec(
+		queryCreate,
+		bReq.ShipmentID,
+		bReq.Status,
+		bReq.Description,
+		bReq.Location,
+		bReq.OccurredAt,
+	)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to insert data", logMsgStr))
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// UpdateShipmentStatus is a method that sets the tracking status of a shipment as of at.
+// The first dispatched status stamps shipped_at, and delivered stamps delivered_at.
+func (s *store) UpdateShipmentStatus(shipmentID uuid.UUID, status model.ShipmentStatus, at time.Time) error {
+	logMsgStr := "Repository:Shipment - UpdateShipmentStatus:"
+
+	tx, err := s.begin()
+	if err != nil {
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	queryUpdate := `
+		UPDATE shipments
+		SET status = $1::varchar,
+			last_event_at = $2::timestamptz,
+			shipped_at = CASE WHEN $3::boolean THEN COALESCE(shipped_at, $2::timestamptz) ELSE shipped_at END,
+			delivered_at = CASE WHEN $1::varchar = $4::varchar THEN $2::timestamptz ELSE delivered_at END,
+			updated_at = NOW()
+		WHERE id = $5
+	`
+	result, err := tx.Exec(queryUpdate, status, at, status.IsDispatched(), model.ShipmentStatusDelivered, shipmentID)
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to update data", logMsgStr))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to get rows affected", logMsgStr))
+		return err
+	}
+
+	if rowsAffected == 0 {
+		tx.Rollback()
+		return model.ErrShipmentNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		s.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
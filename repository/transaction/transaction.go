@@ -0,0 +1,89 @@
+// Package transaction lets usecases run several repository calls as one unit of work.
+//
+// Stores open a transaction per method through Begin. When a store is bound to a
+// transaction owned by a Manager, Begin joins that transaction instead, and the
+// store's own Commit and Rollback calls are left to the Manager.
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// Querier is the set of query methods shared by *sql.DB and *sql.Tx.
+type Querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Tx is the transaction handle a store method works with.
+type Tx interface {
+	Querier
+	Commit() error
+	Rollback() error
+}
+
+// joinedTx wraps a transaction owned by a Manager. Stores bound to it must not
+// end it themselves, so Commit and Rollback are left to the owner.
+type joinedTx struct {
+	*sql.Tx
+}
+
+func (joinedTx) Commit() error   { return nil }
+func (joinedTx) Rollback() error { return nil }
+
+// Begin starts a new transaction on db, or joins outer if the store is bound to one.
+func Begin(db *sql.DB, outer *sql.Tx) (Tx, error) {
+	if outer != nil {
+		return joinedTx{outer}, nil
+	}
+
+	return db.Begin()
+}
+
+// Manager runs a unit of work inside a single database transaction.
+// R is the set of repositories handed to the unit of work, bound to that transaction.
+type Manager[R any] struct {
+	db     *sql.DB
+	bind   func(tx *sql.Tx) R
+	logger zerolog.Logger
+}
+
+// NewManager is a constructor function that returns a new Manager instance.
+func NewManager[R any](db *sql.DB, bind func(tx *sql.Tx) R, logger zerolog.Logger) *Manager[R] {
+	return &Manager[R]{
+		db:     db,
+		bind:   bind,
+		logger: logger,
+	}
+}
+
+// WithTx is a method that calls fn with repositories bound to a new transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+func (m *Manager[R]) WithTx(ctx context.Context, fn func(repos R) error) error {
+	logMsgStr := "Repository:Transaction - WithTx:"
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		m.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Begin tx", logMsgStr))
+		return err
+	}
+
+	if err := fn(m.bind(tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			m.logger.Error().Any("Err", rbErr).Msg(fmt.Sprintf("%v Failed to Rollback tx", logMsgStr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		m.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to Commit tx", logMsgStr))
+		return err
+	}
+
+	return nil
+}
@@ -2,8 +2,14 @@ package routes
 
 import (
 	"cart-order-service/config"
+	"cart-order-service/handlers/analytics"
 	"cart-order-service/handlers/cart"
+	"cart-order-service/handlers/export"
+	"cart-order-service/handlers/invoice"
 	"cart-order-service/handlers/order"
+	"cart-order-service/handlers/payment"
+	"cart-order-service/handlers/returns"
+	"cart-order-service/handlers/shipment"
 	"cart-order-service/util/middleware"
 	"log"
 	"net/http"
@@ -14,9 +20,16 @@ import (
 )
 
 type Routes struct {
-	Router *http.ServeMux
-	Cart   *cart.Handler
-	Order  *order.Handler
+	Router      *http.ServeMux
+	Cart        *cart.Handler
+	Order       *order.Handler
+	Payment     *payment.Handler
+	Returns     *returns.Handler
+	Shipment    *shipment.Handler
+	Invoice     *invoice.Handler
+	Export      *export.Handler
+	Analytics   *analytics.Handler
+	Idempotency func(http.Handler) http.Handler
 }
 
 func URLRewriter(baseURLPath string, next http.Handler) http.HandlerFunc {
@@ -36,13 +49,72 @@ func (r *Routes) SetupBaseURL() {
 
 func (r *Routes) cartRoutes() {
 	r.Router.HandleFunc("GET /cart/{user_id}", middleware.ApplyMiddleware(r.Cart.GetCartByUserID, middleware.EnabledCors, middleware.LoggerMiddleware()))
-	r.Router.HandleFunc("PUT /cart/update/{user_id}", middleware.ApplyMiddleware(r.Cart.UpdateCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
-	r.Router.HandleFunc("POST /cart/add", middleware.ApplyMiddleware(r.Cart.AddCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
-	r.Router.HandleFunc("DELETE /cart/delete/{user_id}", middleware.ApplyMiddleware(r.Cart.DeleteCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /cart/{user_id}/priced", middleware.ApplyMiddleware(r.Cart.GetPricedCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("PUT /cart/update/{user_id}", middleware.ApplyMiddleware(r.Cart.UpdateCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /cart/add", middleware.ApplyMiddleware(r.Cart.AddCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("DELETE /cart/delete/{user_id}", middleware.ApplyMiddleware(r.Cart.DeleteCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+
+	// Guest carts are identified by the token in the X-Cart-Token header.
+	r.Router.HandleFunc("POST /cart/guest", middleware.ApplyMiddleware(r.Cart.CreateGuestCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /cart/guest", middleware.ApplyMiddleware(r.Cart.GetGuestCart, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /cart/guest/add", middleware.ApplyMiddleware(r.Cart.AddGuestCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("PUT /cart/guest/update", middleware.ApplyMiddleware(r.Cart.UpdateGuestCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("DELETE /cart/guest/delete", middleware.ApplyMiddleware(r.Cart.DeleteGuestCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /cart/guest/merge", middleware.ApplyMiddleware(r.Cart.MergeGuestCart, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
 }
 
 func (r *Routes) orderRoutes() {
-	r.Router.HandleFunc("POST /order/create", middleware.ApplyMiddleware(r.Order.CreateOrder, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /order/create", middleware.ApplyMiddleware(r.Order.CreateOrder, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /order/checkout", middleware.ApplyMiddleware(r.Order.Checkout, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /order/shipping/quotes", middleware.ApplyMiddleware(r.Order.QuoteShipping, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /order/{id}", middleware.ApplyMiddleware(r.Order.GetOrderByID, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /order/ref/{ref_code}", middleware.ApplyMiddleware(r.Order.GetOrderByRefCode, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /order/user/{user_id}", middleware.ApplyMiddleware(r.Order.ListOrdersByUserID, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /order/{id}/reorder", middleware.ApplyMiddleware(r.Order.Reorder, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("PATCH /order/{id}/status", middleware.ApplyMiddleware(r.Order.UpdateOrderStatus, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+}
+
+func (r *Routes) paymentRoutes() {
+	r.Router.HandleFunc("POST /order/{id}/payment", middleware.ApplyMiddleware(r.Payment.CreatePaymentIntent, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /payment/webhook", middleware.ApplyMiddleware(r.Payment.Webhook, middleware.LoggerMiddleware()))
+}
+
+func (r *Routes) returnRoutes() {
+	r.Router.HandleFunc("POST /order/{id}/returns", middleware.ApplyMiddleware(r.Returns.CreateReturn, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /order/{id}/{resource}", middleware.ApplyMiddleware(r.orderResource, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /order/{id}/returns/{return_id}", middleware.ApplyMiddleware(r.Returns.GetReturn, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /order/{id}/returns/{return_id}/approve", middleware.ApplyMiddleware(r.Returns.ApproveReturn, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /order/{id}/returns/{return_id}/reject", middleware.ApplyMiddleware(r.Returns.RejectReturn, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /order/{id}/returns/{return_id}/refund", middleware.ApplyMiddleware(r.Returns.RefundReturn, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+}
+
+func (r *Routes) shipmentRoutes() {
+	r.Router.HandleFunc("POST /order/{id}/shipments", middleware.ApplyMiddleware(r.Shipment.CreateShipment, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /order/{id}/shipments/{shipment_id}", middleware.ApplyMiddleware(r.Shipment.GetShipment, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("PUT /order/{id}/shipments/{shipment_id}/tracking", middleware.ApplyMiddleware(r.Shipment.AssignTracking, r.Idempotency, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("POST /shipment/webhook", middleware.ApplyMiddleware(r.Shipment.Webhook, middleware.LoggerMiddleware()))
+}
+
+// orderResource serves GET /order/{id}/{resource}. Literal patterns such as GET /order/{id}/returns would conflict with
+// GET /order/ref/{ref_code} and GET /order/user/{user_id}, which stay more specific than this one.
+func (r *Routes) orderResource(w http.ResponseWriter, req *http.Request) {
+	switch req.PathValue("resource") {
+	case "returns":
+		r.Returns.ListReturns(w, req)
+	case "shipments":
+		r.Shipment.ListShipments(w, req)
+	case "invoice.pdf":
+		r.Invoice.GetInvoicePDF(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *Routes) adminRoutes() {
+	r.Router.HandleFunc("GET /admin/orders", middleware.ApplyMiddleware(r.Order.ListOrders, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /admin/orders/export", middleware.ApplyMiddleware(r.Export.ExportOrders, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /admin/analytics/sales", middleware.ApplyMiddleware(r.Analytics.SalesByPeriod, middleware.EnabledCors, middleware.LoggerMiddleware()))
+	r.Router.HandleFunc("GET /admin/analytics/sales/payment-types", middleware.ApplyMiddleware(r.Analytics.SalesByPaymentType, middleware.EnabledCors, middleware.LoggerMiddleware()))
 }
 
 func (r *Routes) SetupRouter() {
@@ -50,6 +122,10 @@ func (r *Routes) SetupRouter() {
 	r.SetupBaseURL()
 	r.cartRoutes()
 	r.orderRoutes()
+	r.paymentRoutes()
+	r.returnRoutes()
+	r.shipmentRoutes()
+	r.adminRoutes()
 }
 
 func (r *Routes) Run(port string) {
@@ -0,0 +1,259 @@
+package analytics
+
+import (
+	model "cart-order-service/repository/models"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// defaultReportDays is the number of days a report covers when no range is requested, today included.
+const defaultReportDays = 30
+
+// analyticsStore is an interface that defines the methods required to report on sales.
+type analyticsStore interface {
+	GetSalesAggregates(bReq model.SalesReportRequest) (*[]model.SalesAggregate, error)
+}
+
+type analytics struct {
+	analyticsStore analyticsStore
+	location       *time.Location
+	logger         zerolog.Logger
+}
+
+// NewAnalytics is a constructor function that returns a new analytics instance.
+// Reports are bucketed in location unless a request asks for another timezone.
+func NewAnalytics(analyticsStore analyticsStore, location *time.Location, logger zerolog.Logger) *analytics {
+	return &analytics{
+		analyticsStore: analyticsStore,
+		location:       location,
+		logger:         logger,
+	}
+}
+
+// ResolveLocation is a method that returns the location of an IANA timezone name, or the configured location for
+// an empty name. It returns model.ErrInvalidTimezone for an unknown name.
+func (a *analytics) ResolveLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return a.location, nil
+	}
+
+	// "Local" would depend on the server, and Postgres does not know it.
+	if tz == "Local" {
+		return nil, fmt.Errorf("%w: %s", model.ErrInvalidTimezone, tz)
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", model.ErrInvalidTimezone, tz)
+	}
+
+	return loc, nil
+}
+
+// SalesByPeriod is a method that reports sales per day, week or month. Every period of the range has a bucket,
+// also when no orders were placed in it.
+func (a *analytics) SalesByPeriod(bReq model.SalesReportRequest) (*model.SalesReport, error) {
+	bReq, err := a.normalize(bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates, err := a.analyticsStore.GetSalesAggregates(bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keyed by Unix time, as equal times may differ in their time.Time representation.
+	periods := map[int64]*accumulator{}
+	var totals accumulator
+	for _, agg := range *aggregates {
+		acc, ok := periods[agg.PeriodStart.Unix()]
+		if !ok {
+			acc = &accumulator{}
+			periods[agg.PeriodStart.Unix()] = acc
+		}
+		acc.add(agg)
+		totals.add(agg)
+	}
+
+	report := a.newReport(bReq, totals)
+	for start := periodStart(bReq.From, bReq.Interval); start.Before(bReq.To); start = nextPeriod(start, bReq.Interval) {
+		var metrics model.SalesMetrics
+		if acc, ok := periods[start.Unix()]; ok {
+			metrics = acc.metrics()
+		}
+
+		report.Buckets = append(report.Buckets, model.SalesBucket{
+			PeriodStart:  &start,
+			SalesMetrics: metrics,
+		})
+	}
+	report.Interval = bReq.Interval
+
+	return report, nil
+}
+
+// SalesByPaymentType is a method that reports sales per payment type over the whole range,
+// in order of decreasing GMV.
+func (a *analytics) SalesByPaymentType(bReq model.SalesReportRequest) (*model.SalesReport, error) {
+	// The interval only shapes the database grouping here; months keep it small.
+	bReq.Interval = model.AnalyticsIntervalMonth
+	bReq, err := a.normalize(bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates, err := a.analyticsStore.GetSalesAggregates(bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		order        []uuid.UUID
+		paymentTypes = map[uuid.UUID]*accumulator{}
+		totals       accumulator
+	)
+	for _, agg := range *aggregates {
+		acc, ok := paymentTypes[agg.PaymentTypeID]
+		if !ok {
+			acc = &accumulator{}
+			paymentTypes[agg.PaymentTypeID] = acc
+			order = append(order, agg.PaymentTypeID)
+		}
+		acc.add(agg)
+		totals.add(agg)
+	}
+
+	report := a.newReport(bReq, totals)
+	for _, id := range order {
+		report.Buckets = append(report.Buckets, model.SalesBucket{
+			PaymentTypeID: &id,
+			SalesMetrics:  paymentTypes[id].metrics(),
+		})
+	}
+	sortByGMV(report.Buckets)
+
+	return report, nil
+}
+
+// normalize is a method that fills in the defaults of a report request and validates it.
+// Without a range, a report covers the last defaultReportDays days up to the end of today.
+func (a *analytics) normalize(bReq model.SalesReportRequest) (model.SalesReportRequest, error) {
+	if bReq.Location == nil {
+		bReq.Location = a.location
+	}
+
+	switch bReq.Interval {
+	case "":
+		bReq.Interval = model.AnalyticsIntervalDay
+	case model.AnalyticsIntervalDay, model.AnalyticsIntervalWeek, model.AnalyticsIntervalMonth:
+	default:
+		return bReq, fmt.Errorf("%w: %s", model.ErrInvalidInterval, bReq.Interval)
+	}
+
+	if bReq.To.IsZero() {
+		now := time.Now().In(bReq.Location)
+		bReq.To = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, bReq.Location)
+	}
+
+	if bReq.From.IsZero() {
+		bReq.From = bReq.To.AddDate(0, 0, -defaultReportDays)
+	}
+
+	bReq.From = bReq.From.In(bReq.Location)
+	bReq.To = bReq.To.In(bReq.Location)
+
+	if !bReq.From.Before(bReq.To) {
+		return bReq, model.ErrInvalidReportRange
+	}
+
+	return bReq, nil
+}
+
+func (a *analytics) newReport(bReq model.SalesReportRequest, totals accumulator) *model.SalesReport {
+	return &model.SalesReport{
+		Timezone: bReq.Location.String(),
+		From:     bReq.From,
+		To:       bReq.To,
+		Totals:   totals.metrics(),
+		Buckets:  []model.SalesBucket{},
+	}
+}
+
+// accumulator sums sales aggregates until their metrics are computed.
+type accumulator struct {
+	orderCount     int64
+	paidCount      int64
+	cancelledCount int64
+	gmvCount       int64
+	gmv            float64
+}
+
+func (acc *accumulator) add(agg model.SalesAggregate) {
+	acc.orderCount += agg.OrderCount
+	acc.paidCount += agg.PaidCount
+	acc.cancelledCount += agg.CancelledCount
+	acc.gmvCount += agg.GMVCount
+	acc.gmv += agg.GMV
+}
+
+func (acc *accumulator) metrics() model.SalesMetrics {
+	metrics := model.SalesMetrics{
+		OrderCount:     acc.orderCount,
+		PaidCount:      acc.paidCount,
+		UnpaidCount:    acc.orderCount - acc.paidCount,
+		CancelledCount: acc.cancelledCount,
+		GMV:            round(acc.gmv, 2),
+	}
+
+	if acc.gmvCount > 0 {
+		metrics.AOV = round(acc.gmv/float64(acc.gmvCount), 2)
+	}
+
+	if acc.orderCount > 0 {
+		metrics.PaidRatio = round(float64(acc.paidCount)/float64(acc.orderCount), 4)
+		metrics.CancellationRate = round(float64(acc.cancelledCount)/float64(acc.orderCount), 4)
+	}
+
+	return metrics
+}
+
+// periodStart returns the start of the period t falls in, matching Postgres date_trunc: weeks start on Monday.
+func periodStart(t time.Time, interval string) time.Time {
+	switch interval {
+	case model.AnalyticsIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case model.AnalyticsIntervalWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+func nextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case model.AnalyticsIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	case model.AnalyticsIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func sortByGMV(buckets []model.SalesBucket) {
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return buckets[i].GMV > buckets[j].GMV
+	})
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,215 @@
+package analytics
+
+import (
+	model "cart-order-service/repository/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// fakeStore returns fixed aggregates and remembers the request it was given.
+type fakeStore struct {
+	aggregates []model.SalesAggregate
+	bReq       model.SalesReportRequest
+}
+
+func (s *fakeStore) GetSalesAggregates(bReq model.SalesReportRequest) (*[]model.SalesAggregate, error) {
+	s.bReq = bReq
+	aggregates := append([]model.SalesAggregate(nil), s.aggregates...)
+	return &aggregates, nil
+}
+
+func jakarta(t *testing.T) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestSalesByPeriodFillsEveryPeriod(t *testing.T) {
+	loc := jakarta(t)
+	card, transfer := uuid.New(), uuid.New()
+	oct2 := time.Date(2026, 10, 2, 0, 0, 0, 0, loc)
+
+	store := &fakeStore{aggregates: []model.SalesAggregate{
+		{PeriodStart: oct2, PaymentTypeID: card, OrderCount: 3, PaidCount: 2, CancelledCount: 1, GMVCount: 2, GMV: 150},
+		// The same period in another representation, as the database driver may return it.
+		{PeriodStart: oct2.UTC(), PaymentTypeID: transfer, OrderCount: 1, PaidCount: 1, GMVCount: 1, GMV: 50.005},
+	}}
+	a := NewAnalytics(store, loc, zerolog.Nop())
+
+	report, err := a.SalesByPeriod(model.SalesReportRequest{
+		From: time.Date(2026, 10, 1, 0, 0, 0, 0, loc),
+		To:   time.Date(2026, 10, 4, 0, 0, 0, 0, loc),
+	})
+	if err != nil {
+		t.Fatalf("SalesByPeriod: %v", err)
+	}
+
+	if report.Interval != model.AnalyticsIntervalDay || report.Timezone != "Asia/Jakarta" {
+		t.Errorf("report is by %q in %q, want by day in Asia/Jakarta", report.Interval, report.Timezone)
+	}
+	if len(report.Buckets) != 3 {
+		t.Fatalf("report has %d buckets, want one per day", len(report.Buckets))
+	}
+
+	for i, bucket := range report.Buckets {
+		want := time.Date(2026, 10, 1+i, 0, 0, 0, 0, loc)
+		if !bucket.PeriodStart.Equal(want) {
+			t.Errorf("bucket %d starts at %v, want %v", i, bucket.PeriodStart, want)
+		}
+		if i != 1 && bucket.OrderCount != 0 {
+			t.Errorf("bucket %d has %d orders, want none", i, bucket.OrderCount)
+		}
+	}
+
+	want := model.SalesMetrics{
+		OrderCount:       4,
+		PaidCount:        3,
+		UnpaidCount:      1,
+		CancelledCount:   1,
+		GMV:              200.01,
+		AOV:              66.67,
+		PaidRatio:        0.75,
+		CancellationRate: 0.25,
+	}
+	if got := report.Buckets[1].SalesMetrics; got != want {
+		t.Errorf("metrics of Oct 2 = %+v, want %+v", got, want)
+	}
+	if report.Totals != want {
+		t.Errorf("totals = %+v, want %+v", report.Totals, want)
+	}
+}
+
+func TestSalesByPeriodIntervals(t *testing.T) {
+	loc := jakarta(t)
+	from := time.Date(2026, 10, 7, 15, 0, 0, 0, loc) // a Wednesday
+	to := time.Date(2026, 12, 2, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		interval  string
+		wantFirst time.Time
+		wantCount int
+	}{
+		{interval: model.AnalyticsIntervalWeek, wantFirst: time.Date(2026, 10, 5, 0, 0, 0, 0, loc), wantCount: 9},
+		{interval: model.AnalyticsIntervalMonth, wantFirst: time.Date(2026, 10, 1, 0, 0, 0, 0, loc), wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			a := NewAnalytics(&fakeStore{}, loc, zerolog.Nop())
+
+			report, err := a.SalesByPeriod(model.SalesReportRequest{From: from, To: to, Interval: tt.interval})
+			if err != nil {
+				t.Fatalf("SalesByPeriod: %v", err)
+			}
+
+			if len(report.Buckets) != tt.wantCount {
+				t.Fatalf("report has %d buckets, want %d", len(report.Buckets), tt.wantCount)
+			}
+			if first := report.Buckets[0].PeriodStart; !first.Equal(tt.wantFirst) {
+				t.Errorf("first bucket starts at %v, want %v", first, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestSalesByPaymentTypeSortsByGMV(t *testing.T) {
+	small, large := uuid.New(), uuid.New()
+	sep, oct := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	store := &fakeStore{aggregates: []model.SalesAggregate{
+		{PeriodStart: sep, PaymentTypeID: small, OrderCount: 1, PaidCount: 1, GMVCount: 1, GMV: 30},
+		{PeriodStart: sep, PaymentTypeID: large, OrderCount: 1, PaidCount: 1, GMVCount: 1, GMV: 40},
+		{PeriodStart: oct, PaymentTypeID: large, OrderCount: 2, PaidCount: 1, GMVCount: 1, GMV: 60},
+	}}
+	a := NewAnalytics(store, time.UTC, zerolog.Nop())
+
+	report, err := a.SalesByPaymentType(model.SalesReportRequest{From: sep, To: oct.AddDate(0, 1, 0), Interval: model.AnalyticsIntervalDay})
+	if err != nil {
+		t.Fatalf("SalesByPaymentType: %v", err)
+	}
+
+	if store.bReq.Interval != model.AnalyticsIntervalMonth {
+		t.Errorf("store grouped by %q, want month", store.bReq.Interval)
+	}
+	if len(report.Buckets) != 2 {
+		t.Fatalf("report has %d buckets, want one per payment type", len(report.Buckets))
+	}
+	if *report.Buckets[0].PaymentTypeID != large || report.Buckets[0].GMV != 100 || report.Buckets[0].OrderCount != 3 {
+		t.Errorf("first bucket = %+v, want the larger payment type with GMV 100 over 3 orders", report.Buckets[0])
+	}
+	if *report.Buckets[1].PaymentTypeID != small {
+		t.Errorf("second bucket = %+v, want the smaller payment type", report.Buckets[1])
+	}
+	if report.Totals.GMV != 130 || report.Totals.OrderCount != 4 {
+		t.Errorf("totals = %+v, want GMV 130 over 4 orders", report.Totals)
+	}
+}
+
+func TestNormalizeDefaultsToLastDays(t *testing.T) {
+	loc := jakarta(t)
+	store := &fakeStore{}
+	a := NewAnalytics(store, loc, zerolog.Nop())
+
+	if _, err := a.SalesByPeriod(model.SalesReportRequest{}); err != nil {
+		t.Fatalf("SalesByPeriod: %v", err)
+	}
+
+	now := time.Now().In(loc)
+	wantTo := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	if !store.bReq.To.Equal(wantTo) || store.bReq.Location != loc {
+		t.Errorf("report ends at %v in %v, want the end of today %v", store.bReq.To, store.bReq.Location, wantTo)
+	}
+	if !store.bReq.From.Equal(wantTo.AddDate(0, 0, -defaultReportDays)) {
+		t.Errorf("report starts at %v, want %d days before its end", store.bReq.From, defaultReportDays)
+	}
+}
+
+func TestSalesByPeriodRejects(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		bReq    model.SalesReportRequest
+		wantErr error
+	}{
+		{name: "unknown interval", bReq: model.SalesReportRequest{Interval: "hour"}, wantErr: model.ErrInvalidInterval},
+		{name: "empty range", bReq: model.SalesReportRequest{From: day, To: day}, wantErr: model.ErrInvalidReportRange},
+		{name: "reversed range", bReq: model.SalesReportRequest{From: day, To: day.AddDate(0, 0, -1)}, wantErr: model.ErrInvalidReportRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnalytics(&fakeStore{}, time.UTC, zerolog.Nop())
+
+			if _, err := a.SalesByPeriod(tt.bReq); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SalesByPeriod error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	loc := jakarta(t)
+	a := NewAnalytics(&fakeStore{}, loc, zerolog.Nop())
+
+	if got, err := a.ResolveLocation(""); err != nil || got != loc {
+		t.Errorf("ResolveLocation(\"\") = %v, %v, want the configured location", got, err)
+	}
+	if got, err := a.ResolveLocation("UTC"); err != nil || got.String() != "UTC" {
+		t.Errorf("ResolveLocation(UTC) = %v, %v, want UTC", got, err)
+	}
+
+	for _, tz := range []string{"Local", "Mars/Olympus_Mons"} {
+		if _, err := a.ResolveLocation(tz); !errors.Is(err, model.ErrInvalidTimezone) {
+			t.Errorf("ResolveLocation(%q) error = %v, want %v", tz, err, model.ErrInvalidTimezone)
+		}
+	}
+}
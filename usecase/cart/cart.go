@@ -2,6 +2,9 @@ package cart
 
 import (
 	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -10,22 +13,76 @@ import (
 // cartStore is an interface that defines the methods required for managing a shopping cart.
 type cartStore interface {
 	GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error)
-	AddCart(bReq model.Cart) (*uuid.UUID, error)
+	AddCart(bReq model.Cart) (*model.AddCartResponse, error)
 	UpdateQty(userID, productID uuid.UUID, qty int) error
 	DeleteProduct(bReq model.DeleteCartRequest) error
+	MergeCartItems(userID uuid.UUID, lines []model.CartLine, rule string) (*[]model.Cart, error)
+}
+
+// guestCartStore is an interface that defines the methods required for managing guest carts.
+type guestCartStore interface {
+	CreateGuestCart(id uuid.UUID, ttl time.Duration) (*model.GuestCart, error)
+	GetGuestCart(id uuid.UUID) (*model.GuestCart, error)
+	GetGuestCartForUpdate(id uuid.UUID) (*model.GuestCart, error)
+	AddItem(id uuid.UUID, line model.CartLine, ttl time.Duration) (*model.AddCartResponse, error)
+	UpdateQty(id, productID uuid.UUID, qty int, ttl time.Duration) error
+	DeleteProduct(id, productID uuid.UUID, ttl time.Duration) error
+	DeleteGuestCart(id uuid.UUID) error
+	DeleteExpiredGuestCarts(limit int) (int, error)
+}
+
+// outboxStore is an interface that defines the methods required to record cart events.
+type outboxStore interface {
+	CreateEvent(event model.OutboxEvent) error
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Cart      cartStore
+	GuestCart guestCartStore
+	Outbox    outboxStore
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// productCatalog is an interface that provides current product details and prices.
+type productCatalog interface {
+	GetProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.Product, error)
+}
+
+// GuestConfig is a struct that holds the settings of guest carts.
+type GuestConfig struct {
+	// TokenSecret signs the cart tokens of guest carts.
+	TokenSecret string
+	// TTL is how long a guest cart is kept after its last change.
+	TTL time.Duration
+	// MergeRule is the model.CartMergeSum or model.CartMergeMax rule used by merges that do not name one.
+	MergeRule string
 }
 
 // cart is a struct that holds the store for managing a shopping cart.
 type cart struct {
-	store  cartStore
-	logger zerolog.Logger
+	store      cartStore
+	guestStore guestCartStore
+	txManager  txManager
+	catalog    productCatalog
+	guest      GuestConfig
+	logger     zerolog.Logger
 }
 
 // NewCart is a constructor function that returns a new cart instance.
-func NewCart(store cartStore, logger zerolog.Logger) *cart {
+// Every change to a user's cart is committed together with its cart.item_* event in the outbox.
+func NewCart(store cartStore, guestStore guestCartStore, txManager txManager, catalog productCatalog, guest GuestConfig, logger zerolog.Logger) *cart {
 	return &cart{
-		store:  store,
-		logger: logger,
+		store:      store,
+		guestStore: guestStore,
+		txManager:  txManager,
+		catalog:    catalog,
+		guest:      guest,
+		logger:     logger,
 	}
 }
 
@@ -43,40 +100,139 @@ func (c *cart) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error)
 	return result, nil
 }
 
-func (c *cart) AddCart(bReq model.Cart) (*uuid.UUID, error) {
-	id, err := c.store.AddCart(bReq)
+// AddCart is a method that adds a product to a user's cart, merging the qty into the product's line if it is
+// in the cart already. The response tells which of the two happened.
+// The product's current catalog price is recorded on the line.
+func (c *cart) AddCart(ctx context.Context, bReq model.Cart) (*model.AddCartResponse, error) {
+	prices, err := c.currentPrices(ctx, []uuid.UUID{bReq.ProductID})
 	if err != nil {
 		return nil, err
 	}
+	bReq.AddedUnitPrice = prices[bReq.ProductID]
+
+	var bResp *model.AddCartResponse
+
+	err = c.txManager.WithTx(ctx, func(repos Repositories) error {
+		var err error
+		bResp, err = repos.Cart.AddCart(bReq)
+		if err != nil {
+			return err
+		}
 
-	return id, nil
+		return recordCartEvent(repos, model.OutboxEventCartItemAdded, bReq.UserID, bReq.ProductID, bResp.Qty)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bResp, nil
 }
 
 // UpdateQty is a method that updates the quantity of a product in a user's cart or deletes the product if the quantity is 0.
-func (c *cart) UpdateQty(bReq model.Cart) (string, error) {
+func (c *cart) UpdateQty(ctx context.Context, bReq model.Cart) (string, error) {
 	// if Qty is 0, delete the product from the cart
 	if bReq.Qty == 0 {
-		if err := c.store.DeleteProduct(model.DeleteCartRequest{
+		return c.DeleteCart(ctx, model.DeleteCartRequest{
 			UserID:    bReq.UserID,
 			ProductID: bReq.ProductID,
-		}); err != nil {
-			return "", err
-		}
-
-		return "Product deleted from cart", nil
+		})
 	}
 
-	if err := c.store.UpdateQty(bReq.UserID, bReq.ProductID, bReq.Qty); err != nil {
+	err := c.txManager.WithTx(ctx, func(repos Repositories) error {
+		if err := repos.Cart.UpdateQty(bReq.UserID, bReq.ProductID, bReq.Qty); err != nil {
+			return err
+		}
+
+		return recordCartEvent(repos, model.OutboxEventCartItemUpdated, bReq.UserID, bReq.ProductID, bReq.Qty)
+	})
+	if err != nil {
 		return "", err
 	}
 
 	return "Product updated in cart", nil
 }
 
-func (c *cart) DeleteCart(bReq model.DeleteCartRequest) (string, error) {
-	if err := c.store.DeleteProduct(bReq); err != nil {
+func (c *cart) DeleteCart(ctx context.Context, bReq model.DeleteCartRequest) (string, error) {
+	err := c.txManager.WithTx(ctx, func(repos Repositories) error {
+		if err := repos.Cart.DeleteProduct(bReq); err != nil {
+			return err
+		}
+
+		return recordCartEvent(repos, model.OutboxEventCartItemRemoved, bReq.UserID, bReq.ProductID, 0)
+	})
+	if err != nil {
 		return "", err
 	}
 
 	return "Product deleted from cart", nil
 }
+
+// AddItems is a method that adds several products to a user's cart at once, merging each qty into the
+// product's existing cart row. Lines for the same product are combined first.
+// The products' current catalog prices are recorded on the lines.
+func (c *cart) AddItems(ctx context.Context, userID uuid.UUID, lines []model.CartLine) (*[]model.Cart, error) {
+	if len(lines) == 0 {
+		return &[]model.Cart{}, nil
+	}
+
+	merged := make([]model.CartLine, 0, len(lines))
+	index := make(map[uuid.UUID]int, len(lines))
+	for _, line := range lines {
+		if line.Qty <= 0 {
+			return nil, fmt.Errorf("%w: product %s", model.ErrInvalidQty, line.ProductID)
+		}
+
+		if i, ok := index[line.ProductID]; ok {
+			merged[i].Qty += line.Qty
+			continue
+		}
+		index[line.ProductID] = len(merged)
+		merged = append(merged, line)
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(merged))
+	for _, line := range merged {
+		productIDs = append(productIDs, line.ProductID)
+	}
+
+	prices, err := c.currentPrices(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range merged {
+		merged[i].AddedUnitPrice = prices[merged[i].ProductID]
+	}
+
+	var carts *[]model.Cart
+
+	err = c.txManager.WithTx(ctx, func(repos Repositories) error {
+		var err error
+		carts, err = repos.Cart.MergeCartItems(userID, merged, model.CartMergeSum)
+		if err != nil {
+			return err
+		}
+
+		for _, cart := range *carts {
+			if err := recordCartEvent(repos, model.OutboxEventCartItemAdded, userID, cart.ProductID, cart.Qty); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return carts, nil
+}
+
+// recordCartEvent writes a cart.item_* event to the outbox. qty is the product's qty in the cart after the change.
+func recordCartEvent(repos Repositories, eventType model.OutboxEventType, userID, productID uuid.UUID, qty int) error {
+	event, err := model.NewCartItemEvent(eventType, userID, productID, qty)
+	if err != nil {
+		return err
+	}
+
+	return repos.Outbox.CreateEvent(event)
+}
@@ -0,0 +1,183 @@
+package cart
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/carttoken"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// CreateGuestCart is a method that starts an empty guest cart and returns the token that identifies it.
+func (c *cart) CreateGuestCart() (*model.GuestCartToken, error) {
+	guest, err := c.guestStore.CreateGuestCart(uuid.New(), c.guest.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.GuestCartToken{
+		CartToken: carttoken.Sign([]byte(c.guest.TokenSecret), guest.ID),
+		ExpiresAt: guest.ExpiresAt,
+	}, nil
+}
+
+// GetGuestCart is a method that retrieves the guest cart identified by token.
+func (c *cart) GetGuestCart(token string) (*model.GuestCart, error) {
+	id, err := carttoken.Verify([]byte(c.guest.TokenSecret), token)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.guestStore.GetGuestCart(id)
+}
+
+// AddGuestCart is a method that adds a product to the guest cart identified by token, merging the qty into
+// the product's line if it is in the cart already. The product's current catalog price is recorded on the line.
+func (c *cart) AddGuestCart(ctx context.Context, token string, line model.CartLine) (*model.AddCartResponse, error) {
+	id, err := carttoken.Verify([]byte(c.guest.TokenSecret), token)
+	if err != nil {
+		return nil, err
+	}
+
+	if line.Qty <= 0 {
+		return nil, fmt.Errorf("%w: product %s", model.ErrInvalidQty, line.ProductID)
+	}
+
+	prices, err := c.currentPrices(ctx, []uuid.UUID{line.ProductID})
+	if err != nil {
+		return nil, err
+	}
+	line.AddedUnitPrice = prices[line.ProductID]
+
+	return c.guestStore.AddItem(id, line, c.guest.TTL)
+}
+
+// UpdateGuestQty is a method that updates the quantity of a product in the guest cart identified by token,
+// or deletes the product if the quantity is 0.
+func (c *cart) UpdateGuestQty(token string, line model.CartLine) (string, error) {
+	if line.Qty == 0 {
+		return c.DeleteGuestProduct(token, line.ProductID)
+	}
+
+	id, err := carttoken.Verify([]byte(c.guest.TokenSecret), token)
+	if err != nil {
+		return "", err
+	}
+
+	if line.Qty < 0 {
+		return "", fmt.Errorf("%w: product %s", model.ErrInvalidQty, line.ProductID)
+	}
+
+	if err := c.guestStore.UpdateQty(id, line.ProductID, line.Qty, c.guest.TTL); err != nil {
+		return "", err
+	}
+
+	return "Product updated in cart", nil
+}
+
+// DeleteGuestProduct is a method that removes a product from the guest cart identified by token.
+func (c *cart) DeleteGuestProduct(token string, productID uuid.UUID) (string, error) {
+	id, err := carttoken.Verify([]byte(c.guest.TokenSecret), token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.guestStore.DeleteProduct(id, productID, c.guest.TTL); err != nil {
+		return "", err
+	}
+
+	return "Product deleted from cart", nil
+}
+
+// MergeGuestCart is a method that moves the guest cart identified by the request's token into the user's cart
+// after login and deletes the guest cart, in one transaction.
+// A product in both carts gets the sum of the two quantities under model.CartMergeSum, or the larger of them
+// under model.CartMergeMax. Every merged line is recorded in the outbox as a cart.item_added event.
+func (c *cart) MergeGuestCart(ctx context.Context, bReq model.MergeGuestCartRequest) (*model.MergeGuestCartResponse, error) {
+	rule := bReq.Rule
+	if rule == "" {
+		rule = c.guest.MergeRule
+	}
+
+	if rule != model.CartMergeSum && rule != model.CartMergeMax {
+		return nil, fmt.Errorf("%w: %q", model.ErrInvalidMergeRule, rule)
+	}
+
+	id, err := carttoken.Verify([]byte(c.guest.TokenSecret), bReq.CartToken)
+	if err != nil {
+		return nil, err
+	}
+
+	bResp := &model.MergeGuestCartResponse{
+		UserID: bReq.UserID,
+		Rule:   rule,
+		Items:  []model.Cart{},
+	}
+
+	err = c.txManager.WithTx(ctx, func(repos Repositories) error {
+		guest, err := repos.GuestCart.GetGuestCartForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if len(guest.Items) > 0 {
+			lines := make([]model.CartLine, 0, len(guest.Items))
+			for _, item := range guest.Items {
+				lines = append(lines, model.CartLine{
+					ProductID:      item.ProductID,
+					Qty:            item.Qty,
+					AddedUnitPrice: item.AddedUnitPrice,
+				})
+			}
+
+			carts, err := repos.Cart.MergeCartItems(bReq.UserID, lines, rule)
+			if err != nil {
+				return err
+			}
+			bResp.Items = *carts
+
+			for _, cart := range *carts {
+				if err := recordCartEvent(repos, model.OutboxEventCartItemAdded, bReq.UserID, cart.ProductID, cart.Qty); err != nil {
+					return err
+				}
+			}
+		}
+
+		return repos.GuestCart.DeleteGuestCart(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bResp, nil
+}
+
+// CleanupGuestCarts is a method that deletes expired guest carts in batches of batchSize and returns how many it deleted.
+// Several replicas may run it at the same time.
+func (c *cart) CleanupGuestCarts(ctx context.Context, batchSize int) (int, error) {
+	logMsgStr := "Usecase:Cart - CleanupGuestCarts:"
+
+	var deleted int
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		batch, err := c.guestStore.DeleteExpiredGuestCarts(batchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted += batch
+		if batch < batchSize {
+			break
+		}
+	}
+
+	if deleted > 0 {
+		c.logger.Info().Msg(fmt.Sprintf("%v Deleted %d expired guest carts", logMsgStr, deleted))
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,510 @@
+package cart
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/carttoken"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const testSecret = "test-secret"
+
+// errNoRows is what the stores return when a change matches no cart line.
+var errNoRows = errors.New("no rows affected")
+
+type cartKey struct {
+	userID    uuid.UUID
+	productID uuid.UUID
+}
+
+// memCartStore is an in-memory cartStore.
+type memCartStore struct {
+	lines map[cartKey]model.Cart
+	// rules are the merge rules MergeCartItems was called with.
+	rules []string
+}
+
+func (s *memCartStore) GetCartByUserID(bReq model.GetCartRequest) (*[]model.Cart, error) {
+	carts := []model.Cart{}
+	for key, line := range s.lines {
+		if key.userID == bReq.UserID {
+			carts = append(carts, line)
+		}
+	}
+	return &carts, nil
+}
+
+func (s *memCartStore) AddCart(bReq model.Cart) (*model.AddCartResponse, error) {
+	carts, err := s.MergeCartItems(bReq.UserID, []model.CartLine{{ProductID: bReq.ProductID, Qty: bReq.Qty, AddedUnitPrice: bReq.AddedUnitPrice}}, model.CartMergeSum)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AddCartResponse{Qty: (*carts)[0].Qty}, nil
+}
+
+func (s *memCartStore) UpdateQty(userID, productID uuid.UUID, qty int) error {
+	line, ok := s.lines[cartKey{userID, productID}]
+	if !ok {
+		return errNoRows
+	}
+	line.Qty = qty
+	s.lines[cartKey{userID, productID}] = line
+	return nil
+}
+
+func (s *memCartStore) DeleteProduct(bReq model.DeleteCartRequest) error {
+	if _, ok := s.lines[cartKey{bReq.UserID, bReq.ProductID}]; !ok {
+		return errNoRows
+	}
+	delete(s.lines, cartKey{bReq.UserID, bReq.ProductID})
+	return nil
+}
+
+func (s *memCartStore) MergeCartItems(userID uuid.UUID, lines []model.CartLine, rule string) (*[]model.Cart, error) {
+	s.rules = append(s.rules, rule)
+
+	carts := make([]model.Cart, 0, len(lines))
+	for _, l := range lines {
+		key := cartKey{userID, l.ProductID}
+		line, ok := s.lines[key]
+		switch {
+		case !ok:
+			line = model.Cart{ID: uuid.New(), UserID: userID, ProductID: l.ProductID, Qty: l.Qty}
+		case rule == model.CartMergeMax:
+			line.Qty = max(line.Qty, l.Qty)
+		default:
+			line.Qty += l.Qty
+		}
+		line.AddedUnitPrice = l.AddedUnitPrice
+		s.lines[key] = line
+		carts = append(carts, line)
+	}
+	return &carts, nil
+}
+
+// memGuestStore is an in-memory guestCartStore.
+type memGuestStore struct {
+	carts map[uuid.UUID]*model.GuestCart
+	// deletedExpired is how many expired carts each DeleteExpiredGuestCarts call reports, in order.
+	deletedExpired []int
+}
+
+func (s *memGuestStore) CreateGuestCart(id uuid.UUID, ttl time.Duration) (*model.GuestCart, error) {
+	guest := &model.GuestCart{ID: id, Items: []model.GuestCartItem{}, ExpiresAt: time.Now().Add(ttl)}
+	s.carts[id] = guest
+	return guest, nil
+}
+
+func (s *memGuestStore) GetGuestCart(id uuid.UUID) (*model.GuestCart, error) {
+	guest, ok := s.carts[id]
+	if !ok {
+		return nil, model.ErrGuestCartNotFound
+	}
+	return guest, nil
+}
+
+func (s *memGuestStore) GetGuestCartForUpdate(id uuid.UUID) (*model.GuestCart, error) {
+	return s.GetGuestCart(id)
+}
+
+func (s *memGuestStore) AddItem(id uuid.UUID, line model.CartLine, ttl time.Duration) (*model.AddCartResponse, error) {
+	guest, err := s.GetGuestCart(id)
+	if err != nil {
+		return nil, err
+	}
+	guest.ExpiresAt = time.Now().Add(ttl)
+
+	for i := range guest.Items {
+		if guest.Items[i].ProductID == line.ProductID {
+			guest.Items[i].Qty += line.Qty
+			guest.Items[i].AddedUnitPrice = line.AddedUnitPrice
+			return &model.AddCartResponse{Qty: guest.Items[i].Qty}, nil
+		}
+	}
+
+	guest.Items = append(guest.Items, model.GuestCartItem{ID: uuid.New(), ProductID: line.ProductID, Qty: line.Qty, AddedUnitPrice: line.AddedUnitPrice})
+	return &model.AddCartResponse{Qty: line.Qty}, nil
+}
+
+func (s *memGuestStore) UpdateQty(id, productID uuid.UUID, qty int, ttl time.Duration) error {
+	guest, err := s.GetGuestCart(id)
+	if err != nil {
+		return err
+	}
+
+	for i := range guest.Items {
+		if guest.Items[i].ProductID == productID {
+			guest.Items[i].Qty = qty
+			guest.ExpiresAt = time.Now().Add(ttl)
+			return nil
+		}
+	}
+	return errNoRows
+}
+
+func (s *memGuestStore) DeleteProduct(id, productID uuid.UUID, ttl time.Duration) error {
+	guest, err := s.GetGuestCart(id)
+	if err != nil {
+		return err
+	}
+
+	for i := range guest.Items {
+		if guest.Items[i].ProductID == productID {
+			guest.Items = append(guest.Items[:i], guest.Items[i+1:]...)
+			guest.ExpiresAt = time.Now().Add(ttl)
+			return nil
+		}
+	}
+	return errNoRows
+}
+
+func (s *memGuestStore) DeleteGuestCart(id uuid.UUID) error {
+	delete(s.carts, id)
+	return nil
+}
+
+func (s *memGuestStore) DeleteExpiredGuestCarts(limit int) (int, error) {
+	if len(s.deletedExpired) == 0 {
+		return 0, nil
+	}
+	n := s.deletedExpired[0]
+	s.deletedExpired = s.deletedExpired[1:]
+	return n, nil
+}
+
+// memOutbox is an in-memory outboxStore.
+type memOutbox struct {
+	events []model.OutboxEvent
+}
+
+func (s *memOutbox) CreateEvent(event model.OutboxEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// memTxManager runs a unit of work against the in-memory stores.
+type memTxManager struct {
+	cart   *memCartStore
+	guest  *memGuestStore
+	outbox *memOutbox
+}
+
+func (m memTxManager) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	return fn(Repositories{Cart: m.cart, GuestCart: m.guest, Outbox: m.outbox})
+}
+
+// fakeCatalog is a productCatalog holding a fixed set of products.
+type fakeCatalog map[uuid.UUID]model.Product
+
+func (f fakeCatalog) GetProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.Product, error) {
+	products := make(map[uuid.UUID]model.Product, len(productIDs))
+	for _, id := range productIDs {
+		if product, ok := f[id]; ok {
+			products[id] = product
+		}
+	}
+	return products, nil
+}
+
+// fixture is a cart usecase over in-memory stores.
+type fixture struct {
+	cartStore  *memCartStore
+	guestStore *memGuestStore
+	outbox     *memOutbox
+	catalog    fakeCatalog
+	c          *cart
+}
+
+func newFixture(mergeRule string) *fixture {
+	f := &fixture{
+		cartStore:  &memCartStore{lines: map[cartKey]model.Cart{}},
+		guestStore: &memGuestStore{carts: map[uuid.UUID]*model.GuestCart{}},
+		outbox:     &memOutbox{},
+		catalog:    fakeCatalog{},
+	}
+	tx := memTxManager{cart: f.cartStore, guest: f.guestStore, outbox: f.outbox}
+	guest := GuestConfig{TokenSecret: testSecret, TTL: time.Hour, MergeRule: mergeRule}
+	f.c = NewCart(f.cartStore, f.guestStore, tx, f.catalog, guest, zerolog.Nop())
+	return f
+}
+
+// seedGuestCart adds a guest cart holding items and returns its token.
+func (f *fixture) seedGuestCart(items ...model.GuestCartItem) string {
+	id := uuid.New()
+	f.guestStore.carts[id] = &model.GuestCart{ID: id, Items: items, ExpiresAt: time.Now().Add(time.Hour)}
+	return carttoken.Sign([]byte(testSecret), id)
+}
+
+func TestMergeGuestCartRules(t *testing.T) {
+	shared, guestOnly := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		defaultRule string
+		rule        string
+		wantRule    string
+		wantShared  int
+	}{
+		{name: "default sum", defaultRule: model.CartMergeSum, wantRule: model.CartMergeSum, wantShared: 5},
+		{name: "default max", defaultRule: model.CartMergeMax, wantRule: model.CartMergeMax, wantShared: 3},
+		{name: "sum overrides default", defaultRule: model.CartMergeMax, rule: model.CartMergeSum, wantRule: model.CartMergeSum, wantShared: 5},
+		{name: "max overrides default", defaultRule: model.CartMergeSum, rule: model.CartMergeMax, wantRule: model.CartMergeMax, wantShared: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(tt.defaultRule)
+			userID := uuid.New()
+			f.cartStore.lines[cartKey{userID, shared}] = model.Cart{ID: uuid.New(), UserID: userID, ProductID: shared, Qty: 2}
+			token := f.seedGuestCart(
+				model.GuestCartItem{ProductID: shared, Qty: 3},
+				model.GuestCartItem{ProductID: guestOnly, Qty: 1},
+			)
+
+			bResp, err := f.c.MergeGuestCart(context.Background(), model.MergeGuestCartRequest{CartToken: token, UserID: userID, Rule: tt.rule})
+			if err != nil {
+				t.Fatalf("MergeGuestCart: %v", err)
+			}
+
+			if bResp.Rule != tt.wantRule {
+				t.Errorf("rule = %q, want %q", bResp.Rule, tt.wantRule)
+			}
+			if got := f.cartStore.rules; len(got) != 1 || got[0] != tt.wantRule {
+				t.Errorf("store merged with rules %q, want [%q]", got, tt.wantRule)
+			}
+			if got := f.cartStore.lines[cartKey{userID, shared}].Qty; got != tt.wantShared {
+				t.Errorf("qty of product in both carts = %d, want %d", got, tt.wantShared)
+			}
+			if got := f.cartStore.lines[cartKey{userID, guestOnly}].Qty; got != 1 {
+				t.Errorf("qty of guest-only product = %d, want 1", got)
+			}
+			if len(bResp.Items) != 2 {
+				t.Errorf("response has %d items, want 2", len(bResp.Items))
+			}
+			if len(f.guestStore.carts) != 0 {
+				t.Error("guest cart was not deleted after the merge")
+			}
+			if len(f.outbox.events) != 2 {
+				t.Errorf("recorded %d events, want one per merged line", len(f.outbox.events))
+			}
+		})
+	}
+}
+
+func TestMergeGuestCartEmpty(t *testing.T) {
+	f := newFixture(model.CartMergeSum)
+	token := f.seedGuestCart()
+
+	bResp, err := f.c.MergeGuestCart(context.Background(), model.MergeGuestCartRequest{CartToken: token, UserID: uuid.New()})
+	if err != nil {
+		t.Fatalf("MergeGuestCart: %v", err)
+	}
+
+	if len(bResp.Items) != 0 || len(f.cartStore.rules) != 0 || len(f.outbox.events) != 0 {
+		t.Errorf("empty guest cart merged %d items and %d events, want none", len(bResp.Items), len(f.outbox.events))
+	}
+	if len(f.guestStore.carts) != 0 {
+		t.Error("empty guest cart was not deleted")
+	}
+}
+
+func TestMergeGuestCartRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		token   func(f *fixture) string
+		wantErr error
+	}{
+		{
+			name:    "unknown rule",
+			rule:    "replace",
+			token:   func(f *fixture) string { return f.seedGuestCart(model.GuestCartItem{ProductID: uuid.New(), Qty: 1}) },
+			wantErr: model.ErrInvalidMergeRule,
+		},
+		{
+			name: "token of another secret",
+			token: func(f *fixture) string {
+				f.seedGuestCart(model.GuestCartItem{ProductID: uuid.New(), Qty: 1})
+				for id := range f.guestStore.carts {
+					return carttoken.Sign([]byte("other-secret"), id)
+				}
+				return ""
+			},
+			wantErr: model.ErrInvalidCartToken,
+		},
+		{
+			name:    "expired guest cart",
+			token:   func(f *fixture) string { return carttoken.Sign([]byte(testSecret), uuid.New()) },
+			wantErr: model.ErrGuestCartNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(model.CartMergeSum)
+			token := tt.token(f)
+			guests := len(f.guestStore.carts)
+
+			_, err := f.c.MergeGuestCart(context.Background(), model.MergeGuestCartRequest{CartToken: token, UserID: uuid.New(), Rule: tt.rule})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MergeGuestCart error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(f.cartStore.lines) != 0 || len(f.outbox.events) != 0 {
+				t.Error("a rejected merge changed the user's cart")
+			}
+			if len(f.guestStore.carts) != guests {
+				t.Error("a rejected merge deleted the guest cart")
+			}
+		})
+	}
+}
+
+func TestGuestCartLifecycle(t *testing.T) {
+	f := newFixture(model.CartMergeSum)
+	productID := uuid.New()
+	f.catalog[productID] = model.Product{ID: productID, Price: 25, Available: true}
+
+	issued, err := f.c.CreateGuestCart()
+	if err != nil {
+		t.Fatalf("CreateGuestCart: %v", err)
+	}
+	if time.Until(issued.ExpiresAt) <= 0 {
+		t.Errorf("new guest cart expires at %v, want in the future", issued.ExpiresAt)
+	}
+
+	for _, qty := range []int{2, 3} {
+		if _, err := f.c.AddGuestCart(context.Background(), issued.CartToken, model.CartLine{ProductID: productID, Qty: qty}); err != nil {
+			t.Fatalf("AddGuestCart: %v", err)
+		}
+	}
+
+	guest, err := f.c.GetGuestCart(issued.CartToken)
+	if err != nil {
+		t.Fatalf("GetGuestCart: %v", err)
+	}
+	if len(guest.Items) != 1 || guest.Items[0].Qty != 5 {
+		t.Fatalf("guest cart items = %+v, want one line of 5", guest.Items)
+	}
+	if price := guest.Items[0].AddedUnitPrice; price == nil || *price != 25 {
+		t.Errorf("recorded price = %v, want the catalog price 25", price)
+	}
+
+	if _, err := f.c.UpdateGuestQty(issued.CartToken, model.CartLine{ProductID: productID, Qty: 1}); err != nil {
+		t.Fatalf("UpdateGuestQty: %v", err)
+	}
+	if guest.Items[0].Qty != 1 {
+		t.Errorf("qty after update = %d, want 1", guest.Items[0].Qty)
+	}
+
+	// A qty of 0 removes the product.
+	if _, err := f.c.UpdateGuestQty(issued.CartToken, model.CartLine{ProductID: productID, Qty: 0}); err != nil {
+		t.Fatalf("UpdateGuestQty to 0: %v", err)
+	}
+	if len(guest.Items) != 0 {
+		t.Errorf("guest cart items after setting qty 0 = %+v, want none", guest.Items)
+	}
+
+	if len(f.cartStore.lines) != 0 || len(f.outbox.events) != 0 {
+		t.Error("guest cart changes touched user carts or the outbox")
+	}
+}
+
+func TestGuestCartRejects(t *testing.T) {
+	productID := uuid.New()
+
+	tests := []struct {
+		name    string
+		call    func(c *cart, token string) error
+		wantErr error
+	}{
+		{
+			name: "add zero qty",
+			call: func(c *cart, token string) error {
+				_, err := c.AddGuestCart(context.Background(), token, model.CartLine{ProductID: productID, Qty: 0})
+				return err
+			},
+			wantErr: model.ErrInvalidQty,
+		},
+		{
+			name: "negative qty",
+			call: func(c *cart, token string) error {
+				_, err := c.UpdateGuestQty(token, model.CartLine{ProductID: productID, Qty: -1})
+				return err
+			},
+			wantErr: model.ErrInvalidQty,
+		},
+		{
+			name: "forged token",
+			call: func(c *cart, token string) error {
+				_, err := c.GetGuestCart(carttoken.Sign([]byte("guessed"), uuid.New()))
+				return err
+			},
+			wantErr: model.ErrInvalidCartToken,
+		},
+		{
+			name: "missing token",
+			call: func(c *cart, token string) error {
+				_, err := c.AddGuestCart(context.Background(), "", model.CartLine{ProductID: productID, Qty: 1})
+				return err
+			},
+			wantErr: model.ErrInvalidCartToken,
+		},
+		{
+			name: "expired cart",
+			call: func(c *cart, token string) error {
+				_, err := c.DeleteGuestProduct(carttoken.Sign([]byte(testSecret), uuid.New()), productID)
+				return err
+			},
+			wantErr: model.ErrGuestCartNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(model.CartMergeSum)
+			token := f.seedGuestCart()
+
+			if err := tt.call(f.c, token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCleanupGuestCartsRunsUntilShortBatch(t *testing.T) {
+	f := newFixture(model.CartMergeSum)
+	f.guestStore.deletedExpired = []int{100, 100, 7, 100}
+
+	deleted, err := f.c.CleanupGuestCarts(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("CleanupGuestCarts: %v", err)
+	}
+
+	if deleted != 207 {
+		t.Errorf("deleted = %d, want 207", deleted)
+	}
+	if len(f.guestStore.deletedExpired) != 1 {
+		t.Errorf("%d batches left, want the run to stop after the short batch", len(f.guestStore.deletedExpired))
+	}
+}
+
+func TestCleanupGuestCartsStopsWhenCancelled(t *testing.T) {
+	f := newFixture(model.CartMergeSum)
+	f.guestStore.deletedExpired = []int{100, 100}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := f.c.CleanupGuestCarts(ctx, 100); !errors.Is(err, context.Canceled) {
+		t.Errorf("CleanupGuestCarts error = %v, want %v", err, context.Canceled)
+	}
+	if len(f.guestStore.deletedExpired) != 2 {
+		t.Error("a cancelled cleanup deleted carts")
+	}
+}
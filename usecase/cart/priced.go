@@ -0,0 +1,114 @@
+package cart
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// priceTolerance absorbs rounding differences when comparing prices.
+const priceTolerance = 0.005
+
+// GetPricedCart is a method that returns a user's cart with every line priced from the catalog.
+// Lines whose product is unknown or unavailable are kept with a warning but left out of the item count and
+// subtotal, and lines whose price changed since they were added carry a warning too.
+func (c *cart) GetPricedCart(ctx context.Context, userID uuid.UUID) (*model.PricedCart, error) {
+	carts, err := c.store.GetCartByUserID(model.GetCartRequest{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	bResp := &model.PricedCart{
+		UserID:   userID,
+		Items:    make([]model.PricedCartLine, 0, len(*carts)),
+		Warnings: []model.CartWarning{},
+	}
+	if len(*carts) == 0 {
+		return bResp, nil
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(*carts))
+	for _, line := range *carts {
+		productIDs = append(productIDs, line.ProductID)
+	}
+
+	catalog, err := c.catalog.GetProducts(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range *carts {
+		item := model.PricedCartLine{
+			ID:             line.ID,
+			ProductID:      line.ProductID,
+			Qty:            line.Qty,
+			AddedUnitPrice: line.AddedUnitPrice,
+		}
+
+		product, ok := catalog[line.ProductID]
+		if !ok {
+			bResp.Items = append(bResp.Items, item)
+			bResp.Warnings = append(bResp.Warnings, model.CartWarning{
+				ProductID: line.ProductID,
+				Code:      model.CartWarningNotFound,
+				Message:   "product is no longer sold",
+			})
+			continue
+		}
+
+		item.ProductName = product.Name
+		item.ProductSKU = product.SKU
+		item.UnitPrice = product.Price
+		item.Available = product.Available
+
+		if !product.Available {
+			bResp.Items = append(bResp.Items, item)
+			bResp.Warnings = append(bResp.Warnings, model.CartWarning{
+				ProductID: line.ProductID,
+				Code:      model.CartWarningUnavailable,
+				Message:   fmt.Sprintf("%s is not available", product.Name),
+			})
+			continue
+		}
+
+		if line.AddedUnitPrice != nil && math.Abs(*line.AddedUnitPrice-product.Price) >= priceTolerance {
+			bResp.Warnings = append(bResp.Warnings, model.CartWarning{
+				ProductID: line.ProductID,
+				Code:      model.CartWarningPriceChanged,
+				Message:   fmt.Sprintf("price of %s changed from %.2f to %.2f", product.Name, *line.AddedUnitPrice, product.Price),
+			})
+		}
+
+		item.LineSubtotal = roundPrice(product.Price * float64(line.Qty))
+		bResp.Items = append(bResp.Items, item)
+		bResp.ItemCount += line.Qty
+		bResp.Subtotal += item.LineSubtotal
+	}
+	bResp.Subtotal = roundPrice(bResp.Subtotal)
+
+	return bResp, nil
+}
+
+// currentPrices is a method that returns the catalog price of each known product among productIDs.
+// Unknown products are left out, so their cart lines get no recorded price.
+func (c *cart) currentPrices(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]*float64, error) {
+	catalog, err := c.catalog.GetProducts(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[uuid.UUID]*float64, len(catalog))
+	for id, product := range catalog {
+		price := product.Price
+		prices[id] = &price
+	}
+
+	return prices, nil
+}
+
+func roundPrice(v float64) float64 {
+	return math.Round(v*100) / 100
+}
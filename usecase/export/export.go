@@ -0,0 +1,176 @@
+package export
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// orderStore is an interface that defines the order methods required to export orders.
+type orderStore interface {
+	StreamOrders(ctx context.Context, bReq model.ExportOrdersRequest, batchSize int, fn func(order model.Order) error) error
+}
+
+type export struct {
+	orderStore orderStore
+	batchSize  int
+	logger     zerolog.Logger
+}
+
+// NewExport is a constructor function that returns a new export instance.
+// Orders are fetched from the database batchSize rows at a time.
+func NewExport(orderStore orderStore, batchSize int, logger zerolog.Logger) *export {
+	return &export{
+		orderStore: orderStore,
+		batchSize:  batchSize,
+		logger:     logger,
+	}
+}
+
+// exportRow is an order as written to an export. Times are UTC in RFC 3339.
+type exportRow struct {
+	ID              uuid.UUID         `json:"id"`
+	OrderNumber     string            `json:"order_number"`
+	RefCode         string            `json:"ref_code"`
+	UserID          uuid.UUID         `json:"user_id"`
+	PaymentTypeID   uuid.UUID         `json:"payment_type_id"`
+	Status          model.OrderStatus `json:"status"`
+	IsPaid          bool              `json:"is_paid"`
+	Subtotal        float64           `json:"subtotal"`
+	ShippingFee     float64           `json:"shipping_fee"`
+	ShippingService string            `json:"shipping_service"`
+	TotalPrice      float64           `json:"total_price"`
+	CreatedAt       string            `json:"created_at"`
+	UpdatedAt       string            `json:"updated_at"`
+}
+
+// csvHeader names the CSV columns in the order written by csvRecord.
+var csvHeader = []string{
+	"id",
+	"order_number",
+	"ref_code",
+	"user_id",
+	"payment_type_id",
+	"status",
+	"is_paid",
+	"subtotal",
+	"shipping_fee",
+	"shipping_service",
+	"total_price",
+	"created_at",
+	"updated_at",
+}
+
+func newExportRow(order model.Order) exportRow {
+	return exportRow{
+		ID:              order.ID,
+		OrderNumber:     order.OrderNumber,
+		RefCode:         order.RefCode,
+		UserID:          order.UserID,
+		PaymentTypeID:   order.PaymentTypeID,
+		Status:          order.Status,
+		IsPaid:          order.IsPaid,
+		Subtotal:        order.Subtotal,
+		ShippingFee:     order.ShippingFee,
+		ShippingService: order.ShippingService,
+		TotalPrice:      order.TotalPrice,
+		CreatedAt:       formatTime(order.CreatedAt),
+		UpdatedAt:       formatTime(order.UpdatedAt),
+	}
+}
+
+func (r exportRow) csvRecord() []string {
+	return []string{
+		r.ID.String(),
+		r.OrderNumber,
+		r.RefCode,
+		r.UserID.String(),
+		r.PaymentTypeID.String(),
+		string(r.Status),
+		strconv.FormatBool(r.IsPaid),
+		formatAmount(r.Subtotal),
+		formatAmount(r.ShippingFee),
+		r.ShippingService,
+		formatAmount(r.TotalPrice),
+		r.CreatedAt,
+		r.UpdatedAt,
+	}
+}
+
+// ValidateExport is a method that checks an export request before anything is written.
+// It returns model.ErrInvalidExportFormat for an unknown format.
+func (e *export) ValidateExport(bReq model.ExportOrdersRequest) error {
+	switch bReq.Format {
+	case model.ExportFormatCSV, model.ExportFormatNDJSON:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", model.ErrInvalidExportFormat, bReq.Format)
+	}
+}
+
+// ExportOrders is a method that writes the orders matching the request to w, one row at a time, and returns the
+// number of orders written. CSV exports start with a header row; NDJSON exports hold one JSON object per line.
+func (e *export) ExportOrders(ctx context.Context, bReq model.ExportOrdersRequest, w io.Writer) (int, error) {
+	logMsgStr := "Usecase:Export - ExportOrders:"
+
+	if err := e.ValidateExport(bReq); err != nil {
+		return 0, err
+	}
+
+	var (
+		write func(row exportRow) error
+		flush func() error
+	)
+	switch bReq.Format {
+	case model.ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return 0, err
+		}
+		write = func(row exportRow) error { return cw.Write(row.csvRecord()) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case model.ExportFormatNDJSON:
+		enc := json.NewEncoder(w)
+		write = func(row exportRow) error { return enc.Encode(row) }
+		flush = func() error { return nil }
+	}
+
+	count := 0
+	err := e.orderStore.StreamOrders(ctx, bReq, e.batchSize, func(order model.Order) error {
+		count++
+		return write(newExportRow(order))
+	})
+	if err != nil {
+		e.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed after %v orders", logMsgStr, count))
+		return count, err
+	}
+
+	if err := flush(); err != nil {
+		return count, err
+	}
+
+	e.logger.Info().Msg(fmt.Sprintf("%v Exported %v orders as %v", logMsgStr, count, bReq.Format))
+	return count, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
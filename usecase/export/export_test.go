@@ -0,0 +1,162 @@
+package export
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// fakeStore streams a fixed list of orders, then fails with err if it is set.
+type fakeStore struct {
+	orders []model.Order
+	err    error
+	// batchSize is the batch size StreamOrders was called with.
+	batchSize int
+}
+
+func (s *fakeStore) StreamOrders(ctx context.Context, bReq model.ExportOrdersRequest, batchSize int, fn func(order model.Order) error) error {
+	s.batchSize = batchSize
+	for _, order := range s.orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func testOrders() []model.Order {
+	created := time.Date(2026, 10, 17, 9, 30, 0, 0, time.FixedZone("WIB", 7*3600))
+	return []model.Order{
+		{
+			ID:              uuid.New(),
+			OrderNumber:     "ORD-1",
+			RefCode:         "REF-1",
+			UserID:          uuid.New(),
+			PaymentTypeID:   uuid.New(),
+			Status:          model.OrderStatusPaid,
+			IsPaid:          true,
+			Subtotal:        100,
+			ShippingFee:     12.5,
+			ShippingService: "jne-reg, next day",
+			TotalPrice:      112.5,
+			CreatedAt:       &created,
+		},
+		{
+			ID:          uuid.New(),
+			OrderNumber: "ORD-2",
+			RefCode:     "REF-2",
+			Status:      model.OrderStatusPending,
+			TotalPrice:  40,
+		},
+	}
+}
+
+func TestExportOrdersCSV(t *testing.T) {
+	store := &fakeStore{orders: testOrders()}
+	e := NewExport(store, 500, zerolog.Nop())
+
+	var buf bytes.Buffer
+	count, err := e.ExportOrders(context.Background(), model.ExportOrdersRequest{Format: model.ExportFormatCSV}, &buf)
+	if err != nil {
+		t.Fatalf("ExportOrders: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	if store.batchSize != 500 {
+		t.Errorf("streamed in batches of %d, want 500", store.batchSize)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("CSV has %d records, want a header and 2 rows", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Errorf("header = %v, want %v", records[0], csvHeader)
+	}
+
+	row := map[string]string{}
+	for i, column := range csvHeader {
+		row[column] = records[1][i]
+	}
+	want := map[string]string{
+		"order_number":     "ORD-1",
+		"status":           "paid",
+		"is_paid":          "true",
+		"shipping_fee":     "12.50",
+		"shipping_service": "jne-reg, next day",
+		"total_price":      "112.50",
+		"created_at":       "2026-10-17T02:30:00Z",
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("%s = %q, want %q", column, row[column], value)
+		}
+	}
+	if got := records[2][len(csvHeader)-2]; got != "" {
+		t.Errorf("created_at of an order without one = %q, want empty", got)
+	}
+}
+
+func TestExportOrdersNDJSON(t *testing.T) {
+	orders := testOrders()
+	e := NewExport(&fakeStore{orders: orders}, 500, zerolog.Nop())
+
+	var buf bytes.Buffer
+	if _, err := e.ExportOrders(context.Background(), model.ExportOrdersRequest{Format: model.ExportFormatNDJSON}, &buf); err != nil {
+		t.Fatalf("ExportOrders: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(orders) {
+		t.Fatalf("export has %d lines, want one per order", len(lines))
+	}
+	for i, line := range lines {
+		var row exportRow
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i+1, err)
+		}
+		if row.ID != orders[i].ID || row.TotalPrice != orders[i].TotalPrice {
+			t.Errorf("line %d = %+v, want order %s", i+1, row, orders[i].ID)
+		}
+	}
+}
+
+func TestExportOrdersRejectsUnknownFormat(t *testing.T) {
+	store := &fakeStore{orders: testOrders()}
+	e := NewExport(store, 500, zerolog.Nop())
+
+	var buf bytes.Buffer
+	_, err := e.ExportOrders(context.Background(), model.ExportOrdersRequest{Format: "xlsx"}, &buf)
+	if !errors.Is(err, model.ErrInvalidExportFormat) {
+		t.Fatalf("ExportOrders error = %v, want %v", err, model.ErrInvalidExportFormat)
+	}
+	if buf.Len() != 0 || store.batchSize != 0 {
+		t.Error("an invalid export read or wrote orders")
+	}
+}
+
+func TestExportOrdersReportsStreamFailure(t *testing.T) {
+	streamErr := errors.New("connection reset")
+	e := NewExport(&fakeStore{orders: testOrders(), err: streamErr}, 500, zerolog.Nop())
+
+	count, err := e.ExportOrders(context.Background(), model.ExportOrdersRequest{Format: model.ExportFormatNDJSON}, &bytes.Buffer{})
+	if !errors.Is(err, streamErr) {
+		t.Fatalf("ExportOrders error = %v, want %v", err, streamErr)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want the 2 orders written before the failure", count)
+	}
+}
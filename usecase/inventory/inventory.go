@@ -0,0 +1,152 @@
+package inventory
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// inventoryClient is an interface that defines the inventory service calls required to settle reservations.
+type inventoryClient interface {
+	Release(ctx context.Context, reservationID uuid.UUID) error
+	Confirm(ctx context.Context, reservationID uuid.UUID) error
+}
+
+// reservationStore is an interface that defines the methods required for reconciling stock reservations.
+type reservationStore interface {
+	GetDueReservationsForUpdate(limit int) (*[]model.StockReservation, error)
+	UpdateReservationStatus(reservationID uuid.UUID, from, to model.ReservationStatus, lastError string) error
+	RetryReservation(reservationID uuid.UUID, lastError string, retryIn time.Duration) error
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Reservation reservationStore
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// Config holds how the reconciler batches and retries reservations.
+type Config struct {
+	BatchSize int
+	// RetryInterval is how long a reservation is held back after a failed call to the inventory service.
+	RetryInterval time.Duration
+}
+
+type reconciler struct {
+	txManager txManager
+	inventory inventoryClient
+	cfg       Config
+	logger    zerolog.Logger
+}
+
+// NewReconciler is a constructor function that returns a new reconciler instance.
+func NewReconciler(txManager txManager, inventory inventoryClient, cfg Config, logger zerolog.Logger) *reconciler {
+	return &reconciler{
+		txManager: txManager,
+		inventory: inventory,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// Reconcile is a method that carries out the actions owed to the inventory service and returns how many
+// reservations it settled. Pending reservations whose order was never committed and reservations of cancelled
+// orders are released; reservations of paid orders are confirmed.
+// Reservations are handled in batches of cfg.BatchSize, each batch in its own transaction, until none is due.
+// A failed call is retried after cfg.RetryInterval. Several replicas may run it at the same time.
+func (r *reconciler) Reconcile(ctx context.Context) (int, error) {
+	logMsgStr := "Usecase:Inventory - Reconcile:"
+
+	var settled, failed int
+	for {
+		if err := ctx.Err(); err != nil {
+			return settled, err
+		}
+
+		var batch int
+
+		err := r.txManager.WithTx(ctx, func(repos Repositories) error {
+			reservations, err := repos.Reservation.GetDueReservationsForUpdate(r.cfg.BatchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, reservation := range *reservations {
+				next, callErr := r.settle(ctx, reservation)
+				if callErr != nil && next == "" {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+
+					failed++
+					r.logger.Warn().Any("Err", callErr).Msg(fmt.Sprintf("%v Failed to settle %v reservation %v, retrying in %v", logMsgStr, reservation.Status, reservation.ID, r.cfg.RetryInterval))
+					if err := repos.Reservation.RetryReservation(reservation.ID, callErr.Error(), r.cfg.RetryInterval); err != nil {
+						return err
+					}
+					continue
+				}
+
+				lastError := ""
+				if callErr != nil {
+					lastError = callErr.Error()
+					r.logger.Error().Any("Err", callErr).Msg(fmt.Sprintf("%v Reservation %v of order %v could not be confirmed", logMsgStr, reservation.ID, reservation.OrderID))
+				}
+
+				if err := repos.Reservation.UpdateReservationStatus(reservation.ID, reservation.Status, next, lastError); err != nil {
+					return err
+				}
+				settled++
+			}
+
+			batch = len(*reservations)
+
+			return nil
+		})
+		if err != nil {
+			return settled, err
+		}
+
+		if batch == 0 {
+			break
+		}
+	}
+
+	if settled > 0 || failed > 0 {
+		r.logger.Info().Msg(fmt.Sprintf("%v Settled %d reservations, %d to retry", logMsgStr, settled, failed))
+	}
+
+	return settled, nil
+}
+
+// settle calls the inventory service for a due reservation and returns the status to move it to.
+// An empty status means the call failed and should be retried. A reservation the inventory service no longer
+// knows cannot be confirmed; it is marked failed and returned with the error so it can be looked into.
+func (r *reconciler) settle(ctx context.Context, reservation model.StockReservation) (model.ReservationStatus, error) {
+	switch reservation.Status {
+	case model.ReservationStatusPending, model.ReservationStatusReleasing:
+		if err := r.inventory.Release(ctx, reservation.ID); err != nil {
+			return "", err
+		}
+		return model.ReservationStatusReleased, nil
+	case model.ReservationStatusConfirming:
+		err := r.inventory.Confirm(ctx, reservation.ID)
+		if errors.Is(err, model.ErrReservationNotFound) {
+			return model.ReservationStatusFailed, err
+		}
+		if err != nil {
+			return "", err
+		}
+		return model.ReservationStatusConfirmed, nil
+	default:
+		return "", fmt.Errorf("reservation %s is %s, nothing to settle", reservation.ID, reservation.Status)
+	}
+}
@@ -0,0 +1,119 @@
+package inventory
+
+import (
+	"cart-order-service/client/inventory"
+	model "cart-order-service/repository/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// memReservationStore is an in-memory reservationStore. Like the SQL store, it hands out reservations that are
+// pending, releasing or confirming once their next attempt is due.
+type memReservationStore struct {
+	now          time.Time
+	reservations map[uuid.UUID]*model.StockReservation
+}
+
+func (s *memReservationStore) GetDueReservationsForUpdate(limit int) (*[]model.StockReservation, error) {
+	due := []model.StockReservation{}
+	for _, reservation := range s.reservations {
+		switch reservation.Status {
+		case model.ReservationStatusPending, model.ReservationStatusReleasing, model.ReservationStatusConfirming:
+		default:
+			continue
+		}
+
+		if reservation.NextAttemptAt.After(s.now) || len(due) == limit {
+			continue
+		}
+		due = append(due, *reservation)
+	}
+
+	return &due, nil
+}
+
+func (s *memReservationStore) UpdateReservationStatus(reservationID uuid.UUID, from, to model.ReservationStatus, lastError string) error {
+	reservation := s.reservations[reservationID]
+	if reservation.Status != from {
+		return model.ErrReservationNotFound
+	}
+	reservation.Status = to
+
+	return nil
+}
+
+func (s *memReservationStore) RetryReservation(reservationID uuid.UUID, lastError string, retryIn time.Duration) error {
+	reservation := s.reservations[reservationID]
+	next := s.now.Add(retryIn)
+	reservation.Attempts++
+	reservation.LastError = &lastError
+	reservation.NextAttemptAt = &next
+
+	return nil
+}
+
+type memTxManager struct {
+	store *memReservationStore
+}
+
+func (m memTxManager) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	return fn(Repositories{Reservation: m.store})
+}
+
+func TestReconcileReleasesTimedOutPendingReservation(t *testing.T) {
+	const pendingTimeout = 15 * time.Minute
+
+	now := time.Now()
+	productID := uuid.New()
+	stock := inventory.NewFake(map[uuid.UUID]int{productID: 10})
+
+	store := &memReservationStore{now: now, reservations: map[uuid.UUID]*model.StockReservation{}}
+	reserve := func(createdAt time.Time, qty int) uuid.UUID {
+		reservation := model.StockReservation{
+			ID:     uuid.New(),
+			Lines:  []model.StockLine{{ProductID: productID, Qty: qty}},
+			Status: model.ReservationStatusPending,
+		}
+		if err := stock.Reserve(context.Background(), model.ReserveStockRequest{ReservationID: reservation.ID, Lines: reservation.Lines}); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+
+		// The order was never attached, so the reservation becomes due once the pending timeout passes.
+		nextAttemptAt := createdAt.Add(pendingTimeout)
+		reservation.CreatedAt = &createdAt
+		reservation.NextAttemptAt = &nextAttemptAt
+		store.reservations[reservation.ID] = &reservation
+
+		return reservation.ID
+	}
+
+	abandoned := reserve(now.Add(-pendingTimeout-time.Minute), 3)
+	inFlight := reserve(now.Add(-time.Minute), 2)
+
+	r := NewReconciler(memTxManager{store: store}, stock, Config{BatchSize: 10, RetryInterval: time.Minute}, zerolog.Nop())
+
+	settled, err := r.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if settled != 1 {
+		t.Errorf("settled = %d, want 1", settled)
+	}
+
+	if status := store.reservations[abandoned].Status; status != model.ReservationStatusReleased {
+		t.Errorf("timed-out reservation is %s, want %s", status, model.ReservationStatusReleased)
+	}
+
+	if status := store.reservations[inFlight].Status; status != model.ReservationStatusPending {
+		t.Errorf("reservation within its timeout is %s, want %s", status, model.ReservationStatusPending)
+	}
+
+	if got := stock.Stock(productID); got != 8 {
+		t.Errorf("stock = %d, want 8 with only the timed-out reservation put back", got)
+	}
+}
@@ -0,0 +1,219 @@
+package invoice
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Placeholders understood in invoice number formats.
+const (
+	// PlaceholderYear is replaced with the year the invoice is issued in.
+	PlaceholderYear = "{year}"
+	// PlaceholderSeq is replaced with the invoice sequence number, zero-padded to the configured width.
+	PlaceholderSeq = "{seq}"
+)
+
+// orderStore is an interface that defines the order methods required to invoice orders.
+type orderStore interface {
+	GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error)
+	GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error)
+}
+
+// paymentStore is an interface that defines the payment methods required to invoice orders.
+type paymentStore interface {
+	GetPaymentsByOrderID(orderID uuid.UUID) (*[]model.Payment, error)
+}
+
+// invoiceStore is an interface that defines the methods required for issuing invoices.
+type invoiceStore interface {
+	GetInvoiceByOrderID(orderID uuid.UUID) (*model.Invoice, error)
+	NextInvoiceSeq() (int64, error)
+	CreateInvoice(bReq model.Invoice) (*uuid.UUID, error)
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Order   orderStore
+	Payment paymentStore
+	Invoice invoiceStore
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// Config holds the invoice number format and what is printed about the seller.
+type Config struct {
+	NumberFormat string
+	SeqWidth     int
+	Currency     string
+	Seller       model.InvoiceSeller
+}
+
+type invoice struct {
+	invoiceStore invoiceStore
+	txManager    txManager
+	cfg          Config
+	logger       zerolog.Logger
+}
+
+// NewInvoice is a constructor function that returns a new invoice instance.
+// The number format must contain PlaceholderSeq, which keeps invoice numbers unique.
+func NewInvoice(invoiceStore invoiceStore, txManager txManager, cfg Config, logger zerolog.Logger) (*invoice, error) {
+	if !strings.Contains(cfg.NumberFormat, PlaceholderSeq) {
+		return nil, fmt.Errorf("invoice: number format %q must contain %s", cfg.NumberFormat, PlaceholderSeq)
+	}
+
+	return &invoice{
+		invoiceStore: invoiceStore,
+		txManager:    txManager,
+		cfg:          cfg,
+		logger:       logger,
+	}, nil
+}
+
+// GetInvoicePDF is a method that renders the invoice of a paid order as a PDF.
+// The first call issues the invoice: it takes the next invoice number and freezes what is printed, so every
+// later call renders the same document. It returns model.ErrOrderNotInvoiceable if the order is not paid.
+func (i *invoice) GetInvoicePDF(ctx context.Context, orderID uuid.UUID) (*model.InvoiceFile, error) {
+	inv, err := i.invoiceStore.GetInvoiceByOrderID(orderID)
+	if errors.Is(err, model.ErrInvoiceNotFound) {
+		inv, err = i.issueInvoice(ctx, orderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := renderInvoice(inv.Document)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.InvoiceFile{
+		Filename: inv.InvoiceNumber + ".pdf",
+		Content:  content,
+	}, nil
+}
+
+// issueInvoice is a method that numbers and stores the invoice of a paid order.
+// The order stays locked while the invoice is issued, so concurrent first requests issue it once.
+func (i *invoice) issueInvoice(ctx context.Context, orderID uuid.UUID) (*model.Invoice, error) {
+	logMsgStr := "Usecase:Invoice - issueInvoice:"
+
+	var inv *model.Invoice
+	err := i.txManager.WithTx(ctx, func(repos Repositories) error {
+		order, err := repos.Order.GetOrderForUpdate(orderID)
+		if err != nil {
+			return err
+		}
+
+		inv, err = repos.Invoice.GetInvoiceByOrderID(orderID)
+		if !errors.Is(err, model.ErrInvoiceNotFound) {
+			return err
+		}
+
+		if !order.IsPaid {
+			return fmt.Errorf("%w: order is %s", model.ErrOrderNotInvoiceable, order.Status)
+		}
+
+		items, err := repos.Order.GetOrderItems(orderID)
+		if err != nil {
+			return err
+		}
+
+		payments, err := repos.Payment.GetPaymentsByOrderID(orderID)
+		if err != nil {
+			return err
+		}
+
+		seq, err := repos.Invoice.NextInvoiceSeq()
+		if err != nil {
+			return err
+		}
+
+		// Truncated so the document prints the same before and after its round trip through the database.
+		issuedAt := time.Now().UTC().Truncate(time.Second)
+		inv = &model.Invoice{
+			OrderID:       orderID,
+			Seq:           seq,
+			InvoiceNumber: i.invoiceNumber(seq, issuedAt),
+			IssuedAt:      issuedAt,
+		}
+		inv.Document = i.buildDocument(inv, order, *items, *payments)
+
+		id, err := repos.Invoice.CreateInvoice(*inv)
+		if err != nil {
+			return err
+		}
+		inv.ID = *id
+
+		i.logger.Info().Msg(fmt.Sprintf("%v Issued invoice %v for order %v", logMsgStr, inv.InvoiceNumber, orderID))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+func (i *invoice) invoiceNumber(seq int64, issuedAt time.Time) string {
+	number := strings.ReplaceAll(i.cfg.NumberFormat, PlaceholderYear, strconv.Itoa(issuedAt.Year()))
+	return strings.ReplaceAll(number, PlaceholderSeq, fmt.Sprintf("%0*d", i.cfg.SeqWidth, seq))
+}
+
+// buildDocument is a method that collects what is printed on the invoice of an order.
+// The payment shown is the latest succeeded one.
+func (i *invoice) buildDocument(inv *model.Invoice, order *model.Order, items []model.OrderItem, payments []model.Payment) model.InvoiceDocument {
+	doc := model.InvoiceDocument{
+		InvoiceNumber:   inv.InvoiceNumber,
+		IssuedAt:        inv.IssuedAt,
+		Seller:          i.cfg.Seller,
+		OrderID:         order.ID,
+		OrderNumber:     order.OrderNumber,
+		RefCode:         order.RefCode,
+		OrderedAt:       order.CreatedAt,
+		CustomerID:      order.UserID,
+		PaymentTypeID:   order.PaymentTypeID,
+		Currency:        i.cfg.Currency,
+		Items:           make([]model.InvoiceLine, 0, len(items)),
+		Subtotal:        order.Subtotal,
+		ShippingFee:     order.ShippingFee,
+		ShippingService: order.ShippingService,
+		Total:           order.TotalPrice,
+	}
+
+	for _, item := range items {
+		doc.Items = append(doc.Items, model.InvoiceLine{
+			ProductName: item.ProductName,
+			ProductSKU:  item.ProductSKU,
+			Qty:         item.Qty,
+			UnitPrice:   item.UnitPrice,
+			LineTotal:   item.LineTotal,
+		})
+	}
+
+	for _, p := range payments {
+		if p.Status != model.PaymentStatusSucceeded {
+			continue
+		}
+		doc.Currency = p.Currency
+		doc.Payment = &model.InvoicePayment{
+			Provider: p.Provider,
+			IntentID: p.IntentID,
+			Amount:   p.Amount,
+			PaidAt:   p.UpdatedAt,
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,244 @@
+package invoice
+
+import (
+	"bytes"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// memState is the data held by memStore. It is copied to roll a failed unit of work back.
+type memState struct {
+	lastSeq  int64
+	invoices map[uuid.UUID]model.Invoice
+}
+
+func (s memState) clone() memState {
+	c := s
+	c.invoices = make(map[uuid.UUID]model.Invoice, len(s.invoices))
+	for id, inv := range s.invoices {
+		c.invoices[id] = inv
+	}
+	return c
+}
+
+// memStore is an in-memory orderStore, paymentStore and invoiceStore.
+type memStore struct {
+	memState
+	orders     map[uuid.UUID]model.Order
+	orderItems map[uuid.UUID][]model.OrderItem
+	payments   map[uuid.UUID][]model.Payment
+	// failCreate, when set, is returned once by the next CreateInvoice.
+	failCreate error
+}
+
+func (s *memStore) GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error) {
+	order, ok := s.orders[orderID]
+	if !ok {
+		return nil, model.ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (s *memStore) GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error) {
+	items := s.orderItems[orderID]
+	return &items, nil
+}
+
+func (s *memStore) GetPaymentsByOrderID(orderID uuid.UUID) (*[]model.Payment, error) {
+	payments := s.payments[orderID]
+	return &payments, nil
+}
+
+func (s *memStore) GetInvoiceByOrderID(orderID uuid.UUID) (*model.Invoice, error) {
+	inv, ok := s.invoices[orderID]
+	if !ok {
+		return nil, model.ErrInvoiceNotFound
+	}
+	return &inv, nil
+}
+
+func (s *memStore) NextInvoiceSeq() (int64, error) {
+	s.lastSeq++
+	return s.lastSeq, nil
+}
+
+func (s *memStore) CreateInvoice(bReq model.Invoice) (*uuid.UUID, error) {
+	if s.failCreate != nil {
+		err := s.failCreate
+		s.failCreate = nil
+		return nil, err
+	}
+
+	bReq.ID = uuid.New()
+	s.invoices[bReq.OrderID] = bReq
+	return &bReq.ID, nil
+}
+
+// memTxManager runs a unit of work against memStore and rolls its changes back if it fails.
+type memTxManager struct {
+	store *memStore
+}
+
+func (m memTxManager) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	snapshot := m.store.memState.clone()
+
+	err := fn(Repositories{Order: m.store, Payment: m.store, Invoice: m.store})
+	if err != nil {
+		m.store.memState = snapshot
+	}
+
+	return err
+}
+
+func newTestInvoice(t *testing.T) (*invoice, *memStore) {
+	t.Helper()
+
+	store := &memStore{
+		memState:   memState{invoices: map[uuid.UUID]model.Invoice{}},
+		orders:     map[uuid.UUID]model.Order{},
+		orderItems: map[uuid.UUID][]model.OrderItem{},
+		payments:   map[uuid.UUID][]model.Payment{},
+	}
+
+	i, err := NewInvoice(store, memTxManager{store: store}, Config{
+		NumberFormat: "INV-{year}-{seq}",
+		SeqWidth:     6,
+		Currency:     "IDR",
+		Seller:       model.InvoiceSeller{Name: "Toko"},
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+
+	return i, store
+}
+
+// addOrder adds an order with one line and the given payments.
+func addOrder(store *memStore, isPaid bool, payments ...model.Payment) uuid.UUID {
+	orderID := uuid.New()
+	store.orders[orderID] = model.Order{ID: orderID, RefCode: "REF-" + orderID.String()[:8], IsPaid: isPaid, Status: model.OrderStatusPaid, Subtotal: 50, TotalPrice: 50}
+	store.orderItems[orderID] = []model.OrderItem{{ID: uuid.New(), OrderID: orderID, ProductName: "Kopi", Qty: 2, UnitPrice: 25, LineTotal: 50}}
+	store.payments[orderID] = payments
+	return orderID
+}
+
+func TestNewInvoiceRequiresSeq(t *testing.T) {
+	if _, err := NewInvoice(nil, nil, Config{NumberFormat: "INV-{year}"}, zerolog.Nop()); err == nil {
+		t.Error("NewInvoice accepted a number format without {seq}")
+	}
+}
+
+func TestGetInvoicePDFNumbersInvoicesInOrder(t *testing.T) {
+	i, store := newTestInvoice(t)
+	year := time.Now().UTC().Year()
+
+	for n := 1; n <= 3; n++ {
+		orderID := addOrder(store, true)
+
+		file, err := i.GetInvoicePDF(context.Background(), orderID)
+		if err != nil {
+			t.Fatalf("GetInvoicePDF: %v", err)
+		}
+
+		want := fmt.Sprintf("INV-%d-%06d", year, n)
+		if file.Filename != want+".pdf" {
+			t.Errorf("filename = %q, want %q", file.Filename, want+".pdf")
+		}
+		if !bytes.HasPrefix(file.Content, []byte("%PDF-")) {
+			t.Error("content is not a PDF")
+		}
+	}
+}
+
+func TestGetInvoicePDFReprintsIdentically(t *testing.T) {
+	i, store := newTestInvoice(t)
+	orderID := addOrder(store, true)
+
+	first, err := i.GetInvoicePDF(context.Background(), orderID)
+	if err != nil {
+		t.Fatalf("GetInvoicePDF: %v", err)
+	}
+
+	// Changes after issue are not printed on reprints.
+	order := store.orders[orderID]
+	order.TotalPrice = 999
+	store.orders[orderID] = order
+
+	again, err := i.GetInvoicePDF(context.Background(), orderID)
+	if err != nil {
+		t.Fatalf("GetInvoicePDF again: %v", err)
+	}
+
+	if again.Filename != first.Filename || !bytes.Equal(again.Content, first.Content) {
+		t.Error("reprint differs from the first print")
+	}
+	if store.lastSeq != 1 {
+		t.Errorf("took %d invoice numbers, want 1", store.lastSeq)
+	}
+}
+
+func TestGetInvoicePDFRejectsUnpaidOrder(t *testing.T) {
+	i, store := newTestInvoice(t)
+	orderID := addOrder(store, false)
+
+	if _, err := i.GetInvoicePDF(context.Background(), orderID); !errors.Is(err, model.ErrOrderNotInvoiceable) {
+		t.Fatalf("GetInvoicePDF error = %v, want %v", err, model.ErrOrderNotInvoiceable)
+	}
+	if store.lastSeq != 0 || len(store.invoices) != 0 {
+		t.Error("an unpaid order took an invoice number")
+	}
+
+	if _, err := i.GetInvoicePDF(context.Background(), uuid.New()); !errors.Is(err, model.ErrOrderNotFound) {
+		t.Errorf("GetInvoicePDF of an unknown order error = %v, want %v", err, model.ErrOrderNotFound)
+	}
+}
+
+func TestGetInvoicePDFLeavesNoGapOnFailure(t *testing.T) {
+	i, store := newTestInvoice(t)
+	orderID := addOrder(store, true)
+	store.failCreate = errors.New("connection reset")
+
+	if _, err := i.GetInvoicePDF(context.Background(), orderID); err == nil {
+		t.Fatal("GetInvoicePDF succeeded although the invoice was not stored")
+	}
+
+	file, err := i.GetInvoicePDF(context.Background(), orderID)
+	if err != nil {
+		t.Fatalf("GetInvoicePDF retry: %v", err)
+	}
+	if !strings.HasSuffix(file.Filename, "-000001.pdf") {
+		t.Errorf("filename after a failed first attempt = %q, want invoice number 1", file.Filename)
+	}
+}
+
+func TestBuildDocumentShowsSucceededPayment(t *testing.T) {
+	i, store := newTestInvoice(t)
+	paidAt := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	orderID := addOrder(store, true,
+		model.Payment{Status: model.PaymentStatusFailed, IntentID: "pi_failed", Currency: "IDR", Amount: 50},
+		model.Payment{Status: model.PaymentStatusSucceeded, IntentID: "pi_paid", Provider: "fake", Currency: "USD", Amount: 50, UpdatedAt: &paidAt},
+	)
+
+	if _, err := i.GetInvoicePDF(context.Background(), orderID); err != nil {
+		t.Fatalf("GetInvoicePDF: %v", err)
+	}
+
+	doc := store.invoices[orderID].Document
+	if doc.Payment == nil || doc.Payment.IntentID != "pi_paid" {
+		t.Fatalf("payment = %+v, want the succeeded payment", doc.Payment)
+	}
+	if doc.Currency != "USD" {
+		t.Errorf("currency = %q, want the currency of the payment", doc.Currency)
+	}
+	if len(doc.Items) != 1 || doc.Items[0].LineTotal != 50 || doc.Total != 50 {
+		t.Errorf("document lines %+v total %v, want one line of 50", doc.Items, doc.Total)
+	}
+}
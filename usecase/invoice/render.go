@@ -0,0 +1,208 @@
+package invoice
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/pdf"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Invoice layout, in points.
+const (
+	pageMargin   = 48.0
+	headerHeight = 72.0
+	rowHeight    = 28.0
+	footerTop    = pdf.A4Height - 56
+	// totalsHeight is the room the totals and payment block need below the last row.
+	totalsHeight = 130.0
+
+	colNo        = pageMargin
+	colItem      = pageMargin + 26
+	colQtyRight  = 370.0
+	colUnitRight = 460.0
+	colAmtRight  = pdf.A4Width - pageMargin
+)
+
+const dateLayout = "02 Jan 2006 15:04 MST"
+
+var (
+	brandColor = pdf.RGB(238, 77, 45)
+	white      = pdf.RGB(255, 255, 255)
+	mutedColor = pdf.RGB(110, 110, 110)
+	ruleColor  = pdf.RGB(220, 220, 220)
+	bandColor  = pdf.RGB(245, 245, 245)
+)
+
+// renderInvoice lays out an invoice document as an A4 PDF.
+// It only reads the document, so the same document always renders to the same bytes.
+func renderInvoice(doc model.InvoiceDocument) ([]byte, error) {
+	d := pdf.New(pdf.A4Width, pdf.A4Height)
+	d.Title = "Invoice " + doc.InvoiceNumber
+	d.Author = doc.Seller.Name
+	d.CreatedAt = doc.IssuedAt
+
+	page := d.AddPage()
+	drawHeader(page, doc)
+	y := drawParties(page, doc)
+	y = drawTableHeader(page, y)
+
+	for n, item := range doc.Items {
+		if y+rowHeight > footerTop-12 {
+			page = d.AddPage()
+			drawHeader(page, doc)
+			y = drawTableHeader(page, headerHeight+32)
+		}
+		drawItem(page, y, n+1, item, doc.Currency)
+		y += rowHeight
+	}
+
+	if y+totalsHeight > footerTop {
+		page = d.AddPage()
+		drawHeader(page, doc)
+		y = headerHeight + 32
+	}
+	drawTotals(page, y+8, doc)
+
+	pages := d.Pages()
+	for n, p := range pages {
+		drawFooter(p, doc, n+1, len(pages))
+	}
+
+	return d.Bytes()
+}
+
+func drawHeader(page *pdf.Page, doc model.InvoiceDocument) {
+	page.FillRect(0, 0, pdf.A4Width, headerHeight, brandColor)
+	page.Text(pageMargin, 44, pdf.HelveticaBold, 20, white, pdf.Truncate(pdf.HelveticaBold, 20, doc.Seller.Name, 300))
+	page.TextRight(colAmtRight, 36, pdf.HelveticaBold, 20, white, "INVOICE")
+	page.TextRight(colAmtRight, 54, pdf.Helvetica, 10, white, doc.InvoiceNumber)
+}
+
+// drawParties draws the seller, the customer and the invoice details, and returns where the item table starts.
+func drawParties(page *pdf.Page, doc model.InvoiceDocument) float64 {
+	y := headerHeight + 28
+
+	left := []string{}
+	left = append(left, doc.Seller.Address...)
+	if doc.Seller.TaxID != "" {
+		left = append(left, "Tax ID: "+doc.Seller.TaxID)
+	}
+	if doc.Seller.Email != "" {
+		left = append(left, doc.Seller.Email)
+	}
+	leftY := y
+	for _, line := range left {
+		page.Text(pageMargin, leftY, pdf.Helvetica, 9, mutedColor, pdf.Truncate(pdf.Helvetica, 9, line, 250))
+		leftY += 13
+	}
+
+	leftY += 12
+	page.Text(pageMargin, leftY, pdf.HelveticaBold, 10, pdf.Black, "Bill to")
+	leftY += 14
+	page.Text(pageMargin, leftY, pdf.Helvetica, 9, pdf.Black, "Customer "+doc.CustomerID.String())
+	leftY += 13
+	page.Text(pageMargin, leftY, pdf.Helvetica, 9, mutedColor, "Payment type "+doc.PaymentTypeID.String())
+	leftY += 13
+
+	details := [][2]string{
+		{"Invoice number", doc.InvoiceNumber},
+		{"Issued", formatDate(&doc.IssuedAt)},
+		{"Order number", doc.OrderNumber},
+		{"Ref code", doc.RefCode},
+		{"Ordered", formatDate(doc.OrderedAt)},
+		{"Status", "PAID"},
+	}
+	rightY := y
+	for _, row := range details {
+		page.Text(330, rightY, pdf.Helvetica, 9, mutedColor, row[0])
+		page.TextRight(colAmtRight, rightY, pdf.HelveticaBold, 9, pdf.Black, row[1])
+		rightY += 14
+	}
+
+	return math.Max(leftY, rightY) + 20
+}
+
+// drawTableHeader draws the column titles of the item table and returns where the first row starts.
+func drawTableHeader(page *pdf.Page, y float64) float64 {
+	page.FillRect(pageMargin-6, y, colAmtRight-pageMargin+12, 20, bandColor)
+	page.Text(colNo, y+14, pdf.HelveticaBold, 9, pdf.Black, "#")
+	page.Text(colItem, y+14, pdf.HelveticaBold, 9, pdf.Black, "Item")
+	page.TextRight(colQtyRight, y+14, pdf.HelveticaBold, 9, pdf.Black, "Qty")
+	page.TextRight(colUnitRight, y+14, pdf.HelveticaBold, 9, pdf.Black, "Unit price")
+	page.TextRight(colAmtRight, y+14, pdf.HelveticaBold, 9, pdf.Black, "Amount")
+	return y + 24
+}
+
+func drawItem(page *pdf.Page, y float64, n int, item model.InvoiceLine, currency string) {
+	nameWidth := colQtyRight - colItem - 40
+	page.Text(colNo, y+12, pdf.Helvetica, 9, mutedColor, strconv.Itoa(n))
+	page.Text(colItem, y+12, pdf.Helvetica, 10, pdf.Black, pdf.Truncate(pdf.Helvetica, 10, item.ProductName, nameWidth))
+	if item.ProductSKU != "" {
+		page.Text(colItem, y+23, pdf.Helvetica, 8, mutedColor, pdf.Truncate(pdf.Helvetica, 8, "SKU "+item.ProductSKU, nameWidth))
+	}
+	page.TextRight(colQtyRight, y+12, pdf.Helvetica, 10, pdf.Black, strconv.Itoa(item.Qty))
+	page.TextRight(colUnitRight, y+12, pdf.Helvetica, 10, pdf.Black, formatMoney(currency, item.UnitPrice))
+	page.TextRight(colAmtRight, y+12, pdf.Helvetica, 10, pdf.Black, formatMoney(currency, item.LineTotal))
+	page.Line(pageMargin-6, y+rowHeight-2, colAmtRight+6, y+rowHeight-2, 0.5, ruleColor)
+}
+
+func drawTotals(page *pdf.Page, y float64, doc model.InvoiceDocument) {
+	const labelX = 330.0
+
+	shipping := "Shipping"
+	if doc.ShippingService != "" {
+		shipping += " (" + doc.ShippingService + ")"
+	}
+
+	page.Text(labelX, y+12, pdf.Helvetica, 10, mutedColor, "Subtotal")
+	page.TextRight(colAmtRight, y+12, pdf.Helvetica, 10, pdf.Black, formatMoney(doc.Currency, doc.Subtotal))
+	page.Text(labelX, y+28, pdf.Helvetica, 10, mutedColor, shipping)
+	page.TextRight(colAmtRight, y+28, pdf.Helvetica, 10, pdf.Black, formatMoney(doc.Currency, doc.ShippingFee))
+	page.Line(labelX, y+36, colAmtRight, y+36, 1, pdf.Black)
+	page.Text(labelX, y+52, pdf.HelveticaBold, 12, pdf.Black, "Total")
+	page.TextRight(colAmtRight, y+52, pdf.HelveticaBold, 12, brandColor, formatMoney(doc.Currency, doc.Total))
+
+	if doc.Payment == nil {
+		return
+	}
+
+	paid := fmt.Sprintf("Paid %s via %s on %s", formatMoney(doc.Currency, doc.Payment.Amount), doc.Payment.Provider, formatDate(doc.Payment.PaidAt))
+	page.Text(pageMargin, y+84, pdf.HelveticaBold, 9, pdf.Black, paid)
+	page.Text(pageMargin, y+98, pdf.Helvetica, 8, mutedColor, "Payment reference "+doc.Payment.IntentID)
+}
+
+func drawFooter(page *pdf.Page, doc model.InvoiceDocument, n, total int) {
+	page.Line(pageMargin, footerTop, colAmtRight, footerTop, 0.5, ruleColor)
+	page.Text(pageMargin, footerTop+16, pdf.Helvetica, 8, mutedColor, "Thank you for shopping with "+doc.Seller.Name+".")
+	page.TextRight(colAmtRight, footerTop+16, pdf.Helvetica, 8, mutedColor, fmt.Sprintf("%s - page %d of %d", doc.InvoiceNumber, n, total))
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(dateLayout)
+}
+
+// formatMoney formats v with thousands separators and two decimals, e.g. IDR 1,250,000.00.
+func formatMoney(currency string, v float64) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+
+	sign := ""
+	if v < 0 && s != "0.00" {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s %s%s.%s", currency, sign, b.String(), frac)
+}
@@ -0,0 +1,78 @@
+package order
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// paymentTimeoutNote is the status log note written for orders cancelled by ExpireUnpaidOrders.
+const paymentTimeoutNote = "payment timeout"
+
+// ExpireUnpaidOrders is a method that cancels pending, unpaid orders older than paymentWindow and returns how many it cancelled.
+// Orders are handled in batches of batchSize, each batch in its own transaction: the orders are locked with SKIP LOCKED,
+// moved to the cancelled status with a "payment timeout" log and event, their pending payments are expired and
+// the release of their stock reservations is queued for the reconciler.
+// Several replicas may run it at the same time without cancelling an order twice.
+func (o *order) ExpireUnpaidOrders(ctx context.Context, paymentWindow time.Duration, batchSize int) (int, error) {
+	logMsgStr := "Usecase:Order - ExpireUnpaidOrders:"
+
+	var expired int
+	for {
+		if err := ctx.Err(); err != nil {
+			return expired, err
+		}
+
+		var batch int
+
+		err := o.txManager.WithTx(ctx, func(repos Repositories) error {
+			orders, err := repos.Order.GetExpiredOrdersForUpdate(paymentWindow, batchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, order := range *orders {
+				if err := repos.Order.UpdateOrderStatus(order.ID, model.OrderStatusCancelled); err != nil {
+					return err
+				}
+
+				if err := logStatusChange(repos, model.OrderItemsLogs{
+					OrderID:    order.ID,
+					RefCode:    order.RefCode,
+					FromStatus: order.Status,
+					ToStatus:   model.OrderStatusCancelled,
+					Notes:      paymentTimeoutNote,
+				}); err != nil {
+					return err
+				}
+
+				if err := repos.Payment.ExpirePendingPayments(order.ID); err != nil {
+					return err
+				}
+
+				if err := repos.Reservation.RequestRelease(order.ID); err != nil {
+					return err
+				}
+			}
+
+			batch = len(*orders)
+
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+
+		expired += batch
+		if batch < batchSize {
+			break
+		}
+	}
+
+	if expired > 0 {
+		o.logger.Info().Msg(fmt.Sprintf("%v Cancelled %d unpaid orders", logMsgStr, expired))
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,87 @@
+package order
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InventoryClient is an interface that holds stock at the inventory service for orders.
+// Calls may be repeated with the same reservation ID without further effect.
+type InventoryClient interface {
+	// Reserve holds stock for every line of the request, or returns model.ErrInsufficientStock and holds nothing.
+	Reserve(ctx context.Context, bReq model.ReserveStockRequest) error
+	// Release puts the stock of a reservation back. Unknown reservations count as released.
+	Release(ctx context.Context, reservationID uuid.UUID) error
+	// Confirm turns a reservation into a sale.
+	Confirm(ctx context.Context, reservationID uuid.UUID) error
+}
+
+// reservationStore is an interface that defines the methods required to keep track of stock reservations.
+type reservationStore interface {
+	CreateReservation(bReq model.StockReservation, pendingTimeout time.Duration) error
+	AttachOrder(reservationID, orderID uuid.UUID) error
+	UpdateReservationStatus(reservationID uuid.UUID, from, to model.ReservationStatus, lastError string) error
+	RequestRelease(orderID uuid.UUID) error
+}
+
+// reserveStock is a method that holds stock for the items of an order that is about to be committed and returns
+// the reservation ID. The reservation is recorded as pending before the inventory service is called, so one
+// whose order is never committed is released by the reconciler even if this process dies.
+// The caller must attach the order to the reservation in the order's transaction, or call releaseStock.
+func (o *order) reserveStock(ctx context.Context, items []model.OrderItem) (uuid.UUID, error) {
+	logMsgStr := "Usecase:Order - reserveStock:"
+
+	reservation := model.StockReservation{
+		ID:    uuid.New(),
+		Lines: make([]model.StockLine, 0, len(items)),
+	}
+	for _, item := range items {
+		reservation.Lines = append(reservation.Lines, model.StockLine{ProductID: item.ProductID, Qty: item.Qty})
+	}
+
+	if err := o.reservationStore.CreateReservation(reservation, o.reservationTimeout); err != nil {
+		return uuid.Nil, err
+	}
+
+	err := o.inventory.Reserve(ctx, model.ReserveStockRequest{
+		ReservationID: reservation.ID,
+		Lines:         reservation.Lines,
+	})
+	if errors.Is(err, model.ErrInsufficientStock) {
+		if err := o.reservationStore.UpdateReservationStatus(reservation.ID, model.ReservationStatusPending, model.ReservationStatusFailed, err.Error()); err != nil {
+			o.logger.Warn().Any("Err", err).Msg(fmt.Sprintf("%v Failed to mark reservation %v failed", logMsgStr, reservation.ID))
+		}
+		return uuid.Nil, err
+	}
+	if err != nil {
+		// The stock may be held even though the call failed.
+		o.releaseStock(ctx, reservation.ID)
+		return uuid.Nil, err
+	}
+
+	return reservation.ID, nil
+}
+
+// releaseStock is a method that releases a pending reservation whose order was not committed.
+// If the inventory service cannot be reached, the reservation stays pending and the reconciler releases it
+// once it times out.
+func (o *order) releaseStock(ctx context.Context, reservationID uuid.UUID) {
+	logMsgStr := "Usecase:Order - releaseStock:"
+
+	// The request may have been cancelled, which is often why the order failed; the release must still go out.
+	ctx = context.WithoutCancel(ctx)
+
+	if err := o.inventory.Release(ctx, reservationID); err != nil {
+		o.logger.Warn().Any("Err", err).Msg(fmt.Sprintf("%v Failed to release reservation %v, leaving it to the reconciler", logMsgStr, reservationID))
+		return
+	}
+
+	if err := o.reservationStore.UpdateReservationStatus(reservationID, model.ReservationStatusPending, model.ReservationStatusReleased, ""); err != nil {
+		o.logger.Warn().Any("Err", err).Msg(fmt.Sprintf("%v Failed to mark reservation %v released", logMsgStr, reservationID))
+	}
+}
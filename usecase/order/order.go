@@ -2,6 +2,12 @@ package order
 
 import (
 	model "cart-order-service/repository/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -10,36 +16,586 @@ import (
 type orderStore interface {
 	CreateOrder(bReq model.Order) (*uuid.UUID, *string, error)
 	CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error)
+	GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error)
+	UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error
+	GetOrderByID(orderID uuid.UUID) (*model.Order, error)
+	GetOrderByRefCode(refCode string) (*model.Order, error)
+	GetOrderStatusLogs(orderID uuid.UUID) (*[]model.OrderItemsLogs, error)
+	ListOrders(bReq model.ListOrdersRequest) (*[]model.Order, error)
+	CreateOrderItems(orderID uuid.UUID, items []model.OrderItem) error
+	GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error)
+	GetExpiredOrdersForUpdate(paymentWindow time.Duration, limit int) (*[]model.Order, error)
 }
 
+// cartStore is an interface that defines the cart methods required to check out a cart.
+type cartStore interface {
+	GetCheckoutCart(userID uuid.UUID, productIDs []uuid.UUID) (*[]model.Cart, error)
+	DeleteCartItems(ids []uuid.UUID) error
+}
+
+// paymentStore is an interface that defines the payment methods required to cancel unpaid orders.
+type paymentStore interface {
+	ExpirePendingPayments(orderID uuid.UUID) error
+}
+
+// outboxStore is an interface that defines the methods required to record order and cart events.
+type outboxStore interface {
+	CreateEvent(event model.OutboxEvent) error
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Cart        cartStore
+	Order       orderStore
+	Payment     paymentStore
+	Outbox      outboxStore
+	Reservation reservationStore
+}
+
+// idGenerator is an interface that hands out unique order numbers and ref codes.
+type idGenerator interface {
+	NextOrderNumber() (string, error)
+	NextRefCode() (string, error)
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+
+	// cursorTimeLayout matches the precision of Postgres TIMESTAMP columns.
+	cursorTimeLayout = "2006-01-02 15:04:05.999999"
+)
+
 type order struct {
-	store  orderStore
-	logger zerolog.Logger
+	store               orderStore
+	txManager           txManager
+	catalog             productCatalog
+	cart                cartService
+	idGenerator         idGenerator
+	shipping            ShippingRateProvider
+	inventory           InventoryClient
+	reservationStore    reservationStore
+	reservationTimeout  time.Duration
+	totalMismatchPolicy string
+	logger              zerolog.Logger
 }
 
-func NewOrder(store orderStore, logger zerolog.Logger) *order {
+// NewOrder is a constructor function that returns a new order instance.
+// reservationTimeout is how long a stock reservation may stay without a committed order before the reconciler
+// releases it. totalMismatchPolicy decides what happens to an order whose submitted total differs from the
+// computed one, either model.TotalMismatchReject or model.TotalMismatchFlag.
+func NewOrder(store orderStore, txManager txManager, catalog productCatalog, cart cartService, idGenerator idGenerator, shipping ShippingRateProvider, inventory InventoryClient, reservationStore reservationStore, reservationTimeout time.Duration, totalMismatchPolicy string, logger zerolog.Logger) *order {
 	return &order{
-		store:  store,
-		logger: logger,
+		store:               store,
+		txManager:           txManager,
+		catalog:             catalog,
+		cart:                cart,
+		idGenerator:         idGenerator,
+		shipping:            shipping,
+		inventory:           inventory,
+		reservationStore:    reservationStore,
+		reservationTimeout:  reservationTimeout,
+		totalMismatchPolicy: totalMismatchPolicy,
+		logger:              logger,
 	}
 }
 
-func (o *order) CreateOrder(bReq model.Order) (*uuid.UUID, error) {
-	orderID, refCode, err := o.store.CreateOrder(bReq)
+// CreateOrder is a method that creates an order together with its initial status log.
+// Line prices are computed from the product catalog and the shipping fee from the quote for the picked service.
+// Stock for the items is reserved before the order is written, and released again if the order cannot be committed;
+// the order, its items, its initial status log and its order.created event are written in one transaction,
+// so an order never exists without its history.
+// A new order is always pending and unpaid, whatever status and is_paid the client sent.
+// It returns model.ErrInsufficientStock if the inventory service cannot hold every item.
+func (o *order) CreateOrder(ctx context.Context, bReq model.Order) (*uuid.UUID, error) {
+	bReq.Status = model.OrderStatusPending
+	bReq.IsPaid = false
+
+	var products []model.OrderProduct
+	if err := json.Unmarshal(bReq.ProductOrder, &products); err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidProductOrder, err)
+	}
+
+	priced, err := o.priceProducts(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	submittedTotal := bReq.TotalPrice
+	if err := o.applyShipping(ctx, &bReq, priced); err != nil {
+		return nil, err
+	}
+
+	flagNote, err := o.checkTotal(submittedTotal, bReq.TotalPrice)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = o.store.CreateOrderItemsLogs(model.OrderItemsLogs{
-		OrderID:    *orderID,
-		RefCode:    *refCode,
-		FromStatus: "",
-		ToStatus:   model.OrderStatusPending,
-		Notes:      "Order created",
+	if err := o.assignIdentifiers(&bReq); err != nil {
+		return nil, err
+	}
+
+	reservationID, err := o.reserveStock(ctx, priced.items)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderID *uuid.UUID
+
+	err = o.txManager.WithTx(ctx, func(repos Repositories) error {
+		id, refCode, err := repos.Order.CreateOrder(bReq)
+		if err != nil {
+			return err
+		}
+
+		if err := repos.Order.CreateOrderItems(*id, priced.items); err != nil {
+			return err
+		}
+
+		if err := repos.Reservation.AttachOrder(reservationID, *id); err != nil {
+			return err
+		}
+
+		if _, err := repos.Order.CreateOrderItemsLogs(model.OrderItemsLogs{
+			OrderID:    *id,
+			RefCode:    *refCode,
+			FromStatus: "",
+			ToStatus:   model.OrderStatusPending,
+			Notes:      createdNote("Order created", flagNote),
+		}); err != nil {
+			return err
+		}
+
+		bReq.ID, bReq.RefCode = *id, *refCode
+		event, err := model.NewOrderCreatedEvent(bReq, priced.items)
+		if err != nil {
+			return err
+		}
+
+		if err := repos.Outbox.CreateEvent(event); err != nil {
+			return err
+		}
+
+		orderID = id
+
+		return nil
 	})
 	if err != nil {
+		o.releaseStock(ctx, reservationID)
 		return nil, err
 	}
 
 	return orderID, nil
 }
+
+// Checkout is a method that turns the user's active cart into a pending order.
+// Line prices are computed from the product catalog and the shipping fee from the quote for the picked service.
+// The cart is read, priced, quoted and its stock reserved before the order transaction starts, so no cart row
+// stays locked while the catalog, shipping and inventory services are called; the stock is released again if
+// the checkout fails. The transaction locks the cart rows again and returns model.ErrCartChanged if they changed
+// in the meantime. The order, its initial status log, the removal of the checked-out cart rows and their events
+// are committed together.
+func (o *order) Checkout(ctx context.Context, bReq model.CheckoutRequest) (*model.CheckoutResponse, error) {
+	var carts *[]model.Cart
+
+	err := o.txManager.WithTx(ctx, func(repos Repositories) error {
+		var err error
+		carts, err = repos.Cart.GetCheckoutCart(bReq.UserID, bReq.ProductID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(*carts) == 0 {
+		return nil, model.ErrEmptyCart
+	}
+
+	var cartIDs []uuid.UUID
+	var products []model.OrderProduct
+	for _, c := range *carts {
+		cartIDs = append(cartIDs, c.ID)
+		products = append(products, model.OrderProduct{
+			ProductID: c.ProductID,
+			Qty:       c.Qty,
+		})
+	}
+
+	priced, err := o.priceProducts(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	newOrder := model.Order{
+		UserID:             bReq.UserID,
+		PaymentTypeID:      bReq.PaymentTypeID,
+		ShippingService:    bReq.ShippingService,
+		ShippingPostalCode: bReq.ShippingPostalCode,
+		Status:             model.OrderStatusPending,
+		IsPaid:             false,
+	}
+	if err := o.applyShipping(ctx, &newOrder, priced); err != nil {
+		return nil, err
+	}
+
+	flagNote, err := o.checkTotal(bReq.TotalPrice, newOrder.TotalPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.assignIdentifiers(&newOrder); err != nil {
+		return nil, err
+	}
+
+	reservationID, err := o.reserveStock(ctx, priced.items)
+	if err != nil {
+		return nil, err
+	}
+
+	var bResp *model.CheckoutResponse
+
+	err = o.txManager.WithTx(ctx, func(repos Repositories) error {
+		locked, err := repos.Cart.GetCheckoutCart(bReq.UserID, bReq.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if !sameCart(*carts, *locked) {
+			return model.ErrCartChanged
+		}
+
+		orderID, refCode, err := repos.Order.CreateOrder(newOrder)
+		if err != nil {
+			return err
+		}
+
+		if err := repos.Order.CreateOrderItems(*orderID, priced.items); err != nil {
+			return err
+		}
+
+		if err := repos.Reservation.AttachOrder(reservationID, *orderID); err != nil {
+			return err
+		}
+
+		if _, err := repos.Order.CreateOrderItemsLogs(model.OrderItemsLogs{
+			OrderID:    *orderID,
+			RefCode:    *refCode,
+			FromStatus: "",
+			ToStatus:   model.OrderStatusPending,
+			Notes:      createdNote("Order created from cart", flagNote),
+		}); err != nil {
+			return err
+		}
+
+		newOrder.ID, newOrder.RefCode = *orderID, *refCode
+		event, err := model.NewOrderCreatedEvent(newOrder, priced.items)
+		if err != nil {
+			return err
+		}
+
+		if err := repos.Outbox.CreateEvent(event); err != nil {
+			return err
+		}
+
+		if err := repos.Cart.DeleteCartItems(cartIDs); err != nil {
+			return err
+		}
+
+		for _, c := range *carts {
+			event, err := model.NewCartItemEvent(model.OutboxEventCartItemRemoved, c.UserID, c.ProductID, 0)
+			if err != nil {
+				return err
+			}
+
+			if err := repos.Outbox.CreateEvent(event); err != nil {
+				return err
+			}
+		}
+
+		bResp = &model.CheckoutResponse{
+			OrderID:         *orderID,
+			OrderNumber:     newOrder.OrderNumber,
+			RefCode:         *refCode,
+			Status:          model.OrderStatusPending,
+			Subtotal:        newOrder.Subtotal,
+			ShippingFee:     newOrder.ShippingFee,
+			ShippingService: newOrder.ShippingService,
+			TotalPrice:      newOrder.TotalPrice,
+			Items:           priced.items,
+			PriceFlagged:    flagNote != "",
+		}
+
+		return nil
+	})
+	if err != nil {
+		o.releaseStock(ctx, reservationID)
+		return nil, err
+	}
+
+	return bResp, nil
+}
+
+// sameCart reports whether the cart rows locked for checkout are the rows that were priced and reserved, with the same quantities.
+func sameCart(read, locked []model.Cart) bool {
+	if len(read) != len(locked) {
+		return false
+	}
+
+	qty := make(map[uuid.UUID]int, len(read))
+	for _, c := range read {
+		qty[c.ID] = c.Qty
+	}
+
+	for _, c := range locked {
+		if q, ok := qty[c.ID]; !ok || q != c.Qty {
+			return false
+		}
+	}
+
+	return true
+}
+
+// assignIdentifiers is a method that gives a new order its order number and ref code.
+// Client-supplied values are replaced.
+func (o *order) assignIdentifiers(bReq *model.Order) error {
+	orderNumber, err := o.idGenerator.NextOrderNumber()
+	if err != nil {
+		return err
+	}
+
+	refCode, err := o.idGenerator.NextRefCode()
+	if err != nil {
+		return err
+	}
+
+	bReq.OrderNumber = orderNumber
+	bReq.RefCode = refCode
+
+	return nil
+}
+
+// logStatusChange writes a status log entry together with its order.status_changed event.
+func logStatusChange(repos Repositories, statusLog model.OrderItemsLogs) error {
+	if _, err := repos.Order.CreateOrderItemsLogs(statusLog); err != nil {
+		return err
+	}
+
+	event, err := model.NewOrderStatusChangedEvent(statusLog)
+	if err != nil {
+		return err
+	}
+
+	return repos.Outbox.CreateEvent(event)
+}
+
+// createdNote appends the price flag note, if any, to the note of an order's initial status log.
+func createdNote(note, flagNote string) string {
+	if flagNote == "" {
+		return note
+	}
+	return note + "; flagged: " + flagNote
+}
+
+// UpdateOrderStatus is a method that moves an order to a new status and records the change in the status log and the outbox.
+// Cancelling an order queues the release of its stock reservation for the reconciler.
+// It returns model.ErrInvalidStatusTransition if the order may not move from its current status to the requested one,
+// if either status belongs to the returns workflow, or if the requested status is set by the payment webhook or
+// the shipment workflow.
+func (o *order) UpdateOrderStatus(ctx context.Context, bReq model.UpdateOrderStatusRequest) (*model.OrderItemsLogs, error) {
+	if !bReq.Status.IsValid() {
+		return nil, model.ErrInvalidOrderStatus
+	}
+
+	var bResp *model.OrderItemsLogs
+
+	err := o.txManager.WithTx(ctx, func(repos Repositories) error {
+		current, err := repos.Order.GetOrderForUpdate(bReq.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if !current.Status.CanTransitionTo(bReq.Status) {
+			return fmt.Errorf("%w: %s to %s", model.ErrInvalidStatusTransition, current.Status, bReq.Status)
+		}
+
+		if current.Status.IsReturnStatus() || bReq.Status.IsReturnStatus() {
+			return fmt.Errorf("%w: %s to %s is managed by the returns workflow", model.ErrInvalidStatusTransition, current.Status, bReq.Status)
+		}
+
+		if bReq.Status.IsPaymentStatus() {
+			return fmt.Errorf("%w: %s to %s is set when the payment succeeds", model.ErrInvalidStatusTransition, current.Status, bReq.Status)
+		}
+
+		if bReq.Status.IsShipmentStatus() {
+			return fmt.Errorf("%w: %s to %s is managed by the shipment workflow", model.ErrInvalidStatusTransition, current.Status, bReq.Status)
+		}
+
+		if err := repos.Order.UpdateOrderStatus(current.ID, bReq.Status); err != nil {
+			return err
+		}
+
+		if bReq.Status == model.OrderStatusCancelled {
+			if err := repos.Reservation.RequestRelease(current.ID); err != nil {
+				return err
+			}
+		}
+
+		statusLog := model.OrderItemsLogs{
+			OrderID:    current.ID,
+			RefCode:    current.RefCode,
+			FromStatus: current.Status,
+			ToStatus:   bReq.Status,
+			Notes:      bReq.Notes,
+		}
+		if err := logStatusChange(repos, statusLog); err != nil {
+			return err
+		}
+
+		bResp = &statusLog
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bResp, nil
+}
+
+// GetOrderByID is a method that retrieves an order with its line items and status timeline.
+func (o *order) GetOrderByID(orderID uuid.UUID) (*model.OrderDetail, error) {
+	result, err := o.store.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.orderDetail(result)
+}
+
+// GetOrderByRefCode is a method that retrieves an order by reference code with its line items and status timeline.
+func (o *order) GetOrderByRefCode(refCode string) (*model.OrderDetail, error) {
+	result, err := o.store.GetOrderByRefCode(refCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.orderDetail(result)
+}
+
+// orderDetail attaches the line items and status timeline of an order.
+func (o *order) orderDetail(result *model.Order) (*model.OrderDetail, error) {
+	items, err := o.store.GetOrderItems(result.ID)
+	if err != nil {
+		return nil, err
+	}
+	result.Items = *items
+
+	timeline, err := o.store.GetOrderStatusLogs(result.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OrderDetail{
+		Order:    *result,
+		Timeline: *timeline,
+	}, nil
+}
+
+// ListOrders is a method that retrieves a page of orders and the cursor of the next page.
+// NextCursor is empty when there are no more orders.
+func (o *order) ListOrders(bReq model.ListOrdersRequest) (*model.ListOrdersResponse, error) {
+	if bReq.SortBy != model.OrderSortByTotalPrice {
+		bReq.SortBy = model.OrderSortByCreatedAt
+	}
+
+	if bReq.SortOrder != model.SortOrderAsc {
+		bReq.SortOrder = model.SortOrderDesc
+	}
+
+	if bReq.Limit <= 0 {
+		bReq.Limit = defaultListLimit
+	}
+	if bReq.Limit > maxListLimit {
+		bReq.Limit = maxListLimit
+	}
+
+	if bReq.Cursor != "" {
+		after, err := decodeOrderCursor(bReq.Cursor, bReq.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		bReq.After = after
+	}
+
+	// Fetch one extra row to know whether another page exists.
+	limit := bReq.Limit
+	bReq.Limit = limit + 1
+
+	result, err := o.store.ListOrders(bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := *result
+	bResp := &model.ListOrdersResponse{}
+
+	if len(orders) > limit {
+		orders = orders[:limit]
+
+		last := orders[len(orders)-1]
+		cursor := model.OrderCursor{
+			ID: last.ID,
+		}
+		if bReq.SortBy == model.OrderSortByTotalPrice {
+			cursor.Value = strconv.FormatFloat(last.TotalPrice, 'g', -1, 64)
+		} else if last.CreatedAt != nil {
+			cursor.Value = last.CreatedAt.Format(cursorTimeLayout)
+		}
+
+		bResp.NextCursor, err = encodeOrderCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bResp.Orders = orders
+
+	return bResp, nil
+}
+
+func encodeOrderCursor(cursor model.OrderCursor) (string, error) {
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeOrderCursor decodes a cursor and checks that its value matches the sort column of the request.
+func decodeOrderCursor(s string, sortBy string) (*model.OrderCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, model.ErrInvalidCursor
+	}
+
+	var cursor model.OrderCursor
+	if err := json.Unmarshal(b, &cursor); err != nil || cursor.Value == "" || cursor.ID == uuid.Nil {
+		return nil, model.ErrInvalidCursor
+	}
+
+	if sortBy == model.OrderSortByTotalPrice {
+		_, err = strconv.ParseFloat(cursor.Value, 64)
+	} else {
+		_, err = time.Parse(cursorTimeLayout, cursor.Value)
+	}
+	if err != nil {
+		return nil, model.ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,94 @@
+package order
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// priceTolerance absorbs rounding differences between client and server totals.
+const priceTolerance = 0.005
+
+// productCatalog is an interface that provides authoritative product data.
+type productCatalog interface {
+	GetProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.Product, error)
+}
+
+// pricedProducts are order lines priced from the catalog.
+type pricedProducts struct {
+	items       []model.OrderItem
+	subtotal    float64
+	weightGrams int
+}
+
+// priceProducts is a method that turns order lines into items priced from the catalog and sums their totals and weights.
+// Each item keeps a snapshot of the product name and SKU.
+func (o *order) priceProducts(ctx context.Context, products []model.OrderProduct) (*pricedProducts, error) {
+	if len(products) == 0 {
+		return nil, model.ErrEmptyOrder
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(products))
+	for _, p := range products {
+		if p.Qty <= 0 {
+			return nil, fmt.Errorf("%w: product %s", model.ErrInvalidQty, p.ProductID)
+		}
+		productIDs = append(productIDs, p.ProductID)
+	}
+
+	catalog, err := o.catalog.GetProducts(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	priced := &pricedProducts{
+		items: make([]model.OrderItem, 0, len(products)),
+	}
+	for _, p := range products {
+		product, ok := catalog[p.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", model.ErrProductNotFound, p.ProductID)
+		}
+
+		if !product.Available {
+			return nil, fmt.Errorf("%w: %s", model.ErrProductUnavailable, p.ProductID)
+		}
+
+		item := model.OrderItem{
+			ProductID:   p.ProductID,
+			Qty:         p.Qty,
+			UnitPrice:   product.Price,
+			LineTotal:   roundPrice(product.Price * float64(p.Qty)),
+			ProductName: product.Name,
+			ProductSKU:  product.SKU,
+		}
+		priced.subtotal += item.LineTotal
+		priced.weightGrams += product.WeightGrams * p.Qty
+		priced.items = append(priced.items, item)
+	}
+	priced.subtotal = roundPrice(priced.subtotal)
+
+	return priced, nil
+}
+
+// checkTotal is a method that compares a submitted total against the computed one.
+// It returns a note for the status log when the order is flagged, and model.ErrTotalPriceMismatch when it is rejected.
+// A zero submitted total means the client left the total to the server.
+func (o *order) checkTotal(submitted, computed float64) (string, error) {
+	if submitted == 0 || math.Abs(submitted-computed) < priceTolerance {
+		return "", nil
+	}
+
+	if o.totalMismatchPolicy == model.TotalMismatchFlag {
+		return fmt.Sprintf("submitted total %.2f differs from computed total %.2f", submitted, computed), nil
+	}
+
+	return "", fmt.Errorf("%w: submitted %.2f, computed %.2f", model.ErrTotalPriceMismatch, submitted, computed)
+}
+
+func roundPrice(v float64) float64 {
+	return math.Round(v*100) / 100
+}
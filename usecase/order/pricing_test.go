@@ -0,0 +1,113 @@
+package order
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// stubCatalog is a productCatalog holding a fixed set of products.
+type stubCatalog map[uuid.UUID]model.Product
+
+func (c stubCatalog) GetProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.Product, error) {
+	result := make(map[uuid.UUID]model.Product, len(productIDs))
+	for _, id := range productIDs {
+		if p, ok := c[id]; ok {
+			result[id] = p
+		}
+	}
+	return result, nil
+}
+
+func TestCheckTotal(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		submitted float64
+		computed  float64
+		wantNote  bool
+		wantErr   error
+	}{
+		{name: "matching total", policy: model.TotalMismatchReject, submitted: 150.25, computed: 150.25},
+		{name: "zero submitted total is left to the server", policy: model.TotalMismatchReject, submitted: 0, computed: 150.25},
+		{name: "difference within rounding tolerance", policy: model.TotalMismatchReject, submitted: 150.254, computed: 150.25},
+		{name: "mismatch rejected", policy: model.TotalMismatchReject, submitted: 140, computed: 150.25, wantErr: model.ErrTotalPriceMismatch},
+		{name: "one cent off rejected", policy: model.TotalMismatchReject, submitted: 150.26, computed: 150.25, wantErr: model.ErrTotalPriceMismatch},
+		{name: "mismatch flagged", policy: model.TotalMismatchFlag, submitted: 140, computed: 150.25, wantNote: true},
+		{name: "matching total not flagged", policy: model.TotalMismatchFlag, submitted: 150.25, computed: 150.25},
+		{name: "unknown policy rejects", policy: "", submitted: 140, computed: 150.25, wantErr: model.ErrTotalPriceMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &order{totalMismatchPolicy: tt.policy}
+
+			note, err := o.checkTotal(tt.submitted, tt.computed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if (note != "") != tt.wantNote {
+				t.Errorf("note = %q, want note: %v", note, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestPriceProducts(t *testing.T) {
+	pen := model.Product{ID: uuid.New(), Name: "Pen", SKU: "PEN", Price: 0.1, Available: true, WeightGrams: 10}
+	book := model.Product{ID: uuid.New(), Name: "Book", SKU: "BOOK", Price: 19.99, Available: true, WeightGrams: 400}
+	gone := model.Product{ID: uuid.New(), Name: "Old", SKU: "OLD", Price: 5, Available: false}
+	o := &order{catalog: stubCatalog{pen.ID: pen, book.ID: book, gone.ID: gone}}
+
+	tests := []struct {
+		name         string
+		products     []model.OrderProduct
+		wantSubtotal float64
+		wantTotals   []float64
+		wantWeight   int
+		wantErr      error
+	}{
+		{
+			name:         "line totals and subtotal are rounded to cents",
+			products:     []model.OrderProduct{{ProductID: pen.ID, Qty: 3}, {ProductID: book.ID, Qty: 3}},
+			wantSubtotal: 60.27,
+			wantTotals:   []float64{0.3, 59.97},
+			wantWeight:   1230,
+		},
+		{name: "no products", wantErr: model.ErrEmptyOrder},
+		{name: "zero qty", products: []model.OrderProduct{{ProductID: pen.ID, Qty: 0}}, wantErr: model.ErrInvalidQty},
+		{name: "negative qty", products: []model.OrderProduct{{ProductID: pen.ID, Qty: -1}}, wantErr: model.ErrInvalidQty},
+		{name: "unknown product", products: []model.OrderProduct{{ProductID: uuid.New(), Qty: 1}}, wantErr: model.ErrProductNotFound},
+		{name: "unavailable product", products: []model.OrderProduct{{ProductID: gone.ID, Qty: 1}}, wantErr: model.ErrProductUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priced, err := o.priceProducts(context.Background(), tt.products)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if priced.subtotal != tt.wantSubtotal {
+				t.Errorf("subtotal = %v, want %v", priced.subtotal, tt.wantSubtotal)
+			}
+
+			if priced.weightGrams != tt.wantWeight {
+				t.Errorf("weight = %d, want %d", priced.weightGrams, tt.wantWeight)
+			}
+
+			for i, item := range priced.items {
+				if item.LineTotal != tt.wantTotals[i] {
+					t.Errorf("items[%d].LineTotal = %v, want %v", i, item.LineTotal, tt.wantTotals[i])
+				}
+			}
+		})
+	}
+}
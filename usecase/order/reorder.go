@@ -0,0 +1,98 @@
+package order
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// cartService is an interface that adds products to carts.
+type cartService interface {
+	AddItems(ctx context.Context, userID uuid.UUID, lines []model.CartLine) (*[]model.Cart, error)
+}
+
+// Reorder is a method that puts the products of a past order back into its owner's cart.
+// Quantities are merged into the cart rows the products already have. Products the catalog no longer has, or no
+// longer sells, are left out and reported with the reason.
+func (o *order) Reorder(ctx context.Context, orderID uuid.UUID) (*model.ReorderResponse, error) {
+	order, err := o.store.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := o.store.GetOrderItems(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// An order may hold several lines of the same product; the cart holds one row per product.
+	var lines []model.ReorderItem
+	index := map[uuid.UUID]int{}
+	productIDs := make([]uuid.UUID, 0, len(*items))
+	for _, item := range *items {
+		if i, ok := index[item.ProductID]; ok {
+			lines[i].Qty += item.Qty
+			continue
+		}
+		index[item.ProductID] = len(lines)
+		lines = append(lines, model.ReorderItem{
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Qty:         item.Qty,
+		})
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	bResp := &model.ReorderResponse{
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		Added:       []model.ReorderItem{},
+		Unavailable: []model.ReorderItem{},
+	}
+	if len(lines) == 0 {
+		return bResp, nil
+	}
+
+	catalog, err := o.catalog.GetProducts(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var cartLines []model.CartLine
+	for _, line := range lines {
+		product, ok := catalog[line.ProductID]
+		switch {
+		case !ok:
+			line.Reason = model.ReorderReasonNotFound
+		case !product.Available:
+			line.Reason = model.ReorderReasonUnavailable
+			line.ProductName = product.Name
+		default:
+			line.ProductName = product.Name
+			cartLines = append(cartLines, model.CartLine{ProductID: line.ProductID, Qty: line.Qty})
+			bResp.Added = append(bResp.Added, line)
+			continue
+		}
+		bResp.Unavailable = append(bResp.Unavailable, line)
+	}
+
+	if len(cartLines) == 0 {
+		return bResp, nil
+	}
+
+	carts, err := o.cart.AddItems(ctx, order.UserID, cartLines)
+	if err != nil {
+		return nil, err
+	}
+
+	cartQty := make(map[uuid.UUID]int, len(*carts))
+	for _, c := range *carts {
+		cartQty[c.ProductID] = c.Qty
+	}
+	for i := range bResp.Added {
+		bResp.Added[i].CartQty = cartQty[bResp.Added[i].ProductID]
+	}
+
+	return bResp, nil
+}
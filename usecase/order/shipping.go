@@ -0,0 +1,102 @@
+package order
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ShippingRateProvider is an interface that quotes the fee of sending a parcel, one quote per service level offered
+// for the destination.
+type ShippingRateProvider interface {
+	Quote(ctx context.Context, bReq model.ShippingQuoteRequest) ([]model.ShippingQuote, error)
+}
+
+// QuoteShipping is a method that prices the products of an order that has not been placed yet and returns the
+// shipping service levels the client may pick from at checkout.
+func (o *order) QuoteShipping(ctx context.Context, bReq model.QuoteShippingRequest) (*model.QuoteShippingResponse, error) {
+	products := bReq.Products
+	if len(products) == 0 && bReq.UserID != uuid.Nil {
+		err := o.txManager.WithTx(ctx, func(repos Repositories) error {
+			carts, err := repos.Cart.GetCheckoutCart(bReq.UserID, bReq.ProductID)
+			if err != nil {
+				return err
+			}
+
+			for _, c := range *carts {
+				products = append(products, model.OrderProduct{
+					ProductID: c.ProductID,
+					Qty:       c.Qty,
+				})
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(products) == 0 {
+			return nil, model.ErrEmptyCart
+		}
+	}
+
+	priced, err := o.priceProducts(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, err := o.shipping.Quote(ctx, model.ShippingQuoteRequest{
+		PostalCode:  bReq.PostalCode,
+		WeightGrams: priced.weightGrams,
+		Subtotal:    priced.subtotal,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.QuoteShippingResponse{
+		Subtotal:    priced.subtotal,
+		WeightGrams: priced.weightGrams,
+		Quotes:      quotes,
+	}, nil
+}
+
+// applyShipping is a method that prices the shipping service picked for an order and sets the order's subtotal,
+// shipping fee and total. An order without a service has no shipping fee.
+// It returns model.ErrShippingServiceNotFound if the service is not quoted for the destination.
+func (o *order) applyShipping(ctx context.Context, bReq *model.Order, priced *pricedProducts) error {
+	bReq.Subtotal = priced.subtotal
+	bReq.ShippingFee = 0
+	bReq.TotalPrice = priced.subtotal
+
+	if bReq.ShippingService == "" {
+		bReq.ShippingPostalCode = ""
+		return nil
+	}
+
+	if bReq.ShippingPostalCode == "" {
+		return model.ErrShippingPostalCode
+	}
+
+	quotes, err := o.shipping.Quote(ctx, model.ShippingQuoteRequest{
+		PostalCode:  bReq.ShippingPostalCode,
+		WeightGrams: priced.weightGrams,
+		Subtotal:    priced.subtotal,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, quote := range quotes {
+		if quote.Service == bReq.ShippingService {
+			bReq.ShippingFee = quote.Fee
+			bReq.TotalPrice = roundPrice(priced.subtotal + quote.Fee)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s to %s", model.ErrShippingServiceNotFound, bReq.ShippingService, bReq.ShippingPostalCode)
+}
@@ -0,0 +1,55 @@
+package order
+
+import (
+	"cart-order-service/client/shipping"
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestApplyShipping(t *testing.T) {
+	rates, err := shipping.NewTable(shipping.TableConfig{
+		DefaultZone: "other",
+		Services: []shipping.ServiceConfig{
+			{Code: "REG", Name: "Regular", Fees: map[string]shipping.FeeConfig{"other": {BaseFee: 10, PerKg: 2.5}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTable: %v", err)
+	}
+	o := &order{shipping: rates}
+	priced := &pricedProducts{subtotal: 100.1, weightGrams: 2500}
+
+	tests := []struct {
+		name      string
+		service   string
+		postal    string
+		wantFee   float64
+		wantTotal float64
+		wantErr   error
+	}{
+		{name: "no service", wantTotal: 100.1},
+		{name: "quoted service", service: "REG", postal: "80361", wantFee: 15, wantTotal: 115.1},
+		{name: "unknown service level", service: "SAME", postal: "80361", wantErr: model.ErrShippingServiceNotFound},
+		{name: "missing postal code", service: "REG", wantErr: model.ErrShippingPostalCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bReq := model.Order{ShippingService: tt.service, ShippingPostalCode: tt.postal}
+
+			err := o.applyShipping(context.Background(), &bReq, priced)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if bReq.ShippingFee != tt.wantFee || bReq.TotalPrice != tt.wantTotal || bReq.Subtotal != priced.subtotal {
+				t.Errorf("fee %v, total %v, subtotal %v, want fee %v, total %v", bReq.ShippingFee, bReq.TotalPrice, bReq.Subtotal, tt.wantFee, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package outbox
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Publisher is an interface that delivers outbox events to their consumers.
+type Publisher interface {
+	// Publish delivers an event. An error means the event was not delivered and is retried later.
+	Publish(ctx context.Context, event model.OutboxEvent) error
+}
+
+// outboxStore is an interface that defines the methods required for relaying outbox events.
+type outboxStore interface {
+	ClaimEvents(leaseID uuid.UUID, limit int, lease time.Duration) (*[]model.OutboxEvent, error)
+	MarkPublished(eventID, leaseID uuid.UUID) error
+	MarkFailed(eventID, leaseID uuid.UUID, lastError string, retryIn time.Duration) error
+	DeadLetter(eventID, leaseID uuid.UUID, lastError string) error
+}
+
+// Config holds how the relay batches and retries events.
+type Config struct {
+	BatchSize int
+	// Lease is how long a claimed batch is reserved for the relay that claimed it. It must outlast publishing
+	// the batch; events not reported back by then are claimed again by the next run.
+	Lease time.Duration
+	// MaxAttempts is how many times an event is published before it is moved to the dead-letter table.
+	MaxAttempts int
+	// RetryBackoff is how long an event is held back after its first failed publish.
+	// It doubles with every further failure, up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+}
+
+type relay struct {
+	store     outboxStore
+	publisher Publisher
+	cfg       Config
+	logger    zerolog.Logger
+}
+
+// NewRelay is a constructor function that returns a new relay instance.
+func NewRelay(store outboxStore, publisher Publisher, cfg Config, logger zerolog.Logger) *relay {
+	return &relay{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// RelayEvents is a method that publishes the pending outbox events and returns how many it published.
+// Events are claimed in batches of cfg.BatchSize: each batch is leased in its own short transaction and
+// published after it commits, so no row lock is held while a publish is in flight. A failed event is held
+// back with an exponential backoff and moved to the dead-letter table after cfg.MaxAttempts failures.
+// The events of an aggregate are published in order, so a failing event holds back the later events of its
+// aggregate until it is published or dead-lettered.
+// Several replicas may run it at the same time; an event is published at least once, and again if its lease
+// runs out before it is marked published.
+func (r *relay) RelayEvents(ctx context.Context) (int, error) {
+	logMsgStr := "Usecase:Outbox - RelayEvents:"
+
+	var published, failed int
+	for {
+		if err := ctx.Err(); err != nil {
+			return published, err
+		}
+
+		leaseID := uuid.New()
+		leasedUntil := time.Now().Add(r.cfg.Lease)
+
+		events, err := r.store.ClaimEvents(leaseID, r.cfg.BatchSize, r.cfg.Lease)
+		if err != nil {
+			return published, err
+		}
+
+		if len(*events) == 0 {
+			break
+		}
+
+		for _, event := range *events {
+			if time.Now().After(leasedUntil) {
+				// The rest of the batch may already be claimed by another relay; leave it to the next run.
+				r.logger.Warn().Msg(fmt.Sprintf("%v Lease %v ran out before the batch was published", logMsgStr, leaseID))
+				break
+			}
+
+			if err := r.publisher.Publish(ctx, event); err != nil {
+				if ctx.Err() != nil {
+					return published, ctx.Err()
+				}
+
+				failed++
+				err = r.fail(event, leaseID, err)
+				if err != nil && !errors.Is(err, model.ErrOutboxLeaseLost) {
+					return published, err
+				}
+				continue
+			}
+
+			err := r.store.MarkPublished(event.ID, leaseID)
+			if errors.Is(err, model.ErrOutboxLeaseLost) {
+				r.logger.Warn().Msg(fmt.Sprintf("%v Lease on %v event %v was lost, it may be published again", logMsgStr, event.EventType, event.ID))
+				continue
+			}
+			if err != nil {
+				return published, err
+			}
+			published++
+		}
+	}
+
+	if published > 0 || failed > 0 {
+		r.logger.Info().Msg(fmt.Sprintf("%v Published %d events, %d failed", logMsgStr, published, failed))
+	}
+
+	return published, nil
+}
+
+// fail records a failed publish of event, or moves the event to the dead-letter table if it has no attempts left.
+func (r *relay) fail(event model.OutboxEvent, leaseID uuid.UUID, publishErr error) error {
+	logMsgStr := "Usecase:Outbox - RelayEvents:"
+
+	attempts := event.Attempts + 1
+	if attempts >= r.cfg.MaxAttempts {
+		r.logger.Error().Any("Err", publishErr).Msg(fmt.Sprintf("%v Dead-lettering %v event %v after %d attempts", logMsgStr, event.EventType, event.ID, attempts))
+		return r.store.DeadLetter(event.ID, leaseID, publishErr.Error())
+	}
+
+	retryIn := r.backoff(attempts)
+	r.logger.Warn().Any("Err", publishErr).Msg(fmt.Sprintf("%v Failed to publish %v event %v, retrying in %v", logMsgStr, event.EventType, event.ID, retryIn))
+	return r.store.MarkFailed(event.ID, leaseID, publishErr.Error(), retryIn)
+}
+
+// backoff returns how long an event is held back after its attempts-th failed publish.
+func (r *relay) backoff(attempts int) time.Duration {
+	retryIn := r.cfg.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		retryIn *= 2
+		if retryIn >= r.cfg.MaxBackoff {
+			return r.cfg.MaxBackoff
+		}
+	}
+
+	return min(retryIn, r.cfg.MaxBackoff)
+}
@@ -0,0 +1,286 @@
+package outbox
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// memEvent is an outbox row held by memOutboxStore.
+type memEvent struct {
+	event       model.OutboxEvent
+	leaseID     uuid.UUID
+	retryIn     time.Duration
+	published   bool
+	deadLetter  string
+	unavailable bool
+}
+
+// memOutboxStore is an in-memory outboxStore. A failed event stays unavailable until the test makes it due again.
+type memOutboxStore struct {
+	events []*memEvent
+	// stealLease, when set, makes another relay take over every event right after it is claimed.
+	stealLease bool
+}
+
+func (s *memOutboxStore) add(n int, aggregateID uuid.UUID) {
+	for i := 0; i < n; i++ {
+		s.events = append(s.events, &memEvent{event: model.OutboxEvent{
+			ID:            uuid.New(),
+			AggregateType: model.OutboxAggregateOrder,
+			AggregateID:   aggregateID,
+			EventType:     model.OutboxEventOrderStatusChanged,
+		}})
+	}
+}
+
+func (s *memOutboxStore) ClaimEvents(leaseID uuid.UUID, limit int, lease time.Duration) (*[]model.OutboxEvent, error) {
+	events := []model.OutboxEvent{}
+	blocked := map[uuid.UUID]bool{}
+	for _, e := range s.events {
+		if e.published || e.deadLetter != "" {
+			continue
+		}
+		if blocked[e.event.AggregateID] || e.unavailable || e.leaseID != uuid.Nil || len(events) == limit {
+			blocked[e.event.AggregateID] = true
+			continue
+		}
+		blocked[e.event.AggregateID] = true
+
+		e.leaseID = leaseID
+		if s.stealLease {
+			e.leaseID = uuid.New()
+		}
+		events = append(events, e.event)
+	}
+
+	return &events, nil
+}
+
+func (s *memOutboxStore) leased(eventID, leaseID uuid.UUID) (*memEvent, error) {
+	for _, e := range s.events {
+		if e.event.ID == eventID && e.leaseID == leaseID && !e.published && e.deadLetter == "" {
+			return e, nil
+		}
+	}
+	return nil, model.ErrOutboxLeaseLost
+}
+
+func (s *memOutboxStore) MarkPublished(eventID, leaseID uuid.UUID) error {
+	e, err := s.leased(eventID, leaseID)
+	if err != nil {
+		return err
+	}
+	e.event.Attempts++
+	e.published = true
+	e.leaseID = uuid.Nil
+	return nil
+}
+
+func (s *memOutboxStore) MarkFailed(eventID, leaseID uuid.UUID, lastError string, retryIn time.Duration) error {
+	e, err := s.leased(eventID, leaseID)
+	if err != nil {
+		return err
+	}
+	e.event.Attempts++
+	e.event.LastError = &lastError
+	e.retryIn = retryIn
+	e.unavailable = true
+	e.leaseID = uuid.Nil
+	return nil
+}
+
+func (s *memOutboxStore) DeadLetter(eventID, leaseID uuid.UUID, lastError string) error {
+	e, err := s.leased(eventID, leaseID)
+	if err != nil {
+		return err
+	}
+	e.event.Attempts++
+	e.deadLetter = lastError
+	e.leaseID = uuid.Nil
+	return nil
+}
+
+// makeDue makes every failed event due for publishing again.
+func (s *memOutboxStore) makeDue() {
+	for _, e := range s.events {
+		e.unavailable = false
+	}
+}
+
+// stubPublisher records the events it publishes and fails those listed in fail.
+type stubPublisher struct {
+	store     *memOutboxStore
+	published []uuid.UUID
+	fail      map[uuid.UUID]bool
+	// leaseHeld reports whether every event was still leased when it was published.
+	leaseHeld bool
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event model.OutboxEvent) error {
+	for _, e := range p.store.events {
+		if e.event.ID == event.ID && e.leaseID == uuid.Nil {
+			p.leaseHeld = false
+		}
+	}
+
+	if p.fail[event.ID] {
+		return errors.New("webhook responded with 503")
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func newRelay(store *memOutboxStore, cfg Config) (*relay, *stubPublisher) {
+	publisher := &stubPublisher{store: store, fail: map[uuid.UUID]bool{}, leaseHeld: true}
+	if cfg.Lease == 0 {
+		cfg.Lease = time.Minute
+	}
+	return NewRelay(store, publisher, cfg, zerolog.Nop()), publisher
+}
+
+func TestRelayEventsPublishesEveryDueEvent(t *testing.T) {
+	store := &memOutboxStore{}
+	store.add(3, uuid.New())
+	store.add(1, uuid.New())
+	r, publisher := newRelay(store, Config{BatchSize: 1, MaxAttempts: 3, RetryBackoff: time.Second, MaxBackoff: time.Minute})
+
+	published, err := r.RelayEvents(context.Background())
+	if err != nil {
+		t.Fatalf("RelayEvents: %v", err)
+	}
+
+	if published != 4 {
+		t.Errorf("published %d events, want 4", published)
+	}
+	if !publisher.leaseHeld {
+		t.Error("an event was published without a lease")
+	}
+	for i, id := range publisher.published[:3] {
+		if id != store.events[i].event.ID {
+			t.Errorf("event %d of the aggregate was published out of order", i)
+		}
+	}
+	for _, e := range store.events {
+		if !e.published || e.leaseID != uuid.Nil {
+			t.Errorf("event %v: published %v, lease %v, want published and released", e.event.ID, e.published, e.leaseID)
+		}
+	}
+}
+
+func TestRelayEventsBacksOffAndDeadLetters(t *testing.T) {
+	store := &memOutboxStore{}
+	aggregateID := uuid.New()
+	store.add(2, aggregateID)
+	failing := store.events[0]
+
+	r, publisher := newRelay(store, Config{BatchSize: 10, MaxAttempts: 4, RetryBackoff: 10 * time.Second, MaxBackoff: 30 * time.Second})
+	publisher.fail[failing.event.ID] = true
+
+	wantBackoff := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second}
+	for i, want := range wantBackoff {
+		published, err := r.RelayEvents(context.Background())
+		if err != nil {
+			t.Fatalf("run %d: RelayEvents: %v", i+1, err)
+		}
+		if published != 0 {
+			t.Errorf("run %d: published %d events, want the failing event to hold back its aggregate", i+1, published)
+		}
+		if failing.event.Attempts != i+1 || failing.retryIn != want {
+			t.Errorf("run %d: attempts %d, retry in %v, want %d and %v", i+1, failing.event.Attempts, failing.retryIn, i+1, want)
+		}
+		if failing.leaseID != uuid.Nil {
+			t.Errorf("run %d: the failed event is still leased", i+1)
+		}
+		store.makeDue()
+	}
+
+	published, err := r.RelayEvents(context.Background())
+	if err != nil {
+		t.Fatalf("last run: RelayEvents: %v", err)
+	}
+	if failing.deadLetter == "" || failing.event.Attempts != 4 {
+		t.Errorf("failing event: dead letter %q after %d attempts, want dead-lettered after 4", failing.deadLetter, failing.event.Attempts)
+	}
+	if published != 1 || !store.events[1].published {
+		t.Errorf("published %d events, want the next event of the aggregate published after the dead letter", published)
+	}
+}
+
+func TestRelayEventsSkipsLostLeases(t *testing.T) {
+	store := &memOutboxStore{stealLease: true}
+	store.add(2, uuid.New())
+	store.add(1, uuid.New())
+	r, publisher := newRelay(store, Config{BatchSize: 10, MaxAttempts: 1, RetryBackoff: time.Second, MaxBackoff: time.Minute})
+	publisher.fail[store.events[2].event.ID] = true
+
+	published, err := r.RelayEvents(context.Background())
+	if err != nil {
+		t.Fatalf("RelayEvents: %v", err)
+	}
+
+	if published != 0 {
+		t.Errorf("published %d events, want none marked published by a relay that lost its lease", published)
+	}
+	for _, e := range store.events {
+		if e.published || e.deadLetter != "" {
+			t.Errorf("event %v was updated without its lease", e.event.ID)
+		}
+	}
+}
+
+func TestRelayEventsStopsWhenTheLeaseRunsOut(t *testing.T) {
+	store := &memOutboxStore{}
+	store.add(1, uuid.New())
+	r, publisher := newRelay(store, Config{BatchSize: 10, Lease: -time.Second, MaxAttempts: 3, RetryBackoff: time.Second, MaxBackoff: time.Minute})
+
+	published, err := r.RelayEvents(context.Background())
+	if err != nil {
+		t.Fatalf("RelayEvents: %v", err)
+	}
+
+	if published != 0 || len(publisher.published) != 0 {
+		t.Errorf("published %d events after the lease ran out, want none", len(publisher.published))
+	}
+	if store.events[0].leaseID == uuid.Nil {
+		t.Error("the event was released, want it left to be claimed again once the lease runs out")
+	}
+}
+
+func TestRelayEventsStopsWhenCancelled(t *testing.T) {
+	store := &memOutboxStore{}
+	store.add(1, uuid.New())
+	r, _ := newRelay(store, Config{BatchSize: 10, MaxAttempts: 3, RetryBackoff: time.Second, MaxBackoff: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.RelayEvents(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r, _ := newRelay(&memOutboxStore{}, Config{RetryBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		{attempts: 40, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package payment
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// amountTolerance absorbs rounding differences between gateway and order amounts.
+const amountTolerance = 0.005
+
+// PaymentGateway is an interface that collects payments for orders through a payment provider.
+type PaymentGateway interface {
+	// Provider returns the name recorded on payments made through the gateway.
+	Provider() string
+	// CreateIntent asks the provider to collect payment for an order.
+	CreateIntent(ctx context.Context, bReq model.PaymentIntentRequest) (*model.PaymentIntent, error)
+	// ParseWebhook verifies the signature of a provider webhook and decodes its payment event.
+	ParseWebhook(header http.Header, body []byte) (*model.PaymentEvent, error)
+}
+
+// orderStore is an interface that defines the order methods required to take payments.
+type orderStore interface {
+	GetOrderByID(orderID uuid.UUID) (*model.Order, error)
+	GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error)
+	UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error
+	MarkOrderPaid(orderID uuid.UUID) error
+	CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error)
+}
+
+// paymentStore is an interface that defines the methods required for recording payments.
+type paymentStore interface {
+	CreatePayment(bReq model.Payment) (*uuid.UUID, error)
+	GetPaymentsByOrderID(orderID uuid.UUID) (*[]model.Payment, error)
+	GetPaymentByIntentIDForUpdate(provider, intentID string) (*model.Payment, error)
+	UpdatePaymentStatus(paymentID uuid.UUID, status model.PaymentStatus) error
+}
+
+// outboxStore is an interface that defines the methods required to record order events.
+type outboxStore interface {
+	CreateEvent(event model.OutboxEvent) error
+}
+
+// reservationStore is an interface that defines the reservation methods required to take payments.
+type reservationStore interface {
+	RequestConfirm(orderID uuid.UUID) error
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Order       orderStore
+	Payment     paymentStore
+	Outbox      outboxStore
+	Reservation reservationStore
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+type payment struct {
+	orderStore   orderStore
+	paymentStore paymentStore
+	txManager    txManager
+	gateway      PaymentGateway
+	currency     string
+	logger       zerolog.Logger
+}
+
+// NewPayment is a constructor function that returns a new payment instance.
+func NewPayment(orderStore orderStore, paymentStore paymentStore, txManager txManager, gateway PaymentGateway, currency string, logger zerolog.Logger) *payment {
+	return &payment{
+		orderStore:   orderStore,
+		paymentStore: paymentStore,
+		txManager:    txManager,
+		gateway:      gateway,
+		currency:     currency,
+		logger:       logger,
+	}
+}
+
+// CreatePaymentIntent is a method that starts a payment for a pending, unpaid order, or returns the payment
+// already pending for it, so a repeated request does not open a second intent.
+// It returns model.ErrOrderNotPayable if the order is paid already or past the pending status.
+func (p *payment) CreatePaymentIntent(ctx context.Context, orderID uuid.UUID) (*model.Payment, error) {
+	order, err := p.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.IsPaid || order.Status != model.OrderStatusPending {
+		return nil, fmt.Errorf("%w: order is %s", model.ErrOrderNotPayable, order.Status)
+	}
+
+	payments, err := p.paymentStore.GetPaymentsByOrderID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := openPayment(*payments)
+	if err != nil || pending != nil {
+		return pending, err
+	}
+
+	// Concurrent requests send the same key, so the gateway opens one intent for them.
+	intent, err := p.gateway.CreateIntent(ctx, model.PaymentIntentRequest{
+		IdempotencyKey: paymentIdempotencyKey(order.ID, len(*payments)+1),
+		OrderID:        order.ID,
+		RefCode:        order.RefCode,
+		Amount:         order.TotalPrice,
+		Currency:       p.currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bReq := model.Payment{
+		OrderID:     order.ID,
+		Provider:    p.gateway.Provider(),
+		IntentID:    intent.IntentID,
+		Amount:      order.TotalPrice,
+		Currency:    p.currency,
+		Status:      model.PaymentStatusPending,
+		CheckoutURL: intent.CheckoutURL,
+	}
+
+	id, err := p.paymentStore.CreatePayment(bReq)
+	if errors.Is(err, model.ErrPaymentPending) {
+		// A concurrent request recorded its payment first.
+		payments, err := p.paymentStore.GetPaymentsByOrderID(order.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		pending, err := openPayment(*payments)
+		if err != nil || pending != nil {
+			return pending, err
+		}
+		return nil, model.ErrPaymentPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	bReq.ID = *id
+
+	return &bReq, nil
+}
+
+// HandleWebhook is a method that applies a signed gateway webhook.
+// A succeeded payment marks the order paid, moves it to the paid status and records the change in the status log
+// and the outbox, and queues the confirmation of its stock reservation, all in one transaction. Repeated webhooks for the same status are ignored.
+func (p *payment) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	logMsgStr := "Usecase:Payment - HandleWebhook:"
+
+	event, err := p.gateway.ParseWebhook(header, body)
+	if err != nil {
+		return err
+	}
+
+	return p.txManager.WithTx(ctx, func(repos Repositories) error {
+		payment, err := repos.Payment.GetPaymentByIntentIDForUpdate(p.gateway.Provider(), event.IntentID)
+		if err != nil {
+			return err
+		}
+
+		// Succeeded is final; a failed payment may still succeed if the customer retries at the gateway.
+		if payment.Status == event.Status || payment.Status == model.PaymentStatusSucceeded {
+			p.logger.Info().Msg(fmt.Sprintf("%v Ignoring %v event for %v payment %v", logMsgStr, event.Status, payment.Status, payment.ID))
+			return nil
+		}
+
+		switch event.Status {
+		case model.PaymentStatusFailed:
+			return repos.Payment.UpdatePaymentStatus(payment.ID, model.PaymentStatusFailed)
+		case model.PaymentStatusSucceeded:
+		default:
+			return fmt.Errorf("%w: unknown status %q", model.ErrInvalidWebhookPayload, event.Status)
+		}
+
+		if math.Abs(event.Amount-payment.Amount) >= amountTolerance {
+			return fmt.Errorf("%w: paid %.2f, expected %.2f", model.ErrPaymentAmountMismatch, event.Amount, payment.Amount)
+		}
+
+		if err := repos.Payment.UpdatePaymentStatus(payment.ID, model.PaymentStatusSucceeded); err != nil {
+			return err
+		}
+
+		order, err := repos.Order.GetOrderForUpdate(payment.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if order.IsPaid || !order.Status.CanTransitionTo(model.OrderStatusPaid) {
+			// The money is captured, but the order moved on without it (e.g. it was cancelled).
+			// Keep the payment on record so it can be refunded.
+			p.logger.Warn().Msg(fmt.Sprintf("%v Payment %v succeeded for order %v in status %v", logMsgStr, payment.ID, order.ID, order.Status))
+			return nil
+		}
+
+		if err := repos.Order.MarkOrderPaid(order.ID); err != nil {
+			return err
+		}
+
+		if err := repos.Order.UpdateOrderStatus(order.ID, model.OrderStatusPaid); err != nil {
+			return err
+		}
+
+		if err := repos.Reservation.RequestConfirm(order.ID); err != nil {
+			return err
+		}
+
+		statusLog := model.OrderItemsLogs{
+			OrderID:    order.ID,
+			RefCode:    order.RefCode,
+			FromStatus: order.Status,
+			ToStatus:   model.OrderStatusPaid,
+			Notes:      fmt.Sprintf("Payment %s received via %s", payment.IntentID, payment.Provider),
+		}
+		if _, err := repos.Order.CreateOrderItemsLogs(statusLog); err != nil {
+			return err
+		}
+
+		event, err := model.NewOrderStatusChangedEvent(statusLog)
+		if err != nil {
+			return err
+		}
+
+		return repos.Outbox.CreateEvent(event)
+	})
+}
+
+// openPayment returns the pending payment among payments, if any. It returns model.ErrOrderNotPayable if one of
+// them succeeded already.
+func openPayment(payments []model.Payment) (*model.Payment, error) {
+	var pending *model.Payment
+	for i, payment := range payments {
+		switch payment.Status {
+		case model.PaymentStatusSucceeded:
+			return nil, fmt.Errorf("%w: payment %s succeeded already", model.ErrOrderNotPayable, payment.IntentID)
+		case model.PaymentStatusPending:
+			pending = &payments[i]
+		}
+	}
+
+	return pending, nil
+}
+
+// paymentIdempotencyKey returns the key the gateway deduplicates the attempt-th payment intent of an order by.
+func paymentIdempotencyKey(orderID uuid.UUID, attempt int) string {
+	return fmt.Sprintf("order-%s-%d", orderID, attempt)
+}
@@ -0,0 +1,216 @@
+package payment
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// memOrderStore is an in-memory orderStore holding a single order.
+type memOrderStore struct {
+	order model.Order
+}
+
+func (s *memOrderStore) GetOrderByID(orderID uuid.UUID) (*model.Order, error) {
+	if orderID != s.order.ID {
+		return nil, model.ErrOrderNotFound
+	}
+	order := s.order
+	return &order, nil
+}
+
+func (s *memOrderStore) GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error) {
+	return s.GetOrderByID(orderID)
+}
+
+func (s *memOrderStore) UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error {
+	s.order.Status = status
+	return nil
+}
+
+func (s *memOrderStore) MarkOrderPaid(orderID uuid.UUID) error {
+	s.order.IsPaid = true
+	return nil
+}
+
+func (s *memOrderStore) CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error) {
+	return &bReq.RefCode, nil
+}
+
+// memPaymentStore is an in-memory paymentStore that allows one pending payment per order, like the
+// idx_payments_pending_order index.
+type memPaymentStore struct {
+	payments []model.Payment
+	// beforeCreate, when set, runs once before the next payment is recorded, e.g. to record a concurrent one.
+	beforeCreate func()
+}
+
+func (s *memPaymentStore) CreatePayment(bReq model.Payment) (*uuid.UUID, error) {
+	if s.beforeCreate != nil {
+		before := s.beforeCreate
+		s.beforeCreate = nil
+		before()
+	}
+
+	for _, p := range s.payments {
+		if p.OrderID == bReq.OrderID && p.Status == model.PaymentStatusPending && bReq.Status == model.PaymentStatusPending {
+			return nil, model.ErrPaymentPending
+		}
+	}
+
+	bReq.ID = uuid.New()
+	s.payments = append(s.payments, bReq)
+	return &bReq.ID, nil
+}
+
+func (s *memPaymentStore) GetPaymentsByOrderID(orderID uuid.UUID) (*[]model.Payment, error) {
+	payments := []model.Payment{}
+	for _, p := range s.payments {
+		if p.OrderID == orderID {
+			payments = append(payments, p)
+		}
+	}
+	return &payments, nil
+}
+
+func (s *memPaymentStore) GetPaymentByIntentIDForUpdate(provider, intentID string) (*model.Payment, error) {
+	for _, p := range s.payments {
+		if p.Provider == provider && p.IntentID == intentID {
+			return &p, nil
+		}
+	}
+	return nil, model.ErrPaymentNotFound
+}
+
+func (s *memPaymentStore) UpdatePaymentStatus(paymentID uuid.UUID, status model.PaymentStatus) error {
+	for i := range s.payments {
+		if s.payments[i].ID == paymentID {
+			s.payments[i].Status = status
+			return nil
+		}
+	}
+	return model.ErrPaymentNotFound
+}
+
+// stubGateway opens one intent per idempotency key.
+type stubGateway struct {
+	keys []string
+}
+
+func (g *stubGateway) Provider() string {
+	return "stub"
+}
+
+func (g *stubGateway) CreateIntent(ctx context.Context, bReq model.PaymentIntentRequest) (*model.PaymentIntent, error) {
+	g.keys = append(g.keys, bReq.IdempotencyKey)
+	return &model.PaymentIntent{IntentID: "pi_" + bReq.IdempotencyKey, CheckoutURL: "https://pay.example/" + bReq.IdempotencyKey}, nil
+}
+
+func (g *stubGateway) ParseWebhook(header http.Header, body []byte) (*model.PaymentEvent, error) {
+	return nil, model.ErrInvalidWebhookPayload
+}
+
+func newPaymentFixture() (*payment, *memOrderStore, *memPaymentStore, *stubGateway) {
+	orders := &memOrderStore{order: model.Order{
+		ID:         uuid.New(),
+		RefCode:    "REF-1",
+		TotalPrice: 110,
+		Status:     model.OrderStatusPending,
+	}}
+	payments := &memPaymentStore{}
+	gateway := &stubGateway{}
+
+	return NewPayment(orders, payments, nil, gateway, "IDR", zerolog.Nop()), orders, payments, gateway
+}
+
+func TestCreatePaymentIntentReturnsThePendingPayment(t *testing.T) {
+	p, orders, payments, gateway := newPaymentFixture()
+
+	first, err := p.CreatePaymentIntent(context.Background(), orders.order.ID)
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent: %v", err)
+	}
+	second, err := p.CreatePaymentIntent(context.Background(), orders.order.ID)
+	if err != nil {
+		t.Fatalf("second CreatePaymentIntent: %v", err)
+	}
+
+	if second.ID != first.ID || second.IntentID != first.IntentID {
+		t.Errorf("second payment = %+v, want the pending %+v", second, first)
+	}
+	if len(payments.payments) != 1 || len(gateway.keys) != 1 {
+		t.Errorf("%d payments and %d intents, want 1 of each", len(payments.payments), len(gateway.keys))
+	}
+}
+
+func TestCreatePaymentIntentAfterAFailedPayment(t *testing.T) {
+	p, orders, payments, gateway := newPaymentFixture()
+
+	first, err := p.CreatePaymentIntent(context.Background(), orders.order.ID)
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent: %v", err)
+	}
+	payments.UpdatePaymentStatus(first.ID, model.PaymentStatusFailed)
+
+	second, err := p.CreatePaymentIntent(context.Background(), orders.order.ID)
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent after the failure: %v", err)
+	}
+
+	if second.ID == first.ID || second.Status != model.PaymentStatusPending {
+		t.Errorf("payment = %+v, want a new pending payment", second)
+	}
+	if len(gateway.keys) != 2 || gateway.keys[0] == gateway.keys[1] {
+		t.Errorf("intent keys = %v, want a new key for the new intent", gateway.keys)
+	}
+}
+
+func TestCreatePaymentIntentRejectsPaidOrders(t *testing.T) {
+	p, orders, payments, gateway := newPaymentFixture()
+	payments.payments = append(payments.payments, model.Payment{
+		ID:       uuid.New(),
+		OrderID:  orders.order.ID,
+		IntentID: "pi_paid",
+		Status:   model.PaymentStatusSucceeded,
+	})
+
+	if _, err := p.CreatePaymentIntent(context.Background(), orders.order.ID); !errors.Is(err, model.ErrOrderNotPayable) {
+		t.Errorf("order with a succeeded payment: err = %v, want %v", err, model.ErrOrderNotPayable)
+	}
+
+	orders.order.IsPaid = true
+	if _, err := p.CreatePaymentIntent(context.Background(), orders.order.ID); !errors.Is(err, model.ErrOrderNotPayable) {
+		t.Errorf("paid order: err = %v, want %v", err, model.ErrOrderNotPayable)
+	}
+
+	if len(gateway.keys) != 0 {
+		t.Errorf("opened %d intents, want none", len(gateway.keys))
+	}
+}
+
+func TestCreatePaymentIntentLosingAConcurrentRequest(t *testing.T) {
+	p, orders, payments, _ := newPaymentFixture()
+
+	var winner *model.Payment
+	payments.beforeCreate = func() {
+		var err error
+		winner, err = p.CreatePaymentIntent(context.Background(), orders.order.ID)
+		if err != nil {
+			t.Fatalf("concurrent CreatePaymentIntent: %v", err)
+		}
+	}
+
+	got, err := p.CreatePaymentIntent(context.Background(), orders.order.ID)
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent: %v", err)
+	}
+
+	if got.ID != winner.ID || len(payments.payments) != 1 {
+		t.Errorf("payment = %+v with %d recorded, want the concurrent %+v", got, len(payments.payments), winner)
+	}
+}
@@ -0,0 +1,166 @@
+package reminder
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Notifier is an interface that delivers abandoned-cart reminders to users.
+// A reminder may be delivered more than once, see SendReminders.
+type Notifier interface {
+	NotifyAbandonedCart(ctx context.Context, cart model.AbandonedCart) error
+}
+
+// reminderStore is an interface that defines the methods required to find abandoned carts and record reminders.
+type reminderStore interface {
+	GetAbandonedCarts(threshold time.Duration, limit, maxAttempts int) (*[]model.AbandonedCart, error)
+	CreateReminder(bReq model.CartReminder) (bool, error)
+	RecordFailure(bReq model.CartReminder, lastError string, retryIn time.Duration) error
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Reminder reminderStore
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// Config is a struct that holds the settings of the abandoned-cart job.
+type Config struct {
+	// Thresholds are the idle times after which a cart gets a reminder.
+	Thresholds []time.Duration
+	// BatchSize is the most carts reminded per threshold in one run.
+	BatchSize int
+	// MaxAttempts is how many times a reminder is tried before it is given up.
+	MaxAttempts int
+	// RetryBackoff is how long a reminder is held back after its first failed send.
+	// It doubles with every further failure, up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+}
+
+type reminder struct {
+	store     reminderStore
+	txManager txManager
+	notifier  Notifier
+	cfg       Config
+	logger    zerolog.Logger
+}
+
+// NewReminder is a constructor function that returns a new reminder instance.
+func NewReminder(store reminderStore, txManager txManager, notifier Notifier, cfg Config, logger zerolog.Logger) *reminder {
+	// Longest first, so a cart idle past several thresholds only gets the reminder of the longest one.
+	thresholds := append([]time.Duration(nil), cfg.Thresholds...)
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i] > thresholds[j]
+	})
+	cfg.Thresholds = thresholds
+
+	return &reminder{
+		store:     store,
+		txManager: txManager,
+		notifier:  notifier,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// SendReminders is a method that notifies the users whose carts have been idle past a threshold and returns how
+// many reminders it sent. A cart gets at most one reminder per threshold for each idle period; any change to
+// the cart starts a new one.
+// Each reminder is recorded in the transaction it is sent in, so replicas running the job at the same time
+// do not send the same reminder concurrently. Delivery is at least once: the reminder is sent before the
+// transaction commits, so if the commit fails, or the process dies in between, it is sent again by a later
+// run. A reminder that fails to send is recorded as failed and retried after a backoff, up to cfg.MaxAttempts
+// times, so failing carts do not hold back the others.
+func (r *reminder) SendReminders(ctx context.Context) (int, error) {
+	logMsgStr := "Usecase:Reminder - SendReminders:"
+
+	var sent int
+	for _, threshold := range r.cfg.Thresholds {
+		carts, err := r.store.GetAbandonedCarts(threshold, r.cfg.BatchSize, r.cfg.MaxAttempts)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, cart := range *carts {
+			if err := ctx.Err(); err != nil {
+				return sent, err
+			}
+
+			var recorded bool
+
+			bReq := model.CartReminder{
+				UserID:         cart.UserID,
+				Threshold:      threshold,
+				CartActivityAt: cart.LastActivityAt,
+			}
+
+			err := r.txManager.WithTx(ctx, func(repos Repositories) error {
+				var err error
+				recorded, err = repos.Reminder.CreateReminder(bReq)
+				if err != nil || !recorded {
+					return err
+				}
+
+				return r.notifier.NotifyAbandonedCart(ctx, cart)
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return sent, ctx.Err()
+				}
+
+				if err := r.fail(bReq, cart.Attempts+1, err); err != nil {
+					r.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to record the failed reminder of user %v", logMsgStr, cart.UserID))
+				}
+				continue
+			}
+
+			if recorded {
+				sent++
+			}
+		}
+	}
+
+	if sent > 0 {
+		r.logger.Info().Msg(fmt.Sprintf("%v Sent %d abandoned-cart reminders", logMsgStr, sent))
+	}
+
+	return sent, nil
+}
+
+// fail records the attempts-th failed send of a reminder and holds it back, or gives it up if it has no
+// attempts left.
+func (r *reminder) fail(bReq model.CartReminder, attempts int, sendErr error) error {
+	logMsgStr := "Usecase:Reminder - SendReminders:"
+
+	retryIn := r.backoff(attempts)
+	if attempts >= r.cfg.MaxAttempts {
+		r.logger.Error().Any("Err", sendErr).Msg(fmt.Sprintf("%v Giving up the %v reminder of user %v after %d attempts", logMsgStr, bReq.Threshold, bReq.UserID, attempts))
+	} else {
+		r.logger.Warn().Any("Err", sendErr).Msg(fmt.Sprintf("%v Failed to send the %v reminder of user %v, retrying in %v", logMsgStr, bReq.Threshold, bReq.UserID, retryIn))
+	}
+
+	return r.store.RecordFailure(bReq, sendErr.Error(), retryIn)
+}
+
+// backoff returns how long a reminder is held back after its attempts-th failed send.
+func (r *reminder) backoff(attempts int) time.Duration {
+	retryIn := r.cfg.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		retryIn *= 2
+		if retryIn >= r.cfg.MaxBackoff {
+			return r.cfg.MaxBackoff
+		}
+	}
+
+	return min(retryIn, r.cfg.MaxBackoff)
+}
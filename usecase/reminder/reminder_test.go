@@ -0,0 +1,154 @@
+package reminder
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type failure struct {
+	reminder model.CartReminder
+	retryIn  time.Duration
+}
+
+// memReminderStore is an in-memory reminderStore holding the abandoned carts of a single threshold.
+type memReminderStore struct {
+	carts    []model.AbandonedCart
+	sent     map[uuid.UUID]bool
+	failures []failure
+}
+
+func (s *memReminderStore) GetAbandonedCarts(threshold time.Duration, limit, maxAttempts int) (*[]model.AbandonedCart, error) {
+	carts := []model.AbandonedCart{}
+	for _, cart := range s.carts {
+		if !s.sent[cart.UserID] && cart.Attempts < maxAttempts && len(carts) < limit {
+			cart.Threshold = threshold
+			carts = append(carts, cart)
+		}
+	}
+
+	return &carts, nil
+}
+
+func (s *memReminderStore) CreateReminder(bReq model.CartReminder) (bool, error) {
+	if s.sent[bReq.UserID] {
+		return false, nil
+	}
+	s.sent[bReq.UserID] = true
+
+	return true, nil
+}
+
+func (s *memReminderStore) RecordFailure(bReq model.CartReminder, lastError string, retryIn time.Duration) error {
+	s.failures = append(s.failures, failure{reminder: bReq, retryIn: retryIn})
+
+	return nil
+}
+
+// memTxManager undoes the reminders created in a unit of work that fails, like a rolled-back transaction.
+type memTxManager struct {
+	store *memReminderStore
+}
+
+func (m memTxManager) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	sent := make(map[uuid.UUID]bool, len(m.store.sent))
+	for userID := range m.store.sent {
+		sent[userID] = true
+	}
+
+	if err := fn(Repositories{Reminder: m.store}); err != nil {
+		m.store.sent = sent
+		return err
+	}
+
+	return nil
+}
+
+type stubNotifier struct {
+	failFor  map[uuid.UUID]bool
+	notified []uuid.UUID
+}
+
+func (n *stubNotifier) NotifyAbandonedCart(ctx context.Context, cart model.AbandonedCart) error {
+	if n.failFor[cart.UserID] {
+		return errors.New("mail server unavailable")
+	}
+	n.notified = append(n.notified, cart.UserID)
+
+	return nil
+}
+
+func TestSendRemindersRecordsFailures(t *testing.T) {
+	now := time.Now()
+	failing, retried, healthy := uuid.New(), uuid.New(), uuid.New()
+
+	store := &memReminderStore{
+		carts: []model.AbandonedCart{
+			{UserID: failing, LastActivityAt: now.Add(-3 * time.Hour)},
+			{UserID: retried, LastActivityAt: now.Add(-2 * time.Hour), Attempts: 2},
+			{UserID: healthy, LastActivityAt: now.Add(-90 * time.Minute)},
+		},
+		sent: map[uuid.UUID]bool{},
+	}
+	notifier := &stubNotifier{failFor: map[uuid.UUID]bool{failing: true, retried: true}}
+
+	r := NewReminder(store, memTxManager{store: store}, notifier, Config{
+		Thresholds:   []time.Duration{time.Hour},
+		BatchSize:    10,
+		MaxAttempts:  5,
+		RetryBackoff: time.Minute,
+		MaxBackoff:   3 * time.Minute,
+	}, zerolog.Nop())
+
+	sent, err := r.SendReminders(context.Background())
+	if err != nil {
+		t.Fatalf("SendReminders: %v", err)
+	}
+
+	if sent != 1 || len(notifier.notified) != 1 || notifier.notified[0] != healthy {
+		t.Errorf("sent %d reminders to %v, want 1 to %v", sent, notifier.notified, healthy)
+	}
+
+	if store.sent[failing] || store.sent[retried] {
+		t.Error("a reminder that failed to send is recorded as sent")
+	}
+
+	want := map[uuid.UUID]time.Duration{
+		failing: time.Minute,
+		retried: 3 * time.Minute,
+	}
+	if len(store.failures) != len(want) {
+		t.Fatalf("failures = %+v, want %d", store.failures, len(want))
+	}
+	for _, f := range store.failures {
+		if f.reminder.Threshold != time.Hour || f.retryIn != want[f.reminder.UserID] {
+			t.Errorf("failure of user %v: threshold %v, retry in %v, want 1h0m0s and %v", f.reminder.UserID, f.reminder.Threshold, f.retryIn, want[f.reminder.UserID])
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := &reminder{cfg: Config{RetryBackoff: time.Minute, MaxBackoff: 10 * time.Minute}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 4, want: 8 * time.Minute},
+		{attempts: 5, want: 10 * time.Minute},
+		{attempts: 50, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
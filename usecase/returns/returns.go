@@ -0,0 +1,585 @@
+package returns
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// amountTolerance absorbs rounding differences between refund amounts.
+const amountTolerance = 0.005
+
+// refundGateway is an interface that sends refunds through the payment provider an order was paid with.
+type refundGateway interface {
+	Provider() string
+	Refund(ctx context.Context, bReq model.RefundRequest) (*model.RefundResult, error)
+}
+
+// orderStore is an interface that defines the order methods required to handle returns.
+type orderStore interface {
+	GetOrderByID(orderID uuid.UUID) (*model.Order, error)
+	GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error)
+	GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error)
+	UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error
+	CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error)
+}
+
+// paymentStore is an interface that defines the payment methods required to refund returns.
+type paymentStore interface {
+	GetPaymentsByOrderID(orderID uuid.UUID) (*[]model.Payment, error)
+}
+
+// returnStore is an interface that defines the methods required for recording returns and refunds.
+type returnStore interface {
+	CreateReturn(bReq model.ReturnRequest) (*uuid.UUID, error)
+	CreateReturnItems(returnID uuid.UUID, items []model.ReturnItem) error
+	GetReturnByID(orderID, returnID uuid.UUID) (*model.ReturnRequest, error)
+	GetReturnForUpdate(orderID, returnID uuid.UUID) (*model.ReturnRequest, error)
+	GetReturnsByOrderID(orderID uuid.UUID) (*[]model.ReturnRequest, error)
+	GetReturnItems(returnIDs []uuid.UUID) (*[]model.ReturnItem, error)
+	GetReturnedQty(orderID uuid.UUID) (map[uuid.UUID]int, error)
+	UpdateReturnStatus(returnID uuid.UUID, status model.ReturnStatus, notes string) error
+	CreateRefund(bReq model.Refund) (*uuid.UUID, error)
+	UpdateRefund(refundID uuid.UUID, status model.RefundStatus, providerRefundID string) error
+	GetRefunds(returnIDs []uuid.UUID) (*[]model.Refund, error)
+	GetPaymentRefundedAmount(paymentID uuid.UUID) (float64, error)
+	GetOrderRefundedAmount(orderID uuid.UUID) (float64, error)
+	GetStalePendingRefunds(olderThan time.Duration, limit int) (*[]model.PendingRefund, error)
+}
+
+// outboxStore is an interface that defines the methods required to record order events.
+type outboxStore interface {
+	CreateEvent(event model.OutboxEvent) error
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Order   orderStore
+	Payment paymentStore
+	Return  returnStore
+	Outbox  outboxStore
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+type returns struct {
+	orderStore  orderStore
+	returnStore returnStore
+	txManager   txManager
+	gateway     refundGateway
+	logger      zerolog.Logger
+}
+
+// NewReturns is a constructor function that returns a new returns instance.
+func NewReturns(orderStore orderStore, returnStore returnStore, txManager txManager, gateway refundGateway, logger zerolog.Logger) *returns {
+	return &returns{
+		orderStore:  orderStore,
+		returnStore: returnStore,
+		txManager:   txManager,
+		gateway:     gateway,
+		logger:      logger,
+	}
+}
+
+// CreateReturn is a method that opens a return request for items of a completed order and moves the order to
+// return_requested. Each item may be returned up to the qty bought, less the qty in earlier returns that were not rejected.
+// An order has at most one open return at a time.
+func (r *returns) CreateReturn(ctx context.Context, bReq model.CreateReturnRequest) (*model.ReturnRequest, error) {
+	var bResp *model.ReturnRequest
+
+	err := r.txManager.WithTx(ctx, func(repos Repositories) error {
+		order, err := repos.Order.GetOrderForUpdate(bReq.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if !order.Status.CanTransitionTo(model.OrderStatusReturnRequested) {
+			return fmt.Errorf("%w: order is %s", model.ErrOrderNotReturnable, order.Status)
+		}
+
+		orderItems, err := repos.Order.GetOrderItems(order.ID)
+		if err != nil {
+			return err
+		}
+
+		byID := make(map[uuid.UUID]model.OrderItem, len(*orderItems))
+		for _, item := range *orderItems {
+			byID[item.ID] = item
+		}
+
+		returned, err := repos.Return.GetReturnedQty(order.ID)
+		if err != nil {
+			return err
+		}
+
+		ret := model.ReturnRequest{
+			OrderID: order.ID,
+			Status:  model.ReturnStatusRequested,
+			Reason:  bReq.Reason,
+			Items:   make([]model.ReturnItem, 0, len(bReq.Items)),
+		}
+		for _, item := range bReq.Items {
+			orderItem, ok := byID[item.OrderItemID]
+			if !ok {
+				return fmt.Errorf("%w: %s", model.ErrUnknownOrderItem, item.OrderItemID)
+			}
+
+			returned[orderItem.ID] += item.Qty
+			if returned[orderItem.ID] > orderItem.Qty {
+				return fmt.Errorf("%w: item %s", model.ErrReturnQtyExceeded, orderItem.ID)
+			}
+
+			lineTotal := roundPrice(orderItem.UnitPrice * float64(item.Qty))
+			ret.Items = append(ret.Items, model.ReturnItem{
+				OrderItemID: orderItem.ID,
+				ProductID:   orderItem.ProductID,
+				Qty:         item.Qty,
+				UnitPrice:   orderItem.UnitPrice,
+				LineTotal:   lineTotal,
+				Reason:      item.Reason,
+				Photos:      item.Photos,
+			})
+			ret.RefundAmount += lineTotal
+		}
+		ret.RefundAmount = roundPrice(ret.RefundAmount)
+
+		id, err := repos.Return.CreateReturn(ret)
+		if err != nil {
+			return err
+		}
+		ret.ID = *id
+
+		if err := repos.Return.CreateReturnItems(ret.ID, ret.Items); err != nil {
+			return err
+		}
+
+		if err := r.moveOrder(repos, order, model.OrderStatusReturnRequested, fmt.Sprintf("return %s requested: %s", ret.ID, ret.Reason)); err != nil {
+			return err
+		}
+
+		bResp = &ret
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetReturn(bResp.OrderID, bResp.ID)
+}
+
+// ListReturns is a method that retrieves the return requests of an order with their items and refunds.
+func (r *returns) ListReturns(orderID uuid.UUID) (*[]model.ReturnRequest, error) {
+	if _, err := r.orderStore.GetOrderByID(orderID); err != nil {
+		return nil, err
+	}
+
+	returns, err := r.returnStore.GetReturnsByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.fillReturns(*returns); err != nil {
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+// GetReturn is a method that retrieves a return request of an order with its items and refunds.
+func (r *returns) GetReturn(orderID, returnID uuid.UUID) (*model.ReturnRequest, error) {
+	ret, err := r.returnStore.GetReturnByID(orderID, returnID)
+	if err != nil {
+		return nil, err
+	}
+
+	returns := []model.ReturnRequest{*ret}
+	if err := r.fillReturns(returns); err != nil {
+		return nil, err
+	}
+
+	return &returns[0], nil
+}
+
+// fillReturns loads the items and refunds of returns.
+func (r *returns) fillReturns(returns []model.ReturnRequest) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(returns))
+	index := make(map[uuid.UUID]int, len(returns))
+	for i := range returns {
+		ids = append(ids, returns[i].ID)
+		index[returns[i].ID] = i
+		returns[i].Items = []model.ReturnItem{}
+		returns[i].Refunds = []model.Refund{}
+	}
+
+	items, err := r.returnStore.GetReturnItems(ids)
+	if err != nil {
+		return err
+	}
+	for _, item := range *items {
+		i := index[item.ReturnID]
+		returns[i].Items = append(returns[i].Items, item)
+	}
+
+	refunds, err := r.returnStore.GetRefunds(ids)
+	if err != nil {
+		return err
+	}
+	for _, refund := range *refunds {
+		i := index[refund.ReturnID]
+		returns[i].Refunds = append(returns[i].Refunds, refund)
+	}
+
+	return nil
+}
+
+// ApproveReturn is a method that approves a requested return so that it can be refunded.
+func (r *returns) ApproveReturn(ctx context.Context, bReq model.ReviewReturnRequest) (*model.ReturnRequest, error) {
+	err := r.txManager.WithTx(ctx, func(repos Repositories) error {
+		ret, err := repos.Return.GetReturnForUpdate(bReq.OrderID, bReq.ReturnID)
+		if err != nil {
+			return err
+		}
+
+		if !ret.Status.CanTransitionTo(model.ReturnStatusApproved) {
+			return fmt.Errorf("%w: %s to %s", model.ErrInvalidReturnTransition, ret.Status, model.ReturnStatusApproved)
+		}
+
+		return repos.Return.UpdateReturnStatus(ret.ID, model.ReturnStatusApproved, bReq.Notes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetReturn(bReq.OrderID, bReq.ReturnID)
+}
+
+// RejectReturn is a method that rejects an open return and moves the order back to the status it had before the
+// return was requested.
+func (r *returns) RejectReturn(ctx context.Context, bReq model.ReviewReturnRequest) (*model.ReturnRequest, error) {
+	err := r.txManager.WithTx(ctx, func(repos Repositories) error {
+		order, err := repos.Order.GetOrderForUpdate(bReq.OrderID)
+		if err != nil {
+			return err
+		}
+
+		ret, err := repos.Return.GetReturnForUpdate(order.ID, bReq.ReturnID)
+		if err != nil {
+			return err
+		}
+
+		if !ret.Status.CanTransitionTo(model.ReturnStatusRejected) {
+			return fmt.Errorf("%w: %s to %s", model.ErrInvalidReturnTransition, ret.Status, model.ReturnStatusRejected)
+		}
+
+		refunds, err := repos.Return.GetRefunds([]uuid.UUID{ret.ID})
+		if err != nil {
+			return err
+		}
+		if hasPendingRefund(*refunds) {
+			return model.ErrRefundInProgress
+		}
+
+		if err := repos.Return.UpdateReturnStatus(ret.ID, model.ReturnStatusRejected, bReq.Notes); err != nil {
+			return err
+		}
+
+		refunded, err := repos.Return.GetOrderRefundedAmount(order.ID)
+		if err != nil {
+			return err
+		}
+
+		next := model.OrderStatusCompleted
+		if refunded > 0 {
+			next = model.OrderStatusPartiallyRefunded
+		}
+
+		return r.moveOrder(repos, order, next, fmt.Sprintf("return %s rejected: %s", ret.ID, bReq.Notes))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetReturn(bReq.OrderID, bReq.ReturnID)
+}
+
+// RefundReturn is a method that refunds an approved return against the payment the order was paid with.
+// The refund is recorded as pending before the gateway is called and settled afterwards, so the gateway call
+// is not made inside a transaction. A succeeded refund closes the return and moves the order to refunded once
+// the items subtotal of the order has been refunded, or to partially_refunded otherwise. A failed refund leaves
+// the return approved so that it can be retried.
+// Every attempt for a return is sent with the same idempotency key and amount, so retrying a refund whose
+// outcome is unknown cannot pay it out twice. A refund still pending is sent again as it is instead of
+// starting a new one.
+func (r *returns) RefundReturn(ctx context.Context, bReq model.RefundReturnRequest) (*model.ReturnRequest, error) {
+	var (
+		refund  model.Refund
+		payment model.Payment
+	)
+
+	err := r.txManager.WithTx(ctx, func(repos Repositories) error {
+		order, err := repos.Order.GetOrderForUpdate(bReq.OrderID)
+		if err != nil {
+			return err
+		}
+
+		ret, err := repos.Return.GetReturnForUpdate(order.ID, bReq.ReturnID)
+		if err != nil {
+			return err
+		}
+
+		if !ret.Status.CanTransitionTo(model.ReturnStatusRefunded) {
+			return fmt.Errorf("%w: %s to %s", model.ErrInvalidReturnTransition, ret.Status, model.ReturnStatusRefunded)
+		}
+
+		payments, err := repos.Payment.GetPaymentsByOrderID(order.ID)
+		if err != nil {
+			return err
+		}
+
+		refunds, err := repos.Return.GetRefunds([]uuid.UUID{ret.ID})
+		if err != nil {
+			return err
+		}
+
+		amount := roundPrice(bReq.Amount)
+
+		if pending := pendingRefund(*refunds); pending != nil {
+			if amount != 0 && math.Abs(amount-pending.Amount) >= amountTolerance {
+				return fmt.Errorf("%w: %.2f is pending", model.ErrRefundInProgress, pending.Amount)
+			}
+
+			for _, p := range *payments {
+				if p.ID == pending.PaymentID {
+					refund, payment = *pending, p
+					return nil
+				}
+			}
+			return model.ErrNoRefundablePayment
+		}
+
+		// An earlier attempt may have gone through at the gateway even though it failed here. A retry is sent with
+		// the same idempotency key, so it has to ask for the same amount.
+		if last := lastRefund(*refunds); last != nil {
+			if amount == 0 {
+				amount = last.Amount
+			}
+			if math.Abs(amount-last.Amount) >= amountTolerance {
+				return fmt.Errorf("%w: %.2f requested, %.2f tried before", model.ErrRefundAmountChanged, amount, last.Amount)
+			}
+		}
+
+		if amount == 0 {
+			amount = ret.RefundAmount
+		}
+		if amount-ret.RefundAmount >= amountTolerance {
+			return fmt.Errorf("%w: %.2f requested, %.2f returned", model.ErrRefundAmountExceeded, amount, ret.RefundAmount)
+		}
+
+		found := false
+		for _, p := range *payments {
+			if p.Status == model.PaymentStatusSucceeded {
+				payment, found = p, true
+				break
+			}
+		}
+		if !found {
+			return model.ErrNoRefundablePayment
+		}
+
+		refunded, err := repos.Return.GetPaymentRefundedAmount(payment.ID)
+		if err != nil {
+			return err
+		}
+		if refunded+amount-payment.Amount >= amountTolerance {
+			return fmt.Errorf("%w: %.2f requested, %.2f left on the payment", model.ErrRefundAmountExceeded, amount, payment.Amount-refunded)
+		}
+
+		refund = model.Refund{
+			ReturnID:  ret.ID,
+			PaymentID: payment.ID,
+			Provider:  payment.Provider,
+			Amount:    amount,
+			Currency:  payment.Currency,
+			Status:    model.RefundStatusPending,
+		}
+
+		id, err := repos.Return.CreateRefund(refund)
+		if err != nil {
+			return err
+		}
+		refund.ID = *id
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.settleRefund(ctx, bReq.OrderID, bReq.Notes, refund, payment.IntentID); err != nil {
+		return nil, err
+	}
+
+	return r.GetReturn(bReq.OrderID, bReq.ReturnID)
+}
+
+// ReconcileRefunds is a method that sends refunds left pending for at least olderThan to the gateway again and
+// records their outcome, e.g. after the process died between the gateway call and recording its result.
+// It handles at most batchSize refunds per run and returns how many it settled. Several replicas may run it
+// at the same time: the gateway recognises a resent refund by its idempotency key, and only the first outcome
+// recorded for a refund counts.
+func (r *returns) ReconcileRefunds(ctx context.Context, olderThan time.Duration, batchSize int) (int, error) {
+	logMsgStr := "Usecase:Returns - ReconcileRefunds:"
+
+	refunds, err := r.returnStore.GetStalePendingRefunds(olderThan, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var settled int
+	for _, refund := range *refunds {
+		if err := ctx.Err(); err != nil {
+			return settled, err
+		}
+
+		notes := fmt.Sprintf("refund %s settled by the refund reconciler", refund.ID)
+		if err := r.settleRefund(ctx, refund.OrderID, notes, refund.Refund, refund.IntentID); err != nil {
+			r.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Failed to settle refund %v of return %v", logMsgStr, refund.ID, refund.ReturnID))
+			continue
+		}
+		settled++
+	}
+
+	if settled > 0 {
+		r.logger.Info().Msg(fmt.Sprintf("%v Settled %d pending refunds", logMsgStr, settled))
+	}
+
+	return settled, nil
+}
+
+// settleRefund sends a pending refund to the gateway and records the outcome, unless the refund has been
+// settled in the meantime.
+func (r *returns) settleRefund(ctx context.Context, orderID uuid.UUID, notes string, refund model.Refund, intentID string) error {
+	logMsgStr := "Usecase:Returns - settleRefund:"
+
+	result, err := r.gateway.Refund(ctx, model.RefundRequest{
+		IdempotencyKey: refundIdempotencyKey(refund.ReturnID),
+		IntentID:       intentID,
+		Amount:         refund.Amount,
+		Currency:       refund.Currency,
+	})
+	if err != nil {
+		r.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Gateway refused refund %v", logMsgStr, refund.ID))
+		if uerr := r.returnStore.UpdateRefund(refund.ID, model.RefundStatusFailed, ""); uerr != nil && !errors.Is(uerr, model.ErrRefundNotPending) {
+			return uerr
+		}
+		return err
+	}
+
+	return r.txManager.WithTx(ctx, func(repos Repositories) error {
+		order, err := repos.Order.GetOrderForUpdate(orderID)
+		if err != nil {
+			return err
+		}
+
+		err = repos.Return.UpdateRefund(refund.ID, model.RefundStatusSucceeded, result.ProviderRefundID)
+		if errors.Is(err, model.ErrRefundNotPending) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := repos.Return.UpdateReturnStatus(refund.ReturnID, model.ReturnStatusRefunded, notes); err != nil {
+			return err
+		}
+
+		refunded, err := repos.Return.GetOrderRefundedAmount(order.ID)
+		if err != nil {
+			return err
+		}
+
+		// Returns refund items only, so the order is fully refunded once its items subtotal is, whatever the
+		// shipping fee.
+		next := model.OrderStatusPartiallyRefunded
+		if order.Subtotal-refunded < amountTolerance {
+			next = model.OrderStatusRefunded
+		}
+
+		return r.moveOrder(repos, order, next, fmt.Sprintf("refund %s of %.2f %s for return %s", refund.ID, refund.Amount, refund.Currency, refund.ReturnID))
+	})
+}
+
+// moveOrder moves a locked order to next and records the change in the status log and the outbox.
+func (r *returns) moveOrder(repos Repositories, order *model.Order, next model.OrderStatus, notes string) error {
+	if !order.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", model.ErrInvalidStatusTransition, order.Status, next)
+	}
+
+	if err := repos.Order.UpdateOrderStatus(order.ID, next); err != nil {
+		return err
+	}
+
+	statusLog := model.OrderItemsLogs{
+		OrderID:    order.ID,
+		RefCode:    order.RefCode,
+		FromStatus: order.Status,
+		ToStatus:   next,
+		Notes:      notes,
+	}
+	if _, err := repos.Order.CreateOrderItemsLogs(statusLog); err != nil {
+		return err
+	}
+
+	event, err := model.NewOrderStatusChangedEvent(statusLog)
+	if err != nil {
+		return err
+	}
+
+	return repos.Outbox.CreateEvent(event)
+}
+
+func hasPendingRefund(refunds []model.Refund) bool {
+	return pendingRefund(refunds) != nil
+}
+
+// pendingRefund returns the refund of a return that is waiting for the outcome at the gateway, if any.
+func pendingRefund(refunds []model.Refund) *model.Refund {
+	for i := range refunds {
+		if refunds[i].Status == model.RefundStatusPending {
+			return &refunds[i]
+		}
+	}
+	return nil
+}
+
+// lastRefund returns the most recent refund of a return, if any. refunds are ordered oldest first.
+func lastRefund(refunds []model.Refund) *model.Refund {
+	if len(refunds) == 0 {
+		return nil
+	}
+	return &refunds[len(refunds)-1]
+}
+
+// refundIdempotencyKey returns the key the gateway deduplicates the refund of a return by. A return is refunded
+// at most once, so the key is derived from the return rather than from a single attempt.
+func refundIdempotencyKey(returnID uuid.UUID) string {
+	return "return-" + returnID.String()
+}
+
+func roundPrice(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,722 @@
+package returns
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// memState is the data held by memStore. It is copied to roll a failed unit of work back.
+type memState struct {
+	orders      map[uuid.UUID]model.Order
+	orderItems  map[uuid.UUID][]model.OrderItem
+	payments    []model.Payment
+	returns     []model.ReturnRequest
+	returnItems []model.ReturnItem
+	refunds     []model.Refund
+	statusLogs  []model.OrderItemsLogs
+	events      []model.OutboxEvent
+}
+
+func (s memState) clone() memState {
+	c := s
+	c.orders = make(map[uuid.UUID]model.Order, len(s.orders))
+	for id, order := range s.orders {
+		c.orders[id] = order
+	}
+	c.payments = append([]model.Payment(nil), s.payments...)
+	c.returns = append([]model.ReturnRequest(nil), s.returns...)
+	c.returnItems = append([]model.ReturnItem(nil), s.returnItems...)
+	c.refunds = append([]model.Refund(nil), s.refunds...)
+	c.statusLogs = append([]model.OrderItemsLogs(nil), s.statusLogs...)
+	c.events = append([]model.OutboxEvent(nil), s.events...)
+	return c
+}
+
+// memStore is an in-memory orderStore, paymentStore, returnStore and outboxStore.
+type memStore struct {
+	memState
+	// failSettle, when set, is returned once by the next UpdateRefund that records a succeeded refund.
+	failSettle error
+}
+
+func (s *memStore) GetOrderByID(orderID uuid.UUID) (*model.Order, error) {
+	order, ok := s.orders[orderID]
+	if !ok {
+		return nil, model.ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (s *memStore) GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error) {
+	return s.GetOrderByID(orderID)
+}
+
+func (s *memStore) GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error) {
+	items := s.orderItems[orderID]
+	return &items, nil
+}
+
+func (s *memStore) UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error {
+	order := s.orders[orderID]
+	order.Status = status
+	s.orders[orderID] = order
+	return nil
+}
+
+func (s *memStore) CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error) {
+	s.statusLogs = append(s.statusLogs, bReq)
+	return &bReq.RefCode, nil
+}
+
+func (s *memStore) GetPaymentsByOrderID(orderID uuid.UUID) (*[]model.Payment, error) {
+	payments := []model.Payment{}
+	for _, p := range s.payments {
+		if p.OrderID == orderID {
+			payments = append(payments, p)
+		}
+	}
+	return &payments, nil
+}
+
+func (s *memStore) CreateReturn(bReq model.ReturnRequest) (*uuid.UUID, error) {
+	bReq.ID = uuid.New()
+	bReq.Items = nil
+	s.returns = append(s.returns, bReq)
+	return &bReq.ID, nil
+}
+
+func (s *memStore) CreateReturnItems(returnID uuid.UUID, items []model.ReturnItem) error {
+	for _, item := range items {
+		item.ID = uuid.New()
+		item.ReturnID = returnID
+		s.returnItems = append(s.returnItems, item)
+	}
+	return nil
+}
+
+func (s *memStore) GetReturnByID(orderID, returnID uuid.UUID) (*model.ReturnRequest, error) {
+	for _, ret := range s.returns {
+		if ret.ID == returnID && ret.OrderID == orderID {
+			return &ret, nil
+		}
+	}
+	return nil, model.ErrReturnNotFound
+}
+
+func (s *memStore) GetReturnForUpdate(orderID, returnID uuid.UUID) (*model.ReturnRequest, error) {
+	return s.GetReturnByID(orderID, returnID)
+}
+
+func (s *memStore) GetReturnsByOrderID(orderID uuid.UUID) (*[]model.ReturnRequest, error) {
+	returns := []model.ReturnRequest{}
+	for _, ret := range s.returns {
+		if ret.OrderID == orderID {
+			returns = append(returns, ret)
+		}
+	}
+	return &returns, nil
+}
+
+func (s *memStore) GetReturnItems(returnIDs []uuid.UUID) (*[]model.ReturnItem, error) {
+	items := []model.ReturnItem{}
+	for _, item := range s.returnItems {
+		for _, id := range returnIDs {
+			if item.ReturnID == id {
+				items = append(items, item)
+			}
+		}
+	}
+	return &items, nil
+}
+
+func (s *memStore) GetReturnedQty(orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	returned := map[uuid.UUID]int{}
+	for _, ret := range s.returns {
+		if ret.OrderID != orderID || ret.Status == model.ReturnStatusRejected {
+			continue
+		}
+		for _, item := range s.returnItems {
+			if item.ReturnID == ret.ID {
+				returned[item.OrderItemID] += item.Qty
+			}
+		}
+	}
+	return returned, nil
+}
+
+func (s *memStore) UpdateReturnStatus(returnID uuid.UUID, status model.ReturnStatus, notes string) error {
+	for i := range s.returns {
+		if s.returns[i].ID == returnID {
+			s.returns[i].Status = status
+			s.returns[i].ResolutionNotes = notes
+			return nil
+		}
+	}
+	return model.ErrReturnNotFound
+}
+
+func (s *memStore) CreateRefund(bReq model.Refund) (*uuid.UUID, error) {
+	bReq.ID = uuid.New()
+	now := time.Now()
+	bReq.CreatedAt = &now
+	s.refunds = append(s.refunds, bReq)
+	return &bReq.ID, nil
+}
+
+func (s *memStore) UpdateRefund(refundID uuid.UUID, status model.RefundStatus, providerRefundID string) error {
+	if status == model.RefundStatusSucceeded && s.failSettle != nil {
+		err := s.failSettle
+		s.failSettle = nil
+		return err
+	}
+
+	for i := range s.refunds {
+		if s.refunds[i].ID == refundID {
+			if s.refunds[i].Status != model.RefundStatusPending {
+				return model.ErrRefundNotPending
+			}
+			s.refunds[i].Status = status
+			s.refunds[i].ProviderRefundID = providerRefundID
+			return nil
+		}
+	}
+	return model.ErrRefundNotPending
+}
+
+func (s *memStore) GetRefunds(returnIDs []uuid.UUID) (*[]model.Refund, error) {
+	refunds := []model.Refund{}
+	for _, refund := range s.refunds {
+		for _, id := range returnIDs {
+			if refund.ReturnID == id {
+				refunds = append(refunds, refund)
+			}
+		}
+	}
+	return &refunds, nil
+}
+
+func (s *memStore) GetPaymentRefundedAmount(paymentID uuid.UUID) (float64, error) {
+	var amount float64
+	for _, refund := range s.refunds {
+		if refund.PaymentID == paymentID && refund.Status != model.RefundStatusFailed {
+			amount += refund.Amount
+		}
+	}
+	return amount, nil
+}
+
+func (s *memStore) GetOrderRefundedAmount(orderID uuid.UUID) (float64, error) {
+	var amount float64
+	for _, refund := range s.refunds {
+		ret, err := s.findReturn(refund.ReturnID)
+		if err == nil && ret.OrderID == orderID && refund.Status == model.RefundStatusSucceeded {
+			amount += refund.Amount
+		}
+	}
+	return amount, nil
+}
+
+func (s *memStore) GetStalePendingRefunds(olderThan time.Duration, limit int) (*[]model.PendingRefund, error) {
+	refunds := []model.PendingRefund{}
+	for _, refund := range s.refunds {
+		if refund.Status != model.RefundStatusPending || refund.CreatedAt.After(time.Now().Add(-olderThan)) || len(refunds) == limit {
+			continue
+		}
+
+		ret, _ := s.findReturn(refund.ReturnID)
+		pending := model.PendingRefund{Refund: refund, OrderID: ret.OrderID}
+		for _, p := range s.payments {
+			if p.ID == refund.PaymentID {
+				pending.IntentID = p.IntentID
+			}
+		}
+		refunds = append(refunds, pending)
+	}
+	return &refunds, nil
+}
+
+func (s *memStore) CreateEvent(event model.OutboxEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memStore) findReturn(returnID uuid.UUID) (model.ReturnRequest, error) {
+	for _, ret := range s.returns {
+		if ret.ID == returnID {
+			return ret, nil
+		}
+	}
+	return model.ReturnRequest{}, model.ErrReturnNotFound
+}
+
+// memTxManager runs a unit of work against memStore and rolls its changes back if it fails.
+type memTxManager struct {
+	store *memStore
+}
+
+func (m memTxManager) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	snapshot := m.store.memState.clone()
+
+	err := fn(Repositories{Order: m.store, Payment: m.store, Return: m.store, Outbox: m.store})
+	if err != nil {
+		m.store.memState = snapshot
+	}
+
+	return err
+}
+
+// fakeGateway pays each idempotency key out once, like a real gateway.
+type fakeGateway struct {
+	paid map[string]model.RefundRequest
+	keys []string
+	// err is returned by the next call without paying the refund out.
+	err error
+	// lostResponse makes the next call pay the refund out and then fail, like a response lost to a timeout.
+	lostResponse bool
+}
+
+func (g *fakeGateway) Provider() string {
+	return "fake"
+}
+
+func (g *fakeGateway) Refund(ctx context.Context, bReq model.RefundRequest) (*model.RefundResult, error) {
+	g.keys = append(g.keys, bReq.IdempotencyKey)
+
+	if g.err != nil {
+		err := g.err
+		g.err = nil
+		return nil, err
+	}
+
+	if _, ok := g.paid[bReq.IdempotencyKey]; !ok {
+		g.paid[bReq.IdempotencyKey] = bReq
+	}
+
+	if g.lostResponse {
+		g.lostResponse = false
+		return nil, context.DeadlineExceeded
+	}
+
+	return &model.RefundResult{ProviderRefundID: "re_" + bReq.IdempotencyKey}, nil
+}
+
+// fixture is a completed order of two lines worth 100 in total plus 10 shipping, paid in full.
+type fixture struct {
+	store   *memStore
+	gateway *fakeGateway
+	r       *returns
+	orderID uuid.UUID
+	lineA   model.OrderItem
+	lineB   model.OrderItem
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	orderID := uuid.New()
+	lineA := model.OrderItem{ID: uuid.New(), OrderID: orderID, ProductID: uuid.New(), Qty: 2, UnitPrice: 30, LineTotal: 60}
+	lineB := model.OrderItem{ID: uuid.New(), OrderID: orderID, ProductID: uuid.New(), Qty: 1, UnitPrice: 40, LineTotal: 40}
+
+	store := &memStore{memState: memState{
+		orders: map[uuid.UUID]model.Order{orderID: {
+			ID:          orderID,
+			RefCode:     "REF-1",
+			Subtotal:    100,
+			ShippingFee: 10,
+			TotalPrice:  110,
+			Status:      model.OrderStatusCompleted,
+			IsPaid:      true,
+		}},
+		orderItems: map[uuid.UUID][]model.OrderItem{orderID: {lineA, lineB}},
+		payments: []model.Payment{{
+			ID:       uuid.New(),
+			OrderID:  orderID,
+			Provider: "fake",
+			IntentID: "pi_1",
+			Amount:   110,
+			Currency: "IDR",
+			Status:   model.PaymentStatusSucceeded,
+		}},
+	}}
+	gateway := &fakeGateway{paid: map[string]model.RefundRequest{}}
+
+	return &fixture{
+		store:   store,
+		gateway: gateway,
+		r:       NewReturns(store, store, memTxManager{store: store}, gateway, zerolog.Nop()),
+		orderID: orderID,
+		lineA:   lineA,
+		lineB:   lineB,
+	}
+}
+
+func (f *fixture) orderStatus() model.OrderStatus {
+	return f.store.orders[f.orderID].Status
+}
+
+// approvedReturn opens and approves a return of the given qty of line A and line B.
+func (f *fixture) approvedReturn(t *testing.T, qtyA, qtyB int) *model.ReturnRequest {
+	t.Helper()
+
+	var items []model.CreateReturnItem
+	if qtyA > 0 {
+		items = append(items, model.CreateReturnItem{OrderItemID: f.lineA.ID, Qty: qtyA})
+	}
+	if qtyB > 0 {
+		items = append(items, model.CreateReturnItem{OrderItemID: f.lineB.ID, Qty: qtyB})
+	}
+
+	ret, err := f.r.CreateReturn(context.Background(), model.CreateReturnRequest{OrderID: f.orderID, Reason: "damaged", Items: items})
+	if err != nil {
+		t.Fatalf("CreateReturn: %v", err)
+	}
+
+	ret, err = f.r.ApproveReturn(context.Background(), model.ReviewReturnRequest{OrderID: f.orderID, ReturnID: ret.ID})
+	if err != nil {
+		t.Fatalf("ApproveReturn: %v", err)
+	}
+
+	return ret
+}
+
+func TestCreateReturn(t *testing.T) {
+	f := newFixture(t)
+
+	ret, err := f.r.CreateReturn(context.Background(), model.CreateReturnRequest{
+		OrderID: f.orderID,
+		Reason:  "wrong size",
+		Items:   []model.CreateReturnItem{{OrderItemID: f.lineA.ID, Qty: 1}, {OrderItemID: f.lineB.ID, Qty: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateReturn: %v", err)
+	}
+
+	if ret.Status != model.ReturnStatusRequested || ret.RefundAmount != 70 || len(ret.Items) != 2 {
+		t.Errorf("return = %+v, want requested with 2 items worth 70", ret)
+	}
+	if f.orderStatus() != model.OrderStatusReturnRequested {
+		t.Errorf("order is %s, want %s", f.orderStatus(), model.OrderStatusReturnRequested)
+	}
+	if len(f.store.statusLogs) != 1 || len(f.store.events) != 1 {
+		t.Errorf("%d status logs and %d events, want 1 of each", len(f.store.statusLogs), len(f.store.events))
+	}
+
+	if _, err := f.r.CreateReturn(context.Background(), model.CreateReturnRequest{
+		OrderID: f.orderID,
+		Reason:  "second return",
+		Items:   []model.CreateReturnItem{{OrderItemID: f.lineA.ID, Qty: 1}},
+	}); !errors.Is(err, model.ErrOrderNotReturnable) {
+		t.Errorf("second open return: err = %v, want %v", err, model.ErrOrderNotReturnable)
+	}
+}
+
+func TestCreateReturnQtyCaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(f *fixture)
+		items   func(f *fixture) []model.CreateReturnItem
+		wantErr error
+	}{
+		{
+			name: "whole qty",
+			items: func(f *fixture) []model.CreateReturnItem {
+				return []model.CreateReturnItem{{OrderItemID: f.lineA.ID, Qty: 2}}
+			},
+		},
+		{
+			name: "more than bought",
+			items: func(f *fixture) []model.CreateReturnItem {
+				return []model.CreateReturnItem{{OrderItemID: f.lineA.ID, Qty: 3}}
+			},
+			wantErr: model.ErrReturnQtyExceeded,
+		},
+		{
+			name: "same line twice in one request",
+			items: func(f *fixture) []model.CreateReturnItem {
+				return []model.CreateReturnItem{{OrderItemID: f.lineB.ID, Qty: 1}, {OrderItemID: f.lineB.ID, Qty: 1}}
+			},
+			wantErr: model.ErrReturnQtyExceeded,
+		},
+		{
+			name: "unknown order item",
+			items: func(f *fixture) []model.CreateReturnItem {
+				return []model.CreateReturnItem{{OrderItemID: uuid.New(), Qty: 1}}
+			},
+			wantErr: model.ErrUnknownOrderItem,
+		},
+		{
+			name: "qty in an earlier refunded return counts",
+			prepare: func(f *fixture) {
+				ret := f.approvedReturn(t, 1, 0)
+				if _, err := f.r.RefundReturn(context.Background(), model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}); err != nil {
+					t.Fatalf("RefundReturn: %v", err)
+				}
+			},
+			items: func(f *fixture) []model.CreateReturnItem {
+				return []model.CreateReturnItem{{OrderItemID: f.lineA.ID, Qty: 2}}
+			},
+			wantErr: model.ErrReturnQtyExceeded,
+		},
+		{
+			name: "qty in an earlier rejected return does not count",
+			prepare: func(f *fixture) {
+				ret := f.approvedReturn(t, 2, 0)
+				if _, err := f.r.RejectReturn(context.Background(), model.ReviewReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}); err != nil {
+					t.Fatalf("RejectReturn: %v", err)
+				}
+			},
+			items: func(f *fixture) []model.CreateReturnItem {
+				return []model.CreateReturnItem{{OrderItemID: f.lineA.ID, Qty: 2}}
+			},
+		},
+		{
+			name: "order not completed",
+			prepare: func(f *fixture) {
+				order := f.store.orders[f.orderID]
+				order.Status = model.OrderStatusShipped
+				f.store.orders[f.orderID] = order
+			},
+			items: func(f *fixture) []model.CreateReturnItem {
+				return []model.CreateReturnItem{{OrderItemID: f.lineA.ID, Qty: 1}}
+			},
+			wantErr: model.ErrOrderNotReturnable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+			returns := len(f.store.returns)
+
+			_, err := f.r.CreateReturn(context.Background(), model.CreateReturnRequest{OrderID: f.orderID, Reason: "r", Items: tt.items(f)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && len(f.store.returns) != returns {
+				t.Error("a refused return was recorded")
+			}
+		})
+	}
+}
+
+func TestReturnStatusGuards(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	ret, err := f.r.CreateReturn(ctx, model.CreateReturnRequest{
+		OrderID: f.orderID,
+		Reason:  "damaged",
+		Items:   []model.CreateReturnItem{{OrderItemID: f.lineB.ID, Qty: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateReturn: %v", err)
+	}
+	review := model.ReviewReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}
+
+	if _, err := f.r.RefundReturn(ctx, model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}); !errors.Is(err, model.ErrInvalidReturnTransition) {
+		t.Errorf("refund of a requested return: err = %v, want %v", err, model.ErrInvalidReturnTransition)
+	}
+
+	if _, err := f.r.ApproveReturn(ctx, review); err != nil {
+		t.Fatalf("ApproveReturn: %v", err)
+	}
+	if _, err := f.r.ApproveReturn(ctx, review); !errors.Is(err, model.ErrInvalidReturnTransition) {
+		t.Errorf("second approval: err = %v, want %v", err, model.ErrInvalidReturnTransition)
+	}
+
+	if _, err := f.r.RejectReturn(ctx, review); err != nil {
+		t.Fatalf("RejectReturn: %v", err)
+	}
+	if f.orderStatus() != model.OrderStatusCompleted {
+		t.Errorf("order is %s after the rejection, want %s", f.orderStatus(), model.OrderStatusCompleted)
+	}
+
+	for name, call := range map[string]func() error{
+		"approve": func() error { _, err := f.r.ApproveReturn(ctx, review); return err },
+		"reject":  func() error { _, err := f.r.RejectReturn(ctx, review); return err },
+		"refund": func() error {
+			_, err := f.r.RefundReturn(ctx, model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID})
+			return err
+		},
+	} {
+		if err := call(); !errors.Is(err, model.ErrInvalidReturnTransition) {
+			t.Errorf("%s a rejected return: err = %v, want %v", name, err, model.ErrInvalidReturnTransition)
+		}
+	}
+
+	if _, err := f.r.ApproveReturn(ctx, model.ReviewReturnRequest{OrderID: f.orderID, ReturnID: uuid.New()}); !errors.Is(err, model.ErrReturnNotFound) {
+		t.Errorf("approve an unknown return: err = %v, want %v", err, model.ErrReturnNotFound)
+	}
+}
+
+func TestRefundReturn(t *testing.T) {
+	tests := []struct {
+		name       string
+		qtyA, qtyB int
+		amount     float64
+		wantAmount float64
+		wantOrder  model.OrderStatus
+	}{
+		{name: "every item refunds the order, shipping aside", qtyA: 2, qtyB: 1, wantAmount: 100, wantOrder: model.OrderStatusRefunded},
+		{name: "some items", qtyA: 1, wantAmount: 30, wantOrder: model.OrderStatusPartiallyRefunded},
+		{name: "partial amount", qtyA: 2, qtyB: 1, amount: 55.5, wantAmount: 55.5, wantOrder: model.OrderStatusPartiallyRefunded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			ret := f.approvedReturn(t, tt.qtyA, tt.qtyB)
+
+			got, err := f.r.RefundReturn(context.Background(), model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID, Amount: tt.amount})
+			if err != nil {
+				t.Fatalf("RefundReturn: %v", err)
+			}
+
+			if got.Status != model.ReturnStatusRefunded || len(got.Refunds) != 1 {
+				t.Fatalf("return = %+v, want refunded with one refund", got)
+			}
+			refund := got.Refunds[0]
+			if refund.Status != model.RefundStatusSucceeded || refund.Amount != tt.wantAmount {
+				t.Errorf("refund = %+v, want succeeded for %v", refund, tt.wantAmount)
+			}
+			if f.orderStatus() != tt.wantOrder {
+				t.Errorf("order is %s, want %s", f.orderStatus(), tt.wantOrder)
+			}
+
+			wantKey := refundIdempotencyKey(ret.ID)
+			if len(f.gateway.keys) != 1 || f.gateway.keys[0] != wantKey {
+				t.Errorf("gateway keys = %v, want [%s]", f.gateway.keys, wantKey)
+			}
+		})
+	}
+}
+
+func TestRefundReturnRefused(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFixture(t)
+	ret := f.approvedReturn(t, 1, 0)
+	if _, err := f.r.RefundReturn(ctx, model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID, Amount: 30.01}); !errors.Is(err, model.ErrRefundAmountExceeded) {
+		t.Errorf("refund above the returned value: err = %v, want %v", err, model.ErrRefundAmountExceeded)
+	}
+
+	f = newFixture(t)
+	f.store.payments[0].Status = model.PaymentStatusPending
+	ret = f.approvedReturn(t, 1, 0)
+	if _, err := f.r.RefundReturn(ctx, model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}); !errors.Is(err, model.ErrNoRefundablePayment) {
+		t.Errorf("refund without a succeeded payment: err = %v, want %v", err, model.ErrNoRefundablePayment)
+	}
+
+	if len(f.store.refunds) != 0 || len(f.gateway.keys) != 0 {
+		t.Error("a refused refund was recorded or sent")
+	}
+}
+
+func TestRefundReturnRetryAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	ret := f.approvedReturn(t, 2, 1)
+	bReq := model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}
+
+	// The gateway pays the refund out, but the response is lost.
+	f.gateway.lostResponse = true
+	if _, err := f.r.RefundReturn(ctx, bReq); err == nil {
+		t.Fatal("RefundReturn succeeded although the gateway call failed")
+	}
+	if f.store.refunds[0].Status != model.RefundStatusFailed {
+		t.Errorf("refund is %s, want %s", f.store.refunds[0].Status, model.RefundStatusFailed)
+	}
+	if status, _ := f.store.findReturn(ret.ID); status.Status != model.ReturnStatusApproved {
+		t.Errorf("return is %s, want %s", status.Status, model.ReturnStatusApproved)
+	}
+
+	if _, err := f.r.RefundReturn(ctx, model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID, Amount: 50}); !errors.Is(err, model.ErrRefundAmountChanged) {
+		t.Errorf("retry with another amount: err = %v, want %v", err, model.ErrRefundAmountChanged)
+	}
+
+	if _, err := f.r.RefundReturn(ctx, bReq); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	if len(f.gateway.keys) != 2 || f.gateway.keys[0] != f.gateway.keys[1] {
+		t.Errorf("gateway keys = %v, want the same key for both attempts", f.gateway.keys)
+	}
+	if len(f.gateway.paid) != 1 {
+		t.Errorf("paid out %d times, want once", len(f.gateway.paid))
+	}
+	if f.orderStatus() != model.OrderStatusRefunded {
+		t.Errorf("order is %s, want %s", f.orderStatus(), model.OrderStatusRefunded)
+	}
+}
+
+func TestPendingRefundIsRetriedAndReconciled(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*fixture, *model.ReturnRequest) {
+		f := newFixture(t)
+		ret := f.approvedReturn(t, 1, 1)
+
+		// The gateway pays the refund out, but recording it fails.
+		f.store.failSettle = errors.New("connection reset")
+		if _, err := f.r.RefundReturn(ctx, model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}); err == nil {
+			t.Fatal("RefundReturn succeeded although recording the refund failed")
+		}
+		if len(f.store.refunds) != 1 || f.store.refunds[0].Status != model.RefundStatusPending {
+			t.Fatalf("refunds = %+v, want one pending", f.store.refunds)
+		}
+
+		if _, err := f.r.RejectReturn(ctx, model.ReviewReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}); !errors.Is(err, model.ErrRefundInProgress) {
+			t.Errorf("reject with a pending refund: err = %v, want %v", err, model.ErrRefundInProgress)
+		}
+
+		return f, ret
+	}
+
+	check := func(t *testing.T, f *fixture) {
+		if len(f.store.refunds) != 1 || f.store.refunds[0].Status != model.RefundStatusSucceeded {
+			t.Errorf("refunds = %+v, want the pending one succeeded", f.store.refunds)
+		}
+		if len(f.gateway.paid) != 1 {
+			t.Errorf("paid out %d times, want once", len(f.gateway.paid))
+		}
+		if f.orderStatus() != model.OrderStatusPartiallyRefunded {
+			t.Errorf("order is %s, want %s", f.orderStatus(), model.OrderStatusPartiallyRefunded)
+		}
+	}
+
+	t.Run("retry reuses the pending refund", func(t *testing.T) {
+		f, ret := setup(t)
+
+		if _, err := f.r.RefundReturn(ctx, model.RefundReturnRequest{OrderID: f.orderID, ReturnID: ret.ID}); err != nil {
+			t.Fatalf("retry: %v", err)
+		}
+		check(t, f)
+	})
+
+	t.Run("reconciler settles the pending refund", func(t *testing.T) {
+		f, _ := setup(t)
+
+		if settled, err := f.r.ReconcileRefunds(ctx, time.Hour, 10); err != nil || settled != 0 {
+			t.Fatalf("ReconcileRefunds of a fresh refund = %d, %v, want 0", settled, err)
+		}
+
+		settled, err := f.r.ReconcileRefunds(ctx, 0, 10)
+		if err != nil || settled != 1 {
+			t.Fatalf("ReconcileRefunds = %d, %v, want 1", settled, err)
+		}
+		check(t, f)
+
+		if settled, err := f.r.ReconcileRefunds(ctx, 0, 10); err != nil || settled != 0 {
+			t.Errorf("second ReconcileRefunds = %d, %v, want 0", settled, err)
+		}
+	})
+}
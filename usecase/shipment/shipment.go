@@ -0,0 +1,414 @@
+package shipment
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// courierWebhook is an interface that verifies and decodes courier tracking webhooks.
+type courierWebhook interface {
+	ParseWebhook(header http.Header, body []byte) (*model.CourierEvent, error)
+}
+
+// orderStore is an interface that defines the order methods required to ship orders.
+type orderStore interface {
+	GetOrderByID(orderID uuid.UUID) (*model.Order, error)
+	GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error)
+	GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error)
+	UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error
+	CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error)
+}
+
+// shipmentStore is an interface that defines the methods required for recording shipments and their tracking.
+type shipmentStore interface {
+	CreateShipment(bReq model.Shipment) (*uuid.UUID, error)
+	CreateShipmentItems(shipmentID uuid.UUID, items []model.ShipmentItem) error
+	GetShippedQty(orderID uuid.UUID) (map[uuid.UUID]int, error)
+	GetShipmentByID(orderID, shipmentID uuid.UUID) (*model.Shipment, error)
+	GetShipmentForUpdate(orderID, shipmentID uuid.UUID) (*model.Shipment, error)
+	GetShipmentByTrackingForUpdate(courier, trackingNumber string) (*model.Shipment, error)
+	GetShipmentsByOrderID(orderID uuid.UUID) (*[]model.Shipment, error)
+	GetShipmentItems(shipmentIDs []uuid.UUID) (*[]model.ShipmentItem, error)
+	GetShipmentEvents(shipmentIDs []uuid.UUID) (*[]model.ShipmentEvent, error)
+	UpdateTracking(shipmentID uuid.UUID, courier, trackingNumber string) error
+	CreateShipmentEvent(bReq model.ShipmentEvent) (bool, error)
+	UpdateShipmentStatus(shipmentID uuid.UUID, status model.ShipmentStatus, at time.Time) error
+}
+
+// outboxStore is an interface that defines the methods required to record order events.
+type outboxStore interface {
+	CreateEvent(event model.OutboxEvent) error
+}
+
+// Repositories is a struct that holds the stores bound to a single transaction.
+type Repositories struct {
+	Order    orderStore
+	Shipment shipmentStore
+	Outbox   outboxStore
+}
+
+// txManager is an interface that runs a unit of work inside a single transaction.
+type txManager interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+type shipment struct {
+	orderStore    orderStore
+	shipmentStore shipmentStore
+	txManager     txManager
+	webhook       courierWebhook
+	logger        zerolog.Logger
+}
+
+// NewShipment is a constructor function that returns a new shipment instance.
+func NewShipment(orderStore orderStore, shipmentStore shipmentStore, txManager txManager, webhook courierWebhook, logger zerolog.Logger) *shipment {
+	return &shipment{
+		orderStore:    orderStore,
+		shipmentStore: shipmentStore,
+		txManager:     txManager,
+		webhook:       webhook,
+		logger:        logger,
+	}
+}
+
+// shippableStatuses are the order statuses in which new shipments may be created.
+// Orders already shipped may get more shipments when they are split.
+var shippableStatuses = map[model.OrderStatus]bool{
+	model.OrderStatusPaid:    true,
+	model.OrderStatusPacking: true,
+	model.OrderStatusPickup:  true,
+	model.OrderStatusShipped: true,
+}
+
+// CreateShipment is a method that packs items of an order into a new shipment.
+// Each item may be shipped up to the qty ordered, less the qty in earlier shipments; without items,
+// everything not shipped yet is packed. A paid order moves to packing.
+func (s *shipment) CreateShipment(ctx context.Context, bReq model.CreateShipmentRequest) (*model.Shipment, error) {
+	var shipmentID uuid.UUID
+
+	err := s.txManager.WithTx(ctx, func(repos Repositories) error {
+		order, err := repos.Order.GetOrderForUpdate(bReq.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if !order.IsPaid || !shippableStatuses[order.Status] {
+			return fmt.Errorf("%w: order is %s", model.ErrOrderNotShippable, order.Status)
+		}
+
+		orderItems, err := repos.Order.GetOrderItems(order.ID)
+		if err != nil {
+			return err
+		}
+
+		shipped, err := repos.Shipment.GetShippedQty(order.ID)
+		if err != nil {
+			return err
+		}
+
+		items, err := shipmentItems(*orderItems, shipped, bReq.Items)
+		if err != nil {
+			return err
+		}
+
+		id, err := repos.Shipment.CreateShipment(model.Shipment{
+			OrderID:        order.ID,
+			Courier:        bReq.Courier,
+			TrackingNumber: bReq.TrackingNumber,
+			Status:         model.ShipmentStatusPending,
+		})
+		if err != nil {
+			return err
+		}
+		shipmentID = *id
+
+		if err := repos.Shipment.CreateShipmentItems(shipmentID, items); err != nil {
+			return err
+		}
+
+		if order.Status == model.OrderStatusPaid {
+			return moveOrder(repos, order, model.OrderStatusPacking, fmt.Sprintf("shipment %s created", shipmentID))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetShipment(bReq.OrderID, shipmentID)
+}
+
+// shipmentItems checks the requested items against the qty left to ship.
+// Without requested items it returns everything left to ship.
+func shipmentItems(orderItems []model.OrderItem, shipped map[uuid.UUID]int, requested []model.CreateShipmentItem) ([]model.ShipmentItem, error) {
+	items := []model.ShipmentItem{}
+
+	if len(requested) == 0 {
+		for _, orderItem := range orderItems {
+			if left := orderItem.Qty - shipped[orderItem.ID]; left > 0 {
+				items = append(items, model.ShipmentItem{OrderItemID: orderItem.ID, Qty: left})
+			}
+		}
+		if len(items) == 0 {
+			return nil, model.ErrNothingToShip
+		}
+		return items, nil
+	}
+
+	byID := make(map[uuid.UUID]model.OrderItem, len(orderItems))
+	for _, orderItem := range orderItems {
+		byID[orderItem.ID] = orderItem
+	}
+
+	for _, item := range requested {
+		orderItem, ok := byID[item.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", model.ErrUnknownOrderItem, item.OrderItemID)
+		}
+
+		shipped[orderItem.ID] += item.Qty
+		if shipped[orderItem.ID] > orderItem.Qty {
+			return nil, fmt.Errorf("%w: item %s", model.ErrShipmentQtyExceeded, orderItem.ID)
+		}
+
+		items = append(items, model.ShipmentItem{OrderItemID: orderItem.ID, Qty: item.Qty})
+	}
+
+	return items, nil
+}
+
+// AssignTracking is a method that sets the courier and tracking number of a shipment that has not been dispatched yet.
+func (s *shipment) AssignTracking(ctx context.Context, bReq model.AssignTrackingRequest) (*model.Shipment, error) {
+	err := s.txManager.WithTx(ctx, func(repos Repositories) error {
+		shipment, err := repos.Shipment.GetShipmentForUpdate(bReq.OrderID, bReq.ShipmentID)
+		if err != nil {
+			return err
+		}
+
+		if shipment.Status.IsDispatched() {
+			return fmt.Errorf("%w: shipment is %s", model.ErrShipmentDispatched, shipment.Status)
+		}
+
+		return repos.Shipment.UpdateTracking(shipment.ID, bReq.Courier, bReq.TrackingNumber)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetShipment(bReq.OrderID, bReq.ShipmentID)
+}
+
+// ListShipments is a method that retrieves the shipments of an order with their items and tracking events.
+func (s *shipment) ListShipments(orderID uuid.UUID) (*[]model.Shipment, error) {
+	if _, err := s.orderStore.GetOrderByID(orderID); err != nil {
+		return nil, err
+	}
+
+	shipments, err := s.shipmentStore.GetShipmentsByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fillShipments(*shipments); err != nil {
+		return nil, err
+	}
+
+	return shipments, nil
+}
+
+// GetShipment is a method that retrieves a shipment of an order with its items and tracking events.
+func (s *shipment) GetShipment(orderID, shipmentID uuid.UUID) (*model.Shipment, error) {
+	shipment, err := s.shipmentStore.GetShipmentByID(orderID, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	shipments := []model.Shipment{*shipment}
+	if err := s.fillShipments(shipments); err != nil {
+		return nil, err
+	}
+
+	return &shipments[0], nil
+}
+
+// fillShipments loads the items and tracking events of shipments.
+func (s *shipment) fillShipments(shipments []model.Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(shipments))
+	index := make(map[uuid.UUID]int, len(shipments))
+	for i := range shipments {
+		ids = append(ids, shipments[i].ID)
+		index[shipments[i].ID] = i
+		shipments[i].Items = []model.ShipmentItem{}
+		shipments[i].Events = []model.ShipmentEvent{}
+	}
+
+	items, err := s.shipmentStore.GetShipmentItems(ids)
+	if err != nil {
+		return err
+	}
+	for _, item := range *items {
+		i := index[item.ShipmentID]
+		shipments[i].Items = append(shipments[i].Items, item)
+	}
+
+	events, err := s.shipmentStore.GetShipmentEvents(ids)
+	if err != nil {
+		return err
+	}
+	for _, event := range *events {
+		i := index[event.ShipmentID]
+		shipments[i].Events = append(shipments[i].Events, event)
+	}
+
+	return nil
+}
+
+// HandleWebhook is a method that appends a signed courier tracking event to its shipment.
+// The shipment takes the status of its latest event, except that delivered is final. When a shipment is dispatched
+// its order moves to shipped, and once every item of the order has been delivered the order moves to delivered.
+// Repeated events are ignored.
+func (s *shipment) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	logMsgStr := "Usecase:Shipment - HandleWebhook:"
+
+	event, err := s.webhook.ParseWebhook(header, body)
+	if err != nil {
+		return err
+	}
+
+	return s.txManager.WithTx(ctx, func(repos Repositories) error {
+		shipment, err := repos.Shipment.GetShipmentByTrackingForUpdate(event.Courier, event.TrackingNumber)
+		if err != nil {
+			return err
+		}
+
+		created, err := repos.Shipment.CreateShipmentEvent(model.ShipmentEvent{
+			ShipmentID:  shipment.ID,
+			Status:      event.Status,
+			Description: event.Description,
+			Location:    event.Location,
+			OccurredAt:  event.OccurredAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !created {
+			s.logger.Info().Msg(fmt.Sprintf("%v Ignoring repeated %v event for shipment %v", logMsgStr, event.Status, shipment.ID))
+			return nil
+		}
+
+		stale := shipment.LastEventAt != nil && event.OccurredAt.Before(*shipment.LastEventAt)
+		if shipment.Status == model.ShipmentStatusDelivered || stale {
+			return nil
+		}
+
+		if err := repos.Shipment.UpdateShipmentStatus(shipment.ID, event.Status, event.OccurredAt); err != nil {
+			return err
+		}
+
+		if !event.Status.IsDispatched() {
+			return nil
+		}
+
+		return s.advanceOrder(repos, shipment, event)
+	})
+}
+
+// advanceOrder moves the order of a dispatched shipment to shipped, and on to delivered once everything is delivered.
+func (s *shipment) advanceOrder(repos Repositories, shipment *model.Shipment, event *model.CourierEvent) error {
+	order, err := repos.Order.GetOrderForUpdate(shipment.OrderID)
+	if err != nil {
+		return err
+	}
+
+	if order.Status.CanTransitionTo(model.OrderStatusShipped) {
+		note := fmt.Sprintf("shipment %s dispatched with %s, tracking number %s", shipment.ID, shipment.Courier, shipment.TrackingNumber)
+		if err := moveOrder(repos, order, model.OrderStatusShipped, note); err != nil {
+			return err
+		}
+		order.Status = model.OrderStatusShipped
+	}
+
+	if event.Status != model.ShipmentStatusDelivered || order.Status != model.OrderStatusShipped {
+		return nil
+	}
+
+	delivered, err := allDelivered(repos, order.ID)
+	if err != nil || !delivered {
+		return err
+	}
+
+	return moveOrder(repos, order, model.OrderStatusDelivered, "all shipments delivered")
+}
+
+// allDelivered reports whether every item of an order has been packed in shipments that were all delivered.
+func allDelivered(repos Repositories, orderID uuid.UUID) (bool, error) {
+	shipments, err := repos.Shipment.GetShipmentsByOrderID(orderID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, shipment := range *shipments {
+		if shipment.Status != model.ShipmentStatusDelivered {
+			return false, nil
+		}
+	}
+
+	orderItems, err := repos.Order.GetOrderItems(orderID)
+	if err != nil {
+		return false, err
+	}
+
+	shipped, err := repos.Shipment.GetShippedQty(orderID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, orderItem := range *orderItems {
+		if shipped[orderItem.ID] < orderItem.Qty {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// moveOrder moves a locked order to next and records the change in the status log and the outbox.
+func moveOrder(repos Repositories, order *model.Order, next model.OrderStatus, notes string) error {
+	if !order.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", model.ErrInvalidStatusTransition, order.Status, next)
+	}
+
+	if err := repos.Order.UpdateOrderStatus(order.ID, next); err != nil {
+		return err
+	}
+
+	statusLog := model.OrderItemsLogs{
+		OrderID:    order.ID,
+		RefCode:    order.RefCode,
+		FromStatus: order.Status,
+		ToStatus:   next,
+		Notes:      notes,
+	}
+	if _, err := repos.Order.CreateOrderItemsLogs(statusLog); err != nil {
+		return err
+	}
+
+	event, err := model.NewOrderStatusChangedEvent(statusLog)
+	if err != nil {
+		return err
+	}
+
+	return repos.Outbox.CreateEvent(event)
+}
@@ -0,0 +1,532 @@
+package shipment
+
+import (
+	model "cart-order-service/repository/models"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// memState is the data held by memStore. It is copied to roll a failed unit of work back.
+type memState struct {
+	orders        map[uuid.UUID]model.Order
+	orderItems    map[uuid.UUID][]model.OrderItem
+	shipments     []model.Shipment
+	shipmentItems []model.ShipmentItem
+	events        []model.ShipmentEvent
+	statusLogs    []model.OrderItemsLogs
+	outbox        []model.OutboxEvent
+}
+
+func (s memState) clone() memState {
+	c := s
+	c.orders = make(map[uuid.UUID]model.Order, len(s.orders))
+	for id, order := range s.orders {
+		c.orders[id] = order
+	}
+	c.shipments = append([]model.Shipment(nil), s.shipments...)
+	c.shipmentItems = append([]model.ShipmentItem(nil), s.shipmentItems...)
+	c.events = append([]model.ShipmentEvent(nil), s.events...)
+	c.statusLogs = append([]model.OrderItemsLogs(nil), s.statusLogs...)
+	c.outbox = append([]model.OutboxEvent(nil), s.outbox...)
+	return c
+}
+
+// memStore is an in-memory orderStore, shipmentStore and outboxStore.
+type memStore struct {
+	memState
+}
+
+func (s *memStore) GetOrderByID(orderID uuid.UUID) (*model.Order, error) {
+	order, ok := s.orders[orderID]
+	if !ok {
+		return nil, model.ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (s *memStore) GetOrderForUpdate(orderID uuid.UUID) (*model.Order, error) {
+	return s.GetOrderByID(orderID)
+}
+
+func (s *memStore) GetOrderItems(orderID uuid.UUID) (*[]model.OrderItem, error) {
+	items := s.orderItems[orderID]
+	return &items, nil
+}
+
+func (s *memStore) UpdateOrderStatus(orderID uuid.UUID, status model.OrderStatus) error {
+	order := s.orders[orderID]
+	order.Status = status
+	s.orders[orderID] = order
+	return nil
+}
+
+func (s *memStore) CreateOrderItemsLogs(bReq model.OrderItemsLogs) (*string, error) {
+	s.statusLogs = append(s.statusLogs, bReq)
+	return &bReq.RefCode, nil
+}
+
+func (s *memStore) CreateShipment(bReq model.Shipment) (*uuid.UUID, error) {
+	bReq.ID = uuid.New()
+	s.shipments = append(s.shipments, bReq)
+	return &bReq.ID, nil
+}
+
+func (s *memStore) CreateShipmentItems(shipmentID uuid.UUID, items []model.ShipmentItem) error {
+	for _, item := range items {
+		item.ID = uuid.New()
+		item.ShipmentID = shipmentID
+		s.shipmentItems = append(s.shipmentItems, item)
+	}
+	return nil
+}
+
+func (s *memStore) GetShippedQty(orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	shipped := map[uuid.UUID]int{}
+	for _, item := range s.shipmentItems {
+		if sh, err := s.findShipment(item.ShipmentID); err == nil && sh.OrderID == orderID {
+			shipped[item.OrderItemID] += item.Qty
+		}
+	}
+	return shipped, nil
+}
+
+func (s *memStore) GetShipmentByID(orderID, shipmentID uuid.UUID) (*model.Shipment, error) {
+	sh, err := s.findShipment(shipmentID)
+	if err != nil || sh.OrderID != orderID {
+		return nil, model.ErrShipmentNotFound
+	}
+	return sh, nil
+}
+
+func (s *memStore) GetShipmentForUpdate(orderID, shipmentID uuid.UUID) (*model.Shipment, error) {
+	return s.GetShipmentByID(orderID, shipmentID)
+}
+
+func (s *memStore) GetShipmentByTrackingForUpdate(courier, trackingNumber string) (*model.Shipment, error) {
+	for _, sh := range s.shipments {
+		if sh.Courier == courier && sh.TrackingNumber == trackingNumber {
+			return &sh, nil
+		}
+	}
+	return nil, model.ErrShipmentNotFound
+}
+
+func (s *memStore) GetShipmentsByOrderID(orderID uuid.UUID) (*[]model.Shipment, error) {
+	shipments := []model.Shipment{}
+	for _, sh := range s.shipments {
+		if sh.OrderID == orderID {
+			shipments = append(shipments, sh)
+		}
+	}
+	return &shipments, nil
+}
+
+func (s *memStore) GetShipmentItems(shipmentIDs []uuid.UUID) (*[]model.ShipmentItem, error) {
+	items := []model.ShipmentItem{}
+	for _, item := range s.shipmentItems {
+		for _, id := range shipmentIDs {
+			if item.ShipmentID == id {
+				items = append(items, item)
+			}
+		}
+	}
+	return &items, nil
+}
+
+func (s *memStore) GetShipmentEvents(shipmentIDs []uuid.UUID) (*[]model.ShipmentEvent, error) {
+	events := []model.ShipmentEvent{}
+	for _, event := range s.events {
+		for _, id := range shipmentIDs {
+			if event.ShipmentID == id {
+				events = append(events, event)
+			}
+		}
+	}
+	return &events, nil
+}
+
+func (s *memStore) UpdateTracking(shipmentID uuid.UUID, courier, trackingNumber string) error {
+	for i := range s.shipments {
+		if s.shipments[i].ID == shipmentID {
+			s.shipments[i].Courier = courier
+			s.shipments[i].TrackingNumber = trackingNumber
+			return nil
+		}
+	}
+	return model.ErrShipmentNotFound
+}
+
+func (s *memStore) CreateShipmentEvent(bReq model.ShipmentEvent) (bool, error) {
+	for _, event := range s.events {
+		if event.ShipmentID == bReq.ShipmentID && event.Status == bReq.Status && event.OccurredAt.Equal(bReq.OccurredAt) {
+			return false, nil
+		}
+	}
+	bReq.ID = uuid.New()
+	s.events = append(s.events, bReq)
+	return true, nil
+}
+
+func (s *memStore) UpdateShipmentStatus(shipmentID uuid.UUID, status model.ShipmentStatus, at time.Time) error {
+	for i := range s.shipments {
+		if s.shipments[i].ID == shipmentID {
+			s.shipments[i].Status = status
+			s.shipments[i].LastEventAt = &at
+			return nil
+		}
+	}
+	return model.ErrShipmentNotFound
+}
+
+func (s *memStore) CreateEvent(event model.OutboxEvent) error {
+	s.outbox = append(s.outbox, event)
+	return nil
+}
+
+func (s *memStore) findShipment(shipmentID uuid.UUID) (*model.Shipment, error) {
+	for _, sh := range s.shipments {
+		if sh.ID == shipmentID {
+			return &sh, nil
+		}
+	}
+	return nil, model.ErrShipmentNotFound
+}
+
+// memTxManager runs a unit of work against memStore and rolls its changes back if it fails.
+type memTxManager struct {
+	store *memStore
+}
+
+func (m memTxManager) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	snapshot := m.store.memState.clone()
+
+	err := fn(Repositories{Order: m.store, Shipment: m.store, Outbox: m.store})
+	if err != nil {
+		m.store.memState = snapshot
+	}
+
+	return err
+}
+
+// fakeWebhook hands out the event it is given as the verified payload of the next webhook.
+type fakeWebhook struct {
+	event *model.CourierEvent
+}
+
+func (w *fakeWebhook) ParseWebhook(header http.Header, body []byte) (*model.CourierEvent, error) {
+	if w.event == nil {
+		return nil, model.ErrInvalidWebhookPayload
+	}
+	return w.event, nil
+}
+
+// fixture is a paid order of two lines, 2 units of lineA and 1 of lineB.
+type fixture struct {
+	store   *memStore
+	webhook *fakeWebhook
+	s       *shipment
+	orderID uuid.UUID
+	lineA   model.OrderItem
+	lineB   model.OrderItem
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	orderID := uuid.New()
+	lineA := model.OrderItem{ID: uuid.New(), OrderID: orderID, ProductID: uuid.New(), Qty: 2}
+	lineB := model.OrderItem{ID: uuid.New(), OrderID: orderID, ProductID: uuid.New(), Qty: 1}
+
+	store := &memStore{memState: memState{
+		orders: map[uuid.UUID]model.Order{orderID: {
+			ID:      orderID,
+			RefCode: "REF-1",
+			Status:  model.OrderStatusPaid,
+			IsPaid:  true,
+		}},
+		orderItems: map[uuid.UUID][]model.OrderItem{orderID: {lineA, lineB}},
+	}}
+	webhook := &fakeWebhook{}
+
+	return &fixture{
+		store:   store,
+		webhook: webhook,
+		s:       NewShipment(store, store, memTxManager{store: store}, webhook, zerolog.Nop()),
+		orderID: orderID,
+		lineA:   lineA,
+		lineB:   lineB,
+	}
+}
+
+func (f *fixture) orderStatus() model.OrderStatus {
+	return f.store.orders[f.orderID].Status
+}
+
+// ship creates a shipment of items with a tracking number.
+func (f *fixture) ship(t *testing.T, trackingNumber string, items ...model.CreateShipmentItem) *model.Shipment {
+	t.Helper()
+
+	sh, err := f.s.CreateShipment(context.Background(), model.CreateShipmentRequest{
+		OrderID:        f.orderID,
+		Courier:        "jne",
+		TrackingNumber: trackingNumber,
+		Items:          items,
+	})
+	if err != nil {
+		t.Fatalf("CreateShipment: %v", err)
+	}
+	return sh
+}
+
+// track delivers a courier event for a tracking number through the webhook.
+func (f *fixture) track(t *testing.T, trackingNumber string, status model.ShipmentStatus, at time.Time) {
+	t.Helper()
+
+	f.webhook.event = &model.CourierEvent{Courier: "jne", TrackingNumber: trackingNumber, Status: status, OccurredAt: at}
+	if err := f.s.HandleWebhook(context.Background(), http.Header{}, nil); err != nil {
+		t.Fatalf("HandleWebhook %s: %v", status, err)
+	}
+}
+
+func TestCreateShipmentPacksEverythingLeft(t *testing.T) {
+	f := newFixture(t)
+
+	sh := f.ship(t, "TRK-1")
+
+	if sh.Status != model.ShipmentStatusPending {
+		t.Errorf("shipment status = %s, want %s", sh.Status, model.ShipmentStatusPending)
+	}
+	packed := map[uuid.UUID]int{}
+	for _, item := range sh.Items {
+		packed[item.OrderItemID] = item.Qty
+	}
+	if packed[f.lineA.ID] != 2 || packed[f.lineB.ID] != 1 {
+		t.Errorf("packed %v, want 2 of line A and 1 of line B", packed)
+	}
+
+	if got := f.orderStatus(); got != model.OrderStatusPacking {
+		t.Errorf("order status = %s, want %s", got, model.OrderStatusPacking)
+	}
+	if len(f.store.statusLogs) != 1 || len(f.store.outbox) != 1 {
+		t.Errorf("recorded %d status logs and %d events, want 1 each", len(f.store.statusLogs), len(f.store.outbox))
+	}
+}
+
+func TestCreateShipmentSplitsOrder(t *testing.T) {
+	f := newFixture(t)
+
+	first := f.ship(t, "TRK-1", model.CreateShipmentItem{OrderItemID: f.lineA.ID, Qty: 1})
+	second := f.ship(t, "TRK-2")
+
+	if len(first.Items) != 1 || first.Items[0].Qty != 1 {
+		t.Errorf("first shipment items = %+v, want 1 of line A", first.Items)
+	}
+	packed := map[uuid.UUID]int{}
+	for _, item := range second.Items {
+		packed[item.OrderItemID] = item.Qty
+	}
+	if packed[f.lineA.ID] != 1 || packed[f.lineB.ID] != 1 {
+		t.Errorf("second shipment packed %v, want the rest: 1 of line A and 1 of line B", packed)
+	}
+
+	shipments, err := f.s.ListShipments(f.orderID)
+	if err != nil {
+		t.Fatalf("ListShipments: %v", err)
+	}
+	if len(*shipments) != 2 {
+		t.Errorf("order has %d shipments, want 2", len(*shipments))
+	}
+
+	if _, err := f.s.CreateShipment(context.Background(), model.CreateShipmentRequest{OrderID: f.orderID}); !errors.Is(err, model.ErrNothingToShip) {
+		t.Errorf("third shipment error = %v, want %v", err, model.ErrNothingToShip)
+	}
+}
+
+func TestCreateShipmentRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(f *fixture)
+		items   func(f *fixture) []model.CreateShipmentItem
+		wantErr error
+	}{
+		{
+			name: "unpaid order",
+			prepare: func(f *fixture) {
+				order := f.store.orders[f.orderID]
+				order.Status, order.IsPaid = model.OrderStatusPending, false
+				f.store.orders[f.orderID] = order
+			},
+			wantErr: model.ErrOrderNotShippable,
+		},
+		{
+			name: "cancelled order",
+			prepare: func(f *fixture) {
+				order := f.store.orders[f.orderID]
+				order.Status = model.OrderStatusCancelled
+				f.store.orders[f.orderID] = order
+			},
+			wantErr: model.ErrOrderNotShippable,
+		},
+		{
+			name: "more than ordered",
+			items: func(f *fixture) []model.CreateShipmentItem {
+				return []model.CreateShipmentItem{{OrderItemID: f.lineA.ID, Qty: 3}}
+			},
+			wantErr: model.ErrShipmentQtyExceeded,
+		},
+		{
+			name: "same line twice beyond the qty ordered",
+			items: func(f *fixture) []model.CreateShipmentItem {
+				return []model.CreateShipmentItem{{OrderItemID: f.lineB.ID, Qty: 1}, {OrderItemID: f.lineB.ID, Qty: 1}}
+			},
+			wantErr: model.ErrShipmentQtyExceeded,
+		},
+		{
+			name: "item of another order",
+			items: func(f *fixture) []model.CreateShipmentItem {
+				return []model.CreateShipmentItem{{OrderItemID: uuid.New(), Qty: 1}}
+			},
+			wantErr: model.ErrUnknownOrderItem,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+			status := f.orderStatus()
+
+			bReq := model.CreateShipmentRequest{OrderID: f.orderID}
+			if tt.items != nil {
+				bReq.Items = tt.items(f)
+			}
+
+			if _, err := f.s.CreateShipment(context.Background(), bReq); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateShipment error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(f.store.shipments) != 0 || f.orderStatus() != status {
+				t.Error("a rejected shipment changed the order")
+			}
+		})
+	}
+}
+
+func TestAssignTracking(t *testing.T) {
+	f := newFixture(t)
+	sh := f.ship(t, "")
+
+	got, err := f.s.AssignTracking(context.Background(), model.AssignTrackingRequest{
+		OrderID: f.orderID, ShipmentID: sh.ID, Courier: "sicepat", TrackingNumber: "TRK-9",
+	})
+	if err != nil {
+		t.Fatalf("AssignTracking: %v", err)
+	}
+	if got.Courier != "sicepat" || got.TrackingNumber != "TRK-9" {
+		t.Errorf("tracking = %s %s, want sicepat TRK-9", got.Courier, got.TrackingNumber)
+	}
+
+	f.webhook.event = &model.CourierEvent{Courier: "sicepat", TrackingNumber: "TRK-9", Status: model.ShipmentStatusPickedUp, OccurredAt: time.Now()}
+	if err := f.s.HandleWebhook(context.Background(), http.Header{}, nil); err != nil {
+		t.Fatalf("HandleWebhook: %v", err)
+	}
+
+	_, err = f.s.AssignTracking(context.Background(), model.AssignTrackingRequest{
+		OrderID: f.orderID, ShipmentID: sh.ID, Courier: "jne", TrackingNumber: "TRK-10",
+	})
+	if !errors.Is(err, model.ErrShipmentDispatched) {
+		t.Errorf("AssignTracking after pickup error = %v, want %v", err, model.ErrShipmentDispatched)
+	}
+}
+
+func TestHandleWebhookAdvancesOrder(t *testing.T) {
+	f := newFixture(t)
+	f.ship(t, "TRK-1", model.CreateShipmentItem{OrderItemID: f.lineA.ID, Qty: 2})
+	f.ship(t, "TRK-2")
+	start := time.Now().Add(-time.Hour)
+
+	f.track(t, "TRK-1", model.ShipmentStatusPickedUp, start)
+	if got := f.orderStatus(); got != model.OrderStatusShipped {
+		t.Fatalf("order status after first pickup = %s, want %s", got, model.OrderStatusShipped)
+	}
+
+	f.track(t, "TRK-1", model.ShipmentStatusDelivered, start.Add(time.Minute))
+	if got := f.orderStatus(); got != model.OrderStatusShipped {
+		t.Errorf("order status with one shipment delivered = %s, want %s", got, model.OrderStatusShipped)
+	}
+
+	f.track(t, "TRK-2", model.ShipmentStatusInTransit, start.Add(2*time.Minute))
+	f.track(t, "TRK-2", model.ShipmentStatusDelivered, start.Add(3*time.Minute))
+	if got := f.orderStatus(); got != model.OrderStatusDelivered {
+		t.Errorf("order status with every shipment delivered = %s, want %s", got, model.OrderStatusDelivered)
+	}
+
+	var moves []model.OrderStatus
+	for _, statusLog := range f.store.statusLogs {
+		moves = append(moves, statusLog.ToStatus)
+	}
+	want := []model.OrderStatus{model.OrderStatusPacking, model.OrderStatusShipped, model.OrderStatusDelivered}
+	if len(moves) != len(want) || len(f.store.outbox) != len(want) {
+		t.Fatalf("order moved %v with %d events, want %v with one event each", moves, len(f.store.outbox), want)
+	}
+	for i := range want {
+		if moves[i] != want[i] {
+			t.Errorf("move %d = %s, want %s", i, moves[i], want[i])
+		}
+	}
+}
+
+func TestHandleWebhookIgnoresRepeatedAndStaleEvents(t *testing.T) {
+	f := newFixture(t)
+	f.ship(t, "TRK-1")
+	start := time.Now().Add(-time.Hour)
+
+	f.track(t, "TRK-1", model.ShipmentStatusInTransit, start.Add(time.Minute))
+	f.track(t, "TRK-1", model.ShipmentStatusInTransit, start.Add(time.Minute))
+	if len(f.store.events) != 1 {
+		t.Errorf("stored %d events for a repeated webhook, want 1", len(f.store.events))
+	}
+
+	f.track(t, "TRK-1", model.ShipmentStatusPickedUp, start)
+	if len(f.store.events) != 2 {
+		t.Errorf("stored %d events, want the late event appended", len(f.store.events))
+	}
+	if got := f.store.shipments[0].Status; got != model.ShipmentStatusInTransit {
+		t.Errorf("shipment status after a late event = %s, want %s", got, model.ShipmentStatusInTransit)
+	}
+
+	f.track(t, "TRK-1", model.ShipmentStatusDelivered, start.Add(2*time.Minute))
+	f.track(t, "TRK-1", model.ShipmentStatusFailed, start.Add(3*time.Minute))
+	if got := f.store.shipments[0].Status; got != model.ShipmentStatusDelivered {
+		t.Errorf("shipment status after an event following delivery = %s, want %s", got, model.ShipmentStatusDelivered)
+	}
+	if got := f.orderStatus(); got != model.OrderStatusDelivered {
+		t.Errorf("order status = %s, want %s", got, model.OrderStatusDelivered)
+	}
+}
+
+func TestHandleWebhookRejects(t *testing.T) {
+	f := newFixture(t)
+	f.ship(t, "TRK-1")
+
+	if err := f.s.HandleWebhook(context.Background(), http.Header{}, nil); !errors.Is(err, model.ErrInvalidWebhookPayload) {
+		t.Errorf("unverified webhook error = %v, want %v", err, model.ErrInvalidWebhookPayload)
+	}
+
+	f.webhook.event = &model.CourierEvent{Courier: "jne", TrackingNumber: "TRK-unknown", Status: model.ShipmentStatusPickedUp, OccurredAt: time.Now()}
+	if err := f.s.HandleWebhook(context.Background(), http.Header{}, nil); !errors.Is(err, model.ErrShipmentNotFound) {
+		t.Errorf("unknown tracking number error = %v, want %v", err, model.ErrShipmentNotFound)
+	}
+
+	if len(f.store.events) != 0 || f.orderStatus() != model.OrderStatusPacking {
+		t.Error("a rejected webhook changed the shipment or order")
+	}
+}
@@ -0,0 +1,47 @@
+// Package carttoken issues and verifies the opaque tokens that identify guest carts.
+package carttoken
+
+import (
+	model "cart-order-service/repository/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/google/uuid"
+)
+
+// Header is the request header a guest cart token is sent in.
+const Header = "X-Cart-Token"
+
+// Sign returns the token of a guest cart: the base64url encoding of the cart ID followed by its HMAC-SHA256,
+// so that a client cannot make up a token for a cart it was not given.
+func Sign(secret []byte, cartID uuid.UUID) string {
+	payload := append(cartID[:], mac(secret, cartID)...)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// Verify returns the guest cart ID carried by token.
+// It returns model.ErrInvalidCartToken if the token is malformed or was not signed with secret.
+func Verify(secret []byte, token string) (uuid.UUID, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(payload) != len(uuid.Nil)+sha256.Size {
+		return uuid.Nil, model.ErrInvalidCartToken
+	}
+
+	cartID, err := uuid.FromBytes(payload[:len(uuid.Nil)])
+	if err != nil {
+		return uuid.Nil, model.ErrInvalidCartToken
+	}
+
+	if !hmac.Equal(payload[len(uuid.Nil):], mac(secret, cartID)) {
+		return uuid.Nil, model.ErrInvalidCartToken
+	}
+
+	return cartID, nil
+}
+
+func mac(secret []byte, cartID uuid.UUID) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write(cartID[:])
+	return m.Sum(nil)
+}
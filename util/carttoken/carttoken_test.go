@@ -0,0 +1,58 @@
+package carttoken
+
+import (
+	model "cart-order-service/repository/models"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	secret := []byte("secret")
+	cartID := uuid.New()
+
+	got, err := Verify(secret, Sign(secret, cartID))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got != cartID {
+		t.Errorf("cart ID = %s, want %s", got, cartID)
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	secret := []byte("secret")
+	cartID := uuid.New()
+	token := Sign(secret, cartID)
+
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatalf("decode token: %v", err)
+	}
+	other := uuid.New()
+	copy(payload, other[:])
+	swapped := base64.RawURLEncoding.EncodeToString(payload)
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+	}{
+		{name: "wrong secret", secret: []byte("other secret"), token: token},
+		{name: "cart ID swapped", secret: secret, token: swapped},
+		{name: "truncated", secret: secret, token: token[:len(token)-4]},
+		{name: "not base64url", secret: secret, token: token[:len(token)-1] + "!"},
+		{name: "bare cart ID", secret: secret, token: cartID.String()},
+		{name: "empty", secret: secret, token: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.secret, tt.token); !errors.Is(err, model.ErrInvalidCartToken) {
+				t.Errorf("Verify error = %v, want %v", err, model.ErrInvalidCartToken)
+			}
+		})
+	}
+}
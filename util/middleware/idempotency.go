@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"cart-order-service/helper"
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/carttoken"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyStore is an interface that records requests made with an Idempotency-Key.
+type idempotencyStore interface {
+	Reserve(bReq model.IdempotencyKey) (bool, error)
+	Get(scope, key, method, path string) (*model.IdempotencyKey, error)
+	Complete(bReq model.IdempotencyKey) error
+	Release(scope, key, method, path string) error
+}
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to retry.
+// Keys are scoped to the caller, see callerScope. The first request with a key runs and its response
+// is recorded; repeats with the same body get the recorded response back, and repeats with a different
+// body, or while the first request is still running, get 409 Conflict.
+// Server errors are not recorded, so the client can retry them with the same key.
+// Requests without the header pass through untouched.
+func Idempotency(store idempotencyStore, logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logMsgStr := "Middleware - Idempotency:"
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				helper.HandleResponse(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				helper.HandleResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.Sum256(body)
+			rec := model.IdempotencyKey{
+				Scope:       callerScope(r),
+				Key:         key,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				RequestHash: hex.EncodeToString(hash[:]),
+			}
+
+			reserved, existing, err := reserve(store, rec)
+			if err != nil {
+				logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to reserve key", logMsgStr))
+				helper.HandleResponse(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			if !reserved {
+				inProgress := fmt.Sprintf("a request with this %s is still in progress, retry it later", IdempotencyKeyHeader)
+				switch {
+				case existing == nil:
+					helper.HandleResponse(w, http.StatusConflict, inProgress)
+				case existing.RequestHash != rec.RequestHash:
+					helper.HandleResponse(w, http.StatusConflict, fmt.Sprintf("%s was already used with a different request body", IdempotencyKeyHeader))
+				case existing.CompletedAt == nil:
+					helper.HandleResponse(w, http.StatusConflict, inProgress)
+				default:
+					replayResponse(w, existing)
+				}
+				return
+			}
+
+			recorder := httptest.NewRecorder()
+			next.ServeHTTP(recorder, r)
+
+			if recorder.Code >= http.StatusInternalServerError {
+				if err := store.Release(rec.Scope, rec.Key, rec.Method, rec.Path); err != nil {
+					logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to release key", logMsgStr))
+				}
+			} else {
+				rec.StatusCode = recorder.Code
+				rec.ResponseBody = recorder.Body.Bytes()
+				rec.ResponseHeaders, err = json.Marshal(recorder.Header())
+				if err == nil {
+					err = store.Complete(rec)
+				}
+				if err != nil {
+					logger.Error().AnErr("Err", err).Msg(fmt.Sprintf("%v failed to record response", logMsgStr))
+				}
+			}
+
+			for k, v := range recorder.Header() {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(recorder.Code)
+			recorder.Body.WriteTo(w)
+		})
+	}
+}
+
+// reserve claims the key of rec, or returns the request that holds it. If that request releases the key
+// before it can be read, the key is claimed again once; if it is taken again, neither is returned.
+func reserve(store idempotencyStore, rec model.IdempotencyKey) (bool, *model.IdempotencyKey, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := store.Reserve(rec)
+		if err != nil || reserved {
+			return reserved, nil, err
+		}
+
+		existing, err := store.Get(rec.Scope, rec.Key, rec.Method, rec.Path)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return false, nil, err
+		}
+
+		return false, existing, nil
+	}
+
+	return false, nil, nil
+}
+
+// callerScope returns the scope an Idempotency-Key is recorded under: a hash of the authenticated user,
+// or else of the credentials the request carries (bearer token or guest cart token), or else of the
+// client address.
+func callerScope(r *http.Request) string {
+	identity := "addr:" + clientAddr(r)
+	switch {
+	case GetUserID(r.Context()) != "":
+		identity = "user:" + GetUserID(r.Context())
+	case r.Header.Get("Authorization") != "":
+		identity = "auth:" + r.Header.Get("Authorization")
+	case r.Header.Get(carttoken.Header) != "":
+		identity = "cart:" + r.Header.Get(carttoken.Header)
+	}
+
+	hash := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(hash[:])
+}
+
+// clientAddr returns the host part of the request's remote address.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func replayResponse(w http.ResponseWriter, rec *model.IdempotencyKey) {
+	var header http.Header
+	if err := json.Unmarshal(rec.ResponseHeaders, &header); err == nil {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	w.Write(rec.ResponseBody)
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	model "cart-order-service/repository/models"
+	"cart-order-service/util/carttoken"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// memIdempotencyStore is an in-memory idempotencyStore. Keys never expire.
+type memIdempotencyStore struct {
+	keys map[string]model.IdempotencyKey
+	// releaseOnGet, when set, releases the key between Reserve and Get, like a request that fails meanwhile.
+	releaseOnGet bool
+}
+
+func newMemIdempotencyStore() *memIdempotencyStore {
+	return &memIdempotencyStore{keys: map[string]model.IdempotencyKey{}}
+}
+
+func memKey(scope, key, method, path string) string {
+	return strings.Join([]string{scope, key, method, path}, " ")
+}
+
+func (s *memIdempotencyStore) Reserve(bReq model.IdempotencyKey) (bool, error) {
+	id := memKey(bReq.Scope, bReq.Key, bReq.Method, bReq.Path)
+	if _, ok := s.keys[id]; ok {
+		return false, nil
+	}
+	s.keys[id] = bReq
+	return true, nil
+}
+
+func (s *memIdempotencyStore) Get(scope, key, method, path string) (*model.IdempotencyKey, error) {
+	if s.releaseOnGet {
+		s.releaseOnGet = false
+		delete(s.keys, memKey(scope, key, method, path))
+	}
+
+	rec, ok := s.keys[memKey(scope, key, method, path)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &rec, nil
+}
+
+func (s *memIdempotencyStore) Complete(bReq model.IdempotencyKey) error {
+	now := time.Now()
+	bReq.CompletedAt = &now
+	s.keys[memKey(bReq.Scope, bReq.Key, bReq.Method, bReq.Path)] = bReq
+	return nil
+}
+
+func (s *memIdempotencyStore) Release(scope, key, method, path string) error {
+	id := memKey(scope, key, method, path)
+	if rec, ok := s.keys[id]; ok && rec.CompletedAt == nil {
+		delete(s.keys, id)
+	}
+	return nil
+}
+
+// countingHandler answers with status and counts how often it ran.
+type countingHandler struct {
+	calls  int
+	status int
+	// during runs inside the handler, while its key is reserved.
+	during func()
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	if h.during != nil {
+		h.during()
+	}
+	w.Header().Set("X-Call", "1")
+	w.WriteHeader(h.status)
+	io.WriteString(w, `{"order":"created"}`)
+}
+
+func idempotentRequest(key, body string, header ...string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/order/create", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return req
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysCompletedRequest(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	h := Idempotency(newMemIdempotencyStore(), zerolog.Nop())(next)
+
+	first := serve(h, idempotentRequest("k1", `{"a":1}`))
+	second := serve(h, idempotentRequest("k1", `{"a":1}`))
+
+	if next.calls != 1 {
+		t.Errorf("handler ran %d times, want once", next.calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get(IdempotencyReplayedHeader) != "true" || second.Header().Get("X-Call") != "1" {
+		t.Errorf("replay headers = %v, want the recorded headers and %s", second.Header(), IdempotencyReplayedHeader)
+	}
+	if first.Header().Get(IdempotencyReplayedHeader) != "" {
+		t.Errorf("first response is marked replayed")
+	}
+}
+
+func TestIdempotencyRejectsDifferentBody(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	h := Idempotency(newMemIdempotencyStore(), zerolog.Nop())(next)
+
+	serve(h, idempotentRequest("k1", `{"a":1}`))
+	got := serve(h, idempotentRequest("k1", `{"a":2}`))
+
+	if got.Code != http.StatusConflict || next.calls != 1 {
+		t.Errorf("status %d after %d calls, want %d after 1", got.Code, next.calls, http.StatusConflict)
+	}
+}
+
+func TestIdempotencyRejectsRequestInFlight(t *testing.T) {
+	store := newMemIdempotencyStore()
+	next := &countingHandler{status: http.StatusCreated}
+	h := Idempotency(store, zerolog.Nop())(next)
+
+	var inFlight *httptest.ResponseRecorder
+	next.during = func() {
+		next.during = nil
+		inFlight = serve(h, idempotentRequest("k1", `{"a":1}`))
+	}
+	serve(h, idempotentRequest("k1", `{"a":1}`))
+
+	if inFlight.Code != http.StatusConflict || next.calls != 1 {
+		t.Errorf("status %d after %d calls, want %d after 1", inFlight.Code, next.calls, http.StatusConflict)
+	}
+}
+
+func TestIdempotencyKeyReleasedBeforeGet(t *testing.T) {
+	store := newMemIdempotencyStore()
+	next := &countingHandler{status: http.StatusCreated}
+	h := Idempotency(store, zerolog.Nop())(next)
+
+	// The first request fails with a server error and releases its key while the second reads it.
+	next.during = func() {
+		next.during = nil
+		store.releaseOnGet = true
+		got := serve(h, idempotentRequest("k1", `{"a":1}`))
+		if got.Code != http.StatusCreated {
+			t.Errorf("request retried after the release: status %d, want %d", got.Code, http.StatusCreated)
+		}
+	}
+	serve(h, idempotentRequest("k1", `{"a":1}`))
+
+	if next.calls != 2 {
+		t.Errorf("handler ran %d times, want twice", next.calls)
+	}
+}
+
+func TestIdempotencyReleasesServerErrors(t *testing.T) {
+	next := &countingHandler{status: http.StatusInternalServerError}
+	h := Idempotency(newMemIdempotencyStore(), zerolog.Nop())(next)
+
+	serve(h, idempotentRequest("k1", `{"a":1}`))
+	next.status = http.StatusCreated
+	got := serve(h, idempotentRequest("k1", `{"a":1}`))
+
+	if got.Code != http.StatusCreated || next.calls != 2 {
+		t.Errorf("retry: status %d after %d calls, want %d after 2", got.Code, next.calls, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyKeysAreScopedToTheCaller(t *testing.T) {
+	tests := []struct {
+		name  string
+		other *http.Request
+	}{
+		{name: "another bearer token", other: idempotentRequest("k1", `{"a":1}`, "Authorization", "Bearer bob")},
+		{name: "a guest cart token", other: idempotentRequest("k1", `{"a":1}`, carttoken.Header, "guest")},
+		{name: "no credentials", other: idempotentRequest("k1", `{"a":1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingHandler{status: http.StatusCreated}
+			h := Idempotency(newMemIdempotencyStore(), zerolog.Nop())(next)
+
+			serve(h, idempotentRequest("k1", `{"a":1}`, "Authorization", "Bearer alice"))
+			got := serve(h, tt.other)
+
+			if got.Header().Get(IdempotencyReplayedHeader) != "" || next.calls != 2 {
+				t.Errorf("another caller got the recorded response; handler ran %d times, want twice", next.calls)
+			}
+		})
+	}
+
+	req := idempotentRequest("k1", "")
+	if callerScope(req) != callerScope(req.WithContext(SetUserID(req.Context(), ""))) {
+		t.Error("an empty user changes the scope")
+	}
+	if callerScope(req) == callerScope(req.WithContext(SetUserID(req.Context(), "alice@example.com"))) {
+		t.Error("the authenticated user is not part of the scope")
+	}
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	h := Idempotency(newMemIdempotencyStore(), zerolog.Nop())(next)
+
+	serve(h, idempotentRequest("", `{"a":1}`))
+	serve(h, idempotentRequest("", `{"a":1}`))
+	got := serve(h, idempotentRequest(strings.Repeat("k", maxIdempotencyKeyLength+1), `{"a":1}`))
+
+	if next.calls != 2 || got.Code != http.StatusBadRequest {
+		t.Errorf("handler ran %d times, long key got %d, want 2 and %d", next.calls, got.Code, http.StatusBadRequest)
+	}
+}
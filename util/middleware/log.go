@@ -4,23 +4,46 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"net/http/httptest"
 	"time"
 )
 
+// statusRecorder passes writes through to the client and remembers the status code for the access log.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush or extend deadlines.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// LoggerMiddleware logs each request once it has been served. Responses are streamed to the client as they are
+// written rather than buffered, so large downloads do not pile up in memory.
 func LoggerMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 
-			recorder := httptest.NewRecorder()
+			recorder := &statusRecorder{ResponseWriter: w}
 			next.ServeHTTP(recorder, r)
-
-			for k, v := range recorder.Header() {
-				w.Header()[k] = v
+			if recorder.code == 0 {
+				recorder.code = http.StatusOK
 			}
-			w.WriteHeader(recorder.Code)
-			recorder.Body.WriteTo(w)
 
 			responseTime := time.Since(start).Seconds()
 			logMessage := fmt.Sprintf("%s - [%s] - \"%s %s %s\" %d %s - [%s]\n",
@@ -29,7 +52,7 @@ func LoggerMiddleware() func(http.Handler) http.Handler {
 				r.Method,
 				r.URL.Path,
 				r.Proto,
-				recorder.Code,
+				recorder.code,
 				r.UserAgent(),
 				fmt.Sprintf("%.9fµs", responseTime),
 			)
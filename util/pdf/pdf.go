@@ -0,0 +1,274 @@
+// Package pdf writes simple PDF documents made of text, lines and filled rectangles.
+//
+// Text is set in the standard Helvetica fonts, which every PDF reader provides, so no font data is embedded.
+// The output only depends on what was drawn, which makes it byte-for-byte reproducible.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A4 page size in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font is one of the standard fonts a document can use.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+// resource returns the name the font is registered under in the page resources.
+func (f Font) resource() string {
+	if f == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// Color is an RGB color with components between 0 and 1.
+type Color struct {
+	R, G, B float64
+}
+
+// RGB returns the color of 8-bit red, green and blue components.
+func RGB(r, g, b uint8) Color {
+	return Color{R: float64(r) / 255, G: float64(g) / 255, B: float64(b) / 255}
+}
+
+// Black is the default text color.
+var Black = Color{}
+
+// Document is a PDF document under construction.
+type Document struct {
+	Title     string
+	Author    string
+	CreatedAt time.Time
+
+	width  float64
+	height float64
+	pages  []*Page
+}
+
+// New is a constructor function that returns an empty document whose pages are width by height points.
+func New(width, height float64) *Document {
+	return &Document{
+		width:  width,
+		height: height,
+	}
+}
+
+// AddPage is a method that appends a blank page to the document and returns it.
+func (d *Document) AddPage() *Page {
+	p := &Page{height: d.height}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages is a method that returns the pages added so far.
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Page is a page of a document. Positions are in points, measured from the top left corner of the page.
+type Page struct {
+	height  float64
+	content bytes.Buffer
+}
+
+// Text is a method that draws s with its baseline starting at x, y.
+func (p *Page) Text(x, y float64, font Font, size float64, c Color, s string) {
+	fmt.Fprintf(&p.content, "BT %s rg /%s %s Tf %s %s Td (%s) Tj ET\n",
+		c.operands(), font.resource(), num(size), num(x), num(p.height-y), encode(s))
+}
+
+// TextRight is a method that draws s with its baseline ending at x, y.
+func (p *Page) TextRight(x, y float64, font Font, size float64, c Color, s string) {
+	p.Text(x-TextWidth(font, size, s), y, font, size, c, s)
+}
+
+// Line is a method that draws a straight line of the given width from x1, y1 to x2, y2.
+func (p *Page) Line(x1, y1, x2, y2, width float64, c Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n",
+		c.operands(), num(width), num(x1), num(p.height-y1), num(x2), num(p.height-y2))
+}
+
+// FillRect is a method that fills a w by h rectangle whose top left corner is at x, y.
+func (p *Page) FillRect(x, y, w, h float64, c Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n",
+		c.operands(), num(x), num(p.height-y-h), num(w), num(h))
+}
+
+// TextWidth returns the width of s set in font at size points.
+func TextWidth(font Font, size float64, s string) float64 {
+	widths := helveticaWidths
+	if font == HelveticaBold {
+		widths = helveticaBoldWidths
+	}
+
+	var units int
+	for _, b := range []byte(toWinAnsi(s)) {
+		if b >= firstWidthChar && int(b-firstWidthChar) < len(widths) {
+			units += widths[b-firstWidthChar]
+		} else {
+			units += defaultWidth
+		}
+	}
+
+	return float64(units) * size / 1000
+}
+
+// Truncate shortens s with an ellipsis until it fits in maxWidth when set in font at size points.
+func Truncate(font Font, size float64, s string, maxWidth float64) string {
+	if TextWidth(font, size, s) <= maxWidth {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		t := strings.TrimRight(string(runes), " ") + "..."
+		if TextWidth(font, size, t) <= maxWidth {
+			return t
+		}
+	}
+
+	return ""
+}
+
+// Bytes is a method that renders the document.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo is a method that renders the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	const (
+		catalogObj = 1
+		pagesObj   = 2
+		fontObj    = 3
+		boldObj    = 4
+		infoObj    = 5
+		firstPage  = 6
+	)
+
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{height: d.height}}
+	}
+
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// The binary comment marks the file as binary for transfer tools.
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		strings.Join(kids, " "), len(pages), num(d.width), num(d.height)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	info := fmt.Sprintf("/Producer (%s)", encode("cart-order-service"))
+	if d.Title != "" {
+		info += fmt.Sprintf(" /Title (%s)", encode(d.Title))
+	}
+	if d.Author != "" {
+		info += fmt.Sprintf(" /Author (%s)", encode(d.Author))
+	}
+	if !d.CreatedAt.IsZero() {
+		info += fmt.Sprintf(" /CreationDate (D:%sZ)", d.CreatedAt.UTC().Format("20060102150405"))
+	}
+	object("<< " + info + " >>")
+
+	for i, p := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, fontObj, boldObj, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, catalogObj, infoObj, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func (c Color) operands() string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B)
+}
+
+// num formats a number with at most three decimals. PDF has no exponent notation.
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 3, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// toWinAnsi maps s to the single-byte encoding of the standard fonts. Characters it cannot hold become '?'.
+func toWinAnsi(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x20:
+			continue
+		case r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+// encode returns s as the contents of a PDF literal string.
+func encode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(toWinAnsi(s)) {
+		switch c {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c >= 0x80 {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func sampleDocument() *Document {
+	d := New(A4Width, A4Height)
+	d.Title = "Invoice (INV-1)"
+	d.CreatedAt = time.Date(2026, 10, 17, 8, 30, 0, 0, time.UTC)
+
+	page := d.AddPage()
+	page.FillRect(0, 0, A4Width, 72, RGB(238, 77, 45))
+	page.Text(48, 40, HelveticaBold, 20, Black, "INVOICE")
+	page.TextRight(A4Width-48, 40, Helvetica, 10, Black, "Café ✓")
+	page.Line(48, 80, A4Width-48, 80, 0.5, Black)
+	d.AddPage()
+	return d
+}
+
+func TestBytesWritesValidXref(t *testing.T) {
+	out, err := sampleDocument().Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("output is not framed by a PDF header and an EOF marker")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	// Catalog, pages, two fonts, info, and a page and content stream per page.
+	if len(entries) != 9 {
+		t.Fatalf("xref has %d objects, want 9", len(entries))
+	}
+	for i, entry := range entries {
+		off, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want object %d", i+1, out[off:off+10], i+1)
+		}
+	}
+
+	if !bytes.Contains(out, []byte(`/Title (Invoice \(INV-1\))`)) {
+		t.Error("title parentheses are not escaped")
+	}
+	if !bytes.Contains(out, []byte(`(Caf\351 ?) Tj`)) {
+		t.Error("text is not mapped to WinAnsi with '?' for unsupported characters")
+	}
+}
+
+func TestBytesIsReproducible(t *testing.T) {
+	a, err := sampleDocument().Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	b, err := sampleDocument().Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+
+	if !bytes.Equal(a, b) {
+		t.Error("the same drawing rendered to different bytes")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := "A very long product name that does not fit"
+
+	if got := Truncate(Helvetica, 10, "Short", 100); got != "Short" {
+		t.Errorf("Truncate of fitting text = %q, want it unchanged", got)
+	}
+
+	got := Truncate(Helvetica, 10, long, 100)
+	if got == long || len(got) < 4 || got[len(got)-3:] != "..." {
+		t.Errorf("Truncate = %q, want a shortened name ending in ...", got)
+	}
+	if w := TextWidth(Helvetica, 10, got); w > 100 {
+		t.Errorf("truncated text is %.1fpt wide, want at most 100", w)
+	}
+}
@@ -0,0 +1,70 @@
+// Package signature signs and verifies webhook bodies with HMAC-SHA256.
+package signature
+
+import (
+	model "cart-order-service/repository/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// tolerance bounds how old a signed webhook may be, to limit replays.
+const tolerance = 5 * time.Minute
+
+// Headers names the headers a webhook sender puts its signature in.
+// The signature header carries the hex HMAC-SHA256 of "<timestamp>.<body>";
+// the timestamp header carries the Unix time the webhook was signed at.
+type Headers struct {
+	Signature string
+	Timestamp string
+}
+
+// Sign returns the webhook signature of body signed at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the signature headers for body signed at t.
+func (h Headers) Sign(secret []byte, body []byte, t time.Time) http.Header {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	header := http.Header{}
+	header.Set(h.Timestamp, timestamp)
+	header.Set(h.Signature, Sign(secret, timestamp, body))
+	return header
+}
+
+// Verify checks the signature headers of a webhook body against secret.
+// It returns model.ErrInvalidSignature if the signature is missing, wrong or too old.
+func (h Headers) Verify(secret []byte, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(h.Timestamp)
+	signature := header.Get(h.Signature)
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("%w: missing signature headers", model.ErrInvalidSignature)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", model.ErrInvalidSignature)
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", model.ErrInvalidSignature)
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return model.ErrInvalidSignature
+	}
+
+	return nil
+}
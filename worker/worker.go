@@ -0,0 +1,55 @@
+// Package worker runs background jobs on a fixed interval.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Job is a unit of background work. It should return once ctx is done.
+type Job func(ctx context.Context) error
+
+// Worker runs a Job every interval until its context is cancelled.
+type Worker struct {
+	name     string
+	interval time.Duration
+	job      Job
+	logger   zerolog.Logger
+}
+
+// NewWorker is a constructor function that returns a new Worker.
+func NewWorker(name string, interval time.Duration, job Job, logger zerolog.Logger) *Worker {
+	return &Worker{
+		name:     name,
+		interval: interval,
+		job:      job,
+		logger:   logger,
+	}
+}
+
+// Run runs the job once straight away and then on every tick, until ctx is cancelled.
+// A failed run is logged and retried on the next tick.
+func (w *Worker) Run(ctx context.Context) {
+	logMsgStr := fmt.Sprintf("Worker:%v - Run:", w.name)
+
+	w.logger.Info().Msg(fmt.Sprintf("%v Started, running every %v", logMsgStr, w.interval))
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.job(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error().Any("Err", err).Msg(fmt.Sprintf("%v Job failed", logMsgStr))
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg(fmt.Sprintf("%v Stopped", logMsgStr))
+			return
+		case <-ticker.C:
+		}
+	}
+}